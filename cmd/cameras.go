@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// cameraPrefix marks the first field of a command as the name of the camera to send the command to.
+	cameraPrefix = "@"
+	// allCameras is the camera name used to send a command to all cameras at once.
+	allCameras = "all"
+)
+
+// newManager creates a client for each configured camera and adds it to a new manager under the camera name.
+func newManager() (*ip.Manager, error) {
+	m := ip.NewManager()
+	store := identityStore()
+	cams := conf.cameras()
+	for _, r := range cams {
+		c, err := ip.NewClient(r.vendor, r.host, uint16(r.port), conf.fname, r.guid, verbosity)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.name, err)
+		}
+
+		c.SetMetrics(ip.NewMetrics())
+		if store != nil {
+			c.SetIdentityStore(store)
+		}
+		name := r.name
+		c.SetPairingHandler(func(ps ip.PairingState) {
+			fmt.Print(pairingMessage(name, ps))
+		})
+		if r.laddr != "" {
+			c.SetLocalAddress(r.laddr)
+		}
+		if r.iface != "" {
+			c.SetInterface(r.iface)
+		}
+		if r.cport != 0 {
+			c.SetCommandDataPort(uint16(r.cport))
+		}
+		if r.eport != 0 {
+			c.SetEventPort(uint16(r.eport))
+		}
+		if r.sport != 0 {
+			c.SetStreamerPort(uint16(r.sport))
+		}
+		for s, l := range subsystemVerbosity {
+			c.SetSubsystemLogLevel(s, l)
+		}
+
+		if err := m.Add(r.name, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// identityStore returns the store used to remember the initiator identity per responder or nil when remembering
+// identities is not enabled or the default store path cannot be determined.
+func identityStore() ip.IdentityStore {
+	if !conf.rememberId {
+		return nil
+	}
+
+	path := conf.idStore
+	if path == "" {
+		p, err := ip.DefaultIdentityStorePath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Not remembering initiator identities - %s\n", err)
+			return nil
+		}
+		path = p
+	}
+
+	return ip.NewFileIdentityStore(path)
+}
+
+// pairingMessage returns the message informing the user about the pairing state of the given camera.
+func pairingMessage(name string, ps ip.PairingState) string {
+	switch ps {
+	case ip.PairingPending:
+		return fmt.Sprintf("Camera '%s' is waiting for the connection to be accepted: please press 'OK' on the camera.\n", name)
+	case ip.PairingAccepted:
+		return fmt.Sprintf("Connection accepted by camera '%s'.\n", name)
+	}
+
+	return fmt.Sprintf("Connection not accepted by camera '%s'.\n", name)
+}
+
+// detectProfiles selects the model profile from the device info for each connected Fuji camera whose friendly name did
+// not match any profile.
+func detectProfiles(m *ip.Manager) {
+	for _, name := range m.Names() {
+		c, _ := m.Client(name)
+		if c.ResponderVendor() != ptp.VE_FujiPhotoFilmCoLtd || ip.FujiProfileForName(c.ResponderFriendlyName()) != nil {
+			continue
+		}
+		p, err := ip.FujiDetectProfile(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to detect the model of camera '%s' - %s\n", name, err)
+			continue
+		}
+		if p == nil {
+			fmt.Fprintf(os.Stderr, "Unknown model for camera '%s', assuming %s.\n", name, ip.FujiGetProfile(c).Name)
+		}
+	}
+}
+
+// viewfinderLayout returns the name of the viewfinder layout to use for the given camera. An empty string means the
+// vendor default will be used.
+func viewfinderLayout(c *ip.Client) string {
+	if c.ResponderVendor() == ptp.VE_FujiPhotoFilmCoLtd {
+		return ip.FujiGetProfile(c).Viewfinder
+	}
+
+	return ""
+}
+
+// openTraces enables the packet trace for all cameras. When there is more than one camera, each camera gets its own
+// trace file named after the camera.
+func openTraces(m *ip.Manager, path string) ([]io.Closer, error) {
+	var files []io.Closer
+	for _, name := range m.Names() {
+		p := path
+		if m.Len() > 1 {
+			p = cameraFileName(path, name)
+		}
+		f, err := os.Create(p)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, f)
+
+		c, _ := m.Client(name)
+		c.SetPacketTrace(ip.NewPacketTrace(f))
+	}
+
+	return files, nil
+}
+
+// cameraFileName adds the camera name to the base name of the given file path.
+func cameraFileName(path, name string) string {
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
+}
+
+// splitTarget splits the camera name from the command fields. The name is empty when no camera was addressed.
+func splitTarget(f []string) (string, []string) {
+	if len(f) > 0 && strings.HasPrefix(f[0], cameraPrefix) {
+		return strings.TrimPrefix(f[0], cameraPrefix), f[1:]
+	}
+
+	return "", f
+}
+
+// dispatch executes the command in the given fields on the addressed camera. When no camera is addressed, the command
+// is sent to the first camera.
+func dispatch(m *ip.Manager, f []string, asyncOut chan<- string) string {
+	target, f := splitTarget(f)
+	if len(f) == 0 {
+		return "missing command\n"
+	}
+
+	cmd := commandByName(f[0])
+	switch target {
+	case allCameras:
+		if _, ok := cmd.(*capture); ok {
+			return captureAll(m, f[1:], asyncOut)
+		}
+		return broadcastCommand(m, cmd, f[1:])
+	case "":
+		names := m.Names()
+		if len(names) == 0 {
+			return "no cameras configured\n"
+		}
+		target = names[0]
+	}
+
+	c, err := m.Client(target)
+	if err != nil {
+		return err.Error() + "\n"
+	}
+
+	return cmd.execute(c, f[1:], asyncOut)
+}
+
+// broadcastCommand executes the command on all cameras in parallel. The output of each camera is prefixed with the
+// camera name.
+func broadcastCommand(m *ip.Manager, cmd command, f []string) string {
+	var (
+		mu  sync.Mutex
+		out = make(map[string]string)
+	)
+	res := m.Broadcast(func(name string, c *ip.Client) error {
+		var lines []string
+		asyncOut := make(chan string)
+		done := make(chan struct{})
+		go func() {
+			for l := range asyncOut {
+				lines = append(lines, l)
+			}
+			close(done)
+		}()
+
+		s := cmd.execute(c, f, asyncOut)
+		close(asyncOut)
+		<-done
+
+		mu.Lock()
+		out[name] = strings.Join(append(lines, s), "\n")
+		mu.Unlock()
+
+		return nil
+	})
+
+	var b strings.Builder
+	for _, r := range res {
+		b.WriteString(prefixLines(r.Name, out[r.Name]))
+	}
+
+	return b.String()
+}
+
+// captureAll releases the shutter of all cameras simultaneously and reports the timing per camera. The capture
+// previews are saved to a file per camera when a file path is given.
+func captureAll(m *ip.Manager, f []string, asyncOut chan<- string) string {
+	var path string
+	view := false
+	if len(f) >= 1 {
+		if view = (capture{}).isView(f[0]); !view {
+			path = f[0]
+		}
+	}
+
+	crs := m.Capture()
+
+	var b strings.Builder
+	for _, cr := range crs {
+		if cr.Err != nil {
+			b.WriteString(prefixLines(cr.Name, cr.Err.Error()))
+			continue
+		}
+
+		s := fmt.Sprintf("captured in %s", cr.Duration)
+		if !cr.Released.IsZero() {
+			s += fmt.Sprintf(", released at %s with a skew of %s", cr.Released.Format("15:04:05.000000"), cr.Skew)
+		} else {
+			s += ", skew unknown"
+		}
+		switch {
+		case view && cr.Preview != nil:
+			asyncOut <- preview(cr.Preview)
+		case path != "" && cr.Preview != nil:
+			file := cameraFileName(path, cr.Name)
+			if err := ioutil.WriteFile(file, cr.Preview, 0644); err != nil {
+				s += "\n" + err.Error()
+			} else {
+				s += "\nImage preview saved to " + file
+			}
+		}
+		b.WriteString(prefixLines(cr.Name, s))
+	}
+	fmt.Fprintf(&b, "Maximum skew between cameras: %s\n", ip.MaxSkew(crs))
+
+	return b.String()
+}
+
+// prefixLines prefixes each line of s with the camera name.
+func prefixLines(name, s string) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+
+	prefix := "[" + name + "] "
+
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix) + "\n"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitTarget(t *testing.T) {
+	target, f := splitTarget([]string{"@left", "get", "0x5001"})
+	if target != "left" || len(f) != 2 || f[0] != "get" {
+		t.Errorf("splitTarget() got = %s, %v; want left, [get 0x5001]", target, f)
+	}
+
+	target, f = splitTarget([]string{"get", "0x5001"})
+	if target != "" || len(f) != 2 {
+		t.Errorf("splitTarget() got = %s, %v; want '', [get 0x5001]", target, f)
+	}
+}
+
+func TestPrefixLines(t *testing.T) {
+	got := prefixLines("left", "line 1\nline 2\n")
+	want := "[left] line 1\n[left] line 2\n"
+	if got != want {
+		t.Errorf("prefixLines() got = '%s'; want '%s'", got, want)
+	}
+
+	if got := prefixLines("left", "\n"); got != "" {
+		t.Errorf("prefixLines() got = '%s'; want ''", got)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	m := ip.NewManager()
+	for _, n := range []string{"left", "right"} {
+		c, err := ip.NewClient(ip.DefaultVendor, ip.DefaultIpAddress, ip.DefaultPort, "", "", ip.LevelSilent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Add(n, c)
+	}
+
+	tests := []struct {
+		f    []string
+		want string
+	}{
+		{[]string{"metrics"}, "metrics are not enabled\n"},
+		{[]string{"@right", "metrics"}, "metrics are not enabled\n"},
+		{[]string{"@all", "metrics"}, "[left] metrics are not enabled\n[right] metrics are not enabled\n"},
+		{[]string{"@middle", "metrics"}, "unknown camera 'middle'\n"},
+		{[]string{"@left"}, "missing command\n"},
+	}
+	for _, tt := range tests {
+		if got := dispatch(m, tt.f, make(chan string)); got != tt.want {
+			t.Errorf("dispatch(%v) got = '%s'; want '%s'", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestCaptureAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ptpip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := ip.NewManager()
+	for _, n := range []string{"left", "right"} {
+		r := iptest.NewResponder("fuji")
+		defer r.Close()
+		r.SetCapturePreview([]byte(n))
+
+		c, err := r.NewClient(n, "", ip.LevelSilent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Dial(); err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		m.Add(n, c)
+	}
+
+	got := dispatch(m, []string{"@all", "capture", filepath.Join(dir, "rig.jpg")}, make(chan string))
+	for _, want := range []string{"[left] captured in ", "[right] captured in ", "Maximum skew between cameras: "} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dispatch() got = '%s'; want it to contain '%s'", got, want)
+		}
+	}
+
+	for _, n := range []string{"left", "right"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "rig-"+n+".jpg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != n {
+			t.Errorf("preview %s got = %s; want %s", n, b, n)
+		}
+	}
+}
+
+func TestPairingMessage(t *testing.T) {
+	check := map[ip.PairingState]string{
+		ip.PairingPending:  "Camera 'left' is waiting for the connection to be accepted: please press 'OK' on the camera.\n",
+		ip.PairingAccepted: "Connection accepted by camera 'left'.\n",
+		ip.PairingFailed:   "Connection not accepted by camera 'left'.\n",
+	}
+
+	for ps, want := range check {
+		if got := pairingMessage("left", ps); got != want {
+			t.Errorf("pairingMessage() got = '%s'; want '%s'", got, want)
+		}
+	}
+}
+
+func TestIdentityStore(t *testing.T) {
+	orig := conf
+	defer func() { conf = orig }()
+
+	conf = &config{idStore: "identities.json"}
+	if s := identityStore(); s != nil {
+		t.Errorf("identityStore() got = %v; want <nil>", s)
+	}
+
+	conf.rememberId = true
+	if s := identityStore(); s == nil {
+		t.Error("identityStore() got = <nil>; want a store")
+	}
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"fmt"
 	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
 	"io/ioutil"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 func init() {
@@ -26,23 +28,42 @@ func (capture) alias() []string {
 
 func (cap capture) execute(c *ip.Client, f []string, asyncOut chan<- string) string {
 	amount := 1
+	var bulb time.Duration
 	if len(f) >= 1 {
 		if val, err := strconv.Atoi(f[0]); err == nil {
 			amount = val
 			f = f[1:] // drop processed amount argument
+		} else if val, err := time.ParseDuration(f[0]); err == nil {
+			bulb = val
+			f = f[1:] // drop processed duration argument
 		}
 	}
 
+	var (
+		full  string
+		saved int
+	)
+	if len(f) >= 1 && cap.isFull(f[0]) {
+		if len(f) < 2 || cap.isView(f[1]) {
+			return fmt.Sprintf("%s requires a %s\n", cap.arguments()[4], cap.arguments()[2])
+		}
+		if c.ResponderVendor() != ptp.VE_FujiPhotoFilmCoLtd || bulb > 0 {
+			return fmt.Sprintf("%s is not supported for this capture\n", cap.arguments()[4])
+		}
+		full = strings.TrimSuffix(f[1], filepath.Ext(f[1]))
+		f = nil
+	}
+
 	var (
 		imgs chan []byte
 		wg   sync.WaitGroup
 	)
 	if len(f) >= 1 {
 		imgs = make(chan []byte, 10)
-		var path string
+		var path, ext string
 		if !cap.isView(f[0]) {
-			ext := filepath.Ext(f[0])
-			path = strings.TrimSuffix(f[0], ext) + "-%d" + ext
+			ext = filepath.Ext(f[0])
+			path = strings.TrimSuffix(f[0], ext)
 		}
 
 		wg.Add(1)
@@ -50,7 +71,7 @@ func (cap capture) execute(c *ip.Client, f []string, asyncOut chan<- string) str
 			i := 1
 			for img := range imgs {
 				if path != "" {
-					file := fmt.Sprintf(path, i)
+					file := fmt.Sprintf("%s-%d%s", path, i, ext)
 					if err := ioutil.WriteFile(file, img, 0644); err != nil {
 						asyncOut <- err.Error()
 						continue
@@ -72,8 +93,22 @@ func (cap capture) execute(c *ip.Client, f []string, asyncOut chan<- string) str
 		if amount > 1 {
 			asyncOut <- fmt.Sprintf("  capturing image %d", i+1)
 		}
-		var err error
-		img, err := c.InitiateCapture()
+		var (
+			img []byte
+			err error
+		)
+		if full != "" {
+			if err := cap.saveFull(c, full, &saved, asyncOut); err != nil {
+				return err.Error()
+			}
+			continue
+		}
+		if bulb > 0 {
+			asyncOut <- fmt.Sprintf("Exposing for %s...", bulb)
+			img, err = c.BulbCapture(bulb)
+		} else {
+			img, err = c.InitiateCapture()
+		}
 		if err != nil {
 			return err.Error()
 		}
@@ -86,6 +121,9 @@ func (cap capture) execute(c *ip.Client, f []string, asyncOut chan<- string) str
 		wg.Wait()
 		return ""
 	}
+	if full != "" {
+		return ""
+	}
 
 	plural := ""
 	if amount > 1 {
@@ -109,6 +147,10 @@ func (cap capture) help() string {
 				help += "\t- " + `"` + arg + `" opens a window to display the capture preview if the camera returns it` + "\n\tOR\n"
 			case 2:
 				help += "\t- a " + arg + " to save the capture preview to\n"
+			case 3:
+				help += "\t- a " + arg + " such as 90s or 5m to hold the shutter open for, instead of the amount. The shutter\n\t  speed must be set to bulb on the camera\n"
+			case 4:
+				help += "\t- " + `"` + arg + `" followed by a filepath saves the full resolution image(s) instead of the preview. When shooting RAW + JPEG, both files are saved. This is currently supported for Fuji only` + "\n"
 			}
 		}
 	}
@@ -117,9 +159,34 @@ func (cap capture) help() string {
 }
 
 func (capture) arguments() []string {
-	return []string{"amount", "view", "filepath"}
+	return []string{"amount", "view", "filepath", "duration", "full"}
 }
 
 func (cap capture) isView(param string) bool {
 	return param == cap.arguments()[1]
 }
+
+func (cap capture) isFull(param string) bool {
+	return param == cap.arguments()[4]
+}
+
+// saveFull captures an image and saves the objects added to the storage using the given path, without extension,
+// followed by a sequence number and the extension matching the contents of each object. The sequence number is taken
+// from saved and incremented for every object so that no file is overwritten, even when the extension is unknown.
+func (capture) saveFull(c *ip.Client, path string, saved *int, asyncOut chan<- string) error {
+	res, err := ip.FujiCapture(c, true)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range res.Objects {
+		*saved++
+		file := fmt.Sprintf("%s-%d%s", path, *saved, o.Extension())
+		if err := ioutil.WriteFile(file, o.Data, 0644); err != nil {
+			return err
+		}
+		asyncOut <- fmt.Sprintf("Image saved to %s", file)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCaptureFull(t *testing.T) {
+	r := iptest.NewResponder("fuji")
+	defer r.Close()
+
+	jpg := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}
+	raf := []byte("FUJIFILMCCD-RAW 0201FF129502")
+	r.SetObject(0x0001, jpg)
+	r.SetObject(0x0002, raf)
+	r.SetDevicePropValue(ip.DPC_Fuji_ImageQuality, []byte{byte(ip.IQ_Fuji_FineAndRAW), 0x00})
+
+	c, err := r.NewClient("capture", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := make(chan string, 10)
+	got := capture{}.execute(c, []string{"full", filepath.Join(dir, "studio.jpg")}, out)
+	if got != "" {
+		t.Errorf("execute() got = '%s'; want ''", got)
+	}
+
+	for file, want := range map[string][]byte{"studio-1.JPG": jpg, "studio-2.RAF": raf} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("execute() error = %s; want %s to be saved", err, file)
+			continue
+		}
+		if !bytes.Equal(b, want) {
+			t.Errorf("execute() %s = %#v; want %#v", file, b, want)
+		}
+	}
+
+	// A percent sign in the path must not be treated as a format verb and objects of an unknown type, which have no
+	// extension, must not overwrite each other.
+	r.SetObject(0x0003, []byte{0xFF, 0xD8, 0x00})
+	r.SetObject(0x0004, []byte{0x00})
+	got = capture{}.execute(c, []string{"full", filepath.Join(dir, "100%")}, out)
+	if got != "" {
+		t.Errorf("execute() got = '%s'; want ''", got)
+	}
+	for file, want := range map[string][]byte{"100%-1.JPG": {0xFF, 0xD8, 0x00}, "100%-2": {0x00}} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("execute() error = %s; want %s to be saved", err, file)
+			continue
+		}
+		if !bytes.Equal(b, want) {
+			t.Errorf("execute() %s = %#v; want %#v", file, b, want)
+		}
+	}
+
+	got = capture{}.execute(c, []string{"full"}, out)
+	want := "full requires a filepath\n"
+	if got != want {
+		t.Errorf("execute() got = '%s'; want '%s'", got, want)
+	}
+}
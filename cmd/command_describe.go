@@ -36,7 +36,7 @@ func (describe) execute(c *ip.Client, f []string, _ chan<- string) string {
 		return fmt.Sprintf(errorFmt, fmt.Sprintf("cannot describe property %#x", cod))
 	}
 
-	return fujiFormatDeviceProperty(res, f[1:])
+	return formatDevicePropDesc(c.ResponderVendor(), res, f[1:])
 }
 
 func (d describe) help() string {
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&focus{})
+}
+
+type focus struct{}
+
+func (focus) name() string {
+	return "focus"
+}
+
+func (focus) alias() []string {
+	return []string{"fp"}
+}
+
+func (fcs focus) execute(c *ip.Client, f []string, _ chan<- string) string {
+	errorFmt := "focus error: %s\n"
+
+	if c.ResponderVendor() != ptp.VE_FujiPhotoFilmCoLtd {
+		return fmt.Sprintf(errorFmt, "command not supported by this vendor")
+	}
+
+	if len(f) == 0 {
+		fp, err := ip.FujiGetFocusPoint(c)
+		if err != nil {
+			return fmt.Sprintf(errorFmt, err)
+		}
+		return fmt.Sprintf("focus point is at %s\n", fp)
+	}
+
+	if f[0] == fcs.arguments()[1] {
+		if err := ip.FujiResetFocusPoint(c); err != nil {
+			return fmt.Sprintf(errorFmt, err)
+		}
+		return "focus point reset\n"
+	}
+
+	x, y, err := fcs.parsePoint(f[0])
+	if err != nil {
+		return fmt.Sprintf(errorFmt, err)
+	}
+	if err := ip.FujiMoveFocusPoint(c, x, y); err != nil {
+		return fmt.Sprintf(errorFmt, err)
+	}
+
+	return fmt.Sprintf("focus point moved to %dx%d\n", x, y)
+}
+
+// parsePoint converts a string in the form of 'x,y' to coordinates.
+func (focus) parsePoint(param string) (uint8, uint8, error) {
+	xy := strings.Split(param, ",")
+	if len(xy) != 2 {
+		return 0, 0, fmt.Errorf("invalid focus point '%s', expected 'x,y'", param)
+	}
+
+	x, err := strconv.ParseUint(strings.TrimSpace(xy[0]), 10, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid x coordinate '%s'", xy[0])
+	}
+	y, err := strconv.ParseUint(strings.TrimSpace(xy[1]), 10, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid y coordinate '%s'", xy[1])
+	}
+
+	return uint8(x), uint8(y), nil
+}
+
+func (fcs focus) help() string {
+	help := `"` + fcs.name() + `" displays or moves the focus point. Without arguments, the current focus point is displayed. This currently is a Fuji specific command!` + "\n"
+	help += helpAddAliases(fcs.alias())
+
+	if args := fcs.arguments(); len(args) > 0 {
+		help += helpAddArgumentsTitle()
+		for i, arg := range args {
+			switch i {
+			case 0:
+				help += "\t- " + arg + ": the coordinates to move the focus point to in the form of 'x,y', e.g. '6,2'\n\tOR\n"
+			case 1:
+				help += "\t- " + `"` + arg + `" to move the focus point back to the centre` + "\n"
+			}
+		}
+	}
+
+	return help
+}
+
+func (focus) arguments() []string {
+	return []string{"x,y", "reset"}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"testing"
+)
+
+func TestFocusParsePoint(t *testing.T) {
+	x, y, err := (focus{}).parsePoint("6,2")
+	if err != nil {
+		t.Errorf("parsePoint() error = %s; want <nil>", err)
+	}
+	if x != 6 || y != 2 {
+		t.Errorf("parsePoint() got = %d,%d; want 6,2", x, y)
+	}
+
+	for _, param := range []string{"6", "6,2,1", "a,2", "6,256"} {
+		if _, _, err := (focus{}).parsePoint(param); err == nil {
+			t.Errorf("parsePoint(%s) error = <nil>; want error", param)
+		}
+	}
+}
+
+func TestFocusExecute(t *testing.T) {
+	r := iptest.NewResponder("fuji")
+	defer r.Close()
+
+	c, err := r.NewClient("focus", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"6,2"}, "focus point moved to 6x2\n"},
+		{[]string{}, "focus point is at 6x2\n"},
+		{[]string{"reset"}, "focus point reset\n"},
+		{[]string{}, "focus point is at 4x4\n"},
+		{[]string{"6"}, "focus error: invalid focus point '6', expected 'x,y'\n"},
+	} {
+		got := focus{}.execute(c, tc.args, make(chan string))
+		if got != tc.want {
+			t.Errorf("execute(%v) got = '%s'; want '%s'", tc.args, got, tc.want)
+		}
+	}
+}
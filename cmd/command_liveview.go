@@ -108,9 +108,11 @@ func liveViewUI(c *ip.Client, withVf bool) error {
 	}
 	defer glfw.Terminate()
 
-	img := <-c.StreamChan
+	frame := <-c.StreamChan
+	img := frame.Data
 	window, err := showImage(img, "Live view")
 	if err != nil {
+		frame.Release()
 		return err
 	}
 
@@ -128,17 +130,22 @@ func liveViewUI(c *ip.Client, withVf bool) error {
 
 		im, _, err := image.Decode(bytes.NewReader(img))
 		if err == nil {
-			vf = viewfinder.NewViewfinder(toRGBA(im), c.ResponderVendor())
+			vf = viewfinder.NewViewfinderForLayout(toRGBA(im), c.ResponderVendor(), viewfinderLayout(c))
 		}
 	} else {
 		ticker.Stop()
 	}
+	frame.Release()
+
+	statsTicker := time.NewTicker(1 * time.Second)
+	defer statsTicker.Stop()
 
 poller:
 	for !window.ShouldClose() {
 		select {
-		case img := <-c.StreamChan:
-			im, _, err := image.Decode(bytes.NewReader(img))
+		case frame := <-c.StreamChan:
+			im, _, err := image.Decode(bytes.NewReader(frame.Data))
+			frame.Release()
 			if err == nil {
 				rgba := toRGBA(im)
 				if vf != nil {
@@ -150,6 +157,8 @@ poller:
 			}
 		case <-ticker.C:
 			s, _ = c.GetDeviceState()
+		case <-statsTicker.C:
+			window.SetTitle("Live view - " + streamStatsString(c.StreamStats()))
 		case <-quit:
 			break poller
 		}
@@ -165,6 +174,11 @@ poller:
 	return nil
 }
 
+// streamStatsString formats the stream statistics to be displayed in the window title.
+func streamStatsString(s ip.StreamStats) string {
+	return fmt.Sprintf("%.1f fps, %d KiB avg, %d dropped, %d missed, %d out of order, %d duplicate", s.FPS, s.AverageSize/1024, s.Dropped, s.Missed, s.OutOfOrder, s.Duplicates)
+}
+
 func toRGBA(img image.Image) *image.RGBA {
 	rgba, ok := img.(*image.RGBA)
 	if !ok {
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/malc0mn/ptp-ip/ip"
+)
+
+func init() {
+	registerCommand(&metrics{})
+}
+
+type metrics struct{}
+
+func (metrics) name() string {
+	return "metrics"
+}
+
+func (metrics) alias() []string {
+	return []string{"stats"}
+}
+
+func (metrics) execute(c *ip.Client, f []string, _ chan<- string) string {
+	m := c.Metrics()
+	if m == nil {
+		return "metrics are not enabled\n"
+	}
+
+	if len(f) > 0 && f[0] == "json" {
+		s := m.String()
+		if len(f) > 1 && f[1] == "pretty" {
+			var b bytes.Buffer
+			if err := json.Indent(&b, []byte(s), "", "  "); err == nil {
+				s = b.String()
+			}
+		}
+		return s + "\n"
+	}
+
+	var b bytes.Buffer
+	if err := m.WritePrometheus(&b); err != nil {
+		return err.Error() + "\n"
+	}
+
+	return b.String()
+}
+
+func (m metrics) help() string {
+	help := `"` + m.name() + `" displays the client metrics such as packets and bytes per channel, transaction latencies, timeouts and live view frame rates. The default output is the Prometheus text format.` + "\n"
+
+	help += helpAddAliases(m.alias())
+
+	if args := m.arguments(); len(args) > 0 {
+		help += helpAddArgumentsTitle()
+		for i, arg := range args {
+			switch i {
+			case 0:
+				help += "\t- " + `"` + arg + `" to output the data in parsable json format` + "\n"
+			case 1:
+				help += "\t- " + `"` + arg + `" to be used together with "` + args[0] + `": format the output in a human readable way` + "\n"
+			}
+		}
+	}
+
+	return help
+}
+
+func (metrics) arguments() []string {
+	return []string{"json", "pretty"}
+}
@@ -29,7 +29,7 @@ func (state) execute(c *ip.Client, f []string, _ chan<- string) string {
 }
 
 func (i state) help() string {
-	help := `"` + i.name() + `" displays the current device state. This currently is a Fuji specific command!` + "\n"
+	help := `"` + i.name() + `" displays the current device state. This currently is supported for Fuji, Sony and Panasonic only!` + "\n"
 
 	if args := i.arguments(); len(args) > 0 {
 		help += helpAddArgumentsTitle()
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	ptpfmt "github.com/malc0mn/ptp-ip/fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is the interval at which the camera state is polled when no interval is passed.
+const defaultWatchInterval = 2 * time.Second
+
+var (
+	// watchers holds the channel stopping the state watcher per client.
+	watchers   = make(map[*ip.Client]chan struct{})
+	watchersMu sync.Mutex
+)
+
+func init() {
+	registerCommand(&watch{})
+}
+
+type watch struct{}
+
+func (watch) name() string {
+	return "watch"
+}
+
+func (watch) alias() []string {
+	return []string{}
+}
+
+func (w watch) execute(c *ip.Client, f []string, _ chan<- string) string {
+	errorFmt := "watch error: %s\n"
+
+	if c.ResponderVendor() != ptp.VE_FujiPhotoFilmCoLtd {
+		return fmt.Sprintf(errorFmt, "command not supported by this vendor")
+	}
+
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	done := watchers[c]
+
+	if len(f) == 0 {
+		if done == nil {
+			return "not watching\n"
+		}
+		return "watching\n"
+	}
+
+	switch f[0] {
+	case w.arguments()[0]:
+		if done != nil {
+			return "already watching!\n"
+		}
+		interval := defaultWatchInterval
+		if len(f) > 1 {
+			d, err := time.ParseDuration(f[1])
+			if err != nil || d <= 0 {
+				return fmt.Sprintf(errorFmt, fmt.Sprintf("invalid interval '%s'", f[1]))
+			}
+			interval = d
+		}
+		done = make(chan struct{})
+		watchers[c] = done
+		name := c.ResponderFriendlyName()
+		go func() {
+			for sc := range ip.FujiWatchState(c, interval, done) {
+				if msg := stateChangeMessage(name, sc); msg != "" {
+					fmt.Print(msg)
+				}
+			}
+		}()
+		return fmt.Sprintf("watching every %s\n", interval)
+	case w.arguments()[1]:
+		if done == nil {
+			return "not watching!\n"
+		}
+		close(done)
+		delete(watchers, c)
+		return "stopped watching\n"
+	}
+
+	return fmt.Sprintf(errorFmt, fmt.Sprintf("unknown argument '%s'", f[0]))
+}
+
+func (w watch) help() string {
+	help := `"` + w.name() + `" watches the camera state and reports device errors, battery level drops, running out of storage and dial mode changes. Without arguments, the watch state is displayed. This currently is a Fuji specific command!` + "\n"
+
+	if args := w.arguments(); len(args) > 0 {
+		help += helpAddArgumentsTitle()
+		for i, arg := range args {
+			switch i {
+			case 0:
+				help += "\t- " + `"` + arg + `" to start watching, optionally followed by the polling interval, e.g. "` + arg + ` 5s"` + "\n\tOR\n"
+			case 1:
+				help += "\t- " + `"` + arg + `" to stop watching` + "\n"
+			}
+		}
+	}
+
+	return help
+}
+
+func (watch) arguments() []string {
+	return []string{"start", "stop"}
+}
+
+// stateChangeMessage returns the message informing the user about the given state change of the given camera. Changes
+// of other properties are not reported, an empty string is returned for those.
+func stateChangeMessage(name string, sc ip.FujiStateChange) string {
+	v := ptpfmt.FujiDevicePropValueAsString(sc.Property, sc.Current)
+
+	switch sc.Kind {
+	case ip.FujiDeviceErrorRaised:
+		return fmt.Sprintf("Camera '%s' reports device error %#x.\n", name, sc.Current)
+	case ip.FujiDeviceErrorCleared:
+		return fmt.Sprintf("Camera '%s' cleared the device error.\n", name)
+	case ip.FujiBatteryLevelDropped:
+		return fmt.Sprintf("Camera '%s' battery level dropped to %s.\n", name, v)
+	case ip.FujiCapturesExhausted:
+		return fmt.Sprintf("Camera '%s' cannot hold any more captures.\n", name)
+	case ip.FujiCommandDialModeChanged, ip.FujiExposureProgramModeChanged:
+		return fmt.Sprintf("Camera '%s' %s changed to %s.\n", name, ptpfmt.FujiDevicePropCodeAsString(sc.Property), v)
+	}
+
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+func TestWatchExecute(t *testing.T) {
+	r := iptest.NewResponder("fuji")
+	defer r.Close()
+
+	c, err := r.NewClient("watch", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{}, "not watching\n"},
+		{[]string{"stop"}, "not watching!\n"},
+		{[]string{"start", "soon"}, "watch error: invalid interval 'soon'\n"},
+		{[]string{"start", "1m"}, "watching every 1m0s\n"},
+		{[]string{"start"}, "already watching!\n"},
+		{[]string{}, "watching\n"},
+		{[]string{"stop"}, "stopped watching\n"},
+		{[]string{"pause"}, "watch error: unknown argument 'pause'\n"},
+	} {
+		got := watch{}.execute(c, tc.args, make(chan string))
+		if got != tc.want {
+			t.Errorf("execute(%v) got = '%s'; want '%s'", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestStateChangeMessage(t *testing.T) {
+	for _, tc := range []struct {
+		sc   ip.FujiStateChange
+		want string
+	}{
+		{ip.FujiStateChange{Kind: ip.FujiDeviceErrorRaised, Property: ip.DPC_Fuji_DeviceError, Current: 0x3}, "Camera 'left' reports device error 0x3.\n"},
+		{ip.FujiStateChange{Kind: ip.FujiDeviceErrorCleared, Property: ip.DPC_Fuji_DeviceError, Previous: 0x3}, "Camera 'left' cleared the device error.\n"},
+		{ip.FujiStateChange{Kind: ip.FujiBatteryLevelDropped, Property: ptp.DPC_BatteryLevel, Current: int64(ip.BAT_Fuji_3bOne)}, "Camera 'left' battery level dropped to 1/3.\n"},
+		{ip.FujiStateChange{Kind: ip.FujiCapturesExhausted, Property: ip.DPC_Fuji_CapturesRemaining}, "Camera 'left' cannot hold any more captures.\n"},
+		{ip.FujiStateChange{Kind: ip.FujiCommandDialModeChanged, Property: ip.DPC_Fuji_CommandDialMode, Current: int64(ip.CMD_Fuji_None)}, "Camera 'left' command dial mode changed to none.\n"},
+		{ip.FujiStateChange{Kind: ip.FujiPropertyChanged, Property: ip.DPC_Fuji_FilmSimulation, Current: int64(ip.FS_Fuji_Velvia)}, ""},
+	} {
+		if got := stateChangeMessage("left", tc.sc); got != tc.want {
+			t.Errorf("stateChangeMessage() got = '%s'; want '%s'", got, tc.want)
+		}
+	}
+}
@@ -63,7 +63,7 @@ func helpAddUnifiedFieldNames() string {
 	return "\t" + `  "` + strings.Join(ptpfmt.UnifiedFieldNames, `", "`) + `"` + "\n"
 }
 
-func readAndExecuteCommand(rw *bufio.ReadWriter, c *ip.Client, lmp string) {
+func readAndExecuteCommand(rw *bufio.ReadWriter, m *ip.Manager, lmp string) {
 	msg, err := rw.ReadString('\n')
 	if err != nil {
 		log.Printf("%s error reading message '%s'", lmp, err)
@@ -76,10 +76,10 @@ func readAndExecuteCommand(rw *bufio.ReadWriter, c *ip.Client, lmp string) {
 	}
 	log.Printf("%s message received: '%s'", lmp, msg)
 
-	executeCommand(msg, rw.Writer, c, lmp)
+	executeCommand(msg, rw.Writer, m, lmp)
 }
 
-func executeCommand(msg string, w *bufio.Writer, c *ip.Client, lmp string) {
+func executeCommand(msg string, w *bufio.Writer, m *ip.Manager, lmp string) {
 	var wg sync.WaitGroup
 	f := strings.Fields(msg)
 	asyncOut := make(chan string)
@@ -99,7 +99,7 @@ func executeCommand(msg string, w *bufio.Writer, c *ip.Client, lmp string) {
 		wg.Done()
 	}()
 
-	_, err := w.Write([]byte(commandByName(f[0]).execute(c, f[1:], asyncOut)))
+	_, err := w.Write([]byte(dispatch(m, f, asyncOut)))
 	close(asyncOut)
 	wg.Wait()
 	if err != nil {
@@ -10,16 +10,20 @@ func TestCommandByName(t *testing.T) {
 	cmds := map[string]command{
 		"capture":  &capture{},
 		"describe": &describe{},
+		"focus":    &focus{},
+		"fp":       &focus{},
 		"get":      &get{},
 		"help":     &help{},
 		"info":     &info{},
 		"liveview": &liveview{},
+		"metrics":  &metrics{},
 		"opreq":    &opreq{},
 		"shoot":    &capture{},
 		"shutter":  &capture{},
 		"snap":     &capture{},
 		"set":      &set{},
 		"state":    &state{},
+		"stats":    &metrics{},
 	}
 	for name, want := range cmds {
 		got := commandByName(name)
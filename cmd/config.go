@@ -18,11 +18,34 @@ type config struct {
 	sport  uint16Value
 	fname  string
 	guid   string
+	laddr  string
+	iface  string
+
+	rememberId bool
+	idStore    string
 
 	srvAddr string
 	srvPort uint16Value
+
+	responders []responderConfig
+}
+
+// responderConfig holds the settings of a single camera.
+type responderConfig struct {
+	name   string
+	vendor string
+	host   string
+	port   uint16Value
+	cport  uint16Value
+	eport  uint16Value
+	sport  uint16Value
+	guid   string
+	laddr  string
+	iface  string
 }
 
+const defaultCamera = "camera1"
+
 var (
 	portSpecAmbiguous = errors.New("ambiguous port specification: use a single port OR define multiple ports")
 
@@ -36,7 +59,7 @@ var (
 )
 
 func loadConfig() {
-	f, err := ini.Load(file)
+	f, err := ini.LoadSources(ini.LoadOptions{AllowNonUniqueSections: true}, file)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening config file - %s\n", err)
 		os.Exit(errOpenConfig)
@@ -50,38 +73,38 @@ func loadConfig() {
 		if k, err := i.GetKey("guid"); err == nil {
 			conf.guid = k.String()
 		}
-	}
-
-	// Responder
-	if i, err := f.GetSection("responder"); err == nil {
-		if k, err := i.GetKey("vendor"); err == nil {
-			conf.vendor = k.String()
-		}
-		if k, err := i.GetKey("host"); err == nil {
-			conf.host = k.String()
+		if k, err := i.GetKey("local_address"); err == nil {
+			conf.laddr = k.String()
 		}
-		if k, err := i.GetKey("port"); err == nil {
-			if err := conf.port.Set(k.String()); err != nil {
-				log.Fatal(valueOutOfRange)
-			}
-		}
-		if k, err := i.GetKey("cmd_data_port"); err == nil {
-			if err := conf.cport.Set(k.String()); err != nil {
-				log.Fatal(valueOutOfRange)
-			}
+		if k, err := i.GetKey("interface"); err == nil {
+			conf.iface = k.String()
 		}
-		if k, err := i.GetKey("event_port"); err == nil {
-			if err := conf.eport.Set(k.String()); err != nil {
-				log.Fatal(valueOutOfRange)
+		if k, err := i.GetKey("remember_identity"); err == nil {
+			if v, err := k.Bool(); err == nil {
+				conf.rememberId = v
 			}
 		}
-		if k, err := i.GetKey("stream_port"); err == nil {
-			if err := conf.sport.Set(k.String()); err != nil {
-				log.Fatal(valueOutOfRange)
-			}
+		if k, err := i.GetKey("identity_store"); err == nil {
+			conf.idStore = k.String()
 		}
 	}
 
+	// Responders: multiple responder sections are allowed to control several cameras at once.
+	secs, _ := f.SectionsByName("responder")
+	base := conf.responder("")
+	conf.responders = nil
+	for n, sec := range secs {
+		r := base
+		r.name = fmt.Sprintf("camera%d", n+1)
+		loadResponder(sec, &r)
+		conf.responders = append(conf.responders, r)
+	}
+	if len(conf.responders) > 0 {
+		// The first responder is the default camera.
+		r := conf.responders[0]
+		conf.vendor, conf.host, conf.port, conf.cport, conf.eport, conf.sport = r.vendor, r.host, r.port, r.cport, r.eport, r.sport
+	}
+
 	// Server
 	if i, err := f.GetSection("server"); err == nil {
 		if k, err := i.GetKey("enabled"); err == nil {
@@ -100,6 +123,73 @@ func loadConfig() {
 	}
 }
 
+func loadResponder(sec *ini.Section, r *responderConfig) {
+	if k, err := sec.GetKey("name"); err == nil {
+		r.name = k.String()
+	}
+	if k, err := sec.GetKey("vendor"); err == nil {
+		r.vendor = k.String()
+	}
+	if k, err := sec.GetKey("host"); err == nil {
+		r.host = k.String()
+	}
+	if k, err := sec.GetKey("guid"); err == nil {
+		r.guid = k.String()
+	}
+	if k, err := sec.GetKey("local_address"); err == nil {
+		r.laddr = k.String()
+	}
+	if k, err := sec.GetKey("interface"); err == nil {
+		r.iface = k.String()
+	}
+	if k, err := sec.GetKey("port"); err == nil {
+		if err := r.port.Set(k.String()); err != nil {
+			log.Fatal(valueOutOfRange)
+		}
+	}
+	if k, err := sec.GetKey("cmd_data_port"); err == nil {
+		if err := r.cport.Set(k.String()); err != nil {
+			log.Fatal(valueOutOfRange)
+		}
+	}
+	if k, err := sec.GetKey("event_port"); err == nil {
+		if err := r.eport.Set(k.String()); err != nil {
+			log.Fatal(valueOutOfRange)
+		}
+	}
+	if k, err := sec.GetKey("stream_port"); err == nil {
+		if err := r.sport.Set(k.String()); err != nil {
+			log.Fatal(valueOutOfRange)
+		}
+	}
+}
+
+// responder returns the responder settings from the command line flags.
+func (c *config) responder(name string) responderConfig {
+	return responderConfig{
+		name:   name,
+		vendor: c.vendor,
+		host:   c.host,
+		port:   c.port,
+		cport:  c.cport,
+		eport:  c.eport,
+		sport:  c.sport,
+		guid:   c.guid,
+		laddr:  c.laddr,
+		iface:  c.iface,
+	}
+}
+
+// cameras returns the settings of all configured cameras. When no responder sections were loaded from a config file, a
+// single camera is returned using the command line flags.
+func (c *config) cameras() []responderConfig {
+	if len(c.responders) == 0 {
+		return []responderConfig{c.responder(defaultCamera)}
+	}
+
+	return c.responders
+}
+
 func checkPorts() {
 	if conf.cport != 0 && conf.eport != 0 {
 		conf.port = 0
@@ -108,4 +198,15 @@ func checkPorts() {
 	if conf.port != 0 && (conf.cport != 0 || conf.eport != 0) {
 		log.Fatal(portSpecAmbiguous)
 	}
+
+	for i := range conf.responders {
+		r := &conf.responders[i]
+		if r.cport != 0 && r.eport != 0 {
+			r.port = 0
+		}
+
+		if r.port != 0 && (r.cport != 0 || r.eport != 0) {
+			log.Fatalf("%s: %s", r.name, portSpecAmbiguous)
+		}
+	}
 }
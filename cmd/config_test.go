@@ -193,3 +193,40 @@ func TestLoadConfigFail2(t *testing.T) {
 		t.Fatalf("loadConfig() ran with err %v, want exit status %d", err, want)
 	}
 }
+
+func TestLoadConfigMulti(t *testing.T) {
+	conf = &config{
+		vendor:  ip.DefaultVendor,
+		host:    ip.DefaultIpAddress,
+		port:    uint16Value(ip.DefaultPort),
+		srvAddr: defaultIp,
+		srvPort: uint16Value(ip.DefaultPort),
+	}
+
+	file = "testdata/test_multi.conf"
+	loadConfig()
+	checkPorts()
+
+	want := []responderConfig{
+		{name: "left", vendor: "fuji", host: "192.168.0.2", port: 15740, guid: "cca455de-79ac-4b12-9731-91e433a899cf"},
+		{name: "right", vendor: "fuji", host: "fe80::1%wlan1", cport: 55740, eport: 55741, guid: "9fe5160c-4951-404d-9505-10baaf725606", iface: "wlan1"},
+		{name: "camera3", vendor: "generic", host: "camera3.lan", port: 15740, guid: "cca455de-79ac-4b12-9731-91e433a899cf", laddr: "192.168.0.10"},
+	}
+	got := conf.cameras()
+	if len(got) != len(want) {
+		t.Fatalf("loadConfig() responders = %d; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("loadConfig() responder %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+
+	if conf.host != want[0].host {
+		t.Errorf("loadConfig() host = %s; want %s", conf.host, want[0].host)
+	}
+
+	if !conf.rememberId {
+		t.Errorf("loadConfig() rememberId = %t; want true", conf.rememberId)
+	}
+}
@@ -24,7 +24,9 @@ var (
 	showHelp    bool
 	showVersion bool
 
-	verbosity ip.LogLevel
+	verbosity          ip.LogLevel
+	subsystemVerbosity ip.SubsystemLevels
+	traceFile          string
 )
 
 // Custom flag type that will only accept uint16 values, ideal for ports!
@@ -46,13 +48,17 @@ func (i *uint16Value) String() string {
 
 func initFlags() {
 	flag.StringVar(&conf.vendor, "t", ip.DefaultVendor, "The vendor of the responder that will be connected to.")
-	flag.StringVar(&conf.host, "h", ip.DefaultIpAddress, "The responder host to connect to.")
+	flag.StringVar(&conf.host, "h", ip.DefaultIpAddress, "The responder host to connect to: an IPv4 or IPv6 address, optionally including a zone, or a host name.")
 	flag.Var(&conf.port, "p", "The responder port to connect to. Use this flag when the responder has only ONE port for all channels!")
 	flag.Var(&conf.cport, "pc", "The responder port used for the Command/Data connection.")
 	flag.Var(&conf.eport, "pe", "The responder port used for the Event connection.")
 	flag.Var(&conf.sport, "ps", "The responder port used for the streamer or 'live view' connection.")
 	flag.StringVar(&conf.fname, "n", "", "A custom friendly name to use for the initiator.")
 	flag.StringVar(&conf.guid, "g", "", "A custom GUID to use for the initiator. (default random)")
+	flag.StringVar(&conf.laddr, "la", "", "The local IP address to connect to the responder from.")
+	flag.StringVar(&conf.iface, "if", "", "The local network interface to connect to the responder from, e.g. when several Wi-Fi adapters are present.")
+	flag.BoolVar(&conf.rememberId, "ri", false, "Remember the initiator identity per responder so a Fuji responder does not prompt to accept the connection again when no GUID is given.")
+	flag.StringVar(&conf.idStore, "id", "", "To be used in combination with '-ri': the file used to remember the initiator identities in. (default <user config dir>/ptpip/identities.json)")
 
 	flag.BoolVar(&interactive, "i", false, fmt.Sprintf("This will run the %s command with an interactive shell.", exe))
 
@@ -67,6 +73,8 @@ func initFlags() {
 	flag.BoolVar(&showVersion, "version", false, "Display version info.")
 
 	flag.Var(&verbosity, "v", "PTP/IP log level verbosity: ranges from v to vvv.")
+	flag.Var(&subsystemVerbosity, "vs", "PTP/IP log level verbosity per subsystem, e.g. 'cmddata=vvv,stream=v'. Subsystems are cmddata, event, stream and vendor.")
+	flag.StringVar(&traceFile, "trace", "", "Write an annotated hex dump of every PTP/IP packet to this file.")
 
 	// Set a custom usage function.
 	flag.Usage = printUsage
@@ -34,54 +34,60 @@ func formatDeviceProperty(c *ip.Client, param string) (ptp.DevicePropCode, error
 
 func formatDeviceInfo(vendor ptp.VendorExtension, data interface{}, f []string) string {
 	switch vendor {
-	case ptp.VE_FujiPhotoFilmCoLtd:
-		return fujiFormatDeviceInfo(data.([]*ptp.DevicePropDesc), f)
+	case ptp.VE_FujiPhotoFilmCoLtd, ptp.VE_SonyCorporation, ptp.VE_PanasonicCorporation:
+		list, ok := data.([]*ptp.DevicePropDesc)
+		if !ok {
+			return fmt.Sprint(data)
+		}
+		return formatDevicePropDescList(vendor, list, f)
 	default:
 		// TODO: add generic device info formatting.
 		return ""
 	}
 }
 
-func fujiFormatDeviceProperty(dpd *ptp.DevicePropDesc, f []string) string {
+func formatDevicePropDesc(vendor ptp.VendorExtension, dpd *ptp.DevicePropDesc, f []string) string {
 	if len(f) >= 1 && f[0] == "json" {
 		var opt string
 		if len(f) > 1 {
 			opt = f[1]
 		}
 
-		return fujiFormatJson(&ptpfmt.DevicePropDescJSON{
+		return formatJson(&ptpfmt.DevicePropDescJSON{
 			DevicePropDesc: dpd,
+			Vendor:         vendor,
 		}, opt)
 	}
 
-	return fujiFormatTable(dpd)
+	return formatTable(vendor, dpd)
 }
 
-func fujiFormatDeviceInfo(list []*ptp.DevicePropDesc, f []string) string {
+func formatDevicePropDescList(vendor ptp.VendorExtension, list []*ptp.DevicePropDesc, f []string) string {
 	if len(f) >= 1 && f[0] == "json" {
 		var opt string
 		if len(f) > 1 {
 			opt = f[1]
 		}
 
-		return fujiFormatJsonList(list, opt)
+		return formatJsonList(vendor, list, opt)
 	}
 
-	return fujiFormatListAsTable(list)
+	return formatListAsTable(vendor, list)
 }
 
-func fujiFormatJsonList(list []*ptp.DevicePropDesc, opt string) string {
+func formatJsonList(vendor ptp.VendorExtension, list []*ptp.DevicePropDesc, opt string) string {
 	lj := make([]*ptpfmt.DevicePropDescJSON, len(list))
 	for i := 0; i < len(list); i++ {
 		lj[i] = &ptpfmt.DevicePropDescJSON{
 			DevicePropDesc: list[i],
+			Vendor:         vendor,
 		}
 	}
 
-	return fujiFormatJson(lj, opt)
+	return formatJson(lj, opt)
 }
 
-func fujiFormatJson(v interface{}, opt string) string {
+func formatJson(v interface{}, opt string) string {
 	var err error
 	var res []byte
 	if opt == "pretty" {
@@ -96,20 +102,20 @@ func fujiFormatJson(v interface{}, opt string) string {
 	return string(res)
 }
 
-func fujiFormatTable(dpd *ptp.DevicePropDesc) string {
+func formatTable(vendor ptp.VendorExtension, dpd *ptp.DevicePropDesc) string {
 	w, buf := newTabWriter()
 	rows := longHeader()
-	rows = append(rows, longPropDescFormat(dpd))
+	rows = append(rows, longPropDescFormat(vendor, dpd))
 	formatRows(w, rows)
 
 	return "\n" + buf.String()
 }
 
-func fujiFormatListAsTable(list []*ptp.DevicePropDesc) string {
+func formatListAsTable(vendor ptp.VendorExtension, list []*ptp.DevicePropDesc) string {
 	w, buf := newTabWriter()
 	rows := shortHeader()
 	for _, dpd := range list {
-		rows = append(rows, shortPropDescFormat(dpd))
+		rows = append(rows, shortPropDescFormat(vendor, dpd))
 	}
 	formatRows(w, rows)
 
@@ -129,11 +135,11 @@ func shortHeader() [][]string {
 	}
 }
 
-func shortPropDescFormat(dpd *ptp.DevicePropDesc) []string {
+func shortPropDescFormat(vendor ptp.VendorExtension, dpd *ptp.DevicePropDesc) []string {
 	return []string{
 		fmt.Sprintf("%0#4x", dpd.DevicePropertyCode),
-		ptpfmt.FujiDevicePropCodeAsString(dpd.DevicePropertyCode),
-		ptpfmt.FujiDevicePropValueAsString(dpd.DevicePropertyCode, dpd.CurrentValueAsInt64()),
+		ptpfmt.VendorDevicePropCodeAsString(vendor, dpd.DevicePropertyCode),
+		ptpfmt.DevicePropValAsString(vendor, dpd.DevicePropertyCode, dpd.CurrentValueAsInt64()),
 		strconv.FormatInt(dpd.CurrentValueAsInt64(), 10),
 		fmt.Sprintf("%0#8x", dpd.CurrentValueAsInt64()),
 	}
@@ -146,7 +152,7 @@ func longHeader() [][]string {
 	}
 }
 
-func longPropDescFormat(dpd *ptp.DevicePropDesc) []string {
+func longPropDescFormat(vendor ptp.VendorExtension, dpd *ptp.DevicePropDesc) []string {
 	var allowed string
 
 	switch form := dpd.Form.(type) {
@@ -166,11 +172,11 @@ func longPropDescFormat(dpd *ptp.DevicePropDesc) []string {
 
 	return []string{
 		fmt.Sprintf("%0#4x", dpd.DevicePropertyCode),
-		ptpfmt.FujiDevicePropCodeAsString(dpd.DevicePropertyCode),
-		ptpfmt.FujiDevicePropValueAsString(dpd.DevicePropertyCode, dpd.FactoryDefaultValueAsInt64()),
+		ptpfmt.VendorDevicePropCodeAsString(vendor, dpd.DevicePropertyCode),
+		ptpfmt.DevicePropValAsString(vendor, dpd.DevicePropertyCode, dpd.FactoryDefaultValueAsInt64()),
 		strconv.FormatInt(dpd.FactoryDefaultValueAsInt64(), 10),
 		fmt.Sprintf("%0#8x", dpd.FactoryDefaultValueAsInt64()),
-		ptpfmt.FujiDevicePropValueAsString(dpd.DevicePropertyCode, dpd.CurrentValueAsInt64()),
+		ptpfmt.DevicePropValAsString(vendor, dpd.DevicePropertyCode, dpd.CurrentValueAsInt64()),
 		strconv.FormatInt(dpd.CurrentValueAsInt64(), 10),
 		fmt.Sprintf("%0#8x", dpd.CurrentValueAsInt64()),
 		allowed,
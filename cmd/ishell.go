@@ -8,7 +8,7 @@ import (
 	"time"
 )
 
-func iShell(c *ip.Client) {
+func iShell(m *ip.Manager) {
 	rw := bufio.NewReadWriter(bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout))
 	fmt.Print("Interactive shell ready to receive commands.\n")
 	for {
@@ -17,7 +17,7 @@ func iShell(c *ip.Client) {
 		time.Sleep(1 * time.Second)
 
 		fmt.Print("> ")
-		readAndExecuteCommand(rw, c, "[iShell]")
+		readAndExecuteCommand(rw, m, "[iShell]")
 		fmt.Print("\n\n")
 	}
 }
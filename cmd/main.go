@@ -2,8 +2,8 @@ package main
 
 import (
 	"bufio"
+	"expvar"
 	"fmt"
-	"github.com/malc0mn/ptp-ip/ip"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -15,6 +15,7 @@ const (
 	errGeneral          = 1
 	errInvalidArgs      = 2
 	errOpenConfig       = 102
+	errOpenTrace        = 103
 	errCreateClient     = 104
 	errResponderConnect = 105
 )
@@ -65,42 +66,54 @@ func main() {
 		close(quit)
 	}()
 
-	client, err := ip.NewClient(conf.vendor, conf.host, uint16(conf.port), conf.fname, conf.guid, verbosity)
+	cams, err := newManager()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating PTP/IP client - %s\n", err)
 		os.Exit(errCreateClient)
 	}
-	defer client.Close()
+	defer cams.Close()
 
-	if conf.cport != 0 {
-		client.SetCommandDataPort(uint16(conf.cport))
+	expvar.Publish("ptpip", cams)
+
+	if traceFile != "" {
+		files, err := openTraces(cams, traceFile)
+		for _, f := range files {
+			defer f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening packet trace file - %s\n", err)
+			os.Exit(errOpenTrace)
+		}
 	}
-	if conf.eport != 0 {
-		client.SetEventPort(uint16(conf.eport))
+
+	for _, name := range cams.Names() {
+		c, _ := cams.Client(name)
+		fmt.Printf("Created new client '%s' with name '%s' and GUID '%s'.\n", name, c.InitiatorFriendlyName(), c.InitiatorGUIDAsString())
+		fmt.Printf("Attempting to connect to %s\n", c.CommandDataAddress())
 	}
-	if conf.sport != 0 {
-		client.SetStreamerPort(uint16(conf.sport))
+	failed := false
+	for _, r := range cams.Dial() {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to responder '%s' - %s\n", r.Name, r.Err)
+			failed = true
+		}
 	}
-
-	fmt.Printf("Created new client with name '%s' and GUID '%s'.\n", client.InitiatorFriendlyName(), client.InitiatorGUIDAsString())
-	fmt.Printf("Attempting to connect to %s\n", client.CommandDataAddress())
-	err = client.Dial()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error connecting to responder - %s\n", err)
+	if failed {
 		os.Exit(errResponderConnect)
 	}
+	detectProfiles(cams)
 
 	if cmd != "" {
-		executeCommand(cmd, bufio.NewWriter(os.Stdout), client, "cli")
+		executeCommand(cmd, bufio.NewWriter(os.Stdout), cams, "cli")
 	}
 
 	if server || interactive {
 		if interactive {
-			go iShell(client)
+			go iShell(cams)
 		}
 
 		if server {
-			go launchServer(client)
+			go launchServer(cams)
 		}
 
 		mainThread()
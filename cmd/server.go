@@ -2,18 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"expvar"
+	"fmt"
 	"github.com/malc0mn/ptp-ip/ip"
+	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 )
 
+// validateAddress makes sure the server address is an IPv4 or IPv6 address, optionally including a zone, or a host name
+// that resolves.
 func validateAddress() {
-	if ip := net.ParseIP(conf.srvAddr); ip == nil {
-		log.Fatalf("Invalid IP address '%s'", conf.srvAddr)
+	if _, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(conf.srvAddr, conf.srvPort.String())); err != nil {
+		log.Fatalf("Invalid address '%s': %s", conf.srvAddr, err)
 	}
 }
 
-func launchServer(c *ip.Client) {
+func launchServer(m *ip.Manager) {
 	validateAddress()
 
 	lmp := "[Local server]"
@@ -32,13 +39,81 @@ func launchServer(c *ip.Client) {
 			log.Printf("%s accept error %s...", lmp, err)
 			continue
 		}
-		go handleMessages(conn, c, lmp)
+		go handleMessages(conn, m, lmp)
 	}
 }
 
-func handleMessages(conn net.Conn, c *ip.Client, lmp string) {
+func handleMessages(conn net.Conn, m *ip.Manager, lmp string) {
 	defer conn.Close()
 	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 
-	readAndExecuteCommand(rw, c, lmp)
+	// Allow scraping the metrics over HTTP on the same socket, e.g. by Prometheus.
+	if b, err := rw.Peek(4); err == nil && string(b) == "GET " {
+		serveHTTP(rw, m, lmp)
+		return
+	}
+
+	readAndExecuteCommand(rw, m, lmp)
+}
+
+// serveHTTP handles a single HTTP GET request for the metrics. The Prometheus text format is served on /metrics, using
+// a camera label to tell the cameras apart, and all published expvars, including the client metrics, on /debug/vars.
+func serveHTTP(rw *bufio.ReadWriter, m *ip.Manager, lmp string) {
+	req, err := http.ReadRequest(rw.Reader)
+	if err != nil {
+		log.Printf("%s error reading HTTP request '%s'", lmp, err)
+		return
+	}
+	log.Printf("%s HTTP request received: '%s %s'", lmp, req.Method, req.URL.Path)
+
+	var body bytes.Buffer
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Request:    req,
+		Header:     make(http.Header),
+		Close:      true,
+	}
+
+	switch req.URL.Path {
+	case "/metrics":
+		res.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		err = m.WritePrometheus(&body)
+	case "/debug/vars":
+		res.Header.Set("Content-Type", "application/json; charset=utf-8")
+		writeExpvars(&body)
+	default:
+		res.StatusCode = http.StatusNotFound
+		body.WriteString("404 page not found\n")
+	}
+	if err != nil {
+		res.StatusCode = http.StatusInternalServerError
+		body.Reset()
+		body.WriteString(err.Error())
+	}
+
+	res.ContentLength = int64(body.Len())
+	res.Body = ioutil.NopCloser(&body)
+	if err := res.Write(rw); err != nil {
+		log.Printf("%s error writing HTTP response '%s'", lmp, err)
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		log.Printf("%s error flushing buffer: '%s'", lmp, err)
+	}
+}
+
+// writeExpvars writes all published expvars in the same format as the expvar package's own HTTP handler.
+func writeExpvars(b *bytes.Buffer) {
+	b.WriteString("{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			b.WriteString(",\n")
+		}
+		first = false
+		fmt.Fprintf(b, "%q: %s", kv.Key, kv.Value)
+	})
+	b.WriteString("\n}\n")
 }
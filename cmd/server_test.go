@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"github.com/malc0mn/ptp-ip/ip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func httpGet(t *testing.T, m *ip.Manager, path string) (*http.Response, string) {
+	srv, cl := net.Pipe()
+	go handleMessages(srv, m, "[test]")
+	defer cl.Close()
+
+	if _, err := cl.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(cl), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, string(body)
+}
+
+func TestHandleMessagesHTTP(t *testing.T) {
+	c, err := ip.NewClient(ip.DefaultVendor, ip.DefaultIpAddress, ip.DefaultPort, "", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetMetrics(ip.NewMetrics())
+	m := ip.NewManager()
+	m.Add(defaultCamera, c)
+
+	res, body := httpGet(t, m, "/metrics")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("GET /metrics status = %d; want %d", res.StatusCode, http.StatusOK)
+	}
+	want := `ptpip_packets_sent_total{camera="camera1",channel="cmd"} 0`
+	if !strings.Contains(body, want) {
+		t.Errorf("GET /metrics body = %s; want it to contain %s", body, want)
+	}
+
+	res, body = httpGet(t, m, "/debug/vars")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("GET /debug/vars status = %d; want %d", res.StatusCode, http.StatusOK)
+	}
+	want = `"memstats": {`
+	if !strings.Contains(body, want) {
+		t.Errorf("GET /debug/vars body = %s; want it to contain %s", body, want)
+	}
+
+	res, _ = httpGet(t, m, "/unknown")
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /unknown status = %d; want %d", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestMetricsCommand(t *testing.T) {
+	c, err := ip.NewClient(ip.DefaultVendor, ip.DefaultIpAddress, ip.DefaultPort, "", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := metrics{}.execute(c, []string{}, make(chan string))
+	want := "metrics are not enabled\n"
+	if got != want {
+		t.Errorf("metrics execute() got = '%s'; want '%s'", got, want)
+	}
+
+	c.SetMetrics(ip.NewMetrics())
+	got = metrics{}.execute(c, []string{"json"}, make(chan string))
+	if !strings.HasPrefix(got, `{"channels":{`) {
+		t.Errorf("metrics execute() got = '%s'; want json", got)
+	}
+}
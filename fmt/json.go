@@ -5,8 +5,11 @@ import (
 	"github.com/malc0mn/ptp-ip/ptp"
 )
 
+// DevicePropDescJSON marshals a device property description to JSON, labeling the property and its values using the
+// names of the given vendor.
 type DevicePropDescJSON struct {
 	*ptp.DevicePropDesc
+	Vendor ptp.VendorExtension
 }
 
 type ValueLabel struct {
@@ -25,10 +28,12 @@ func (dpdj *DevicePropDescJSON) MarshalJSON() ([]byte, error) {
 	case ptp.DPF_FormFlag_Range:
 		form = &RangeFormJSON{
 			RangeForm: dpdj.Form.(*ptp.RangeForm),
+			Vendor:    dpdj.Vendor,
 		}
 	case ptp.DPF_FormFlag_Enum:
 		form = &EnumerationFormJSON{
 			EnumerationForm: dpdj.Form.(*ptp.EnumerationForm),
+			Vendor:          dpdj.Vendor,
 		}
 	}
 
@@ -43,25 +48,20 @@ func (dpdj *DevicePropDescJSON) MarshalJSON() ([]byte, error) {
 	}{
 		DevicePropertyCode: CodeLabel{
 			Code:  ConvertToHexString(dpdj.DevicePropertyCode),
-			Label: DevicePropCodeAsString(dpdj.DevicePropertyCode),
+			Label: VendorDevicePropCodeAsString(dpdj.Vendor, dpdj.DevicePropertyCode),
 		},
-		DataType: DataTypeCodeAsString(dpdj.DataType),
-		GetSet:   dpdj.GetSet != ptp.DPD_GetSet,
-		FactoryDefaultValue: ValueLabel{
-			Value: ConvertToHexString(dpdj.FactoryDefaultValueAsInt64()),
-			Label: FujiDevicePropValueAsString(dpdj.DevicePropertyCode, dpdj.FactoryDefaultValueAsInt64()),
-		},
-		CurrentValue: ValueLabel{
-			Value: ConvertToHexString(dpdj.CurrentValueAsInt64()),
-			Label: FujiDevicePropValueAsString(dpdj.DevicePropertyCode, dpdj.CurrentValueAsInt64()),
-		},
-		FormFlag: FormFlagAsString(dpdj.FormFlag),
-		Form:     form,
+		DataType:            DataTypeCodeAsString(dpdj.DataType),
+		GetSet:              dpdj.GetSet != ptp.DPD_GetSet,
+		FactoryDefaultValue: propValueLabel(dpdj.Vendor, dpdj.DevicePropDesc, dpdj.FactoryDefaultValue, dpdj.FactoryDefaultValueAsInt64()),
+		CurrentValue:        propValueLabel(dpdj.Vendor, dpdj.DevicePropDesc, dpdj.CurrentValue, dpdj.CurrentValueAsInt64()),
+		FormFlag:            FormFlagAsString(dpdj.FormFlag),
+		Form:                form,
 	})
 }
 
 type RangeFormJSON struct {
 	*ptp.RangeForm
+	Vendor ptp.VendorExtension
 }
 
 func (rfj *RangeFormJSON) MarshalJSON() ([]byte, error) {
@@ -70,24 +70,22 @@ func (rfj *RangeFormJSON) MarshalJSON() ([]byte, error) {
 		MaximumValue string `json:"max"`
 		StepSize     string `json:"step"`
 	}{
-		MinimumValue: ConvertToHexString(rfj.MinimumValueAsInt64()),
-		MaximumValue: ConvertToHexString(rfj.MaximumValueAsInt64()),
-		StepSize:     ConvertToHexString(rfj.StepSizeAsInt64()),
+		MinimumValue: propValueLabel(rfj.Vendor, rfj.DevicePropDesc, rfj.MinimumValue, rfj.MinimumValueAsInt64()).Value,
+		MaximumValue: propValueLabel(rfj.Vendor, rfj.DevicePropDesc, rfj.MaximumValue, rfj.MaximumValueAsInt64()).Value,
+		StepSize:     propValueLabel(rfj.Vendor, rfj.DevicePropDesc, rfj.StepSize, rfj.StepSizeAsInt64()).Value,
 	})
 }
 
 type EnumerationFormJSON struct {
 	*ptp.EnumerationForm
+	Vendor ptp.VendorExtension
 }
 
 func (ef *EnumerationFormJSON) MarshalJSON() ([]byte, error) {
 	values := ef.SupportedValuesAsInt64Array()
 	hex := make([]ValueLabel, len(values))
 	for i := 0; i < len(values); i++ {
-		hex[i] = ValueLabel{
-			Value: ConvertToHexString(values[i]),
-			Label: FujiDevicePropValueAsString(ef.DevicePropDesc.DevicePropertyCode, values[i]),
-		}
+		hex[i] = propValueLabel(ef.Vendor, ef.DevicePropDesc, ef.SupportedValues[i], values[i])
 	}
 
 	return json.Marshal(&struct {
@@ -96,3 +94,23 @@ func (ef *EnumerationFormJSON) MarshalJSON() ([]byte, error) {
 		SupportedValues: hex,
 	})
 }
+
+// propValueLabel formats a value of the given property. Integer values are formatted as a hex string of v with a
+// label of the given vendor. Strings, arrays and 128 bit integers that do not fit an int64 are decoded from b and formatted as they are,
+// without a label.
+func propValueLabel(vendor ptp.VendorExtension, dpd *ptp.DevicePropDesc, b []byte, v int64) ValueLabel {
+	if dpd == nil {
+		return ValueLabel{Value: ConvertToHexString(v)}
+	}
+
+	if pv, err := ptp.UnmarshalPropertyValue(b, dpd.DataType); err == nil {
+		if _, ok := pv.Int64(); !ok {
+			return ValueLabel{Value: pv.String()}
+		}
+	}
+
+	return ValueLabel{
+		Value: ConvertToHexString(v),
+		Label: DevicePropValAsString(vendor, dpd.DevicePropertyCode, v),
+	}
+}
@@ -142,6 +142,7 @@ func TestMarshalJSON(t *testing.T) {
 	for i := 0; i < len(list); i++ {
 		lj[i] = &DevicePropDescJSON{
 			DevicePropDesc: list[i],
+			Vendor:         ptp.VE_FujiPhotoFilmCoLtd,
 		}
 	}
 
@@ -170,3 +171,61 @@ func TestMarshalJSON(t *testing.T) {
 		t.Errorf("MarshalJSON() got = %s; want %s", got, want)
 	}
 }
+
+func TestMarshalJSON_String(t *testing.T) {
+	dpd, err := ptp.ReadDevicePropDesc(bytes.NewReader([]byte{
+		0x1e, 0x50, 0xff, 0xff, 0x01,
+		0x00,
+		0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00,
+		0x00,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(&DevicePropDescJSON{DevicePropDesc: dpd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"DevicePropertyCode":{"code":"0x501e","label":"artist"},"dataType":"string","readOnly":false,"FactoryDefaultValue":{"value":"","label":""},"CurrentValue":{"value":"Jo","label":""},"formType":"none","form":null}`
+	if string(got) != want {
+		t.Errorf("MarshalJSON() got = %s; want %s", got, want)
+	}
+}
+
+func TestMarshalJSON_Vendor(t *testing.T) {
+	list := []*DevicePropDescJSON{
+		{
+			DevicePropDesc: &ptp.DevicePropDesc{
+				DevicePropertyCode:  ip.DPC_Sony_PictureEffect,
+				DataType:            ptp.DTC_UINT16,
+				GetSet:              ptp.DPD_Get,
+				FactoryDefaultValue: []uint8{0x0, 0x80},
+				CurrentValue:        []uint8{0x0, 0x80},
+				FormFlag:            ptp.DPF_FormFlag_None,
+			},
+			Vendor: ptp.VE_SonyCorporation,
+		},
+		{
+			DevicePropDesc: &ptp.DevicePropDesc{
+				DevicePropertyCode:  ip.DPC_Sony_ShutterSpeed,
+				DataType:            ptp.DTC_UINT32,
+				GetSet:              ptp.DPD_Get,
+				FactoryDefaultValue: []uint8{0xfa, 0x00, 0x01, 0x00},
+				CurrentValue:        []uint8{0xfa, 0x00, 0x01, 0x00},
+				FormFlag:            ptp.DPF_FormFlag_None,
+			},
+			Vendor: ptp.VE_SonyCorporation,
+		},
+	}
+
+	got, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"DevicePropertyCode":{"code":"0xd21b","label":"picture effect"},"dataType":"uint16","readOnly":true,"FactoryDefaultValue":{"value":"0x8000","label":""},"CurrentValue":{"value":"0x8000","label":""},"formType":"none","form":null},` +
+		`{"DevicePropertyCode":{"code":"0xd20d","label":"shutter speed"},"dataType":"uint32","readOnly":true,"FactoryDefaultValue":{"value":"0x100fa","label":"1/250"},"CurrentValue":{"value":"0x100fa","label":"1/250"},"formType":"none","form":null}]`
+	if string(got) != want {
+		t.Errorf("MarshalJSON() got = %s; want %s", got, want)
+	}
+}
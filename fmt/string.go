@@ -51,15 +51,47 @@ func DevicePropCodeAsString(code ptp.DevicePropCode) string {
 	if res == "" {
 		res = FujiDevicePropCodeAsString(code)
 	}
+	if res == "" {
+		res = NikonDevicePropCodeAsString(code)
+	}
+	if res == "" {
+		res = SonyDevicePropCodeAsString(code)
+	}
+	if res == "" {
+		res = PanasonicDevicePropCodeAsString(code)
+	}
 
 	return res
 }
 
+// VendorDevicePropCodeAsString converts a device property code to a string using the property names of the given
+// vendor.
+func VendorDevicePropCodeAsString(vendor ptp.VendorExtension, code ptp.DevicePropCode) string {
+	switch vendor {
+	case ptp.VE_FujiPhotoFilmCoLtd:
+		return FujiDevicePropCodeAsString(code)
+	case ptp.VE_NikonCorporation:
+		return NikonDevicePropCodeAsString(code)
+	case ptp.VE_SonyCorporation:
+		return SonyDevicePropCodeAsString(code)
+	case ptp.VE_PanasonicCorporation:
+		return PanasonicDevicePropCodeAsString(code)
+	default:
+		return GenericDevicePropCodeAsString(code)
+	}
+}
+
 // PropNameToDevicePropCode converts a string to a device property code.
 func PropNameToDevicePropCode(vendor ptp.VendorExtension, param string) (ptp.DevicePropCode, error) {
 	switch vendor {
 	case ptp.VE_FujiPhotoFilmCoLtd:
 		return FujiPropToDevicePropCode(param)
+	case ptp.VE_NikonCorporation:
+		return NikonPropToDevicePropCode(param)
+	case ptp.VE_SonyCorporation:
+		return SonyPropToDevicePropCode(param)
+	case ptp.VE_PanasonicCorporation:
+		return PanasonicPropToDevicePropCode(param)
 	default:
 		return GenericPropToDevicePropCode(param)
 	}
@@ -69,6 +101,12 @@ func DevicePropValAsString(vendor ptp.VendorExtension, code ptp.DevicePropCode,
 	switch vendor {
 	case ptp.VE_FujiPhotoFilmCoLtd:
 		return FujiDevicePropValueAsString(code, v)
+	case ptp.VE_NikonCorporation:
+		return NikonDevicePropValueAsString(code, v)
+	case ptp.VE_SonyCorporation:
+		return SonyDevicePropValueAsString(code, v)
+	case ptp.VE_PanasonicCorporation:
+		return PanasonicDevicePropValueAsString(code, v)
 	default:
 		return DevicePropValueAsString(code, v)
 	}
@@ -53,6 +53,8 @@ func FujiPropToDevicePropCode(field string) (ptp.DevicePropCode, error) {
 		return ip.DPC_Fuji_ExposureIndex, nil
 	case PRP_Effect:
 		return ip.DPC_Fuji_FilmSimulation, nil
+	case PRP_Exposure:
+		return ip.DPC_Fuji_ShutterSpeed, nil
 	case "recmode":
 		return ip.DPC_Fuji_RecMode, nil
 	case PRP_FocusMeteringMode:
@@ -86,6 +88,8 @@ func FujiDevicePropValueAsString(code ptp.DevicePropCode, v int64) string {
 		return FujiImageAspectRatioAsString(ip.FujiImageSize(v))
 	case ip.DPC_Fuji_ImageQuality:
 		return FujiImageQualityAsString(ip.FujiImageQuality(v))
+	case ip.DPC_Fuji_MovieRemainingTime:
+		return FujiMovieRemainingTimeAsString(uint32(v))
 	case ptp.DPC_WhiteBalance:
 		return FujiWhiteBalanceAsString(ptp.WhiteBalance(v))
 	case ptp.DPC_CaptureDelay:
@@ -247,12 +251,7 @@ func FujiFocusLockAsString(fl ip.FujiFocusLock) string {
 }
 
 func FujiFocusMeteringModeAsString(fmm uint32) string {
-	// TODO: what are the 4 msb?
-	mask := uint32(0x000000FF)
-	x := fmm >> 8 & mask
-	y := fmm & mask
-
-	return fmt.Sprintf("%dx%d", x, y)
+	return ip.NewFujiFocusPoint(fmm).String()
 }
 
 func FujiFocusModeAsString(fm ptp.FocusMode) string {
@@ -329,6 +328,12 @@ func FujiWhiteBalanceAsString(wb ptp.WhiteBalance) string {
 
 // TODO: FujiRecModeAsString(rm ip.FujiRecMode)
 
+// FujiMovieRemainingTimeAsString formats the DPC_Fuji_MovieRemainingTime value, being a number of seconds, as minutes
+// and seconds the way the camera displays it.
+func FujiMovieRemainingTimeAsString(rt uint32) string {
+	return fmt.Sprintf("%d:%02d", rt/60, rt%60)
+}
+
 func FujiSelfTimerAsString(st ip.FujiSelfTimer) string {
 	switch st {
 	case ip.ST_Fuji_1Sec:
@@ -39,6 +39,7 @@ func TestFujiDevicePropCodeAsString(t *testing.T) {
 func TestFujiPropToDevicePropCode(t *testing.T) {
 	check := map[string]ptp.DevicePropCode{
 		PRP_Effect:            ip.DPC_Fuji_FilmSimulation,
+		PRP_Exposure:          ip.DPC_Fuji_ShutterSpeed,
 		PRP_FocusMeteringMode: ip.DPC_Fuji_FocusMeteringMode,
 		PRP_ISO:               ip.DPC_Fuji_ExposureIndex,
 		"recmode":             ip.DPC_Fuji_RecMode,
@@ -301,6 +302,22 @@ func TestFujiWhiteBalanceAsString(t *testing.T) {
 	}
 }
 
+func TestFujiMovieRemainingTimeAsString(t *testing.T) {
+	check := map[uint32]string{
+		0:    "0:00",
+		59:   "0:59",
+		1679: "27:59",
+		6000: "100:00",
+	}
+
+	for rt, want := range check {
+		got := FujiMovieRemainingTimeAsString(rt)
+		if got != want {
+			t.Errorf("FujiMovieRemainingTimeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
 func TestFujiSelfTimerAsString(t *testing.T) {
 	check := map[ip.FujiSelfTimer]string{
 		ip.ST_Fuji_1Sec:     "1 second",
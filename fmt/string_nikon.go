@@ -0,0 +1,146 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+func NikonDevicePropCodeAsString(code ptp.DevicePropCode) string {
+	switch code {
+	case ip.DPC_Nikon_ShootingBank:
+		return "shooting bank"
+	case ip.DPC_Nikon_LensID:
+		return "lens ID"
+	case ip.DPC_Nikon_FocalLengthMin:
+		return "minimum focal length"
+	case ip.DPC_Nikon_FocalLengthMax:
+		return "maximum focal length"
+	case ip.DPC_Nikon_MaxApAtMinFocalLength:
+		return "maximum aperture at minimum focal length"
+	case ip.DPC_Nikon_MaxApAtMaxFocalLength:
+		return "maximum aperture at maximum focal length"
+	case ip.DPC_Nikon_ExposureTime:
+		return "shutter speed"
+	case ip.DPC_Nikon_ACPower:
+		return "AC power"
+	case ip.DPC_Nikon_WarningStatus:
+		return "warning status"
+	case ip.DPC_Nikon_MaximumShots:
+		return "captures remaining"
+	case ip.DPC_Nikon_AFLockStatus:
+		return "AF lock"
+	case ip.DPC_Nikon_AELockStatus:
+		return "AE lock"
+	case ip.DPC_Nikon_FVLockStatus:
+		return "FV lock"
+	case ip.DPC_Nikon_RecordingMedia:
+		return "recording media"
+	case ip.DPC_Nikon_CameraOrientation:
+		return "camera orientation"
+	case ip.DPC_Nikon_AutofocusMode:
+		return "autofocus mode"
+	case ip.DPC_Nikon_LiveViewStatus:
+		return "live view status"
+	case ip.DPC_Nikon_LiveViewImageZoomRatio:
+		return "live view zoom ratio"
+	case ip.DPC_Nikon_LiveViewProhibitCondition:
+		return "live view prohibit condition"
+	case ip.DPC_Nikon_ActivePicCtrlItem:
+		return "picture control"
+	default:
+		return GenericDevicePropCodeAsString(code)
+	}
+}
+
+// NikonPropToDevicePropCode converts a standardised property string to a valid ptp.DevicePropertyCode.
+func NikonPropToDevicePropCode(field string) (ptp.DevicePropCode, error) {
+	switch field {
+	case PRP_Exposure:
+		return ip.DPC_Nikon_ExposureTime, nil
+	case "afmode":
+		return ip.DPC_Nikon_AutofocusMode, nil
+	case "media":
+		return ip.DPC_Nikon_RecordingMedia, nil
+	default:
+		return GenericPropToDevicePropCode(field)
+	}
+}
+
+func NikonDevicePropValueAsString(code ptp.DevicePropCode, v int64) string {
+	switch code {
+	case ip.DPC_Nikon_ExposureTime:
+		return NikonExposureTimeAsString(uint32(v))
+	case ip.DPC_Nikon_AutofocusMode:
+		return NikonAutofocusModeAsString(ip.NikonAutofocusMode(v))
+	case ip.DPC_Nikon_LiveViewStatus:
+		return NikonLiveViewStatusAsString(ip.NikonLiveViewStatus(v))
+	case ip.DPC_Nikon_RecordingMedia:
+		return NikonRecordingMediaAsString(ip.NikonRecordingMedia(v))
+	default:
+		return DevicePropValueAsString(code, v)
+	}
+}
+
+// NikonExposureTimeAsString converts a DPC_Nikon_ExposureTime value, holding the numerator in the upper 16 bits and the
+// denominator in the lower 16 bits, to a shutter speed as displayed by the camera.
+func NikonExposureTimeAsString(et uint32) string {
+	switch et {
+	case ip.ET_Nikon_Bulb:
+		return "bulb"
+	case ip.ET_Nikon_Time:
+		return "time"
+	}
+
+	num := et >> 16
+	den := et & 0xFFFF
+	switch {
+	case den == 0:
+		return ""
+	case num == 1 && den > 1:
+		return fmt.Sprintf("1/%d", den)
+	case num%den == 0:
+		return fmt.Sprintf("%d\"", num/den)
+	default:
+		return fmt.Sprintf("%.1f\"", float64(num)/float64(den))
+	}
+}
+
+func NikonAutofocusModeAsString(afm ip.NikonAutofocusMode) string {
+	switch afm {
+	case ip.AFM_Nikon_Single:
+		return "AF-S"
+	case ip.AFM_Nikon_Continuous:
+		return "AF-C"
+	case ip.AFM_Nikon_Automatic:
+		return "AF-A"
+	case ip.AFM_Nikon_ManualFixed:
+		return "manual (fixed)"
+	case ip.AFM_Nikon_Manual:
+		return "manual"
+	default:
+		return ""
+	}
+}
+
+func NikonLiveViewStatusAsString(lvs ip.NikonLiveViewStatus) string {
+	switch lvs {
+	case ip.LVS_Nikon_Off:
+		return "off"
+	case ip.LVS_Nikon_On:
+		return "on"
+	default:
+		return ""
+	}
+}
+
+func NikonRecordingMediaAsString(rm ip.NikonRecordingMedia) string {
+	switch rm {
+	case ip.RM_Nikon_Card:
+		return "card"
+	case ip.RM_Nikon_SDRAM:
+		return "SDRAM"
+	default:
+		return ""
+	}
+}
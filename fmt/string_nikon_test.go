@@ -0,0 +1,161 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+func TestNikonDevicePropCodeAsString(t *testing.T) {
+	check := map[ptp.DevicePropCode]string{
+		ip.DPC_Nikon_ShootingBank:              "shooting bank",
+		ip.DPC_Nikon_LensID:                    "lens ID",
+		ip.DPC_Nikon_FocalLengthMin:            "minimum focal length",
+		ip.DPC_Nikon_FocalLengthMax:            "maximum focal length",
+		ip.DPC_Nikon_MaxApAtMinFocalLength:     "maximum aperture at minimum focal length",
+		ip.DPC_Nikon_MaxApAtMaxFocalLength:     "maximum aperture at maximum focal length",
+		ip.DPC_Nikon_ExposureTime:              "shutter speed",
+		ip.DPC_Nikon_ACPower:                   "AC power",
+		ip.DPC_Nikon_WarningStatus:             "warning status",
+		ip.DPC_Nikon_MaximumShots:              "captures remaining",
+		ip.DPC_Nikon_AFLockStatus:              "AF lock",
+		ip.DPC_Nikon_AELockStatus:              "AE lock",
+		ip.DPC_Nikon_FVLockStatus:              "FV lock",
+		ip.DPC_Nikon_RecordingMedia:            "recording media",
+		ip.DPC_Nikon_CameraOrientation:         "camera orientation",
+		ip.DPC_Nikon_AutofocusMode:             "autofocus mode",
+		ip.DPC_Nikon_LiveViewStatus:            "live view status",
+		ip.DPC_Nikon_LiveViewImageZoomRatio:    "live view zoom ratio",
+		ip.DPC_Nikon_LiveViewProhibitCondition: "live view prohibit condition",
+		ip.DPC_Nikon_ActivePicCtrlItem:         "picture control",
+		ptp.DPC_ExposureIndex:                  "ISO",
+		ptp.DevicePropCode(0):                  "",
+	}
+
+	for code, want := range check {
+		got := NikonDevicePropCodeAsString(code)
+		if got != want {
+			t.Errorf("NikonDevicePropCodeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestNikonPropToDevicePropCode(t *testing.T) {
+	check := map[string]ptp.DevicePropCode{
+		PRP_Exposure: ip.DPC_Nikon_ExposureTime,
+		PRP_ISO:      ptp.DPC_ExposureIndex,
+		"afmode":     ip.DPC_Nikon_AutofocusMode,
+		"media":      ip.DPC_Nikon_RecordingMedia,
+	}
+
+	for prop, want := range check {
+		got, err := NikonPropToDevicePropCode(prop)
+		if err != nil {
+			t.Errorf("NikonPropToDevicePropCode() error = %s, want <nil>", err)
+		}
+		if got != want {
+			t.Errorf("NikonPropToDevicePropCode() return = '%#x', want '%#x'", got, want)
+		}
+	}
+
+	prop := "test"
+	got, err := NikonPropToDevicePropCode(prop)
+	wantE := fmt.Sprintf("unknown field name '%s'", prop)
+	if err.Error() != wantE {
+		t.Errorf("NikonPropToDevicePropCode() error = %s, want %s", err, wantE)
+	}
+	wantC := ptp.DevicePropCode(0)
+	if got != wantC {
+		t.Errorf("NikonPropToDevicePropCode() return = %d, want %d", got, wantC)
+	}
+}
+
+func TestNikonDevicePropValueAsString(t *testing.T) {
+	check := []struct {
+		code ptp.DevicePropCode
+		v    int64
+		want string
+	}{
+		{ip.DPC_Nikon_ExposureTime, 0x00010002, "1/2"},
+		{ip.DPC_Nikon_AutofocusMode, 2, "AF-A"},
+		{ip.DPC_Nikon_LiveViewStatus, 1, "on"},
+		{ip.DPC_Nikon_RecordingMedia, 1, "SDRAM"},
+		{ptp.DPC_FocusMeteringMode, 1, "center spot"},
+	}
+
+	for _, c := range check {
+		got := NikonDevicePropValueAsString(c.code, c.v)
+		if got != c.want {
+			t.Errorf("NikonDevicePropValueAsString() return = '%s', want '%s'", got, c.want)
+		}
+	}
+}
+
+func TestNikonExposureTimeAsString(t *testing.T) {
+	check := map[uint32]string{
+		ip.ET_Nikon_Bulb: "bulb",
+		ip.ET_Nikon_Time: "time",
+		0x000100FA:       "1/250",
+		0x00010001:       "1\"",
+		0x001E0001:       "30\"",
+		0x000D000A:       "1.3\"",
+		0x0001000A:       "1/10",
+		0x00010000:       "",
+	}
+
+	for et, want := range check {
+		got := NikonExposureTimeAsString(et)
+		if got != want {
+			t.Errorf("NikonExposureTimeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestNikonAutofocusModeAsString(t *testing.T) {
+	check := map[ip.NikonAutofocusMode]string{
+		ip.AFM_Nikon_Single:      "AF-S",
+		ip.AFM_Nikon_Continuous:  "AF-C",
+		ip.AFM_Nikon_Automatic:   "AF-A",
+		ip.AFM_Nikon_ManualFixed: "manual (fixed)",
+		ip.AFM_Nikon_Manual:      "manual",
+		ip.NikonAutofocusMode(9): "",
+	}
+
+	for afm, want := range check {
+		got := NikonAutofocusModeAsString(afm)
+		if got != want {
+			t.Errorf("NikonAutofocusModeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestNikonLiveViewStatusAsString(t *testing.T) {
+	check := map[ip.NikonLiveViewStatus]string{
+		ip.LVS_Nikon_Off:          "off",
+		ip.LVS_Nikon_On:           "on",
+		ip.NikonLiveViewStatus(2): "",
+	}
+
+	for lvs, want := range check {
+		got := NikonLiveViewStatusAsString(lvs)
+		if got != want {
+			t.Errorf("NikonLiveViewStatusAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestNikonRecordingMediaAsString(t *testing.T) {
+	check := map[ip.NikonRecordingMedia]string{
+		ip.RM_Nikon_Card:          "card",
+		ip.RM_Nikon_SDRAM:         "SDRAM",
+		ip.NikonRecordingMedia(2): "",
+	}
+
+	for rm, want := range check {
+		got := NikonRecordingMediaAsString(rm)
+		if got != want {
+			t.Errorf("NikonRecordingMediaAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
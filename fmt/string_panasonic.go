@@ -0,0 +1,107 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+func PanasonicDevicePropCodeAsString(code ptp.DevicePropCode) string {
+	switch code {
+	case ip.DPC_Panasonic_ISO:
+		return "ISO"
+	case ip.DPC_Panasonic_ShutterSpeed:
+		return "shutter speed"
+	case ip.DPC_Panasonic_Aperture:
+		return "aperture"
+	case ip.DPC_Panasonic_WhiteBalance:
+		return "white balance"
+	case ip.DPC_Panasonic_ExposureCompensation:
+		return "exposure bias compensation"
+	default:
+		return GenericDevicePropCodeAsString(code)
+	}
+}
+
+// PanasonicPropToDevicePropCode converts a standardised property string to a valid ptp.DevicePropertyCode.
+func PanasonicPropToDevicePropCode(field string) (ptp.DevicePropCode, error) {
+	switch field {
+	case PRP_Exposure:
+		return ip.DPC_Panasonic_ShutterSpeed, nil
+	case PRP_ExpBias:
+		return ip.DPC_Panasonic_ExposureCompensation, nil
+	case PRP_ISO:
+		return ip.DPC_Panasonic_ISO, nil
+	case PRP_WhiteBalance:
+		return ip.DPC_Panasonic_WhiteBalance, nil
+	case "aperture":
+		return ip.DPC_Panasonic_Aperture, nil
+	default:
+		return GenericPropToDevicePropCode(field)
+	}
+}
+
+func PanasonicDevicePropValueAsString(code ptp.DevicePropCode, v int64) string {
+	switch code {
+	case ip.DPC_Panasonic_ISO:
+		return PanasonicISOAsString(uint32(v))
+	case ip.DPC_Panasonic_ShutterSpeed:
+		return PanasonicShutterSpeedAsString(uint32(v))
+	case ip.DPC_Panasonic_Aperture:
+		return PanasonicApertureAsString(uint16(v))
+	case ip.DPC_Panasonic_ExposureCompensation:
+		return PanasonicExposureCompensationAsString(int16(v))
+	default:
+		return DevicePropValueAsString(code, v)
+	}
+}
+
+func PanasonicISOAsString(iso uint32) string {
+	if iso == ip.ISO_Panasonic_Auto {
+		return "auto"
+	}
+
+	return fmt.Sprintf("%d", iso)
+}
+
+// PanasonicShutterSpeedAsString converts a DPC_Panasonic_ShutterSpeed value to a shutter speed as displayed by the
+// camera. When ip.SS_Panasonic_Fraction is set, the value holds the denominator of a fraction of a second, otherwise the
+// number of seconds, both multiplied by 1000.
+func PanasonicShutterSpeedAsString(ss uint32) string {
+	if ss == ip.SS_Panasonic_Bulb {
+		return "bulb"
+	}
+
+	if ss&ip.SS_Panasonic_Fraction != 0 {
+		den := ss &^ ip.SS_Panasonic_Fraction
+		if den < 1000 {
+			return ""
+		}
+		return fmt.Sprintf("1/%d", den/1000)
+	}
+
+	if ss%1000 == 0 {
+		return fmt.Sprintf("%d\"", ss/1000)
+	}
+	return fmt.Sprintf("%.1f\"", float64(ss)/1000)
+}
+
+// PanasonicApertureAsString converts a DPC_Panasonic_Aperture value, holding the f-number multiplied by 10, to an
+// f-stop.
+func PanasonicApertureAsString(ap uint16) string {
+	if ap == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("f/%.1f", float64(ap)/10)
+}
+
+// PanasonicExposureCompensationAsString converts a DPC_Panasonic_ExposureCompensation value, holding the compensation in
+// thirds of a stop, to the number of stops.
+func PanasonicExposureCompensationAsString(ec int16) string {
+	if ec == 0 {
+		return "0"
+	}
+
+	return fmt.Sprintf("%+.1f", float64(ec)/3)
+}
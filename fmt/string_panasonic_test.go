@@ -0,0 +1,144 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+func TestPanasonicDevicePropCodeAsString(t *testing.T) {
+	check := map[ptp.DevicePropCode]string{
+		ip.DPC_Panasonic_ISO:                  "ISO",
+		ip.DPC_Panasonic_ShutterSpeed:         "shutter speed",
+		ip.DPC_Panasonic_Aperture:             "aperture",
+		ip.DPC_Panasonic_WhiteBalance:         "white balance",
+		ip.DPC_Panasonic_ExposureCompensation: "exposure bias compensation",
+		ptp.DPC_FNumber:                       "F-number",
+		ptp.DevicePropCode(0):                 "",
+	}
+
+	for code, want := range check {
+		got := PanasonicDevicePropCodeAsString(code)
+		if got != want {
+			t.Errorf("PanasonicDevicePropCodeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestPanasonicPropToDevicePropCode(t *testing.T) {
+	check := map[string]ptp.DevicePropCode{
+		PRP_Exposure:     ip.DPC_Panasonic_ShutterSpeed,
+		PRP_ExpBias:      ip.DPC_Panasonic_ExposureCompensation,
+		PRP_ISO:          ip.DPC_Panasonic_ISO,
+		PRP_WhiteBalance: ip.DPC_Panasonic_WhiteBalance,
+		"aperture":       ip.DPC_Panasonic_Aperture,
+	}
+
+	for prop, want := range check {
+		got, err := PanasonicPropToDevicePropCode(prop)
+		if err != nil {
+			t.Errorf("PanasonicPropToDevicePropCode() error = %s, want <nil>", err)
+		}
+		if got != want {
+			t.Errorf("PanasonicPropToDevicePropCode() return = '%#x', want '%#x'", got, want)
+		}
+	}
+
+	prop := "test"
+	got, err := PanasonicPropToDevicePropCode(prop)
+	wantE := fmt.Sprintf("unknown field name '%s'", prop)
+	if err.Error() != wantE {
+		t.Errorf("PanasonicPropToDevicePropCode() error = %s, want %s", err, wantE)
+	}
+	wantC := ptp.DevicePropCode(0)
+	if got != wantC {
+		t.Errorf("PanasonicPropToDevicePropCode() return = %d, want %d", got, wantC)
+	}
+}
+
+func TestPanasonicDevicePropValueAsString(t *testing.T) {
+	check := []struct {
+		code ptp.DevicePropCode
+		v    int64
+		want string
+	}{
+		{ip.DPC_Panasonic_ISO, 200, "200"},
+		{ip.DPC_Panasonic_ShutterSpeed, 0x8001E848, "1/125"},
+		{ip.DPC_Panasonic_Aperture, 56, "f/5.6"},
+		{ip.DPC_Panasonic_ExposureCompensation, 0xFFFD, "-1.0"},
+		{ptp.DPC_FocusMeteringMode, 1, "center spot"},
+	}
+
+	for _, c := range check {
+		got := PanasonicDevicePropValueAsString(c.code, c.v)
+		if got != c.want {
+			t.Errorf("PanasonicDevicePropValueAsString() return = '%s', want '%s'", got, c.want)
+		}
+	}
+}
+
+func TestPanasonicISOAsString(t *testing.T) {
+	check := map[uint32]string{
+		ip.ISO_Panasonic_Auto: "auto",
+		100:                   "100",
+		25600:                 "25600",
+	}
+
+	for iso, want := range check {
+		got := PanasonicISOAsString(iso)
+		if got != want {
+			t.Errorf("PanasonicISOAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestPanasonicShutterSpeedAsString(t *testing.T) {
+	check := map[uint32]string{
+		ip.SS_Panasonic_Bulb:               "bulb",
+		ip.SS_Panasonic_Fraction | 4000000: "1/4000",
+		ip.SS_Panasonic_Fraction | 2000:    "1/2",
+		ip.SS_Panasonic_Fraction | 500:     "",
+		1000:                               "1\"",
+		60000:                              "60\"",
+		1300:                               "1.3\"",
+	}
+
+	for ss, want := range check {
+		got := PanasonicShutterSpeedAsString(ss)
+		if got != want {
+			t.Errorf("PanasonicShutterSpeedAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestPanasonicApertureAsString(t *testing.T) {
+	check := map[uint16]string{
+		0:   "",
+		18:  "f/1.8",
+		110: "f/11.0",
+	}
+
+	for ap, want := range check {
+		got := PanasonicApertureAsString(ap)
+		if got != want {
+			t.Errorf("PanasonicApertureAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestPanasonicExposureCompensationAsString(t *testing.T) {
+	check := map[int16]string{
+		0:  "0",
+		1:  "+0.3",
+		-2: "-0.7",
+		9:  "+3.0",
+	}
+
+	for ec, want := range check {
+		got := PanasonicExposureCompensationAsString(ec)
+		if got != want {
+			t.Errorf("PanasonicExposureCompensationAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
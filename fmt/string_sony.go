@@ -0,0 +1,104 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+func SonyDevicePropCodeAsString(code ptp.DevicePropCode) string {
+	switch code {
+	case ip.DPC_Sony_DRangeOptimize:
+		return "dynamic range optimiser"
+	case ip.DPC_Sony_ImageSize:
+		return "image size"
+	case ip.DPC_Sony_ShutterSpeed:
+		return "shutter speed"
+	case ip.DPC_Sony_ColorTemperature:
+		return "colour temperature"
+	case ip.DPC_Sony_AspectRatio:
+		return "aspect ratio"
+	case ip.DPC_Sony_FocusIndication:
+		return "focus indication"
+	case ip.DPC_Sony_ObjectInMemory:
+		return "objects in memory"
+	case ip.DPC_Sony_BatteryLevel:
+		return "battery level"
+	case ip.DPC_Sony_PictureEffect:
+		return "picture effect"
+	case ip.DPC_Sony_ISO:
+		return "ISO"
+	case ip.DPC_Sony_S1Button:
+		return "shutter half press"
+	case ip.DPC_Sony_S2Button:
+		return "shutter full press"
+	case ip.DPC_Sony_MovieButton:
+		return "movie button"
+	default:
+		return GenericDevicePropCodeAsString(code)
+	}
+}
+
+// SonyPropToDevicePropCode converts a standardised property string to a valid ptp.DevicePropertyCode.
+func SonyPropToDevicePropCode(field string) (ptp.DevicePropCode, error) {
+	switch field {
+	case PRP_Exposure:
+		return ip.DPC_Sony_ShutterSpeed, nil
+	case PRP_ISO:
+		return ip.DPC_Sony_ISO, nil
+	default:
+		return GenericPropToDevicePropCode(field)
+	}
+}
+
+func SonyDevicePropValueAsString(code ptp.DevicePropCode, v int64) string {
+	switch code {
+	case ip.DPC_Sony_ShutterSpeed:
+		return SonyShutterSpeedAsString(uint32(v))
+	case ip.DPC_Sony_ISO:
+		return SonyISOAsString(uint32(v))
+	case ip.DPC_Sony_BatteryLevel:
+		return SonyBatteryLevelAsString(int8(v))
+	default:
+		return DevicePropValueAsString(code, v)
+	}
+}
+
+// SonyShutterSpeedAsString converts a DPC_Sony_ShutterSpeed value, holding the numerator in the upper 16 bits and the
+// denominator in the lower 16 bits, to a shutter speed as displayed by the camera.
+func SonyShutterSpeedAsString(ss uint32) string {
+	if ss == ip.SS_Sony_Bulb {
+		return "bulb"
+	}
+
+	num := ss >> 16
+	den := ss & 0xFFFF
+	switch {
+	case den == 0:
+		return ""
+	case num == 1 && den > 1:
+		return fmt.Sprintf("1/%d", den)
+	case num%den == 0:
+		return fmt.Sprintf("%d\"", num/den)
+	default:
+		return fmt.Sprintf("%.1f\"", float64(num)/float64(den))
+	}
+}
+
+func SonyISOAsString(iso uint32) string {
+	if iso == ip.ISO_Sony_Auto {
+		return "auto"
+	}
+
+	return fmt.Sprintf("%d", iso)
+}
+
+// SonyBatteryLevelAsString converts a DPC_Sony_BatteryLevel value to a percentage. A negative value indicates the level
+// is unknown, which is the case when running on external power.
+func SonyBatteryLevelAsString(bl int8) string {
+	if bl < 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d%%", bl)
+}
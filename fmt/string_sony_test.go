@@ -0,0 +1,132 @@
+package fmt
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+func TestSonyDevicePropCodeAsString(t *testing.T) {
+	check := map[ptp.DevicePropCode]string{
+		ip.DPC_Sony_DRangeOptimize:   "dynamic range optimiser",
+		ip.DPC_Sony_ImageSize:        "image size",
+		ip.DPC_Sony_ShutterSpeed:     "shutter speed",
+		ip.DPC_Sony_ColorTemperature: "colour temperature",
+		ip.DPC_Sony_AspectRatio:      "aspect ratio",
+		ip.DPC_Sony_FocusIndication:  "focus indication",
+		ip.DPC_Sony_ObjectInMemory:   "objects in memory",
+		ip.DPC_Sony_BatteryLevel:     "battery level",
+		ip.DPC_Sony_PictureEffect:    "picture effect",
+		ip.DPC_Sony_ISO:              "ISO",
+		ip.DPC_Sony_S1Button:         "shutter half press",
+		ip.DPC_Sony_S2Button:         "shutter full press",
+		ip.DPC_Sony_MovieButton:      "movie button",
+		ptp.DPC_FNumber:              "F-number",
+		ptp.DevicePropCode(0):        "",
+	}
+
+	for code, want := range check {
+		got := SonyDevicePropCodeAsString(code)
+		if got != want {
+			t.Errorf("SonyDevicePropCodeAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestSonyPropToDevicePropCode(t *testing.T) {
+	check := map[string]ptp.DevicePropCode{
+		PRP_Exposure: ip.DPC_Sony_ShutterSpeed,
+		PRP_ISO:      ip.DPC_Sony_ISO,
+		PRP_ExpBias:  ptp.DPC_ExposureBiasCompensation,
+	}
+
+	for prop, want := range check {
+		got, err := SonyPropToDevicePropCode(prop)
+		if err != nil {
+			t.Errorf("SonyPropToDevicePropCode() error = %s, want <nil>", err)
+		}
+		if got != want {
+			t.Errorf("SonyPropToDevicePropCode() return = '%#x', want '%#x'", got, want)
+		}
+	}
+
+	prop := "test"
+	got, err := SonyPropToDevicePropCode(prop)
+	wantE := fmt.Sprintf("unknown field name '%s'", prop)
+	if err.Error() != wantE {
+		t.Errorf("SonyPropToDevicePropCode() error = %s, want %s", err, wantE)
+	}
+	wantC := ptp.DevicePropCode(0)
+	if got != wantC {
+		t.Errorf("SonyPropToDevicePropCode() return = %d, want %d", got, wantC)
+	}
+}
+
+func TestSonyDevicePropValueAsString(t *testing.T) {
+	check := []struct {
+		code ptp.DevicePropCode
+		v    int64
+		want string
+	}{
+		{ip.DPC_Sony_ShutterSpeed, 0x000100FA, "1/250"},
+		{ip.DPC_Sony_ISO, 0x00FFFFFF, "auto"},
+		{ip.DPC_Sony_BatteryLevel, 80, "80%"},
+		{ptp.DPC_FocusMeteringMode, 1, "center spot"},
+	}
+
+	for _, c := range check {
+		got := SonyDevicePropValueAsString(c.code, c.v)
+		if got != c.want {
+			t.Errorf("SonyDevicePropValueAsString() return = '%s', want '%s'", got, c.want)
+		}
+	}
+}
+
+func TestSonyShutterSpeedAsString(t *testing.T) {
+	check := map[uint32]string{
+		ip.SS_Sony_Bulb: "bulb",
+		0x000100FA:      "1/250",
+		0x000A000A:      "1\"",
+		0x001E0001:      "30\"",
+		0x000D000A:      "1.3\"",
+		0x00010000:      "",
+	}
+
+	for ss, want := range check {
+		got := SonyShutterSpeedAsString(ss)
+		if got != want {
+			t.Errorf("SonyShutterSpeedAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestSonyISOAsString(t *testing.T) {
+	check := map[uint32]string{
+		ip.ISO_Sony_Auto: "auto",
+		100:              "100",
+		12800:            "12800",
+	}
+
+	for iso, want := range check {
+		got := SonyISOAsString(iso)
+		if got != want {
+			t.Errorf("SonyISOAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
+
+func TestSonyBatteryLevelAsString(t *testing.T) {
+	check := map[int8]string{
+		-1:  "",
+		0:   "0%",
+		100: "100%",
+	}
+
+	for bl, want := range check {
+		got := SonyBatteryLevelAsString(bl)
+		if got != want {
+			t.Errorf("SonyBatteryLevelAsString() return = '%s', want '%s'", got, want)
+		}
+	}
+}
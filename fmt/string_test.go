@@ -62,6 +62,26 @@ func TestDevicePropCodeAsString(t *testing.T) {
 	}
 }
 
+func TestVendorDevicePropCodeAsString(t *testing.T) {
+	want := "picture effect"
+	got := VendorDevicePropCodeAsString(ptp.VE_SonyCorporation, ip.DPC_Sony_PictureEffect)
+	if got != want {
+		t.Errorf("VendorDevicePropCodeAsString() got = %s; want %s", got, want)
+	}
+
+	want = "device error"
+	got = VendorDevicePropCodeAsString(ptp.VE_FujiPhotoFilmCoLtd, ip.DPC_Fuji_DeviceError)
+	if got != want {
+		t.Errorf("VendorDevicePropCodeAsString() got = %s; want %s", got, want)
+	}
+
+	want = "ISO"
+	got = VendorDevicePropCodeAsString(ptp.VendorExtension(0), ptp.DPC_ExposureIndex)
+	if got != want {
+		t.Errorf("VendorDevicePropCodeAsString() got = %s; want %s", got, want)
+	}
+}
+
 func TestPropNameToDevicePropCode(t *testing.T) {
 	want := ip.DPC_Fuji_ExposureIndex
 	got, err := PropNameToDevicePropCode(ptp.VE_FujiPhotoFilmCoLtd, "iso")
@@ -0,0 +1,35 @@
+package ip
+
+import "time"
+
+// BulbProbeInterval is the time waited between two probes sent to the Responder to keep the session alive while the
+// shutter is held open by BulbCapture().
+var BulbProbeInterval = 10 * time.Second
+
+// holdOpenCapture blocks until the given duration, measured from start, has passed. The Responder is probed every
+// BulbProbeInterval to keep the session alive. A probe is skipped when the deadline is less than half an interval away
+// so that a slow response cannot delay the end of the exposure. A failing probe is logged but does not end the
+// exposure early: the operation terminating the capture will report a lost session.
+func (c *Client) holdOpenCapture(start time.Time, d time.Duration, probe func(*Client) error) {
+	deadline := start.Add(d)
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	ticker := time.NewTicker(BulbProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return
+		case <-ticker.C:
+			if time.Until(deadline) < BulbProbeInterval/2 {
+				continue
+			}
+			c.debugw(SubsystemVendor, "probing responder", "remaining", time.Until(deadline))
+			if err := probe(c); err != nil {
+				c.warnw(SubsystemVendor, "probing responder failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package ip_test
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+	"time"
+)
+
+func TestClient_initCommandDataConn(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.InitCommandDataConn()
+	if err != nil {
+		t.Errorf("initCommandDataConn() error = %s; want <nil>", err)
+	}
+
+	got := c.TransactionId()
+	want := ptp.TransactionID(0)
+	if got != want {
+		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+	}
+}
+
+func TestClient_initCommandDataConnFail(t *testing.T) {
+	fail := iptest.NewResponder(ip.DefaultVendor)
+	fail.FailInit(ip.FR_FailRejectedInitiator)
+	defer fail.Close()
+
+	c, err := fail.NewClient("testér", "b3ca53e9-bb61-4c85-9fcd-3b446a9e81e6", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.InitCommandDataConn()
+	if err == nil {
+		t.Errorf("initCommandDataConn() error = %s; want rejected: device not allowed", err)
+	}
+
+	got := c.TransactionId()
+	want := ptp.TransactionID(0)
+	if got != want {
+		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+	}
+}
+
+func TestClient_initEventConn(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.InitEventConn()
+	if err != nil {
+		t.Errorf("initEventConn() error = %s; want <nil>", err)
+	}
+
+	got := c.TransactionId()
+	want := ptp.TransactionID(1)
+	if got != want {
+		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+	}
+}
+
+func TestClient_initEventConnFail(t *testing.T) {
+	fail := iptest.NewResponder(ip.DefaultVendor)
+	fail.FailInit(ip.FR_FailRejectedInitiator)
+	defer fail.Close()
+
+	c, err := fail.NewClient("testér", "733e8d71-0f05-4aba-9745-ea9294dd2278", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.InitEventConn()
+	if err == nil {
+		t.Errorf("initEventConn() error = %s; want rejected: device not allowed", err)
+	}
+
+	got := c.TransactionId()
+	want := ptp.TransactionID(0)
+	if got != want {
+		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+	}
+}
+
+func TestClient_Dial(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	fail := iptest.NewResponder(ip.DefaultVendor)
+	fail.FailInit(ip.FR_FailRejectedInitiator)
+	defer fail.Close()
+
+	c, err := res.NewClient("testèr", "7e5ac7d3-46b7-4c50-b0d9-ba56c0e599f0", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Errorf("Dial() err = %s; want <nil>", err)
+	}
+
+	c, err = fail.NewClient("testér", "f62b41f8-a094-4dab-b537-99afd04c6024", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err == nil {
+		t.Errorf("Dial() err = %s; want rejected: device not allowed", err)
+	}
+}
+
+func TestClient_GetDeviceInfo(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	c, err := res.NewClient("tèster", "558acd44-f794-4b26-9129-d460b2a29e8d", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetDeviceInfo()
+	if err != nil {
+		t.Errorf("GetDeviceInfo() err = %s; want <nil>", err)
+	}
+	if got == nil {
+		t.Errorf("GetDeviceInfo() got = %v; want *ip.OperationResponsePacket", got)
+	}
+}
+
+func TestClient_GetDevicePropertyDescription(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	// DPC_Artist: string, get/set, factory default "", current "Jo", no form.
+	res.SetDevicePropDesc(ptp.DPC_Artist, []byte{
+		0x1e, 0x50, 0xff, 0xff, 0x01,
+		0x00,
+		0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00,
+		0x00,
+	})
+
+	c, err := res.NewClient("tèster", "558acd44-f794-4b26-9129-d460b2a29e8d", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetDevicePropertyDescription(ptp.DPC_Artist)
+	if err != nil {
+		t.Fatalf("GetDevicePropertyDescription() err = %s; want <nil>", err)
+	}
+	if got.DataType != ptp.DTC_STR {
+		t.Errorf("GetDevicePropertyDescription() DataType = %#x; want %#x", got.DataType, ptp.DTC_STR)
+	}
+	cur, err := got.Current()
+	if err != nil {
+		t.Fatalf("Current() err = %s; want <nil>", err)
+	}
+	if cur.Value != "Jo" {
+		t.Errorf("Current() got = %v; want Jo", cur.Value)
+	}
+
+	_, err = c.GetDevicePropertyDescription(ptp.DPC_CopyrightInfo)
+	if err == nil || err.Error() != "device property not supported" {
+		t.Errorf("GetDevicePropertyDescription() err = %v; want device property not supported", err)
+	}
+}
+
+func TestClient_BulbCapture(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	c, err := res.NewClient("tèster", "558acd44-f794-4b26-9129-d460b2a29e8d", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interval := ip.BulbProbeInterval
+	ip.BulbProbeInterval = 20 * time.Millisecond
+	defer func() { ip.BulbProbeInterval = interval }()
+
+	d := 100 * time.Millisecond
+	start := time.Now()
+	_, err = c.BulbCapture(d)
+	if err != nil {
+		t.Fatalf("BulbCapture() err = %s; want <nil>", err)
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Errorf("BulbCapture() returned after %s; want at least %s", elapsed, d)
+	}
+
+	open := res.RequestsFor(ptp.OC_InitiateOpenCapture)
+	term := res.RequestsFor(ptp.OC_TerminateOpenCapture)
+	if len(open) != 1 || len(term) != 1 {
+		t.Fatalf("BulbCapture() open/terminate requests = %d/%d; want 1/1", len(open), len(term))
+	}
+	if p := ptp.TransactionID(term[0].Parameter(1)); p != open[0].TransactionID {
+		t.Errorf("BulbCapture() terminated transaction = %d; want %d", p, open[0].TransactionID)
+	}
+	if len(res.RequestsFor(ptp.OC_GetDeviceInfo)) == 0 {
+		t.Errorf("BulbCapture() sent no probes")
+	}
+}
+
+func TestTCPTransport_Open(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	tr := ip.NewTCPTransport(ip.NewResponder(ip.DefaultVendor, res.IpAddress(), res.CommandDataPort(), 0, 0))
+	conn, err := tr.Open(ip.CommandDataChannel)
+	if err != nil {
+		t.Fatalf("Open() err = %s; want <nil>", err)
+	}
+	conn.Close()
+
+	if _, err := tr.Open("usb"); err != ip.ErrChannelNotSupported {
+		t.Errorf("Open() err = %v; want %s", err, ip.ErrChannelNotSupported)
+	}
+}
+
+func TestClient_SetTransport(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The pipe transport hands out non-TCP connections which must not upset the client.
+	c.SetTransport(res.Transport())
+	if err := c.DialWithStreamer(); err != nil {
+		t.Errorf("DialWithStreamer() err = %s; want <nil>", err)
+	}
+}
@@ -0,0 +1,32 @@
+package ip
+
+// This file exports unexported identifiers to the external ip_test package. Tests that need to talk to an
+// iptest.Responder must live in that external package since iptest itself depends on package ip.
+
+// LogLevelUnderTest returns the log level determined by TestMain.
+func LogLevelUnderTest() LogLevel {
+	return logLevel
+}
+
+func (c *Client) InitCommandDataConn() error {
+	return c.initCommandDataConn()
+}
+
+func (c *Client) InitEventConn() error {
+	return c.initEventConn()
+}
+
+// SetFujiProfiles replaces the registered Fuji profiles; call the returned function to restore the original ones.
+func SetFujiProfiles(ps []*FujiProfile) (restore func()) {
+	fujiProfilesMu.Lock()
+	defer fujiProfilesMu.Unlock()
+
+	orig := fujiProfiles
+	fujiProfiles = ps
+
+	return func() {
+		fujiProfilesMu.Lock()
+		defer fujiProfilesMu.Unlock()
+		fujiProfiles = orig
+	}
+}
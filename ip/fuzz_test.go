@@ -0,0 +1,116 @@
+//go:build go1.18
+// +build go1.18
+
+package ip
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The fuzz targets below make sure the library survives arbitrary input coming in from the network. The seed corpus is
+// run as part of the regular tests, to actually fuzz use e.g.:
+//   go test -run=^$ -fuzz=FuzzClient_readResponse ./ip
+
+func FuzzClient_readResponse(f *testing.F) {
+	f.Add([]byte{0x0e, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x20, 0x05, 0x00, 0x00, 0x00})
+	f.Add([]byte{0x12, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x06, 0x40, 0x02, 0x00, 0x00, 0x00, 0x01, 0x50, 0x00, 0x00})
+	f.Add([]byte{0x10, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x02, 0x00, 0x00, 0x00, 0x01})
+	f.Add([]byte{0x08, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00})
+
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "fuzzèr", "8e83f1ed-5a3b-4c5b-b1fc-e16c9e1f8a4d", logLevel)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c.readResponse(bytes.NewReader(data), nil)
+	})
+}
+
+func FuzzClient_readRawResponse(f *testing.F) {
+	f.Add([]byte{0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00})
+	f.Add([]byte{0x02, 0x00, 0x00, 0x00})
+	f.Add([]byte{0xff, 0xff, 0xff, 0x7f, 0x01})
+
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "fuzzèr", "8e83f1ed-5a3b-4c5b-b1fc-e16c9e1f8a4d", logLevel)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := c.readRawResponse(bytes.NewReader(data))
+		if err == nil && len(b) < 4 {
+			t.Errorf("readRawResponse() raw = %v; want at least 4 bytes", b)
+		}
+	})
+}
+
+func FuzzFujiOperationResponse(f *testing.F) {
+	f.Add([]byte{0x0c, 0x00, 0x00, 0x00, 0x03, 0x00, 0x01, 0x20, 0x02, 0x00, 0x00, 0x00})
+	f.Add([]byte{0x10, 0x00, 0x00, 0x00, 0x02, 0x00, 0x15, 0x10, 0x05, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00})
+	f.Add([]byte{0x03, 0x00, 0x00, 0x00})
+
+	c, err := NewClient("fuji", DefaultIpAddress, DefaultPort, "fuzzèr", "8e83f1ed-5a3b-4c5b-b1fc-e16c9e1f8a4d", logLevel)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c.readResponse(bytes.NewReader(data), new(FujiOperationResponsePacket))
+		FujiExtractTransactionId(data, cmdDataConnection)
+	})
+}
+
+func FuzzFujiEvent(f *testing.F) {
+	f.Add([]byte{
+		0x1c, 0x00, 0x00, 0x00,
+		0x04, 0x00, 0x01, 0xc0,
+		0x01, 0x00, 0x00, 0x00,
+		0x06, 0x00, 0x00, 0x00,
+		0x06, 0x00, 0x00, 0x00,
+		0x29, 0xf1, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	})
+	f.Add([]byte{0x08, 0x00, 0x00, 0x00, 0x04, 0x00, 0x01, 0xc0})
+
+	c, err := NewClient("fuji", DefaultIpAddress, DefaultPort, "fuzzèr", "8e83f1ed-5a3b-4c5b-b1fc-e16c9e1f8a4d", logLevel)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c.readResponse(bytes.NewReader(data), NewFujiEventPacket())
+		FujiExtractTransactionId(data, eventConnection)
+	})
+}
+
+func FuzzFujiStreamData(f *testing.F) {
+	f.Add([]byte{
+		0x18, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x2a, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0xd8,
+		0xff, 0xe0, 0x00, 0x10,
+	})
+	f.Add([]byte{0x04, 0x00, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sf, err := FujiParseStreamFrame(data)
+		if err == nil && len(sf.Image) != len(data)-fujiStreamHeaderSize {
+			t.Errorf("FujiParseStreamFrame() image length = %d; want %d", len(sf.Image), len(data)-fujiStreamHeaderSize)
+		}
+	})
+}
+
+func FuzzGenericExtractTransactionId(f *testing.F) {
+	f.Add([]byte{0x0e, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x20, 0x05, 0x00, 0x00, 0x00})
+	f.Add([]byte{0x0c, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00})
+	f.Add([]byte{0x0d, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x20, 0x05, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		GenericExtractTransactionId(data, cmdDataConnection)
+	})
+}
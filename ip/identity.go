@@ -0,0 +1,253 @@
+package ip
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PairingState is reported to the pairing handler set using Client.SetPairingHandler().
+type PairingState int
+
+const (
+	// PairingPending means the Responder is waiting for the user to accept the Initiator, e.g. by pressing the 'OK'
+	// button on the camera.
+	PairingPending PairingState = iota
+	// PairingAccepted means the user accepted the Initiator after PairingPending was reported.
+	PairingAccepted
+	// PairingFailed means the Responder did not accept the Initiator after PairingPending was reported.
+	PairingFailed
+)
+
+func (ps PairingState) String() string {
+	switch ps {
+	case PairingPending:
+		return "pending"
+	case PairingAccepted:
+		return "accepted"
+	case PairingFailed:
+		return "failed"
+	}
+
+	return "unknown"
+}
+
+// Identity is the Initiator identity a Responder was paired with.
+type Identity struct {
+	// ResponderGUID is the GUID of the Responder as returned in the InitCommandAckPacket.
+	ResponderGUID uuid.UUID `json:"responder_guid"`
+	// Address is the IP address or host name the Responder was last connected to on.
+	Address string `json:"address"`
+	// GUID is the GUID of the Initiator.
+	GUID uuid.UUID `json:"guid"`
+	// FriendlyName is the friendly name of the Initiator.
+	FriendlyName string `json:"friendly_name"`
+}
+
+// IdentityStore persists the Initiator identity per Responder. Some Responders, such as Fuji, prompt the user to accept
+// the connection whenever they are connected to by an Initiator they do not know. Reusing the identity the Responder
+// was paired with prevents this.
+type IdentityStore interface {
+	// Load returns all stored identities in the order they were saved, the most recent one last.
+	Load() ([]Identity, error)
+	// Save stores the identity, replacing any identity stored for the same Responder GUID.
+	Save(id Identity) error
+}
+
+// FileIdentityStore is an IdentityStore keeping the identities in a JSON file. It is safe for concurrent use.
+type FileIdentityStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileIdentityStore returns an IdentityStore using the file at the given path. The file, including its parent
+// directories, is created when the first identity is saved.
+func NewFileIdentityStore(path string) *FileIdentityStore {
+	return &FileIdentityStore{path: path}
+}
+
+// DefaultIdentityStorePath returns the default path of the identity store file in the user's configuration directory.
+func DefaultIdentityStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "ptpip", "identities.json"), nil
+}
+
+// Load returns the identities stored in the file. A missing file is not an error, it simply holds no identities.
+func (s *FileIdentityStore) Load() ([]Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FileIdentityStore) load() ([]Identity, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []Identity
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Save stores the identity in the file.
+func (s *FileIdentityStore) Save(id Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	list := make([]Identity, 0, len(ids)+1)
+	for _, i := range ids {
+		if i.ResponderGUID != id.ResponderGUID {
+			list = append(list, i)
+		}
+	}
+	list = append(list, id)
+
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, b, 0600)
+}
+
+// SetIdentityStore sets the store used to persist the Initiator identity after successfully connecting to the
+// Responder. The store is only used when the Initiator was created with a random GUID and the Responder vendor prompts
+// the user to accept unknown Initiators, which currently is Fuji only. The identity the Responder was paired with is
+// then loaded from the store on Dial(), replacing both the GUID and the friendly name. Must be called before calling
+// Dial().
+func (c *Client) SetIdentityStore(s IdentityStore) {
+	c.identityStore = s
+}
+
+// SetPairingHandler sets the function called when the pairing state changes. This allows informing the user that the
+// Responder is waiting for the connection to be accepted. Not all vendors prompt the user: the handler will then never
+// be called. Must be called before calling Dial().
+func (c *Client) SetPairingHandler(h func(PairingState)) {
+	c.pairingHandler = h
+}
+
+// pairing reports the pairing state to the pairing handler, if there is one.
+func (c *Client) pairing(ps PairingState) {
+	c.infow(SubsystemVendor, "pairing state changed", "responder", c.ResponderFriendlyName(), "state", ps)
+	if c.pairingHandler != nil {
+		c.pairingHandler(ps)
+	}
+}
+
+// usesIdentityStore returns true when the identity store applies to the Client: the Initiator was created with a
+// random GUID and the Responder prompts the user to accept Initiators it does not know.
+func (c *Client) usesIdentityStore() bool {
+	return c.identityStore != nil && c.initiator.generated && c.ResponderVendor() == ptp.VE_FujiPhotoFilmCoLtd
+}
+
+// storedIdentities returns the identities in the identity store.
+func (c *Client) storedIdentities() []Identity {
+	if !c.usesIdentityStore() {
+		return nil
+	}
+
+	ids, err := c.identityStore.Load()
+	if err != nil {
+		c.warnw(SubsystemCmdData, "unable to load initiator identities", "error", err)
+		return nil
+	}
+
+	return ids
+}
+
+// useIdentity switches the Initiator to the given identity.
+func (c *Client) useIdentity(id Identity) {
+	c.infow(SubsystemCmdData, "using stored initiator identity", "guid", id.GUID, "name", id.FriendlyName)
+	c.initiator = &Initiator{
+		GUID:         id.GUID,
+		FriendlyName: id.FriendlyName,
+		generated:    true,
+	}
+}
+
+// loadIdentityByAddress switches the Initiator to the identity stored for the Responder that was last seen on the
+// address being dialled. The Responder GUID is only known after initialising the command/data connection, so it must
+// be verified using switchIdentity() later on.
+func (c *Client) loadIdentityByAddress() {
+	ids := c.storedIdentities()
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i].Address == c.responder.IpAddress {
+			c.useIdentity(ids[i])
+			return
+		}
+	}
+}
+
+// switchIdentity switches the Initiator to the identity stored for the Responder with the given GUID. It returns true
+// when the Initiator was switched, meaning the command/data connection must be initialised again using the new
+// identity.
+func (c *Client) switchIdentity(responder uuid.UUID) bool {
+	for _, id := range c.storedIdentities() {
+		if id.ResponderGUID == responder {
+			if id.GUID == c.initiator.GUID && id.FriendlyName == c.initiator.FriendlyName {
+				return false
+			}
+			c.useIdentity(id)
+			return true
+		}
+	}
+
+	return false
+}
+
+// saveIdentity stores the current Initiator identity for the Responder. Identities passed in by the caller are never
+// stored. Failing to do so is not fatal, it only means the Responder might prompt the user again next time.
+func (c *Client) saveIdentity() {
+	if !c.usesIdentityStore() {
+		return
+	}
+
+	id := Identity{
+		ResponderGUID: c.responder.GUID,
+		Address:       c.responder.IpAddress,
+		GUID:          c.initiator.GUID,
+		FriendlyName:  c.initiator.FriendlyName,
+	}
+	ids, err := c.identityStore.Load()
+	if err != nil {
+		c.warnw(SubsystemCmdData, "unable to load initiator identities", "error", err)
+		return
+	}
+	// Only write to the store when something changed; the most recent identity is the last one.
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i].ResponderGUID == id.ResponderGUID {
+			if ids[i] == id && i == len(ids)-1 {
+				return
+			}
+			break
+		}
+	}
+
+	if err := c.identityStore.Save(id); err != nil {
+		c.warnw(SubsystemCmdData, "unable to save initiator identity", "error", err)
+	}
+}
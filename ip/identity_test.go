@@ -0,0 +1,202 @@
+package ip_test
+
+import (
+	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempIdentityStore(t *testing.T) (*ip.FileIdentityStore, func()) {
+	dir, err := ioutil.TempDir("", "ptpip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ip.NewFileIdentityStore(filepath.Join(dir, "ptpip", "identities.json")), func() { os.RemoveAll(dir) }
+}
+
+func TestFileIdentityStore(t *testing.T) {
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	ids, err := s.Load()
+	if err != nil || ids != nil {
+		t.Errorf("Load() got = %v, error = %v; want <nil>, <nil>", ids, err)
+	}
+
+	a := ip.Identity{ResponderGUID: uuid.New(), Address: "192.168.0.1", GUID: uuid.New(), FriendlyName: "a"}
+	b := ip.Identity{ResponderGUID: uuid.New(), Address: "192.168.0.2", GUID: uuid.New(), FriendlyName: "b"}
+	for _, id := range []ip.Identity{a, b} {
+		if err := s.Save(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.FriendlyName = "renamed"
+	if err := s.Save(a); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err = s.Load()
+	if err != nil {
+		t.Errorf("Load() error = %s; want <nil>", err)
+	}
+	if want := []ip.Identity{b, a}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Load() got = %v; want %v", ids, want)
+	}
+}
+
+func TestClient_SetIdentityStore(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	first, err := res.NewClient("testèr", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.SetIdentityStore(s)
+	if err := first.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	want := ip.Identity{
+		ResponderGUID: uuid.MustParse(iptest.ResponderGUID),
+		Address:       res.IpAddress(),
+		GUID:          first.InitiatorGUID(),
+		FriendlyName:  "testèr",
+	}
+	if ids, _ := s.Load(); !reflect.DeepEqual(ids, []ip.Identity{want}) {
+		t.Errorf("Dial() stored identities = %v; want %v", ids, []ip.Identity{want})
+	}
+
+	// A client with a random GUID must reuse the stored identity.
+	second, err := res.NewClient("other", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetIdentityStore(s)
+	if err := second.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	if second.InitiatorGUID() != want.GUID || second.InitiatorFriendlyName() != want.FriendlyName {
+		t.Errorf("Dial() initiator = %s %s; want %s %s", second.InitiatorGUID(), second.InitiatorFriendlyName(), want.GUID, want.FriendlyName)
+	}
+	if i := res.Initiator(); i.GUID != want.GUID {
+		t.Errorf("Dial() responder got GUID %s; want %s", i.GUID, want.GUID)
+	}
+}
+
+func TestClient_SetIdentityStoreReconnect(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	// The responder was paired on another address, so the identity can only be found using the responder GUID.
+	want := ip.Identity{
+		ResponderGUID: uuid.MustParse(iptest.ResponderGUID),
+		Address:       "192.168.0.1",
+		GUID:          uuid.New(),
+		FriendlyName:  "paired",
+	}
+	if err := s.Save(want); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := res.NewClient("testèr", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetIdentityStore(s)
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	if i := res.Initiator(); i.GUID != want.GUID || i.FriendlyName != want.FriendlyName {
+		t.Errorf("Dial() responder got initiator %s %s; want %s %s", i.GUID, i.FriendlyName, want.GUID, want.FriendlyName)
+	}
+	if ids, _ := s.Load(); len(ids) != 1 || ids[0].Address != res.IpAddress() {
+		t.Errorf("Dial() stored identities = %v; want address %s", ids, res.IpAddress())
+	}
+}
+
+func TestClient_SetIdentityStoreExplicitGUID(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	paired := ip.Identity{ResponderGUID: uuid.MustParse(iptest.ResponderGUID), Address: res.IpAddress(), GUID: uuid.New(), FriendlyName: "paired"}
+	if err := s.Save(paired); err != nil {
+		t.Fatal(err)
+	}
+
+	guid := "67bace55-e7a4-4fbc-8e31-5122ee73a17c"
+	c, err := res.NewClient("testèr", guid, ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetIdentityStore(s)
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.InitiatorGUIDAsString(); got != guid {
+		t.Errorf("Dial() initiator GUID = %s; want %s", got, guid)
+	}
+	// An identity passed in by the caller must not be stored.
+	if ids, _ := s.Load(); !reflect.DeepEqual(ids, []ip.Identity{paired}) {
+		t.Errorf("Dial() stored identities = %v; want %v", ids, []ip.Identity{paired})
+	}
+}
+
+func TestClient_SetIdentityStoreNoPairing(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	c, err := res.NewClient("testèr", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetIdentityStore(s)
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generic vendor does not prompt to accept the initiator, so there is nothing to remember.
+	if ids, _ := s.Load(); ids != nil {
+		t.Errorf("Dial() stored identities = %v; want none", ids)
+	}
+}
+
+func TestPairingState_String(t *testing.T) {
+	check := map[ip.PairingState]string{
+		ip.PairingPending:  "pending",
+		ip.PairingAccepted: "accepted",
+		ip.PairingFailed:   "failed",
+		ip.PairingState(9): "unknown",
+	}
+
+	for ps, want := range check {
+		if got := ps.String(); got != want {
+			t.Errorf("String() got = %s; want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Walking each struct using reflection and reading or writing every single field using encoding/binary on every packet
+// is expensive. That shows when receiving live view frames or downloading large amounts of data. So instead, we build a
+// plan for each type the first time we see it and cache it. The plan is a flat list of fields with their type and size,
+// so encoding or decoding a packet becomes a simple loop over the plan using a pooled buffer.
+
+type fieldKind int
+
+const (
+	kindUint fieldKind = iota
+	kindInt
+	kindBool
+	kindFloat
+	// kindBytes is a fixed size byte array such as a uuid.UUID.
+	kindBytes
+	// kindString is a null terminated UTF-16 string of variable length.
+	kindString
+	// kindOther is anything we do not handle ourselves, encoding/binary will deal with these fields.
+	kindOther
+)
+
+type field struct {
+	index []int
+	kind  fieldKind
+	size  int
+}
+
+type plan []field
+
+var (
+	sessionType = reflect.TypeOf((*ptp.Session)(nil)).Elem()
+
+	marshalPlans   sync.Map
+	unmarshalPlans sync.Map
+	fixedSizes     sync.Map
+
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+	scratchPool = sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, 64)
+			return &b
+		},
+	}
+)
+
+// newField classifies a struct field. Fixed size fields are handled by the codec, anything else is left to
+// encoding/binary.
+func newField(index []int, t reflect.Type) field {
+	f := field{index: index, kind: kindOther, size: int(t.Size())}
+
+	switch t.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.kind = kindUint
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.kind = kindInt
+	case reflect.Bool:
+		f.kind = kindBool
+	case reflect.Float32, reflect.Float64:
+		f.kind = kindFloat
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			f.kind = kindBytes
+		}
+	case reflect.String:
+		f.kind = kindString
+		f.size = 0
+	}
+
+	return f
+}
+
+// join returns a new index slice, never sharing the backing array of the parent index.
+func join(parent []int, i int) []int {
+	index := make([]int, len(parent)+1)
+	copy(index, parent)
+	index[len(parent)] = i
+
+	return index
+}
+
+// hasSession indicates if a pointer to the type satisfies ptp.Session.
+func hasSession(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(sessionType)
+}
+
+// buildMarshalPlan follows the rules set out by the PTP/IP protocol: a struct having a SessionID must skip sending it.
+// Any other fixed size struct is sent as a whole, including all of its nested structs.
+func buildMarshalPlan(t reflect.Type, index []int, whole bool) plan {
+	var p plan
+
+	whole = whole || binary.Size(reflect.New(t).Interface()) >= 0 && !hasSession(t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !whole && sf.Name == "SessionID" {
+			continue
+		}
+
+		idx := join(index, i)
+		if sf.Type.Kind() == reflect.Struct {
+			p = append(p, buildMarshalPlan(sf.Type, idx, whole)...)
+			continue
+		}
+		p = append(p, newField(idx, sf.Type))
+	}
+
+	return p
+}
+
+// buildUnmarshalPlan skips any SessionID field since the PTP/IP protocol does not send it.
+func buildUnmarshalPlan(t reflect.Type, index []int) plan {
+	var p plan
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Name == "SessionID" {
+			continue
+		}
+
+		idx := join(index, i)
+		if sf.Type.Kind() == reflect.Struct {
+			p = append(p, buildUnmarshalPlan(sf.Type, idx)...)
+			continue
+		}
+		p = append(p, newField(idx, sf.Type))
+	}
+
+	return p
+}
+
+func marshalPlanFor(t reflect.Type) plan {
+	if p, ok := marshalPlans.Load(t); ok {
+		return p.(plan)
+	}
+	p, _ := marshalPlans.LoadOrStore(t, buildMarshalPlan(t, nil, false))
+
+	return p.(plan)
+}
+
+func unmarshalPlanFor(t reflect.Type) plan {
+	if p, ok := unmarshalPlans.Load(t); ok {
+		return p.(plan)
+	}
+	p, _ := unmarshalPlans.LoadOrStore(t, buildUnmarshalPlan(t, nil))
+
+	return p.(plan)
+}
+
+// addressable returns an addressable struct value for s, copying it when it was not passed in by reference.
+func addressable(s interface{}) reflect.Value {
+	v := reflect.ValueOf(s)
+	if v.Kind() == reflect.Ptr {
+		return v.Elem()
+	}
+
+	a := reflect.New(v.Type()).Elem()
+	a.Set(v)
+
+	return a
+}
+
+func marshal(s interface{}, bo binary.ByteOrder, b *bytes.Buffer) {
+	v := addressable(s)
+	if v.Kind() != reflect.Struct {
+		binary.Write(b, bo, s)
+		return
+	}
+
+	var tmp [8]byte
+	for _, f := range marshalPlanFor(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		switch f.kind {
+		case kindUint:
+			putUint(bo, tmp[:f.size], fv.Uint())
+			b.Write(tmp[:f.size])
+		case kindInt:
+			putUint(bo, tmp[:f.size], uint64(fv.Int()))
+			b.Write(tmp[:f.size])
+		case kindBool:
+			tmp[0] = 0
+			if fv.Bool() {
+				tmp[0] = 1
+			}
+			b.WriteByte(tmp[0])
+		case kindFloat:
+			if f.size == 4 {
+				putUint(bo, tmp[:4], uint64(math.Float32bits(float32(fv.Float()))))
+			} else {
+				putUint(bo, tmp[:8], math.Float64bits(fv.Float()))
+			}
+			b.Write(tmp[:f.size])
+		case kindBytes:
+			b.Write(fv.Slice(0, f.size).Bytes())
+		case kindString:
+			// TODO: the PTP protocol sets a limit of 255 characters per string including the terminating null
+			//  character. We must still enforce this limit here.
+			// A rune in Go is an alias for uint32 but the PTP protocol expects 2 byte Unicode characters according to
+			// the ISO10646 standard, so we convert them to utf16 (which is uint16) here.
+			for _, r := range fv.String() {
+				if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+					bo.PutUint16(tmp[:2], uint16(r1))
+					bo.PutUint16(tmp[2:4], uint16(r2))
+					b.Write(tmp[:4])
+					continue
+				}
+				bo.PutUint16(tmp[:2], uint16(r))
+				b.Write(tmp[:2])
+			}
+			// Strings must be null terminated.
+			bo.PutUint16(tmp[:2], 0)
+			b.Write(tmp[:2])
+		default:
+			// Write the value an interface holds, such as the payload of a data packet, instead of the interface.
+			if fv.Kind() == reflect.Interface {
+				if !fv.IsNil() {
+					binary.Write(b, bo, fv.Elem().Interface())
+				}
+				continue
+			}
+			binary.Write(b, bo, fv.Addr().Interface())
+		}
+	}
+}
+
+// unmarshal fills s using its unmarshal plan. Consecutive fields that we handle ourselves are read from r in one go.
+// The int returned is the left over length of the data that has NOT been unmarshalled. It is the responsibility of the
+// caller to handle it.
+func unmarshal(r io.Reader, s interface{}, l int, vs int, bo binary.ByteOrder) (int, error) {
+	v := reflect.Indirect(reflect.ValueOf(s))
+	p := unmarshalPlanFor(v.Type())
+
+	sp := scratchPool.Get().(*[]byte)
+	defer scratchPool.Put(sp)
+
+	for i := 0; i < len(p); {
+		// Nothing left to read, the remaining fields are simply not sent 'over the wire'.
+		if l <= 0 {
+			return 0, nil
+		}
+
+		if p[i].kind == kindOther {
+			fv := v.FieldByIndex(p[i].index).Addr().Interface()
+			fs := binary.Size(fv)
+			if fs > l {
+				return 0, ShortPacketError(l, fs)
+			}
+			if err := binary.Read(r, bo, fv); err != nil {
+				return 0, shortRead(err, l, fs)
+			}
+			l -= fs
+			i++
+			continue
+		}
+
+		// Gather the run of fields that can be read in one go.
+		var short error
+		n, j := 0, i
+		for ; j < len(p) && p[j].kind != kindOther && n < l; j++ {
+			fs := p[j].size
+			if p[j].kind == kindString {
+				fs = vs
+				if vs < 0 {
+					short = ShortPacketError(l-n, vs)
+					break
+				}
+			}
+			if n+fs > l {
+				short = ShortPacketError(l-n, fs)
+				break
+			}
+			n += fs
+		}
+
+		if cap(*sp) < n {
+			*sp = make([]byte, n)
+		}
+		b := (*sp)[:n]
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, shortRead(err, l, n)
+		}
+
+		for ; i < j; i++ {
+			f := p[i]
+			fv := v.FieldByIndex(f.index)
+			switch f.kind {
+			case kindUint:
+				fv.SetUint(getUint(bo, b[:f.size]))
+			case kindInt:
+				fv.SetInt(getInt(bo, b[:f.size]))
+			case kindBool:
+				fv.SetBool(b[0] != 0)
+			case kindFloat:
+				if f.size == 4 {
+					fv.SetFloat(float64(math.Float32frombits(bo.Uint32(b))))
+				} else {
+					fv.SetFloat(math.Float64frombits(bo.Uint64(b)))
+				}
+			case kindBytes:
+				copy(fv.Slice(0, f.size).Bytes(), b)
+			case kindString:
+				f.size = vs
+				// The PTP protocol expects 2 byte Unicode characters according to the ISO10646 standard, so we
+				// convert them to string here.
+				u := make([]uint16, vs/2)
+				for k := range u {
+					u[k] = bo.Uint16(b[k*2:])
+				}
+				// The slice operation happening here is to drop the null terminator.
+				if len(u) > 0 {
+					u = u[:len(u)-1]
+				}
+				fv.SetString(string(utf16.Decode(u)))
+			}
+			b = b[f.size:]
+		}
+		l -= n
+
+		if short != nil {
+			return 0, short
+		}
+	}
+
+	return l, nil
+}
+
+func putUint(bo binary.ByteOrder, b []byte, v uint64) {
+	switch len(b) {
+	case 1:
+		b[0] = uint8(v)
+	case 2:
+		bo.PutUint16(b, uint16(v))
+	case 4:
+		bo.PutUint32(b, uint32(v))
+	case 8:
+		bo.PutUint64(b, v)
+	}
+}
+
+func getUint(bo binary.ByteOrder, b []byte) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(bo.Uint16(b))
+	case 4:
+		return uint64(bo.Uint32(b))
+	default:
+		return bo.Uint64(b)
+	}
+}
+
+func getInt(bo binary.ByteOrder, b []byte) int64 {
+	switch len(b) {
+	case 1:
+		return int64(int8(b[0]))
+	case 2:
+		return int64(int16(bo.Uint16(b)))
+	case 4:
+		return int64(int32(bo.Uint32(b)))
+	default:
+		return int64(bo.Uint64(b))
+	}
+}
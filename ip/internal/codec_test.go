@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+type testInitAck struct {
+	ConnectionNumber uint32
+	GUID             uuid.UUID
+	FriendlyName     string
+	ProtocolVersion  uint32
+}
+
+type testOperationRequest struct {
+	DataPhase uint32
+	ptp.OperationRequest
+}
+
+type testOperationResponse struct {
+	ptp.OperationResponse
+}
+
+type testHeader struct {
+	Length     uint32
+	PacketType uint32
+}
+
+type testFujiEvent struct {
+	DataPhase     uint16
+	EventCode     ptp.EventCode
+	Amount        uint32
+	TransactionID ptp.TransactionID
+	Parameter1    uint32
+	Parameter2    uint32
+	Parameter3    uint32
+}
+
+type testMixed struct {
+	A int8
+	B bool
+	C float32
+	D int64
+	E [3]uint16
+	F float64
+	G int16
+}
+
+func testPackets() []interface{} {
+	guid, _ := uuid.Parse("7c946ae4-6d6a-4589-90ed-d059f8cc426b")
+
+	return []interface{}{
+		&testInitAck{ConnectionNumber: 1, GUID: guid, FriendlyName: "remôte 📷", ProtocolVersion: 0x00020005},
+		&testInitAck{ConnectionNumber: 2, GUID: guid, FriendlyName: "", ProtocolVersion: 0x00010000},
+		&testOperationRequest{DataPhase: 1, OperationRequest: ptp.OperationRequest{
+			OperationCode: ptp.OC_GetDevicePropValue,
+			SessionID:     7,
+			TransactionID: 5,
+			Parameter1:    0xd212,
+		}},
+		&testOperationResponse{ptp.OperationResponse{ResponseCode: ptp.RC_OK, SessionID: 3, TransactionID: 9, Parameter1: 1, Parameter5: 5}},
+		&ptp.Event{EventCode: ptp.EC_DevicePropChanged, SessionID: 1, TransactionID: 2, Parameter1: []byte{1, 2}},
+		&testFujiEvent{DataPhase: 4, EventCode: 0xc001, Amount: 1, TransactionID: 6, Parameter1: 6, Parameter2: 0xf129},
+		&testMixed{A: -3, B: true, C: 1.5, D: -1 << 40, E: [3]uint16{1, 2, 0xffff}, F: -2.25, G: -300},
+		testHeader{Length: 12, PacketType: 7},
+	}
+}
+
+// reflectMarshal is the reflection based implementation that was used before the field plans were introduced. It is
+// kept here as a reference to compare output and performance with.
+func reflectMarshal(s interface{}, bo binary.ByteOrder, b *bytes.Buffer) {
+	if _, hasSession := s.(ptp.Session); binary.Size(s) < 0 || hasSession {
+		v := reflect.Indirect(reflect.ValueOf(s))
+
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Name == "SessionID" {
+				continue
+			}
+
+			f := v.Field(i)
+			switch f.Kind() {
+			case reflect.Struct:
+				reflectMarshal(f.Addr().Interface(), bo, b)
+			case reflect.String:
+				binary.Write(b, bo, utf16.Encode([]rune(f.String())))
+				binary.Write(b, bo, uint16(0))
+			default:
+				binary.Write(b, bo, f.Addr().Interface())
+			}
+		}
+	} else {
+		binary.Write(b, bo, s)
+	}
+}
+
+// reflectUnmarshal is the reflection based counterpart of reflectMarshal.
+func reflectUnmarshal(r io.Reader, s interface{}, l int, vs int, bo binary.ByteOrder) (int, error) {
+	v := reflect.Indirect(reflect.ValueOf(s))
+
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Name == "SessionID" {
+			continue
+		}
+
+		if l <= 0 {
+			return 0, nil
+		}
+
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Struct:
+			var err error
+			l, err = reflectUnmarshal(r, f.Addr().Interface(), l, vs, bo)
+			if err != nil {
+				return 0, err
+			}
+		case reflect.String:
+			if vs < 0 || vs > l {
+				return 0, ShortPacketError(l, vs)
+			}
+			b := make([]uint16, vs/2)
+			if err := binary.Read(r, bo, b); err != nil {
+				return 0, shortRead(err, l, vs)
+			}
+			if len(b) > 0 {
+				b = b[:len(b)-1]
+			}
+			f.SetString(string(utf16.Decode(b)))
+			if vs%2 != 0 {
+				if _, err := io.CopyN(ioutil.Discard, r, 1); err != nil {
+					return 0, shortRead(err, l, vs)
+				}
+			}
+			l -= vs
+		default:
+			fs := binary.Size(f.Addr().Interface())
+			if fs > l {
+				return 0, ShortPacketError(l, fs)
+			}
+			if err := binary.Read(r, bo, f.Addr().Interface()); err != nil {
+				return 0, shortRead(err, l, fs)
+			}
+			l -= fs
+		}
+	}
+
+	return l, nil
+}
+
+// variableSize returns the size of the variable portion, i.e. the string, of the marshalled packet.
+func variableSize(s interface{}, b []byte) int {
+	return len(b) - TotalSizeOfFixedFields(s)
+}
+
+func TestMarshalLittleEndian(t *testing.T) {
+	for _, p := range testPackets() {
+		var want bytes.Buffer
+		reflectMarshal(p, binary.LittleEndian, &want)
+
+		got := MarshalLittleEndian(p)
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("MarshalLittleEndian(%T) got = %#x; want %#x", p, got, want.Bytes())
+		}
+	}
+}
+
+func TestMarshalLittleEndianDoesNotShareBuffer(t *testing.T) {
+	a := MarshalLittleEndian(&testHeader{Length: 1, PacketType: 2})
+	want := append([]byte(nil), a...)
+	MarshalLittleEndian(&testHeader{Length: 3, PacketType: 4})
+
+	if !bytes.Equal(a, want) {
+		t.Errorf("MarshalLittleEndian() got = %#x; want %#x", a, want)
+	}
+}
+
+func TestMarshalLittleEndianInterface(t *testing.T) {
+	type dataPacket struct {
+		TransactionId ptp.TransactionID
+		DataPayload   interface{}
+	}
+
+	got := MarshalLittleEndian(&dataPacket{TransactionId: 1, DataPayload: []byte{0x0a, 0x0b}})
+	want := []byte{0x01, 0x00, 0x00, 0x00, 0x0a, 0x0b}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalLittleEndian() got = %#x; want %#x", got, want)
+	}
+
+	got = MarshalLittleEndian(&dataPacket{TransactionId: 1})
+	if !bytes.Equal(got, want[:4]) {
+		t.Errorf("MarshalLittleEndian() got = %#x; want %#x", got, want[:4])
+	}
+}
+
+func TestUnmarshalLittleEndian(t *testing.T) {
+	for _, p := range testPackets() {
+		if reflect.TypeOf(p).Kind() != reflect.Ptr {
+			continue
+		}
+		b := MarshalLittleEndian(p)
+		vs := variableSize(p, b)
+
+		// Add some excess data to make sure it is returned untouched.
+		data := append(b, 0xca, 0xfe)
+		l := len(data)
+
+		want := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+		wantLeft, wantErr := reflectUnmarshal(bytes.NewReader(data), want, l, vs, binary.LittleEndian)
+
+		got := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+		xs, err := UnmarshalLittleEndian(bytes.NewReader(data), got, l, vs)
+		if err != wantErr {
+			t.Errorf("UnmarshalLittleEndian(%T) err = %v; want %v", p, err, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UnmarshalLittleEndian(%T) got = %#v; want %#v", p, got, want)
+		}
+		if len(xs) != wantLeft {
+			t.Errorf("UnmarshalLittleEndian(%T) excess bytes = %d; want %d", p, len(xs), wantLeft)
+		}
+	}
+}
+
+func TestUnmarshalLittleEndianShortPacket(t *testing.T) {
+	for _, p := range testPackets() {
+		if reflect.TypeOf(p).Kind() != reflect.Ptr {
+			continue
+		}
+		b := MarshalLittleEndian(p)
+		vs := variableSize(p, b)
+
+		// Claim the full length but only provide part of the data.
+		for n := 0; n < len(b); n++ {
+			want := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+			_, wantErr := reflectUnmarshal(bytes.NewReader(b[:n]), want, len(b), vs, binary.LittleEndian)
+
+			got := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+			_, err := unmarshal(bytes.NewReader(b[:n]), got, len(b), vs, binary.LittleEndian)
+			if errors.Is(err, ErrShortPacket) != errors.Is(wantErr, ErrShortPacket) {
+				t.Errorf("unmarshal(%T) with %d bytes err = %v; want %v", p, n, err, wantErr)
+			}
+		}
+
+		// Now claim a shorter length than the packet needs.
+		for l := 1; l < len(b); l++ {
+			want := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+			wantLeft, wantErr := reflectUnmarshal(bytes.NewReader(b), want, l, vs, binary.LittleEndian)
+
+			got := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+			left, err := unmarshal(bytes.NewReader(b), got, l, vs, binary.LittleEndian)
+			if errors.Is(err, ErrShortPacket) != errors.Is(wantErr, ErrShortPacket) {
+				t.Errorf("unmarshal(%T) with length %d err = %v; want %v", p, l, err, wantErr)
+			}
+			if err == nil && (left != wantLeft || !reflect.DeepEqual(got, want)) {
+				t.Errorf("unmarshal(%T) with length %d got = %#v, %d; want %#v, %d", p, l, got, left, want, wantLeft)
+			}
+		}
+	}
+}
+
+func TestTotalSizeOfFixedFields(t *testing.T) {
+	tests := []struct {
+		s    interface{}
+		want int
+	}{
+		{&testInitAck{}, 24},
+		{&testOperationRequest{}, 30},
+		{&testOperationResponse{}, 26},
+		{&testFujiEvent{}, 24},
+	}
+
+	for _, tt := range tests {
+		// Twice, to hit the cache as well.
+		for i := 0; i < 2; i++ {
+			if got := TotalSizeOfFixedFields(tt.s); got != tt.want {
+				t.Errorf("TotalSizeOfFixedFields(%T) got = %d; want %d", tt.s, got, tt.want)
+			}
+		}
+	}
+}
+
+func benchmarkMarshal(b *testing.B, marshal func(interface{}, binary.ByteOrder, *bytes.Buffer)) {
+	p := testPackets()[0]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		marshal(p, binary.LittleEndian, &buf)
+	}
+}
+
+func BenchmarkMarshalLittleEndian(b *testing.B) {
+	p := testPackets()[0]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MarshalLittleEndian(p)
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	benchmarkMarshal(b, marshal)
+}
+
+func BenchmarkMarshalReflect(b *testing.B) {
+	benchmarkMarshal(b, reflectMarshal)
+}
+
+func benchmarkUnmarshal(b *testing.B, p interface{}, unmarshal func(io.Reader, interface{}, int, int, binary.ByteOrder) (int, error)) {
+	data := MarshalLittleEndian(p)
+	vs := variableSize(p, data)
+	r := bytes.NewReader(data)
+	typ := reflect.TypeOf(p).Elem()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if _, err := unmarshal(r, reflect.New(typ).Interface(), len(data), vs, binary.LittleEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalInitAck(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[0], unmarshal)
+}
+
+func BenchmarkUnmarshalInitAckReflect(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[0], reflectUnmarshal)
+}
+
+func BenchmarkUnmarshalOperationResponse(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[3], unmarshal)
+}
+
+func BenchmarkUnmarshalOperationResponseReflect(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[3], reflectUnmarshal)
+}
+
+func BenchmarkUnmarshalFujiEvent(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[5], unmarshal)
+}
+
+func BenchmarkUnmarshalFujiEventReflect(b *testing.B) {
+	benchmarkUnmarshal(b, testPackets()[5], reflectUnmarshal)
+}
@@ -0,0 +1,40 @@
+//go:build go1.18
+// +build go1.18
+
+package internal
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+type fuzzPacket struct {
+	Number  uint32
+	Name    string
+	Version uint32
+}
+
+func FuzzUnmarshalLittleEndian(f *testing.F) {
+	f.Add([]byte{0x01, 0x00, 0x00, 0x00, 0x61, 0x00, 0x00, 0x00, 0x05, 0x00, 0x02, 0x00}, 12, 4)
+	f.Add([]byte{0x01, 0x00, 0x00, 0x00, 0x61, 0x00}, 12, 4)
+	f.Add([]byte{0x01, 0x00, 0x00}, 3, -5)
+	f.Add([]byte{0x01, 0x00, 0x00, 0x00, 0x61, 0x00, 0x00, 0x00, 0x05}, 9, 1)
+
+	f.Fuzz(func(t *testing.T, data []byte, l int, vs int) {
+		UnmarshalLittleEndian(bytes.NewReader(data), new(fuzzPacket), l, vs)
+	})
+}
+
+func FuzzUnmarshalLittleEndianOperationResponse(f *testing.F) {
+	f.Add([]byte{0x01, 0x20, 0x05, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, 10)
+	f.Add([]byte{0x01, 0x20, 0x05}, 10)
+	f.Add([]byte{0x01, 0x20, 0x05, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, 1<<30)
+
+	f.Fuzz(func(t *testing.T, data []byte, l int) {
+		xs, err := UnmarshalLittleEndian(bytes.NewReader(data), new(ptp.OperationResponse), l, 0)
+		if err == nil && len(xs) > len(data) {
+			t.Errorf("UnmarshalLittleEndian() excess bytes = %d; want at most %d", len(xs), len(data))
+		}
+	})
+}
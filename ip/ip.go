@@ -13,8 +13,10 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,6 +40,9 @@ var (
 	WaitForEventError    = errors.New("timeout reached when waiting for event")
 	InvalidPacketError   = errors.New("invalid packet")
 	NotConnectedError    = errors.New("not connected")
+	// ErrShortPacket is wrapped by all errors caused by a packet that is too small to hold the data it should contain.
+	// Use errors.Is() to check for it.
+	ErrShortPacket = internal.ErrShortPacket
 )
 
 type connectionType string
@@ -46,6 +51,9 @@ type connectionType string
 type Initiator struct {
 	GUID         uuid.UUID
 	FriendlyName string
+	// generated indicates the GUID was randomly generated and may be replaced by an identity loaded from the
+	// IdentityStore.
+	generated bool
 }
 
 // NewDefaultInitiator creates a new Initiator using InitiatorFriendlyName as name and a randomly generated GUID.
@@ -81,6 +89,7 @@ func NewInitiator(friendlyName, guid string) (*Initiator, error) {
 	i := &Initiator{
 		GUID:         id,
 		FriendlyName: friendlyName,
+		generated:    guid == "",
 	}
 
 	return i, nil
@@ -107,17 +116,25 @@ func (r Responder) Network() string {
 
 // CommandDataAddress returns the address of the command/data channel as string in the form of host:port.
 func (r Responder) CommandDataAddress() string {
-	return fmt.Sprintf("%s:%d", r.IpAddress, r.CommandDataPort)
+	return r.address(r.CommandDataPort)
 }
 
 // EventAddress returns the address of the event channel as string in the form of host:port.
 func (r Responder) EventAddress() string {
-	return fmt.Sprintf("%s:%d", r.IpAddress, r.EventPort)
+	return r.address(r.EventPort)
 }
 
 // StreamerAddress returns the address streamer channel as string in the form of host:port.
 func (r Responder) StreamerAddress() string {
-	return fmt.Sprintf("%s:%d", r.IpAddress, r.StreamerPort)
+	return r.address(r.StreamerPort)
+}
+
+// address joins the responder's host and the given port. IPv6 addresses, optionally including a zone, are enclosed in
+// square brackets as required, e.g. [fe80::1%wlan0]:15740.
+func (r Responder) address(port uint16) string {
+	host := strings.TrimSuffix(strings.TrimPrefix(r.IpAddress, "["), "]")
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
 }
 
 // NewResponder creates a new responder struct.
@@ -134,6 +151,7 @@ func NewResponder(vendor string, ip string, cport uint16, eport uint16, sport ui
 // Client holds all parts needed to build our PTP/IP client:
 //   - the connection number
 //   - the current transaction ID
+//   - the transport used to open the connections
 //   - the command/data channel connection
 //   - the event channel connection
 //   - the streamer channel connection
@@ -141,16 +159,21 @@ func NewResponder(vendor string, ip string, cport uint16, eport uint16, sport ui
 //   - the responder info, i.e. camera
 //   - the loaded vendor extensions
 //   - an async event channel receiving events from the Responder's event connection
-//   - an async streamer channel receiving raw image data from the Responder's streaming connection if there is one
+//   - an async streamer channel receiving pooled frames from the Responder's streaming connection if there is one
 //   - a channel to request the streamer to close down
 //   - a logger
 type Client struct {
+	// droppedFrames and captureSentAt are accessed atomically and must remain the first fields to guarantee 64-bit
+	// alignment.
+	droppedFrames    uint64
+	captureSentAt    int64
 	connectionNumber uint32
 	transactionId    ptp.TransactionID
 	transactionIdMu  sync.Mutex
-	commandDataConn  net.Conn
-	eventConn        net.Conn
-	streamConn       net.Conn
+	transport        Transport
+	commandDataConn  Conn
+	eventConn        Conn
+	streamConn       Conn
 	initiator        *Initiator
 	responder        *Responder
 	vendorExtensions *VendorExtensions
@@ -158,8 +181,17 @@ type Client struct {
 	cmdDataSubs      map[ptp.TransactionID]chan<- []byte
 	cmdDataSubsMu    sync.Mutex
 	eventChan        chan EventPacket
-	StreamChan       chan []byte
+	StreamChan       chan *Frame
+	streamDropPolicy FrameDropPolicy
+	streamBufferSize int
+	streamStats      streamStats
 	closeStreamChan  chan struct{}
+	streamMu         sync.Mutex
+	trace            *PacketTrace
+	metrics          *Metrics
+	identityStore    IdentityStore
+	pairingHandler   func(PairingState)
+	fujiProfile      *FujiProfile
 	Logger
 }
 
@@ -269,19 +301,22 @@ func (c *Client) Dial() error {
 
 	err = c.initCommandDataConn()
 	if err != nil {
+		c.metrics.setLastError(err.Error())
 		return err
 	}
 
 	err = c.initEventConn()
 	if err != nil {
+		c.metrics.setLastError(err.Error())
 		return err
 	}
+	c.metrics.dialed()
 
 	return nil
 }
 
 // DialWithStreamer will call Dial and also attempt to open the steamer channel used for live preview. Not all devices
-// have such a channel.
+// have such a channel: Canon and Nikon devices, for example, will be polled for live view frames instead.
 func (c *Client) DialWithStreamer() error {
 	var err error
 
@@ -290,7 +325,7 @@ func (c *Client) DialWithStreamer() error {
 		return err
 	}
 
-	err = c.initStreamConn()
+	err = c.vendorExtensions.toggleLiveView(c, true)
 	if err != nil {
 		return err
 	}
@@ -302,6 +337,9 @@ func (c *Client) DialWithStreamer() error {
 func (c *Client) Close() error {
 	var err error
 
+	// Stop polling for live view frames, if any, so the polling does not outlive the connection.
+	c.stopStream()
+
 	// streamConn must be closed first so we can do it cleanly, otherwise the camera might terminate it for us causing
 	// any possible listeners to panic.
 	if c.streamConn != nil {
@@ -339,6 +377,13 @@ func (c *Client) SendPacketToEventConn(p PacketOut) error {
 	return c.sendPacket(c.eventConn, p)
 }
 
+// writerPool holds the buffered writers used to send out packets so we do not need to allocate one for every packet.
+var writerPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewWriterSize(nil, 1476) // Size is MTU 1500-24 bytes TCP header size.
+	},
+}
+
 // Send a packet to the connection. We use bufio to buffer the packet to avoid
 // fragmenting header and payload across multiple TCP packets.
 func (c *Client) sendPacket(w io.Writer, p PacketOut) error {
@@ -348,36 +393,56 @@ func (c *Client) sendPacket(w io.Writer, p PacketOut) error {
 	if p == nil {
 		return InvalidPacketError
 	}
-	c.Debugf("[sendPacket] sending %T", p)
+	ct := c.connectionFor(w)
+	sub := subsystemFor(ct)
 
 	pl := p.Payload()
 	pll := len(pl)
-	bw := bufio.NewWriterSize(w, 1476) // Size is MTU 1500-24 bytes TCP header size.
+	c.debugw(sub, "sending packet", append([]interface{}{"type", packetName(p), FieldChannel, ct}, requestFields(p)...)...)
+	bw := writerPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		writerPool.Put(bw)
+	}()
 
+	var h [HeaderSize]byte
+	hl := HeaderSize
 	// An invalid packet type means it does not adhere to the PTP/IP standard, so we only send the length field here.
 	if p.PacketType() == PKT_Invalid {
 		// Send length only. The length must include the size of the length field, so we add 4 bytes for that!
-		if _, err := bw.Write(internal.MarshalLittleEndian(uint32(pll + 4))); err != nil {
+		hl = 4
+		binary.LittleEndian.PutUint32(h[:4], uint32(pll+4))
+		if _, err := bw.Write(h[:4]); err != nil {
 			return err
 		}
 	} else {
 		// The packet length MUST include the header, so we add 8 bytes for that!
-		h := internal.MarshalLittleEndian(Header{uint32(pll + HeaderSize), p.PacketType()})
+		binary.LittleEndian.PutUint32(h[:4], uint32(pll+HeaderSize))
+		binary.LittleEndian.PutUint32(h[4:], uint32(p.PacketType()))
 
 		// Send header.
-		n, err := bw.Write(h)
+		n, err := bw.Write(h[:])
 		if err != nil {
 			return err
 		}
 		if n != HeaderSize {
 			return fmt.Errorf(BytesWrittenMismatch, n, HeaderSize)
 		}
-		c.Debugf("[sendPacket] header bytes written %d", n)
+		c.debugw(sub, "header written", FieldChannel, ct, FieldBytes, n)
+	}
+
+	if c.trace != nil {
+		c.traceOut(ct, p, append(h[:hl:hl], pl...))
 	}
 
 	// Send payload.
 	if pll == 0 {
-		c.Debugf("[sendPacket] packet has no payload")
+		c.debugw(sub, "packet has no payload", FieldChannel, ct)
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		c.metrics.packetSent(ct, p, hl)
 		return nil
 	}
 
@@ -394,18 +459,52 @@ func (c *Client) sendPacket(w io.Writer, p PacketOut) error {
 	if n != pll {
 		return fmt.Errorf(BytesWrittenMismatch, n, pll)
 	}
-	c.Debugf("[sendPacket] payload bytes written %d", n)
+	c.debugw(sub, "payload written", FieldChannel, ct, FieldBytes, n)
+	c.metrics.packetSent(ct, p, hl+n)
 
 	return nil
 }
 
+// markCaptureSent records the time the shutter release request is sent out. Each vendor uses its own operations to
+// release the shutter, so the capture functions must call this right before sending the request that does so.
+func (c *Client) markCaptureSent() {
+	atomic.StoreInt64(&c.captureSentAt, time.Now().UnixNano())
+}
+
+// CaptureSentAt returns the time the last shutter release request was sent to the Responder. The zero time is returned
+// when no shutter release request was sent yet.
+func (c *Client) CaptureSentAt() time.Time {
+	ns := atomic.LoadInt64(&c.captureSentAt)
+	if ns == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ns)
+}
+
+// connectionFor returns the type of the connection the writer belongs to. Anything that is not one of the client's
+// connections is considered to be the command/data connection.
+func (c *Client) connectionFor(w io.Writer) connectionType {
+	switch w {
+	case c.eventConn:
+		return eventConnection
+	case c.streamConn:
+		return streamConnection
+	}
+
+	return cmdDataConnection
+}
+
 // readRawFromCmdDataConn reads raw data from the command/data connection with a read timout of 30 seconds.
 func (c *Client) readRawFromCmdDataConn() ([]byte, error) {
 	if c.commandDataConn == nil {
 		return nil, fmt.Errorf("connection lost")
 	}
 	c.commandDataConn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
-	return c.readRawResponse(c.commandDataConn)
+	b, err := c.readRawResponse(c.commandDataConn)
+	c.received(cmdDataConnection, b)
+
+	return b, err
 }
 
 // waitForRawFromCmdDataConn waits 30 seconds for a packet on the command/data connection.
@@ -443,7 +542,7 @@ func (c *Client) readPacketFromCmdDataConn(p PacketIn) (PacketIn, []byte, error)
 		return nil, nil, ConnectionLostError
 	}
 	c.commandDataConn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
-	return c.readResponse(c.commandDataConn, p)
+	return c.readResponseFrom(cmdDataConnection, c.commandDataConn, p)
 }
 
 // waitForPacketFromCmdDataConn waits 30 seconds for a packet on the command/data connection.
@@ -483,7 +582,7 @@ func (c *Client) readPacketFromEventConn(p PacketIn) (PacketIn, []byte, error) {
 		return nil, nil, ConnectionLostError
 	}
 	c.eventConn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
-	return c.readResponse(c.eventConn, p)
+	return c.readResponseFrom(eventConnection, c.eventConn, p)
 }
 
 // waitForPacketFromEventConn waits for a packet on the Event connection.
@@ -518,8 +617,49 @@ func (c *Client) waitForPacketFromEventConn(p EventPacket) (PacketIn, []byte, er
 
 // ReadRawFromStreamConn reads raw data from the streamer connection with a read timout of 30 seconds.
 func (c *Client) ReadRawFromStreamConn() ([]byte, error) {
-	c.commandDataConn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
-	return c.readRawResponse(c.streamConn)
+	var b bytes.Buffer
+	if err := c.readRawFromStreamConnTo(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// readRawFromStreamConnTo reads raw data from the streamer connection with a read timout of 30 seconds and appends it
+// to b.
+func (c *Client) readRawFromStreamConnTo(b *bytes.Buffer) error {
+	if c.streamConn == nil {
+		return ConnectionLostError
+	}
+	c.streamConn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
+	start := b.Len()
+	err := c.readRawResponseTo(c.streamConn, b)
+	c.received(streamConnection, b.Bytes()[start:])
+
+	return err
+}
+
+// readResponseFrom works like readResponse but also traces and meters the raw packet when a PacketTrace or Metrics
+// have been set.
+func (c *Client) readResponseFrom(ct connectionType, r io.Reader, p PacketIn) (PacketIn, []byte, error) {
+	if c.trace == nil && c.metrics == nil {
+		return c.readResponse(r, p)
+	}
+
+	var b bytes.Buffer
+	p, xs, err := c.readResponse(io.TeeReader(r, &b), p)
+	c.received(ct, b.Bytes())
+
+	return p, xs, err
+}
+
+// received traces and meters a packet received on the given connection.
+func (c *Client) received(ct connectionType, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	c.traceIn(ct, p)
+	c.metrics.packetReceived(ct, len(p))
 }
 
 // TODO: this must be refactored to work like the events: continuously read and push to a channel in such a way that we
@@ -536,6 +676,9 @@ func (c *Client) readResponse(r io.Reader, p PacketIn) (PacketIn, []byte, error)
 		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
 			return nil, nil, err
 		}
+		if l < 4 {
+			return nil, nil, internal.ShortPacketError(int(l), 4)
+		}
 		hl = int(l) - 4
 	} else {
 		if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
@@ -545,6 +688,9 @@ func (c *Client) readResponse(r io.Reader, p PacketIn) (PacketIn, []byte, error)
 		if h.Length == 0 {
 			return nil, nil, ReadResponseError
 		}
+		if int(h.Length) < HeaderSize {
+			return nil, nil, internal.ShortPacketError(int(h.Length), HeaderSize)
+		}
 		hl = int(h.Length) - HeaderSize
 	}
 
@@ -572,18 +718,38 @@ func (c *Client) readResponse(r io.Reader, p PacketIn) (PacketIn, []byte, error)
 // The reading approach taken here is so that we can return the full raw data but still reliably read the complete
 // expected data length.
 func (c *Client) readRawResponse(r io.Reader) ([]byte, error) {
-	l := make([]byte, 4)
-	if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+	var b bytes.Buffer
+	if err := c.readRawResponseTo(r, &b); err != nil {
 		return nil, err
 	}
 
-	len := binary.LittleEndian.Uint32(l)
-	b := make([]byte, int(len)-4)
-	if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
-		return nil, err
+	return b.Bytes(), nil
+}
+
+// readRawResponseTo reads a full raw packet, including the length field, and appends it to b. This allows the caller to
+// reuse its buffers.
+func (c *Client) readRawResponseTo(r io.Reader, b *bytes.Buffer) error {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return err
 	}
 
-	return append(l, b...), nil
+	len := int64(binary.LittleEndian.Uint32(l[:]))
+	if len < 4 {
+		return internal.ShortPacketError(int(len), 4)
+	}
+	b.Write(l[:])
+
+	// Do not allocate the full length up front: it comes straight from the network so let the buffer grow as the data is
+	// actually coming in.
+	if n, err := io.CopyN(b, r, len-4); err != nil {
+		if err == io.EOF {
+			return internal.ShortPacketError(int(n)+4, int(len))
+		}
+		return err
+	}
+
+	return nil
 }
 
 // subscribe registers a channel to receive responses for a specific transaction ID.
@@ -613,27 +779,32 @@ func (c *Client) unsubscribe(tid ptp.TransactionID) {
 // subscriber based on the transaction ID of the packet.
 func (c *Client) responseListener() {
 	c.cmdDataChan = make(chan []byte, 10)
-	lmp := "[responseListener]"
-	c.Infof("%s subscribing response listener to command/data connection...", lmp)
+	c.infow(SubsystemCmdData, "subscribing response listener to command/data connection")
 	for {
 		p, err := c.waitForRawFromCmdDataConn()
 		if err == nil {
 			tid, err := c.vendorExtensions.extractTransactionId(p, cmdDataConnection)
 			if err != nil {
-				c.Error(err)
+				c.errorw(SubsystemCmdData, "unable to extract transaction ID", FieldChannel, cmdDataConnection, FieldBytes, len(p), "error", err)
 				continue
 			}
-			c.Debugf("%s publishing new response with length '%d' for transaction ID '%d'...", lmp, binary.LittleEndian.Uint32(p[0:4]), tid)
-
-			if _, ok := c.cmdDataSubs[tid]; !ok {
-				panic(fmt.Sprintf("No subscriber for transaction ID %d!", tid))
+			c.debugw(SubsystemCmdData, "publishing new response", FieldTransactionID, tid, FieldChannel, cmdDataConnection, FieldBytes, len(p))
+			c.metrics.transactionDone(tid)
+
+			c.cmdDataSubsMu.Lock()
+			ch, ok := c.cmdDataSubs[tid]
+			c.cmdDataSubsMu.Unlock()
+			if !ok {
+				// Do not let a stray or malicious packet take down the whole process.
+				c.errorw(SubsystemCmdData, "no subscriber for transaction ID, dropping response", FieldTransactionID, tid, FieldBytes, len(p))
+				continue
 			}
-			c.cmdDataSubs[tid] <- p
+			ch <- p
 			continue
 		} else if err == WaitForResponseError || strings.Contains(err.Error(), "i/o timeout") {
 			continue
 		}
-		c.Errorf("%s message listener stopped: %s", lmp, err)
+		c.errorw(SubsystemCmdData, "response listener stopped", FieldChannel, cmdDataConnection, "error", err)
 		return
 	}
 }
@@ -641,16 +812,17 @@ func (c *Client) responseListener() {
 func (c *Client) initCommandDataConn() error {
 	var err error
 
-	c.commandDataConn, err = internal.RetryDialer(c.Network(), c.CommandDataAddress(), DefaultDialTimeout)
+	c.loadIdentityByAddress()
+
+	c.commandDataConn, err = c.openConn(cmdDataConnection)
 	if err != nil {
 		return err
 	}
 
-	c.configureTcpConn(cmdDataConnection)
-
 	if err := c.vendorExtensions.cmdDataInit(c); err != nil {
 		return fmt.Errorf("command data connection: %s", err)
 	}
+	c.saveIdentity()
 
 	return nil
 }
@@ -668,6 +840,7 @@ func (c *Client) WaitForRawPacketFromCommandDataSubscriber(ch <-chan []byte) ([]
 		case <-timeout:
 			wait = false
 			err = WaitForResponseError
+			c.metrics.timeout()
 		case res = <-ch:
 			wait = false
 		}
@@ -702,21 +875,20 @@ func (c *Client) initEventConn() error {
 		return fmt.Errorf("event connection error: %s", err)
 	}
 
-	lmp := "[eventListener]"
 	c.eventChan = make(chan EventPacket, 10)
 	go func() {
-		c.Infof("%s subscribing event listener to event connection...", lmp)
+		c.infow(SubsystemEvent, "subscribing event listener to event connection")
 		for {
 			p := c.vendorExtensions.newEventPacket()
 			_, _, err := c.waitForPacketFromEventConn(p)
 			if err == nil {
-				c.Debugf("%s publishing new event '%#x' to event channel...", lmp, p.GetEventCode())
+				c.debugw(SubsystemEvent, "publishing new event", "code", p.GetEventCode(), FieldChannel, eventConnection)
 				c.eventChan <- p
 				continue
 			} else if err == WaitForEventError || strings.Contains(err.Error(), "i/o timeout") {
 				continue
 			}
-			c.Errorf("%s message listener stopped: %s", lmp, err)
+			c.errorw(SubsystemEvent, "event listener stopped", FieldChannel, eventConnection, "error", err)
 			return
 		}
 	}()
@@ -732,15 +904,16 @@ func (c *Client) initStreamConn() error {
 	if c.streamConn == nil {
 		var err error
 
-		c.streamConn, err = internal.RetryDialer(c.Network(), c.StreamerAddress(), DefaultDialTimeout)
+		c.streamConn, err = c.openConn(streamConnection)
 		if err != nil {
 			return err
 		}
 
-		c.configureTcpConn(streamConnection)
-
-		c.StreamChan = make(chan []byte, 50)
+		c.streamMu.Lock()
+		c.StreamChan = make(chan *Frame, c.streamBufferSize)
 		c.closeStreamChan = make(chan struct{})
+		c.streamMu.Unlock()
+		c.resetStream()
 
 		return c.vendorExtensions.processStreamData(c)
 	}
@@ -749,9 +922,7 @@ func (c *Client) initStreamConn() error {
 }
 
 func (c *Client) closeStreamConn() error {
-	if c.StreamChan != nil {
-		close(c.closeStreamChan)
-	}
+	c.stopStream()
 
 	err := c.streamConn.Close()
 	c.streamConn = nil
@@ -759,31 +930,26 @@ func (c *Client) closeStreamConn() error {
 	return err
 }
 
-func (c *Client) configureTcpConn(t connectionType) {
-	var conn net.Conn
-
-	switch t {
-	case cmdDataConnection:
-		conn = c.commandDataConn
-	case eventConnection:
-		conn = c.eventConn
-	case streamConnection:
-		conn = c.streamConn
+func (c *Client) configureTcpConn(t connectionType, conn Conn) {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		c.debugw(subsystemFor(t), "not a TCP connection, skipping TCP configuration", FieldChannel, t)
+		return
 	}
 
 	// The PTP/IP protocol specifically asks to enable keep alive.
-	if err := conn.(*net.TCPConn).SetKeepAlive(true); err != nil {
-		c.Warnf("TCP_KEEPALIVE not enabled for %s connection: %s", t, err)
+	if err := tc.SetKeepAlive(true); err != nil {
+		c.warnw(subsystemFor(t), "TCP_KEEPALIVE not enabled", FieldChannel, t, "error", err)
 	} else {
-		c.Infof("TCP_KEEPALIVE enabled for %s connection", t)
+		c.infow(subsystemFor(t), "TCP_KEEPALIVE enabled", FieldChannel, t)
 	}
 
 	// The PTP/IP protocol specifically asks to disable Nagle's algorithm. TCP_NODELAY SHOULD be enabled by default in
 	// golang but there's no harm in making sure since performance here is negligible.
-	if err := conn.(*net.TCPConn).SetNoDelay(true); err != nil {
-		c.Warnf("TCP_NODELAY not enabled for %s connection: %s", t, err)
+	if err := tc.SetNoDelay(true); err != nil {
+		c.warnw(subsystemFor(t), "TCP_NODELAY not enabled", FieldChannel, t, "error", err)
 	} else {
-		c.Infof("TCP_NODELAY enabled for %s connection", t)
+		c.infow(subsystemFor(t), "TCP_NODELAY enabled", FieldChannel, t)
 	}
 }
 
@@ -797,12 +963,14 @@ func NewClient(vendor string, ip string, port uint16, friendlyName string, guid
 	}
 
 	c := &Client{
-		initiator:   i,
-		responder:   NewResponder(vendor, ip, port, port, port),
-		cmdDataSubs: make(map[ptp.TransactionID]chan<- []byte),
-		Logger:      NewLogger(logLevel, os.Stderr, "", log.LstdFlags),
+		initiator:        i,
+		responder:        NewResponder(vendor, ip, port, port, port),
+		cmdDataSubs:      make(map[ptp.TransactionID]chan<- []byte),
+		streamBufferSize: DefaultStreamBufferSize,
+		Logger:           NewLogger(logLevel, os.Stderr, "", log.LstdFlags),
 	}
 
+	c.transport = NewTCPTransport(c.responder)
 	c.loadVendorExtensions()
 
 	return c, nil
@@ -841,19 +1009,37 @@ func (c *Client) OperationRequestRaw(code ptp.OperationCode, params []uint32) ([
 	return c.vendorExtensions.operationRequestRaw(c, code, params)
 }
 
+// Transact performs the given operation request and implements ptp.Transport, so the client can be used by a
+// ptp.Initiator. The transaction ID of the request is assigned by the client.
+func (c *Client) Transact(req ptp.OperationRequest, dataOut []byte) (*ptp.OperationResponse, []byte, error) {
+	req.TransactionID = c.incrementTransactionId()
+
+	return c.vendorExtensions.transact(c, req, dataOut)
+}
+
 // InitiateCapture releases the shutter and captures an image. If the responder supports it, a preview of the captured
 // image is returned as a byte array.
 func (c *Client) InitiateCapture() ([]byte, error) {
 	return c.vendorExtensions.initiateCapture(c)
 }
 
+// BulbCapture holds the shutter open for the given duration, which is required for exposures longer than the
+// slowest shutter speed supported by the Responder. The session is kept alive while the shutter is open. If the
+// responder supports it, a preview of the captured image is returned as a byte array.
+func (c *Client) BulbCapture(d time.Duration) ([]byte, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("invalid bulb duration %s", d)
+	}
+
+	return c.vendorExtensions.bulbCapture(c, d)
+}
+
 // ToggleLiveView opens or closes the streamer connection on the camera, if it has one, and initiates or closes the
 // StreamChan on the client.
-// StreamChan will receive raw image data that can be processed by the client.
+// StreamChan will receive frames holding the raw image data that can be processed by the client. Each frame must be
+// released by calling Frame.Release() when done with it.
+// Devices without a streamer connection, such as Canon and Nikon, are polled for frames which are queued on the StreamChan as
+// well.
 func (c *Client) ToggleLiveView(en bool) error {
-	if en {
-		return c.initStreamConn()
-	}
-
-	return c.closeStreamConn()
+	return c.vendorExtensions.toggleLiveView(c, en)
 }
@@ -2,12 +2,38 @@ package ip
 
 import (
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ip/internal"
 	"github.com/malc0mn/ptp-ip/ptp"
+	"os"
 	"testing"
 )
 
+var logLevel = LevelSilent
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if testing.Verbose() {
+		logLevel = LevelDebug
+	}
+
+	os.Exit(m.Run())
+}
+
+// packetBuffer returns a buffer holding the given packet as it would be sent out by a Responder.
+func packetBuffer(p PacketIn) *bytes.Buffer {
+	pl := internal.MarshalLittleEndian(p)
+
+	b := bytes.NewBuffer(internal.MarshalLittleEndian(Header{uint32(len(pl) + HeaderSize), p.PacketType()}))
+	b.Write(pl)
+
+	return b
+}
+
 func TestNewDefaultInitiator(t *testing.T) {
 	got, err := NewDefaultInitiator()
 	if err != nil {
@@ -64,6 +90,25 @@ func TestNewResponder(t *testing.T) {
 	}
 }
 
+func TestResponder_CommandDataAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"192.168.0.1", "192.168.0.1:15740"},
+		{"camera.local", "camera.local:15740"},
+		{"2001:db8::1", "[2001:db8::1]:15740"},
+		{"[2001:db8::1]", "[2001:db8::1]:15740"},
+		{"fe80::1%wlan0", "[fe80::1%wlan0]:15740"},
+	}
+	for _, tt := range tests {
+		r := NewResponder(DefaultVendor, tt.host, DefaultPort, DefaultPort, DefaultPort)
+		if got := r.CommandDataAddress(); got != tt.want {
+			t.Errorf("CommandDataAddress() got = %s; want %s", got, tt.want)
+		}
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	guid := "cf2407bc-4b4c-4525-9622-afb30db356df"
 	got, err := NewClient(DefaultVendor, DefaultIpAddress, 26831, "", guid, logLevel)
@@ -244,15 +289,9 @@ func TestClient_readResponse(t *testing.T) {
 
 	guidR, _ := uuid.Parse("7c946ae4-6d6a-4589-90ed-d059f8cc426b")
 
-	var b bytes.Buffer
-	sendAnyPacket(
-		&b,
-		&InitCommandAckPacket{uint32(1), guidR, "remôte", uint32(0x00020005)},
-		nil,
-		"[ip_test]",
-	)
+	b := packetBuffer(&InitCommandAckPacket{uint32(1), guidR, "remôte", uint32(0x00020005)})
 
-	rp, xs, err := c.readResponse(&b, nil)
+	rp, xs, err := c.readResponse(b, nil)
 	if len(xs) > 0 {
 		t.Errorf("readResponse() excess bytes = %d; want 0", len(xs))
 	}
@@ -304,15 +343,9 @@ func TestClient_readRawResponse(t *testing.T) {
 
 	guidR, _ := uuid.Parse("d2d4fce6-1181-42dd-a185-5cc40ca68321")
 
-	var b bytes.Buffer
-	sendAnyPacket(
-		&b,
-		&InitCommandAckPacket{uint32(1), guidR, "rèmote", uint32(0x00020005)},
-		nil,
-		"[ip_test]",
-	)
+	b := packetBuffer(&InitCommandAckPacket{uint32(1), guidR, "rèmote", uint32(0x00020005)})
 
-	got, err := c.readRawResponse(&b)
+	got, err := c.readRawResponse(b)
 	if err != nil {
 		t.Errorf("readRawResponse() error = %s; want <nil>", err)
 	}
@@ -323,47 +356,71 @@ func TestClient_readRawResponse(t *testing.T) {
 	}
 }
 
-func TestClient_initCommandDataConn(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, okPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
-	defer c.Close()
+func TestClient_readResponseShortPacket(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "writèr", "d6555687-a599-44b8-a4af-279d599a92f6", logLevel)
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("NewClient() err = %s; want <nil>", err)
 	}
-	err = c.initCommandDataConn()
-	if err != nil {
-		t.Errorf("initCommandDataConn() error = %s; want <nil>", err)
+
+	tests := []struct {
+		name string
+		p    PacketIn
+		data []byte
+	}{
+		{"length field too small", new(FujiOperationResponsePacket), []byte{0x03, 0x00, 0x00, 0x00}},
+		{"header length too small", nil, []byte{0x05, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}},
+		{"partial field", nil, []byte{0x0b, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x20, 0x05}},
+		{"truncated payload", nil, []byte{0x14, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x20, 0x05, 0x00, 0x00, 0x00}},
+		{"truncated excess data", new(FujiOperationResponsePacket), []byte{0x10, 0x00, 0x00, 0x00, 0x02, 0x00, 0x15, 0x10, 0x05, 0x00, 0x00, 0x00, 0x01}},
+		{"string length mismatch", nil, append([]byte{0x1d, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, make([]byte, 17)...)},
 	}
 
-	got := c.TransactionId()
-	want := ptp.TransactionID(0)
-	if got != want {
-		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+	for _, tt := range tests {
+		_, _, err := c.readResponse(bytes.NewReader(tt.data), tt.p)
+		if !errors.Is(err, ErrShortPacket) {
+			t.Errorf("readResponse() %s: err = %v; want %s", tt.name, err, ErrShortPacket)
+		}
 	}
 }
-func TestClient_initCommandDataConnFail(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, failPort, "testér", "b3ca53e9-bb61-4c85-9fcd-3b446a9e81e6", logLevel)
-	defer c.Close()
+
+func TestClient_readResponseUnknownPacketType(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "writèr", "d6555687-a599-44b8-a4af-279d599a92f6", logLevel)
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("NewClient() err = %s; want <nil>", err)
 	}
-	err = c.initCommandDataConn()
-	if err == nil {
-		t.Errorf("initCommandDataConn() error = %s; want rejected: device not allowed", err)
+
+	_, _, err = c.readResponse(bytes.NewReader([]byte{0x08, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00}), nil)
+	if !errors.Is(err, ErrUnknownPacketType) {
+		t.Errorf("readResponse() err = %v; want %s 0xff", err, ErrUnknownPacketType)
 	}
+}
 
-	got := c.TransactionId()
-	want := ptp.TransactionID(0)
-	if got != want {
-		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
+func TestClient_readRawResponseShortPacket(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "wrîter", "617b38ef-b6e6-4ef6-b2ad-ea51cecdbbd3", logLevel)
+	if err != nil {
+		t.Fatalf("NewClient() err = %s; want <nil>", err)
+	}
+
+	for _, data := range [][]byte{
+		{0x02, 0x00, 0x00, 0x00},
+		{0xff, 0xff, 0xff, 0xff, 0x01, 0x02},
+	} {
+		got, err := c.readRawResponse(bytes.NewReader(data))
+		if !errors.Is(err, ErrShortPacket) {
+			t.Errorf("readRawResponse() err = %v; want %s", err, ErrShortPacket)
+		}
+		if got != nil {
+			t.Errorf("readRawResponse() raw = %v; want <nil>", got)
+		}
 	}
 }
 
 func TestClient_subscribe(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, failPort, "testér", "b3ca53e9-bb61-4c85-9fcd-3b446a9e81e6", logLevel)
-	defer c.Close()
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "testér", "b3ca53e9-bb61-4c85-9fcd-3b446a9e81e6", logLevel)
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer c.Close()
 
 	tid := ptp.TransactionID(55)
 	ch := make(chan []byte, 2)
@@ -380,84 +437,3 @@ func TestClient_subscribe(t *testing.T) {
 		t.Errorf("subscribe() got = %#v; want %#v", got, ch)
 	}
 }
-
-func TestClient_initEventConn(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, okPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
-	defer c.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
-	err = c.initEventConn()
-	if err != nil {
-		t.Errorf("initEventConn() error = %s; want <nil>", err)
-	}
-
-	got := c.TransactionId()
-	want := ptp.TransactionID(1)
-	if got != want {
-		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
-	}
-}
-
-func TestClient_initEventConnFail(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, failPort, "testér", "733e8d71-0f05-4aba-9745-ea9294dd2278", logLevel)
-	defer c.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
-	err = c.initEventConn()
-	if err == nil {
-		t.Errorf("initEventConn() error = %s; want rejected: device not allowed", err)
-	}
-
-	got := c.TransactionId()
-	want := ptp.TransactionID(0)
-	if got != want {
-		t.Errorf("TransactionId() got = %#x; want %#x", got, want)
-	}
-}
-
-func TestClient_Dial(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, okPort, "testèr", "7e5ac7d3-46b7-4c50-b0d9-ba56c0e599f0", logLevel)
-	defer c.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = c.Dial()
-	if err != nil {
-		t.Errorf("Dial() err = %s; want <nil>", err)
-	}
-
-	c, err = NewClient(DefaultVendor, address, failPort, "testér", "f62b41f8-a094-4dab-b537-99afd04c6024", logLevel)
-	defer c.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = c.Dial()
-	if err == nil {
-		t.Errorf("Dial() err = %s; want rejected: device not allowed", err)
-	}
-}
-
-func TestClient_GetDeviceInfo(t *testing.T) {
-	c, err := NewClient(DefaultVendor, address, okPort, "tèster", "558acd44-f794-4b26-9129-d460b2a29e8d", logLevel)
-	defer c.Close()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = c.Dial()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	got, err := c.GetDeviceInfo()
-	if err != nil {
-		t.Errorf("GetDeviceInfo() err = %s; want <nil>", err)
-	}
-	if got == nil {
-		t.Errorf("GetDeviceInfo() got = %v; want *ip.OperationResponsePacket", got)
-	}
-}
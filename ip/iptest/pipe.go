@@ -0,0 +1,61 @@
+package iptest
+
+import (
+	"errors"
+	"github.com/malc0mn/ptp-ip/ip"
+	"net"
+)
+
+// ErrClosed is returned when opening a channel to a Responder that has been closed.
+var ErrClosed = errors.New("iptest: responder closed")
+
+// Transport returns an in-memory transport connecting to the Responder using net.Pipe so no network connections are
+// involved. Channels for which the vendor has no separate connection are served by the command/data channel, just like
+// connecting to the ports returned by EventPort and StreamerPort would do.
+func (r *Responder) Transport() ip.Transport {
+	return ip.TransportFunc(r.openPipe)
+}
+
+func (r *Responder) openPipe(ch ip.Channel) (ip.Conn, error) {
+	conns, read := r.conns, func(c *conn) error {
+		return r.dialect.read(r, c)
+	}
+	switch ch {
+	case ip.CommandDataChannel:
+	case ip.EventChannel:
+		if r.eventLn != nil {
+			conns, read = r.eventConns, discard
+		}
+	case ip.StreamChannel:
+		if r.streamLn != nil {
+			conns, read = r.streamConns, discard
+		}
+	default:
+		return nil, ip.ErrChannelNotSupported
+	}
+
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	srv, cl := net.Pipe()
+	r.handle(srv, conns, read)
+
+	return cl, nil
+}
+
+// NewPipeClient returns an ip.Client configured to connect to the Responder using the in-memory transport returned by
+// Transport. Passing an empty string to friendlyName will use the default friendly name; passing an empty string as
+// guid will generate a random one.
+func (r *Responder) NewPipeClient(friendlyName string, guid string, logLevel ip.LogLevel) (*ip.Client, error) {
+	c, err := r.NewClient(friendlyName, guid, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTransport(r.Transport())
+
+	return c, nil
+}
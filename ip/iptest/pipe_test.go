@@ -0,0 +1,52 @@
+package iptest
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"testing"
+)
+
+func TestResponder_Transport(t *testing.T) {
+	for _, vendor := range []string{ip.DefaultVendor, "fuji"} {
+		r := NewResponder(vendor)
+		r.SetCapturePreview([]byte("preview"))
+
+		c, err := r.NewPipeClient("testèr", "", ip.LevelSilent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Dial(); err != nil {
+			t.Errorf("%s Dial() err = %s; want <nil>", vendor, err)
+		}
+		if r.Initiator() == nil || r.Initiator().FriendlyName != "testèr" {
+			t.Errorf("%s Initiator() got = %v; want testèr", vendor, r.Initiator())
+		}
+
+		if vendor == "fuji" {
+			got, err := c.InitiateCapture()
+			if err != nil {
+				t.Errorf("InitiateCapture() err = %s; want <nil>", err)
+			}
+			if string(got) != "preview" {
+				t.Errorf("InitiateCapture() got = %s; want preview", got)
+			}
+		}
+
+		c.Close()
+		r.Close()
+	}
+}
+
+func TestResponder_TransportClosed(t *testing.T) {
+	r := NewResponder(ip.DefaultVendor)
+	r.Close()
+
+	if _, err := r.Transport().Open(ip.CommandDataChannel); err != ErrClosed {
+		t.Errorf("Open() err = %v; want %s", err, ErrClosed)
+	}
+
+	r = NewResponder(ip.DefaultVendor)
+	defer r.Close()
+	if _, err := r.Transport().Open("usb"); err != ip.ErrChannelNotSupported {
+		t.Errorf("Open() err = %v; want %s", err, ip.ErrChannelNotSupported)
+	}
+}
@@ -0,0 +1,880 @@
+// Package iptest provides a scriptable fake PTP/IP Responder, i.e. a fake camera, to be used when testing code that
+// relies on the ip package.
+// A Responder listens on random local ports and answers the Initiator's operation requests with canned responses that
+// can be registered per operation code. It can also push events to the Initiator, simulate timeouts, reject the init
+// sequence and drop connections. Every operation request received is recorded so tests can assert on what the client
+// has actually sent.
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// ResponderGUID is the GUID the Responder will communicate to the Initiator.
+	ResponderGUID string = "3e8626cc-5059-4225-bdd6-d160b2e6a60f"
+	// ResponderFriendlyName is the friendly name the Responder will communicate to the Initiator.
+	ResponderFriendlyName string = "iptest responder"
+	// ConnectionNumber is the connection number the Responder hands out to the Initiator.
+	ConnectionNumber uint32 = 1
+)
+
+// errHangUp is used to have the Responder close the connection.
+var errHangUp = errors.New("responder hung up")
+
+// Handler is called for each operation request the Responder receives for the operation code it was registered for.
+// Returning nil means the Responder will not respond at all.
+type Handler func(*Request) *Response
+
+// Request holds an operation request as it was received by the Responder.
+type Request struct {
+	DataPhase     ip.DataPhase
+	OperationCode ptp.OperationCode
+	TransactionID ptp.TransactionID
+	Parameters    []uint32
+	// Data holds the data sent by the Initiator during the data out phase, if there was one.
+	Data []byte
+}
+
+// Parameter returns the parameter at the given position, counting from 1 as the PTP spec does. When the parameter was
+// not sent, 0 is returned.
+func (r *Request) Parameter(i int) uint32 {
+	if i < 1 || i > len(r.Parameters) {
+		return 0
+	}
+
+	return r.Parameters[i-1]
+}
+
+// Response is the canned answer to an operation request.
+type Response struct {
+	Code ptp.OperationResponseCode
+	// Parameters are the operation response parameters. They are only sent when using the generic PTP/IP protocol.
+	Parameters []uint32
+	// Data, when not nil, will be sent to the Initiator as a data in phase before sending the operation response.
+	Data []byte
+	// Events will be sent on the event connection right after responding. An event with transaction ID 0 will be sent
+	// with the transaction ID of the request.
+	Events []*Event
+	// Delay will postpone sending the response for the given duration.
+	Delay time.Duration
+	// Disconnect will close the command/data connection instead of responding.
+	Disconnect bool
+}
+
+// Event is an event the Responder will send to the Initiator on the event connection.
+type Event struct {
+	Code          ptp.EventCode
+	TransactionID ptp.TransactionID
+	Parameters    []uint32
+}
+
+// OK returns a response with response code ptp.RC_OK.
+func OK() *Response {
+	return &Response{Code: ptp.RC_OK}
+}
+
+// Data returns a response with response code ptp.RC_OK and the given data to be sent as data in phase.
+func Data(b []byte) *Response {
+	return &Response{Code: ptp.RC_OK, Data: b}
+}
+
+// Fail returns a response with the given response code.
+func Fail(code ptp.OperationResponseCode) *Response {
+	return &Response{Code: code}
+}
+
+// Reply returns a Handler that will always answer with the given response.
+func Reply(res *Response) Handler {
+	return func(_ *Request) *Response {
+		return res
+	}
+}
+
+// dialect abstracts the way a vendor puts packets on the wire.
+type dialect interface {
+	// read reads a single packet from the connection and handles it.
+	read(*Responder, *conn) error
+	// respond sends the response to the given operation request.
+	respond(*conn, *Request, *Response) error
+	// event sends the given event.
+	event(io.Writer, *Event) error
+}
+
+// conn wraps a connection from the Initiator so that concurrent writes, e.g. events that are being injected while
+// responding to an operation request, do not get interleaved.
+type conn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Conn.Write(b)
+}
+
+// Responder is a scriptable fake camera. Create one using NewResponder and do not forget to call Close when done.
+type Responder struct {
+	vendor      string
+	dialect     dialect
+	cmdDataLn   net.Listener
+	eventLn     net.Listener
+	streamLn    net.Listener
+	mu          sync.Mutex
+	handlers    map[ptp.OperationCode]Handler
+	dataOut     map[ptp.OperationCode]bool
+	pending     map[ptp.TransactionID]*Request
+	requests    []*Request
+	initFail    ip.FailReason
+	initiator   *ip.Initiator
+	conns       map[*conn]bool
+	eventConns  map[*conn]bool
+	streamConns map[*conn]bool
+	capturePv   []byte
+	propValues  map[ptp.DevicePropCode][]byte
+	propDescs   map[ptp.DevicePropCode][]byte
+	objects     map[ptp.ObjectHandle][]byte
+	canonEvents [][]byte
+	nikonEvents [][]byte
+	nikonBusy   int
+	panasonicLV bool
+	openCapture ptp.TransactionID
+	pairDelay   time.Duration
+	paired      ip.Initiator
+	closed      bool
+	wg          sync.WaitGroup
+	ip.Logger
+}
+
+// NewResponder creates a new Responder for the given vendor, which is to be passed in the same way as it is passed to
+// ip.NewClient(), and starts listening on random ports on the loopback interface.
+// The vendor specific default handlers are registered so that an ip.Client can Dial() the Responder out of the box.
+// NewResponder will panic when it fails to listen.
+func NewResponder(vendor string) *Responder {
+	r := &Responder{
+		vendor:      vendor,
+		handlers:    make(map[ptp.OperationCode]Handler),
+		dataOut:     make(map[ptp.OperationCode]bool),
+		pending:     make(map[ptp.TransactionID]*Request),
+		conns:       make(map[*conn]bool),
+		eventConns:  make(map[*conn]bool),
+		streamConns: make(map[*conn]bool),
+		propValues:  make(map[ptp.DevicePropCode][]byte),
+		propDescs:   make(map[ptp.DevicePropCode][]byte),
+		objects:     make(map[ptp.ObjectHandle][]byte),
+		Logger:      ip.NewLogger(ip.LevelSilent, os.Stderr, "", log.LstdFlags),
+	}
+
+	switch ptp.VendorStringToType(vendor) {
+	case ptp.VE_FujiPhotoFilmCoLtd:
+		r.dialect = fujiDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.eventLn = newLocalListener()
+		r.streamLn = newLocalListener()
+		r.registerFujiHandlers()
+	case ptp.VE_CanonInc:
+		r.dialect = genericDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.registerGenericHandlers()
+		r.registerCanonHandlers()
+	case ptp.VE_NikonCorporation:
+		r.dialect = genericDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.registerGenericHandlers()
+		r.registerNikonHandlers()
+	case ptp.VE_SonyCorporation:
+		r.dialect = genericDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.registerGenericHandlers()
+		r.registerSonyHandlers()
+	case ptp.VE_PanasonicCorporation:
+		r.dialect = genericDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.registerGenericHandlers()
+		r.registerPanasonicHandlers()
+	default:
+		r.dialect = genericDialect{}
+		r.cmdDataLn = newLocalListener()
+		r.registerGenericHandlers()
+	}
+
+	r.serve(r.cmdDataLn, r.conns, func(c *conn) error {
+		return r.dialect.read(r, c)
+	})
+	if r.eventLn != nil {
+		r.serve(r.eventLn, r.eventConns, discard)
+	}
+	if r.streamLn != nil {
+		r.serve(r.streamLn, r.streamConns, discard)
+	}
+
+	return r
+}
+
+func newLocalListener() net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("iptest: failed to listen on a port: %v", err))
+	}
+
+	return ln
+}
+
+// SetLogger allows setting a custom logger. The Responder is silent by default. Set the logger before connecting to the
+// Responder.
+func (r *Responder) SetLogger(log ip.Logger) {
+	r.Logger = log
+}
+
+// Vendor returns the vendor string the Responder was created with.
+func (r *Responder) Vendor() string {
+	return r.vendor
+}
+
+// IpAddress returns the IP address the Responder is listening on.
+func (r *Responder) IpAddress() string {
+	host, _, _ := net.SplitHostPort(r.cmdDataLn.Addr().String())
+	return host
+}
+
+// CommandDataPort returns the port of the command/data channel.
+func (r *Responder) CommandDataPort() uint16 {
+	return listenerPort(r.cmdDataLn)
+}
+
+// EventPort returns the port of the event channel. This will be the same as the command/data port when the vendor
+// does not use a separate event channel.
+func (r *Responder) EventPort() uint16 {
+	if r.eventLn == nil {
+		return r.CommandDataPort()
+	}
+
+	return listenerPort(r.eventLn)
+}
+
+// StreamerPort returns the port of the streamer channel. This will be the same as the command/data port when the
+// vendor does not have a streamer channel.
+func (r *Responder) StreamerPort() uint16 {
+	if r.streamLn == nil {
+		return r.CommandDataPort()
+	}
+
+	return listenerPort(r.streamLn)
+}
+
+func listenerPort(ln net.Listener) uint16 {
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.ParseUint(port, 10, 16)
+
+	return uint16(p)
+}
+
+// NewClient returns an ip.Client configured to connect to the Responder. Passing an empty string to friendlyName will
+// use the default friendly name; passing an empty string as guid will generate a random one.
+func (r *Responder) NewClient(friendlyName string, guid string, logLevel ip.LogLevel) (*ip.Client, error) {
+	c, err := ip.NewClient(r.vendor, r.IpAddress(), r.CommandDataPort(), friendlyName, guid, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	c.SetEventPort(r.EventPort())
+	c.SetStreamerPort(r.StreamerPort())
+
+	return c, nil
+}
+
+// DialClient returns an ip.Client created using NewClient that has been dialed to the Responder. The test is stopped
+// when creating or dialing the client fails. The caller must close the client.
+func (r *Responder) DialClient(tb testing.TB, friendlyName string, guid string, logLevel ip.LogLevel) *ip.Client {
+	tb.Helper()
+
+	c, err := r.NewClient(friendlyName, guid, logLevel)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := c.Dial(); err != nil {
+		c.Close()
+		tb.Fatal(err)
+	}
+
+	return c
+}
+
+// Handle registers the handler for the given operation code, replacing any handler previously registered.
+func (r *Responder) Handle(code ptp.OperationCode, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[code] = h
+}
+
+// HandleDataOut registers the handler for the given operation code and indicates that the operation request will be
+// followed by a data out phase. The handler will only be called once the data has been received. This is only required
+// for vendors that do not use the PTP/IP data packets to send data to the Responder, such as Fuji.
+func (r *Responder) HandleDataOut(code ptp.OperationCode, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[code] = h
+	r.dataOut[code] = true
+}
+
+// Timeout removes the handler for the given operation code so the Responder will never respond to it, causing the
+// Initiator to time out. The operation request is still recorded.
+func (r *Responder) Timeout(code ptp.OperationCode) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.handlers, code)
+}
+
+// DisconnectOn makes the Responder close the command/data connection upon receiving the given operation code.
+func (r *Responder) DisconnectOn(code ptp.OperationCode) {
+	r.Handle(code, Reply(&Response{Disconnect: true}))
+}
+
+// FailInit makes the Responder reject the init sequence with the given reason. Pass 0 to accept the init sequence
+// again.
+func (r *Responder) FailInit(reason ip.FailReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.initFail = reason
+}
+
+// SetCapturePreview sets the image data that will be returned as capture preview by vendors supporting it.
+func (r *Responder) SetCapturePreview(b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.capturePv = b
+}
+
+func (r *Responder) capturePreview() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.capturePv
+}
+
+// SetObject stores an object with the given handle. The object is listed by ptp.OC_GetObjectHandles and returned by
+// ptp.OC_GetObject when the vendor supports these operations.
+func (r *Responder) SetObject(h ptp.ObjectHandle, b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.objects[h] = b
+}
+
+// objectHandles returns the handles of all stored objects in ascending order.
+func (r *Responder) objectHandles() []ptp.ObjectHandle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hs := make([]ptp.ObjectHandle, 0, len(r.objects))
+	for h := range r.objects {
+		hs = append(hs, h)
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i] < hs[j] })
+
+	return hs
+}
+
+// handleGetObjectHandles returns the handles of all stored objects.
+func (r *Responder) handleGetObjectHandles(_ *Request) *Response {
+	hs := r.objectHandles()
+
+	b := le(uint32(len(hs)))
+	for _, h := range hs {
+		b = append(b, le(uint32(h))...)
+	}
+
+	return Data(b)
+}
+
+// handleGetObject returns the object stored for the handle passed as first parameter.
+func (r *Responder) handleGetObject(req *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.objects[ptp.ObjectHandle(req.Parameter(1))]
+	if !ok {
+		return Fail(ptp.RC_InvalidObjectHandle)
+	}
+
+	return Data(append([]byte{}, b...))
+}
+
+// handleGetObjectInfo returns the ObjectInfo dataset of the object stored for the handle passed as first parameter.
+// Objects starting with the JPEG start of image marker are reported as ptp.OFC_EXIF_JPEG, any other object as
+// ptp.OFC_Undefined.
+func (r *Responder) handleGetObjectInfo(req *Request) *Response {
+	r.mu.Lock()
+	b, ok := r.objects[ptp.ObjectHandle(req.Parameter(1))]
+	r.mu.Unlock()
+	if !ok {
+		return Fail(ptp.RC_InvalidObjectHandle)
+	}
+
+	oi := &ptp.ObjectInfo{ObjectFormat: ptp.OFC_Undefined, ObjectCompressedSize: uint32(len(b))}
+	if bytes.HasPrefix(b, []byte{0xFF, 0xD8}) {
+		oi.ObjectFormat = ptp.OFC_EXIF_JPEG
+	}
+	d, err := oi.MarshalPTP()
+	if err != nil {
+		return Fail(ptp.RC_GeneralError)
+	}
+
+	return Data(d)
+}
+
+// SetDevicePropValue sets the raw value the Responder will return when the value of the given device property is
+// requested.
+func (r *Responder) SetDevicePropValue(code ptp.DevicePropCode, v []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.propValues[code] = v
+}
+
+// DevicePropValue returns the current raw value of the given device property. The value will reflect any changes made
+// by the Initiator.
+func (r *Responder) DevicePropValue(code ptp.DevicePropCode) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.propValues[code]
+
+	return v, ok
+}
+
+// SetDevicePropDesc sets the raw device property description the Responder will return when the description of the
+// given device property is requested.
+func (r *Responder) SetDevicePropDesc(code ptp.DevicePropCode, d []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.propDescs[code] = d
+}
+
+// handleGetDevicePropDesc returns the description stored for the device property passed as first parameter. When there
+// is no description, no data will be returned which is how a camera responds to an unknown device property.
+func (r *Responder) handleGetDevicePropDesc(req *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Data(append([]byte{}, r.propDescs[ptp.DevicePropCode(req.Parameter(1))]...))
+}
+
+// handleGetDevicePropValue returns the value stored for the device property passed as first parameter.
+func (r *Responder) handleGetDevicePropValue(req *Request) *Response {
+	v, _ := r.DevicePropValue(ptp.DevicePropCode(req.Parameter(1)))
+
+	return Data(append([]byte{}, v...))
+}
+
+// handleSetDevicePropValue stores the value received in the data out phase for the device property passed as first
+// parameter.
+func (r *Responder) handleSetDevicePropValue(req *Request) *Response {
+	r.SetDevicePropValue(ptp.DevicePropCode(req.Parameter(1)), req.Data)
+
+	return OK()
+}
+
+// handleInitiateOpenCapture remembers the transaction ID which must be passed to ptp.OC_TerminateOpenCapture.
+func (r *Responder) handleInitiateOpenCapture(req *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.openCapture = req.TransactionID
+
+	return OK()
+}
+
+// terminateOpenCapture returns true when the first parameter of the request refers to the open capture, which is then
+// closed.
+func (r *Responder) terminateOpenCapture(req *Request) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.openCapture == 0 || ptp.TransactionID(req.Parameter(1)) != r.openCapture {
+		return false
+	}
+	r.openCapture = 0
+
+	return true
+}
+
+// Initiator returns the identity the Initiator communicated in its InitCommandRequest or nil when none was received.
+func (r *Responder) Initiator() *ip.Initiator {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.initiator
+}
+
+// Requests returns all operation requests received so far in the order they were received.
+func (r *Responder) Requests() []*Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reqs := make([]*Request, len(r.requests))
+	copy(reqs, r.requests)
+
+	return reqs
+}
+
+// RequestsFor returns all operation requests received so far for the given operation code.
+func (r *Responder) RequestsFor(code ptp.OperationCode) []*Request {
+	var reqs []*Request
+	for _, req := range r.Requests() {
+		if req.OperationCode == code {
+			reqs = append(reqs, req)
+		}
+	}
+
+	return reqs
+}
+
+// Received indicates if at least one operation request was received for the given operation code.
+func (r *Responder) Received(code ptp.OperationCode) bool {
+	return len(r.RequestsFor(code)) > 0
+}
+
+// SendEvent sends the given event to all open event connections. When the Initiator has not yet connected to the event
+// channel, SendEvent will wait for up to one second for it to do so.
+func (r *Responder) SendEvent(e *Event) error {
+	conns, err := r.waitForConns(r.eventConns)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conns {
+		if err := r.dialect.event(c, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendStreamData sends the given data, prefixed with the packet length, to all open streamer connections. When the
+// Initiator has not yet connected to the streamer channel, SendStreamData will wait for up to one second for it to do
+// so.
+func (r *Responder) SendStreamData(b []byte) error {
+	conns, err := r.waitForConns(r.streamConns)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conns {
+		if err := writePacket(c, ip.PKT_Invalid, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForConns waits for up to one second for at least one connection to be present in cs.
+func (r *Responder) waitForConns(cs map[*conn]bool) ([]*conn, error) {
+	var conns []*conn
+	for timeout := time.Now().Add(time.Second); ; {
+		r.mu.Lock()
+		for c := range cs {
+			conns = append(conns, c)
+		}
+		r.mu.Unlock()
+
+		if len(conns) > 0 {
+			return conns, nil
+		}
+		if time.Now().After(timeout) {
+			return nil, ip.NotConnectedError
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Disconnect closes all open connections to the Responder. The Responder will keep listening for new connections.
+func (r *Responder) Disconnect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, cs := range []map[*conn]bool{r.conns, r.eventConns, r.streamConns} {
+		for c := range cs {
+			c.Close()
+			delete(cs, c)
+		}
+	}
+}
+
+// Close stops the Responder from listening and closes all open connections.
+func (r *Responder) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	for _, ln := range []net.Listener{r.cmdDataLn, r.eventLn, r.streamLn} {
+		if ln != nil {
+			ln.Close()
+		}
+	}
+	r.Disconnect()
+	r.wg.Wait()
+}
+
+func (r *Responder) serve(ln net.Listener, conns map[*conn]bool, read func(*conn) error) {
+	r.Infof("%s listening on %s...", r.lmp(), ln.Addr().String())
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r.handle(nc, conns, read)
+		}
+	}()
+}
+
+func (r *Responder) lmp() string {
+	return fmt.Sprintf("[iptest %s responder]", r.vendor)
+}
+
+// handle reads from the connection until the Initiator hangs up or the Responder is told to disconnect.
+func (r *Responder) handle(nc net.Conn, conns map[*conn]bool, read func(*conn) error) {
+	lmp := r.lmp()
+	r.Infof("%s new connection from %s...", lmp, nc.RemoteAddr().String())
+
+	c := &conn{Conn: nc}
+	r.mu.Lock()
+	conns[c] = true
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		// NO defer c.Close() in the read loop since we need to mock a real responder and thus need to keep the
+		// connection open until the Initiator hangs up or the Responder is told to disconnect.
+		for {
+			if err := read(c); err != nil {
+				if err != io.EOF {
+					r.Infof("%s closing connection: %s", lmp, err)
+				}
+				break
+			}
+		}
+		c.Close()
+		r.mu.Lock()
+		delete(conns, c)
+		delete(r.eventConns, c)
+		r.mu.Unlock()
+	}()
+}
+
+// discard reads and drops anything the Initiator sends on a connection that is not supposed to receive anything.
+func discard(c *conn) error {
+	if _, err := io.Copy(ioutil.Discard, c); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// process records the operation request and calls the registered handler to respond to it.
+func (r *Responder) process(c *conn, req *Request) error {
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	h := r.handlers[req.OperationCode]
+	r.mu.Unlock()
+
+	if h == nil {
+		r.Infof("[iptest %s responder] no handler for operation %#x, not responding", r.vendor, req.OperationCode)
+		return nil
+	}
+
+	res := h(req)
+	if res == nil {
+		return nil
+	}
+
+	if res.Delay > 0 {
+		time.Sleep(res.Delay)
+	}
+
+	if res.Disconnect {
+		return errHangUp
+	}
+
+	if err := r.dialect.respond(c, req, res); err != nil {
+		return err
+	}
+
+	for _, e := range res.Events {
+		evt := *e
+		if evt.TransactionID == 0 {
+			evt.TransactionID = req.TransactionID
+		}
+		if err := r.SendEvent(&evt); err != nil {
+			r.Warnf("[iptest %s responder] unable to send event %#x: %s", r.vendor, evt.Code, err)
+		}
+	}
+
+	return nil
+}
+
+// expectsDataOut checks if the operation request must be held back until the data out phase has been received.
+func (r *Responder) expectsDataOut(req *Request) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dataOut[req.OperationCode] {
+		r.pending[req.TransactionID] = req
+		return true
+	}
+
+	return false
+}
+
+// pendingRequest returns the operation request waiting for its data out phase, if any, for the given transaction ID.
+// When last is true, the request will no longer be considered pending.
+func (r *Responder) pendingRequest(tid ptp.TransactionID, last bool) *Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req := r.pending[tid]
+	if last {
+		delete(r.pending, tid)
+	}
+
+	return req
+}
+
+// initCommand handles the InitCommandRequest by either accepting or rejecting it.
+func (r *Responder) initCommand(c *conn, p ip.InitCommandRequestPacket) error {
+	r.mu.Lock()
+	r.initiator = &ip.Initiator{
+		GUID:         p.GetGUID(),
+		FriendlyName: p.GetFriendlyName(),
+	}
+	reason := r.initFail
+	r.mu.Unlock()
+
+	if reason != 0 {
+		return r.rejectInit(c, reason)
+	}
+
+	guid, _ := uuid.Parse(ResponderGUID)
+	return writePacket(c, ip.PKT_InitCommandAck, internal.MarshalLittleEndian(&ip.InitCommandAckPacket{
+		ConnectionNumber:         ConnectionNumber,
+		ResponderGUID:            guid,
+		ResponderFriendlyName:    ResponderFriendlyName,
+		ResponderProtocolVersion: uint32(p.GetProtocolVersion()),
+	}))
+}
+
+// initEvent handles the InitEventRequest by either accepting or rejecting it.
+func (r *Responder) initEvent(c *conn) error {
+	r.mu.Lock()
+	reason := r.initFail
+	if reason == 0 {
+		r.eventConns[c] = true
+	}
+	r.mu.Unlock()
+
+	if reason != 0 {
+		return r.rejectInit(c, reason)
+	}
+
+	return writePacket(c, ip.PKT_InitEventAck)
+}
+
+// rejectInit sends an InitFail packet. TCP connections are closed by the Responder on failure, so errHangUp is returned
+// when the packet was sent successfully.
+func (r *Responder) rejectInit(c *conn, reason ip.FailReason) error {
+	if err := writePacket(c, ip.PKT_InitFail, internal.MarshalLittleEndian(&ip.InitFailPacket{Reason: reason})); err != nil {
+		return err
+	}
+
+	return errHangUp
+}
+
+// writePacket sends a packet in one go to avoid fragmenting header and payload. An ip.PKT_Invalid packet type means the
+// packet does not adhere to the PTP/IP standard so only the length field is sent as header.
+func writePacket(w io.Writer, pt ip.PacketType, pl ...[]byte) error {
+	var b bytes.Buffer
+
+	l := 0
+	for _, p := range pl {
+		l += len(p)
+	}
+
+	if pt == ip.PKT_Invalid {
+		// The length must include the size of the length field, so we add 4 bytes for that!
+		binary.Write(&b, binary.LittleEndian, uint32(l+4))
+	} else {
+		// The packet length MUST include the header, so we add 8 bytes for that!
+		binary.Write(&b, binary.LittleEndian, ip.Header{Length: uint32(l + ip.HeaderSize), PacketType: pt})
+	}
+
+	for _, p := range pl {
+		b.Write(p)
+	}
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// le marshals fixed size values to a byte array, Little Endian format.
+func le(vs ...interface{}) []byte {
+	var b bytes.Buffer
+	for _, v := range vs {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+
+	return b.Bytes()
+}
+
+// parameters converts a byte array to a list of uint32 values, ignoring any trailing bytes.
+func parameters(b []byte) []uint32 {
+	var ps []uint32
+	for i := 0; i+4 <= len(b); i += 4 {
+		ps = append(ps, binary.LittleEndian.Uint32(b[i:i+4]))
+	}
+
+	return ps
+}
+
+// eventParameters returns exactly three event parameters, padding with 0 when needed.
+func eventParameters(e *Event) []uint32 {
+	ps := make([]uint32, 3)
+	copy(ps, e.Parameters)
+
+	return ps
+}
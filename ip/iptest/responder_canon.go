@@ -0,0 +1,140 @@
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+// Canon EOS devices speak the generic PTP/IP protocol but report events through OC_Canon_EOS_GetEvent instead of the
+// event connection.
+
+func (r *Responder) registerCanonHandlers() {
+	r.SetDevicePropValue(ip.DPC_Canon_EOS_Aperture, le(uint32(0x30)))
+	r.SetDevicePropValue(ip.DPC_Canon_EOS_ShutterSpeed, le(uint32(0x6d)))
+	r.SetDevicePropValue(ip.DPC_Canon_EOS_ISOSpeed, le(uint32(0x48)))
+	r.SetDevicePropValue(ip.DPC_Canon_EOS_EVFOutputDevice, le(uint32(ip.EVF_Canon_None)))
+
+	r.Handle(ip.OC_Canon_EOS_SetRemoteMode, Reply(OK()))
+	r.Handle(ip.OC_Canon_EOS_SetEventMode, r.handleCanonSetEventMode)
+	r.Handle(ip.OC_Canon_EOS_GetEvent, r.handleCanonGetEvent)
+	r.Handle(ip.OC_Canon_EOS_SetDevicePropValueEx, r.handleCanonSetDevicePropValueEx)
+	r.Handle(ip.OC_Canon_EOS_RemoteRelease, r.handleCanonRemoteRelease)
+	r.Handle(ip.OC_Canon_EOS_RemoteReleaseOn, r.handleCanonRemoteReleaseOn)
+	r.Handle(ip.OC_Canon_EOS_RemoteReleaseOff, Reply(OK()))
+	r.Handle(ip.OC_Canon_EOS_BulbStart, Reply(OK()))
+	r.Handle(ip.OC_Canon_EOS_BulbEnd, Reply(OK()))
+	r.Handle(ip.OC_Canon_EOS_GetViewFinderData, r.handleCanonGetViewFinderData)
+}
+
+// QueueCanonEvent queues an event record that will be returned by the next OC_Canon_EOS_GetEvent request. The data is
+// the payload following the record length and type.
+func (r *Responder) QueueCanonEvent(code ptp.EventCode, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.canonEvents = append(r.canonEvents, canonRecord(uint32(code), data))
+}
+
+// canonRecord returns a record as used by OC_Canon_EOS_GetEvent and OC_Canon_EOS_GetViewFinderData: the record length,
+// including the length field itself, followed by the record type and the data.
+func canonRecord(t uint32, data []byte) []byte {
+	return append(le(uint32(len(data)+8), t), data...)
+}
+
+// canonPropValueChanged returns the payload of an EC_Canon_EOS_PropValueChanged event.
+func canonPropValueChanged(code ptp.DevicePropCode, v []byte) []byte {
+	return append(le(uint32(code)), v...)
+}
+
+// handleCanonSetEventMode queues the current value of all device properties, like a real device reports them on the
+// first OC_Canon_EOS_GetEvent request.
+func (r *Responder) handleCanonSetEventMode(_ *Request) *Response {
+	r.mu.Lock()
+	codes := make([]ptp.DevicePropCode, 0, len(r.propValues))
+	for code := range r.propValues {
+		codes = append(codes, code)
+	}
+	r.mu.Unlock()
+
+	for _, code := range codes {
+		v, _ := r.DevicePropValue(code)
+		r.QueueCanonEvent(ip.EC_Canon_EOS_PropValueChanged, canonPropValueChanged(code, v))
+	}
+
+	return OK()
+}
+
+// handleCanonGetEvent returns all queued events followed by the terminating empty record.
+func (r *Responder) handleCanonGetEvent(_ *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, e := range r.canonEvents {
+		b.Write(e)
+	}
+	b.Write(canonRecord(0, nil))
+	r.canonEvents = nil
+
+	return Data(b.Bytes())
+}
+
+// handleCanonSetDevicePropValueEx stores the value received in the data out phase and queues the resulting
+// EC_Canon_EOS_PropValueChanged event.
+func (r *Responder) handleCanonSetDevicePropValueEx(req *Request) *Response {
+	if len(req.Data) < 8 || int(binary.LittleEndian.Uint32(req.Data[0:4])) != len(req.Data) {
+		return Fail(ptp.RC_InvalidDevicePropValue)
+	}
+
+	code := ptp.DevicePropCode(binary.LittleEndian.Uint32(req.Data[4:8]))
+	v := append([]byte{}, req.Data[8:]...)
+	r.SetDevicePropValue(code, v)
+	r.QueueCanonEvent(ip.EC_Canon_EOS_PropValueChanged, canonPropValueChanged(code, v))
+
+	return OK()
+}
+
+// handleCanonRemoteRelease queues an EC_Canon_EOS_ObjectAddedEx event for the captured image.
+func (r *Responder) handleCanonRemoteRelease(req *Request) *Response {
+	r.queueCanonObjectAdded(req.TransactionID)
+
+	return OK()
+}
+
+// handleCanonRemoteReleaseOn queues an EC_Canon_EOS_ObjectAddedEx event for the captured image when the shutter button
+// is pressed all the way.
+func (r *Responder) handleCanonRemoteReleaseOn(req *Request) *Response {
+	if req.Parameter(1) == ip.PM_Canon_EOS_ShutterFull {
+		r.queueCanonObjectAdded(req.TransactionID)
+	}
+
+	return OK()
+}
+
+// queueCanonObjectAdded queues an EC_Canon_EOS_ObjectAddedEx event for a JPEG image using the transaction ID as object
+// handle. The size of the object is the size of the capture preview.
+func (r *Responder) queueCanonObjectAdded(tid ptp.TransactionID) {
+	d := make([]byte, 0x20)
+	binary.LittleEndian.PutUint32(d[0x00:0x04], uint32(tid))
+	binary.LittleEndian.PutUint32(d[0x04:0x08], 0x00010001)
+	binary.LittleEndian.PutUint16(d[0x08:0x0A], uint16(ptp.OFC_EXIF_JPEG))
+	binary.LittleEndian.PutUint32(d[0x14:0x18], uint32(len(r.capturePreview())))
+	d = append(d, "IMG_0001.JPG\x00"...)
+
+	r.QueueCanonEvent(ip.EC_Canon_EOS_ObjectAddedEx, d)
+}
+
+// handleCanonGetViewFinderData returns the capture preview as live view frame when DPC_Canon_EOS_EVFOutputDevice is set
+// to EVF_Canon_PC. Otherwise, the request fails with RC_Canon_NotReady just like a real device does.
+func (r *Responder) handleCanonGetViewFinderData(_ *Request) *Response {
+	v, _ := r.DevicePropValue(ip.DPC_Canon_EOS_EVFOutputDevice)
+	img := r.capturePreview()
+	if len(v) < 4 || ip.CanonEVFOutputDevice(binary.LittleEndian.Uint32(v)) != ip.EVF_Canon_PC || img == nil {
+		return Fail(ip.RC_Canon_NotReady)
+	}
+
+	// A real device sends additional records holding e.g. the zoom position, which the Initiator must skip.
+	return Data(append(canonRecord(0x04, le(uint32(1))), canonRecord(0x01, img)...))
+}
@@ -0,0 +1,304 @@
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"time"
+)
+
+// fujiEventDataPhase is the 'data phase' field of a Fuji event which always seems to be set to 0x0004.
+const fujiEventDataPhase uint16 = 0x0004
+
+// fujiDialect speaks the Fuji flavour of the PTP/IP protocol which does not send out packet types except for the
+// InitCommandRequest and InitCommandAck packets. Fuji also does not use data packets for the data out phase, it sends a
+// second operation request packet instead.
+type fujiDialect struct{}
+
+func (fujiDialect) read(r *Responder, c *conn) error {
+	var l uint32
+	if err := binary.Read(c, binary.LittleEndian, &l); err != nil {
+		return err
+	}
+	if l < 8 {
+		return fmt.Errorf("invalid packet length %d", l)
+	}
+
+	b := make([]byte, int(l)-4)
+	if _, err := io.ReadFull(c, b); err != nil {
+		return err
+	}
+
+	// The InitCommandRequest is the only packet that is sent out with a packet type by the Fuji Initiator.
+	if binary.LittleEndian.Uint32(b[0:4]) == uint32(ip.PKT_InitCommandRequest) {
+		p := new(ip.FujiInitCommandRequestPacket)
+		vs := len(b) - 4 - internal.TotalSizeOfFixedFields(p)
+		if _, err := internal.UnmarshalLittleEndian(bytes.NewReader(b[4:]), p, len(b)-4, vs); err != nil {
+			return err
+		}
+		return r.initCommand(c, p)
+	}
+
+	req := &Request{
+		DataPhase:     ip.DataPhase(binary.LittleEndian.Uint16(b[0:2])),
+		OperationCode: ptp.OperationCode(binary.LittleEndian.Uint16(b[2:4])),
+		TransactionID: ptp.TransactionID(binary.LittleEndian.Uint32(b[4:8])),
+		Parameters:    parameters(b[8:]),
+	}
+
+	// The data out phase is a second operation request packet with the same transaction ID, its first parameter being
+	// the actual data.
+	if req.DataPhase == ip.DP_DataOut {
+		if p := r.pendingRequest(req.TransactionID, true); p != nil {
+			p.Data = le(req.Parameter(1))
+			return r.process(c, p)
+		}
+	} else if r.expectsDataOut(req) {
+		return nil
+	}
+
+	return r.process(c, req)
+}
+
+func (fujiDialect) respond(c *conn, req *Request, res *Response) error {
+	tid := uint32(req.TransactionID)
+
+	if res.Data != nil {
+		// Fuji uses the operation code as response code when sending data.
+		if err := writePacket(c, ip.PKT_Invalid, le(uint16(ip.DP_DataOut), uint16(req.OperationCode), tid), res.Data); err != nil {
+			return err
+		}
+	}
+
+	// The end of data packet carrying the actual response code.
+	return writePacket(c, ip.PKT_Invalid, le(uint16(ip.DP_Unknown), uint16(res.Code), tid))
+}
+
+func (fujiDialect) event(w io.Writer, e *Event) error {
+	// The field following the event code always seems to be set to 1.
+	return writePacket(w, ip.PKT_Invalid, le(fujiEventDataPhase, uint16(e.Code), uint32(1), uint32(e.TransactionID), eventParameters(e)))
+}
+
+func (r *Responder) registerFujiHandlers() {
+	r.SetDevicePropValue(ip.DPC_Fuji_AppVersion, le(uint32(ip.PM_Fuji_AppVersion)))
+	r.SetDevicePropValue(ip.DPC_Fuji_CurrentState, fujiCurrentState)
+	r.SetDevicePropDesc(ip.DPC_Fuji_FocusMeteringMode, fujiFocusMeteringModeDesc)
+	r.SetDevicePropValue(ip.DPC_Fuji_FocusMeteringMode, le(fujiFocusPoint))
+	r.SetDevicePropValue(ip.DPC_Fuji_MovieRemainingTime, le(uint32(1679)))
+	r.SetDevicePropValue(ip.DPC_Fuji_ImageQuality, le(uint16(ip.IQ_Fuji_Fine)))
+	r.SetDevicePropDesc(ptp.DPC_WhiteBalance, fujiWhiteBalanceDesc)
+	r.SetDevicePropDesc(ip.DPC_Fuji_FilmSimulation, fujiFilmSimulationDesc)
+	r.SetDevicePropDesc(ip.DPC_Fuji_ImageQuality, fujiImageQualityDesc)
+	r.setFujiDeviceInfoDescs()
+
+	r.Handle(ptp.OC_OpenSession, Reply(OK()))
+	r.Handle(ptp.OC_InitiateOpenCapture, r.handleInitiateOpenCapture)
+	r.Handle(ptp.OC_TerminateOpenCapture, r.handleFujiTerminateOpenCapture)
+	r.Handle(ip.OC_Fuji_GetDeviceInfo, Reply(Data(fujiDeviceInfo)))
+	r.Handle(ptp.OC_GetDevicePropDesc, r.handleGetDevicePropDesc)
+	r.Handle(ptp.OC_GetDevicePropValue, r.handleGetDevicePropValue)
+	r.HandleDataOut(ptp.OC_SetDevicePropValue, r.handleFujiSetDevicePropValue)
+	r.Handle(ptp.OC_InitiateCapture, r.handleFujiInitiateCapture)
+	r.Handle(ip.OC_Fuji_GetCapturePreview, r.handleFujiGetCapturePreview)
+	r.Handle(ptp.OC_GetObjectHandles, r.handleGetObjectHandles)
+	r.Handle(ptp.OC_GetObjectInfo, r.handleGetObjectInfo)
+	r.Handle(ptp.OC_GetObject, r.handleGetObject)
+	r.Handle(ip.OC_Fuji_SetFocusPoint, r.handleFujiSetFocusPoint)
+	r.Handle(ip.OC_Fuji_ResetFocusPoint, r.handleFujiResetFocusPoint)
+}
+
+// setFujiDeviceInfoDescs stores the descriptions held by fujiDeviceInfo for all device properties that have no
+// description yet.
+func (r *Responder) setFujiDeviceInfoDescs() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Each description is preceded by its length, which includes the 4 bytes of the length itself.
+	d := fujiDeviceInfo[4:]
+	for len(d) >= 6 {
+		l := int(binary.LittleEndian.Uint32(d))
+		if l < 6 || len(d) < l {
+			return
+		}
+		code := ptp.DevicePropCode(binary.LittleEndian.Uint16(d[4:]))
+		if _, ok := r.propDescs[code]; !ok {
+			r.propDescs[code] = d[4:l]
+		}
+		d = d[l:]
+	}
+}
+
+// PromptPairing makes the Fuji Responder prompt the user to accept the connection of an Initiator with a GUID or
+// friendly name differing from the one it was last paired with: setting ip.DPC_Fuji_InitSequence is only acknowledged after the given
+// delay, simulating the user pressing the 'OK' button. Pass 0 to never prompt, which is the default.
+func (r *Responder) PromptPairing(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pairDelay = d
+}
+
+// SetFujiStateValue sets the value of the given device property in ip.DPC_Fuji_CurrentState, adding the property when
+// it is not part of the state yet. This simulates the camera state changing, e.g. the battery draining.
+func (r *Responder) SetFujiStateValue(code ptp.DevicePropCode, v uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := append([]byte{}, r.propValues[ip.DPC_Fuji_CurrentState]...)
+	if len(s) < 2 {
+		s = make([]byte, 2)
+	}
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	for i := 2; i+6 <= len(s); i += 6 {
+		if ptp.DevicePropCode(binary.LittleEndian.Uint16(s[i:])) == code {
+			copy(s[i+2:], b[:])
+			r.propValues[ip.DPC_Fuji_CurrentState] = s
+			return
+		}
+	}
+
+	binary.LittleEndian.PutUint16(s, binary.LittleEndian.Uint16(s)+1)
+	s = append(s, byte(code), byte(code>>8))
+	r.propValues[ip.DPC_Fuji_CurrentState] = append(s, b[:]...)
+}
+
+// handleFujiSetDevicePropValue stores the value received in the data out phase. Setting ip.DPC_Fuji_InitSequence pairs
+// the Initiator, prompting the user when required.
+func (r *Responder) handleFujiSetDevicePropValue(req *Request) *Response {
+	res := r.handleSetDevicePropValue(req)
+	if ptp.DevicePropCode(req.Parameter(1)) != ip.DPC_Fuji_InitSequence {
+		return res
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.initiator; i != nil && (i.GUID != r.paired.GUID || i.FriendlyName != r.paired.FriendlyName) {
+		res.Delay = r.pairDelay
+		r.paired = *i
+	}
+
+	return res
+}
+
+// fujiFocusPoint is the value of DPC_Fuji_FocusMeteringMode as returned by an X-T1 with the focus point in the centre.
+const fujiFocusPoint uint32 = 0x03020404
+
+// handleFujiSetFocusPoint stores the focus point passed as first parameter as the value of
+// DPC_Fuji_FocusMeteringMode.
+func (r *Responder) handleFujiSetFocusPoint(req *Request) *Response {
+	r.SetDevicePropValue(ip.DPC_Fuji_FocusMeteringMode, le(req.Parameter(1)))
+
+	return OK()
+}
+
+// handleFujiResetFocusPoint moves the focus point back to the centre, keeping the focus point grid.
+func (r *Responder) handleFujiResetFocusPoint(_ *Request) *Response {
+	v, _ := r.DevicePropValue(ip.DPC_Fuji_FocusMeteringMode)
+	fp := ip.NewFujiFocusPoint(fujiFocusPoint)
+	if len(v) == 4 {
+		fp.GridSize = ip.NewFujiFocusPoint(binary.LittleEndian.Uint32(v)).GridSize
+	}
+	r.SetDevicePropValue(ip.DPC_Fuji_FocusMeteringMode, le(fp.Uint32()))
+
+	return OK()
+}
+
+// handleFujiInitiateCapture responds to the capture request and sends out the object added and preview available
+// events. Parameter1 of Fuji events is always set to the transaction ID.
+func (r *Responder) handleFujiInitiateCapture(req *Request) *Response {
+	res := OK()
+	res.Events = []*Event{
+		{Code: ip.EC_Fuji_ObjectAdded, Parameters: []uint32{uint32(req.TransactionID)}},
+		{Code: ip.EC_Fuji_PreviewAvailable, Parameters: []uint32{uint32(req.TransactionID), uint32(len(r.capturePreview()))}},
+	}
+
+	return res
+}
+
+// handleFujiTerminateOpenCapture closes the open capture and sends out the same events as handleFujiInitiateCapture.
+func (r *Responder) handleFujiTerminateOpenCapture(req *Request) *Response {
+	if !r.terminateOpenCapture(req) {
+		return Fail(ptp.RC_InvalidTransactionID)
+	}
+
+	return r.handleFujiInitiateCapture(req)
+}
+
+// handleFujiGetCapturePreview sends the capture preview followed by the capture complete event.
+func (r *Responder) handleFujiGetCapturePreview(req *Request) *Response {
+	res := Data(r.capturePreview())
+	if res.Data == nil {
+		res.Data = []byte{}
+	}
+	res.Events = []*Event{
+		{Code: ptp.EC_CaptureComplete, Parameters: []uint32{uint32(req.TransactionID)}},
+	}
+
+	return res
+}
+
+// fujiDeviceInfo is the response of an X-T1 to OC_Fuji_GetDeviceInfo.
+var fujiDeviceInfo = []byte{
+	0x08, 0x00, 0x00, 0x00, 0x16, 0x00, 0x00, 0x00, 0x12, 0x50, 0x04, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02,
+	0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x04, 0x00, 0x14, 0x00, 0x00, 0x00, 0x0c, 0x50, 0x04, 0x00, 0x01, 0x02,
+	0x00, 0x09, 0x80, 0x02, 0x02, 0x00, 0x09, 0x80, 0x0a, 0x80, 0x24, 0x00, 0x00, 0x00, 0x05, 0x50, 0x04, 0x00,
+	0x01, 0x02, 0x00, 0x02, 0x00, 0x02, 0x0a, 0x00, 0x02, 0x00, 0x04, 0x00, 0x06, 0x80, 0x01, 0x80, 0x02, 0x80,
+	0x03, 0x80, 0x06, 0x00, 0x0a, 0x80, 0x0b, 0x80, 0x0c, 0x80, 0x36, 0x00, 0x00, 0x00, 0x10, 0x50, 0x03, 0x00,
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x13, 0x00, 0x48, 0xf4, 0x95, 0xf5, 0xe3, 0xf6, 0x30, 0xf8, 0x7d, 0xf9,
+	0xcb, 0xfa, 0x18, 0xfc, 0x65, 0xfd, 0xb3, 0xfe, 0x00, 0x00, 0x4d, 0x01, 0x9b, 0x02, 0xe8, 0x03, 0x35, 0x05,
+	0x83, 0x06, 0xd0, 0x07, 0x1d, 0x09, 0x6b, 0x0a, 0xb8, 0x0b, 0x26, 0x00, 0x00, 0x00, 0x01, 0xd0, 0x04, 0x00,
+	0x01, 0x01, 0x00, 0x02, 0x00, 0x02, 0x0b, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04, 0x00, 0x05, 0x00,
+	0x06, 0x00, 0x07, 0x00, 0x08, 0x00, 0x09, 0x00, 0x0a, 0x00, 0x0b, 0x00, 0x78, 0x00, 0x00, 0x00, 0x2a, 0xd0,
+	0x06, 0x00, 0x01, 0xff, 0xff, 0xff, 0xff, 0x00, 0x19, 0x00, 0x80, 0x02, 0x19, 0x00, 0x90, 0x01, 0x00, 0x80,
+	0x20, 0x03, 0x00, 0x80, 0x40, 0x06, 0x00, 0x80, 0x80, 0x0c, 0x00, 0x80, 0x00, 0x19, 0x00, 0x80, 0x64, 0x00,
+	0x00, 0x40, 0xc8, 0x00, 0x00, 0x00, 0xfa, 0x00, 0x00, 0x00, 0x40, 0x01, 0x00, 0x00, 0x90, 0x01, 0x00, 0x00,
+	0xf4, 0x01, 0x00, 0x00, 0x80, 0x02, 0x00, 0x00, 0x20, 0x03, 0x00, 0x00, 0xe8, 0x03, 0x00, 0x00, 0xe2, 0x04,
+	0x00, 0x00, 0x40, 0x06, 0x00, 0x00, 0xd0, 0x07, 0x00, 0x00, 0xc4, 0x09, 0x00, 0x00, 0x80, 0x0c, 0x00, 0x00,
+	0xa0, 0x0f, 0x00, 0x00, 0x88, 0x13, 0x00, 0x00, 0x00, 0x19, 0x00, 0x00, 0x00, 0x32, 0x00, 0x40, 0x00, 0x64,
+	0x00, 0x40, 0x00, 0xc8, 0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x19, 0xd0, 0x04, 0x00, 0x01, 0x01, 0x00, 0x01,
+	0x00, 0x02, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x1e, 0x00, 0x00, 0x00, 0x7c, 0xd1, 0x06, 0x00, 0x01, 0x00,
+	0x00, 0x00, 0x00, 0x02, 0x07, 0x02, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00, 0x07, 0x07, 0x09, 0x10, 0x01, 0x00,
+	0x00, 0x00,
+}
+
+// fujiCurrentState is the value of DPC_Fuji_CurrentState as returned by an X-T1.
+var fujiCurrentState = []byte{
+	0x11, 0x00, 0x01, 0x50, 0x02, 0x00, 0x00, 0x00, 0x41, 0xd2, 0x0a, 0x00, 0x00, 0x00, 0x05, 0x50, 0x02, 0x00,
+	0x00, 0x00, 0x0a, 0x50, 0x01, 0x80, 0x00, 0x00, 0x0c, 0x50, 0x0a, 0x80, 0x00, 0x00, 0x0e, 0x50, 0x02, 0x00,
+	0x00, 0x00, 0x10, 0x50, 0xb3, 0xfe, 0x00, 0x00, 0x12, 0x50, 0x00, 0x00, 0x00, 0x00, 0x01, 0xd0, 0x02, 0x00,
+	0x00, 0x00, 0x18, 0xd0, 0x04, 0x00, 0x00, 0x00, 0x28, 0xd0, 0x00, 0x00, 0x00, 0x00, 0x2a, 0xd0, 0x00, 0x19,
+	0x00, 0x80, 0x7c, 0xd1, 0x02, 0x07, 0x02, 0x03, 0x09, 0xd2, 0x00, 0x00, 0x00, 0x00, 0x1b, 0xd2, 0x00, 0x00,
+	0x00, 0x00, 0x29, 0xd2, 0xd6, 0x05, 0x00, 0x00, 0x2a, 0xd2, 0x8f, 0x06, 0x00, 0x00,
+}
+
+// fujiFocusMeteringModeDesc is the description of DPC_Fuji_FocusMeteringMode as returned by an X-T1.
+var fujiFocusMeteringModeDesc = []byte{
+	0x7c, 0xd1, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x07, 0x02, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00,
+	0x07, 0x07, 0x09, 0x10, 0x01, 0x00, 0x00, 0x00,
+}
+
+// fujiWhiteBalanceDesc is the description of ptp.DPC_WhiteBalance as returned by an X-T1.
+var fujiWhiteBalanceDesc = []byte{
+	0x05, 0x50, 0x04, 0x00, 0x01, 0x02, 0x00, 0x02, 0x00, 0x02, 0x0a, 0x00, 0x02, 0x00, 0x04, 0x00, 0x06, 0x80,
+	0x01, 0x80, 0x02, 0x80, 0x03, 0x80, 0x06, 0x00, 0x0a, 0x80, 0x0b, 0x80, 0x0c, 0x80,
+}
+
+// fujiImageQualityDesc is the description of DPC_Fuji_ImageQuality listing the image qualities the camera accepts in
+// remote control mode.
+var fujiImageQualityDesc = []byte{
+	0x18, 0xd0, 0x04, 0x00, 0x01, 0x02, 0x00, 0x02, 0x00, 0x02, 0x04, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04, 0x00,
+	0x05, 0x00,
+}
+
+// fujiFilmSimulationDesc is the description of DPC_Fuji_FilmSimulation as returned by an X-T1.
+var fujiFilmSimulationDesc = []byte{
+	0x01, 0xd0, 0x04, 0x00, 0x01, 0x01, 0x00, 0x01, 0x00, 0x02, 0x0b, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00,
+	0x04, 0x00, 0x05, 0x00, 0x06, 0x00, 0x07, 0x00, 0x08, 0x00, 0x09, 0x00, 0x0a, 0x00, 0x0b, 0x00,
+}
@@ -0,0 +1,137 @@
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+)
+
+// genericDialect speaks the PTP/IP protocol as it is specified.
+type genericDialect struct{}
+
+func (genericDialect) read(r *Responder, c *conn) error {
+	var h ip.Header
+	if err := binary.Read(c, binary.LittleEndian, &h); err != nil {
+		return err
+	}
+	if int(h.Length) < ip.HeaderSize {
+		return fmt.Errorf("invalid packet length %d", h.Length)
+	}
+
+	b := make([]byte, int(h.Length)-ip.HeaderSize)
+	if _, err := io.ReadFull(c, b); err != nil {
+		return err
+	}
+
+	switch h.PacketType {
+	case ip.PKT_InitCommandRequest:
+		p := new(ip.GenericInitCommandRequestPacket)
+		vs := len(b) - internal.TotalSizeOfFixedFields(p)
+		if _, err := internal.UnmarshalLittleEndian(bytes.NewReader(b), p, len(b), vs); err != nil {
+			return err
+		}
+		return r.initCommand(c, p)
+	case ip.PKT_InitEventRequest:
+		return r.initEvent(c)
+	case ip.PKT_OperationRequest:
+		if len(b) < 10 {
+			return fmt.Errorf("operation request too small: got length %d", len(b))
+		}
+		req := &Request{
+			DataPhase:     ip.DataPhase(binary.LittleEndian.Uint32(b[0:4])),
+			OperationCode: ptp.OperationCode(binary.LittleEndian.Uint16(b[4:6])),
+			TransactionID: ptp.TransactionID(binary.LittleEndian.Uint32(b[6:10])),
+			Parameters:    parameters(b[10:]),
+		}
+		// The data out phase will follow using data packets, we will respond once they have all been received.
+		if req.DataPhase == ip.DP_DataOut {
+			r.mu.Lock()
+			r.pending[req.TransactionID] = req
+			r.mu.Unlock()
+			return nil
+		}
+		return r.process(c, req)
+	case ip.PKT_StartData:
+		return nil
+	case ip.PKT_Data, ip.PKT_EndData:
+		if len(b) < 4 {
+			return fmt.Errorf("data packet too small: got length %d", len(b))
+		}
+		last := h.PacketType == ip.PKT_EndData
+		req := r.pendingRequest(ptp.TransactionID(binary.LittleEndian.Uint32(b[0:4])), last)
+		if req == nil {
+			return nil
+		}
+		req.Data = append(req.Data, b[4:]...)
+		if last {
+			return r.process(c, req)
+		}
+		return nil
+	case ip.PKT_ProbeRequest:
+		return writePacket(c, ip.PKT_ProbeResponse)
+	}
+
+	r.Errorf("[iptest %s responder] unknown packet type %#x", r.vendor, h.PacketType)
+
+	return nil
+}
+
+func (genericDialect) respond(c *conn, req *Request, res *Response) error {
+	tid := uint32(req.TransactionID)
+
+	if res.Data != nil {
+		if err := writePacket(c, ip.PKT_StartData, le(tid, uint64(len(res.Data)))); err != nil {
+			return err
+		}
+		if err := writePacket(c, ip.PKT_EndData, le(tid), res.Data); err != nil {
+			return err
+		}
+	}
+
+	return writePacket(c, ip.PKT_OperationResponse, le(uint16(res.Code), tid, res.Parameters))
+}
+
+func (genericDialect) event(w io.Writer, e *Event) error {
+	return writePacket(w, ip.PKT_Event, le(uint16(e.Code), uint32(e.TransactionID), eventParameters(e)))
+}
+
+func (r *Responder) registerGenericHandlers() {
+	r.Handle(ptp.OC_GetDeviceInfo, Reply(OK()))
+	r.Handle(ptp.OC_OpenSession, Reply(OK()))
+	r.Handle(ptp.OC_CloseSession, Reply(OK()))
+	r.Handle(ptp.OC_GetDevicePropDesc, r.handleGenericGetDevicePropDesc)
+	r.Handle(ptp.OC_InitiateOpenCapture, r.handleInitiateOpenCapture)
+	r.Handle(ptp.OC_TerminateOpenCapture, r.handleGenericTerminateOpenCapture)
+}
+
+// handleGenericGetDevicePropDesc returns the description stored for the device property passed as first parameter. A
+// Responder following the specification fails the request for unknown device properties.
+func (r *Responder) handleGenericGetDevicePropDesc(req *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.propDescs[ptp.DevicePropCode(req.Parameter(1))]
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+
+	return Data(append([]byte{}, d...))
+}
+
+// handleGenericTerminateOpenCapture closes the open capture and sends out the capture complete event.
+func (r *Responder) handleGenericTerminateOpenCapture(req *Request) *Response {
+	if !r.terminateOpenCapture(req) {
+		return Fail(ptp.RC_InvalidTransactionID)
+	}
+
+	res := OK()
+	res.Events = []*Event{
+		{Code: ptp.EC_CaptureComplete, Parameters: []uint32{req.Parameter(1)}},
+	}
+
+	return res
+}
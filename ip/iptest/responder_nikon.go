@@ -0,0 +1,148 @@
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+// Nikon devices speak the generic PTP/IP protocol but need to be polled using OC_Nikon_DeviceReady after each
+// operation that takes time to complete.
+
+const (
+	// nikonSdramHandle is the object handle used by Nikon devices for a capture stored in SDRAM.
+	nikonSdramHandle uint32 = 0xFFFF0001
+	// nikonLiveViewHeaderSize is the size of the header preceding the JPEG image returned by OC_Nikon_GetLiveViewImg.
+	nikonLiveViewHeaderSize = 0x180
+)
+
+// nikonLiveViewAreas are the big endian values the live view header starts with: a 640x424 JPEG image of a 6000x4000
+// whole image, showing the whole image and having a 300x200 AF area centered in it. These are made up values, no frame
+// captured from a real device is available.
+var nikonLiveViewAreas = []uint16{640, 424, 6000, 4000, 6000, 4000, 3000, 2000, 300, 200, 3000, 2000}
+
+func (r *Responder) registerNikonHandlers() {
+	r.SetDevicePropValue(ip.DPC_Nikon_LiveViewStatus, le(uint8(ip.LVS_Nikon_Off)))
+	r.SetDevicePropValue(ip.DPC_Nikon_RecordingMedia, le(uint8(ip.RM_Nikon_SDRAM)))
+
+	r.Handle(ptp.OC_GetDevicePropValue, r.handleGetDevicePropValue)
+	r.Handle(ptp.OC_SetDevicePropValue, r.handleSetDevicePropValue)
+	r.Handle(ip.OC_Nikon_CheckEvent, r.handleNikonCheckEvent)
+	r.Handle(ip.OC_Nikon_DeviceReady, r.handleNikonDeviceReady)
+	r.Handle(ip.OC_Nikon_AfDrive, r.handleNikonAfDrive)
+	r.Handle(ip.OC_Nikon_InitiateCaptureRecInSdram, r.handleNikonInitiateCaptureRecInSdram)
+	r.Handle(ip.OC_Nikon_StartLiveView, r.handleNikonStartLiveView)
+	r.Handle(ip.OC_Nikon_EndLiveView, r.handleNikonEndLiveView)
+	r.Handle(ip.OC_Nikon_GetLiveViewImg, r.handleNikonGetLiveViewImg)
+}
+
+// QueueNikonEvent queues an event that will be returned by the next OC_Nikon_CheckEvent request.
+func (r *Responder) QueueNikonEvent(code ptp.EventCode, param uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nikonEvents = append(r.nikonEvents, le(uint16(code), param))
+}
+
+// setNikonBusy makes the Responder reply ptp.RC_DeviceBusy to the next n OC_Nikon_DeviceReady requests.
+func (r *Responder) setNikonBusy(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nikonBusy = n
+}
+
+// nikonLiveView returns true when live view has been started.
+func (r *Responder) nikonLiveView() bool {
+	v, _ := r.DevicePropValue(ip.DPC_Nikon_LiveViewStatus)
+
+	return len(v) > 0 && ip.NikonLiveViewStatus(v[0]) == ip.LVS_Nikon_On
+}
+
+// handleNikonCheckEvent returns the number of queued events followed by the events.
+func (r *Responder) handleNikonCheckEvent(_ *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b bytes.Buffer
+	b.Write(le(uint16(len(r.nikonEvents))))
+	for _, e := range r.nikonEvents {
+		b.Write(e)
+	}
+	r.nikonEvents = nil
+
+	return Data(b.Bytes())
+}
+
+// handleNikonDeviceReady reports the device as busy until the operation that made it busy has been polled for long
+// enough.
+func (r *Responder) handleNikonDeviceReady(_ *Request) *Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nikonBusy > 0 {
+		r.nikonBusy--
+		return Fail(ptp.RC_DeviceBusy)
+	}
+
+	return OK()
+}
+
+// handleNikonAfDrive keeps the device busy for a single OC_Nikon_DeviceReady request while focusing.
+func (r *Responder) handleNikonAfDrive(_ *Request) *Response {
+	r.setNikonBusy(1)
+
+	return OK()
+}
+
+// handleNikonInitiateCaptureRecInSdram keeps the device busy for two OC_Nikon_DeviceReady requests and queues the
+// events a real device sends after capturing an image to SDRAM.
+func (r *Responder) handleNikonInitiateCaptureRecInSdram(_ *Request) *Response {
+	r.setNikonBusy(2)
+	r.QueueNikonEvent(ip.EC_Nikon_ObjectAddedInSdram, nikonSdramHandle)
+	r.QueueNikonEvent(ip.EC_Nikon_CaptureCompleteRecInSdram, 0)
+
+	return OK()
+}
+
+// handleNikonStartLiveView sets DPC_Nikon_LiveViewStatus and keeps the device busy for a single OC_Nikon_DeviceReady
+// request.
+func (r *Responder) handleNikonStartLiveView(_ *Request) *Response {
+	r.SetDevicePropValue(ip.DPC_Nikon_LiveViewStatus, le(uint8(ip.LVS_Nikon_On)))
+	r.setNikonBusy(1)
+
+	return OK()
+}
+
+// handleNikonEndLiveView resets DPC_Nikon_LiveViewStatus. The request fails with RC_Nikon_NotLiveView when live view
+// was not started, just like a real device does.
+func (r *Responder) handleNikonEndLiveView(_ *Request) *Response {
+	if !r.nikonLiveView() {
+		return Fail(ip.RC_Nikon_NotLiveView)
+	}
+	r.SetDevicePropValue(ip.DPC_Nikon_LiveViewStatus, le(uint8(ip.LVS_Nikon_Off)))
+
+	return OK()
+}
+
+// handleNikonGetLiveViewImg returns the capture preview as live view frame, preceded by a header holding
+// nikonLiveViewAreas, when live view has been started. Otherwise, the request fails with RC_Nikon_NotLiveView. When there is no capture preview, the
+// device reports being busy.
+func (r *Responder) handleNikonGetLiveViewImg(_ *Request) *Response {
+	if !r.nikonLiveView() {
+		return Fail(ip.RC_Nikon_NotLiveView)
+	}
+
+	img := r.capturePreview()
+	if img == nil {
+		return Fail(ptp.RC_DeviceBusy)
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, nikonLiveViewAreas)
+	b.Write(make([]byte, nikonLiveViewHeaderSize-b.Len()))
+	b.Write(img)
+
+	return Data(b.Bytes())
+}
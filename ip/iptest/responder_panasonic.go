@@ -0,0 +1,113 @@
+package iptest
+
+import (
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+// Panasonic devices speak the generic PTP/IP protocol but use their own operations to get and set properties, which
+// take a 32 bit property code. The values are stored using the lower 16 bits of the property code so they can be
+// manipulated using SetDevicePropValue() and DevicePropValue().
+
+// panasonicLiveViewHeaderSize is the size of the information preceding the JPEG image returned by
+// OC_Panasonic_LiveViewImage.
+const panasonicLiveViewHeaderSize = 0x1C0
+
+func (r *Responder) registerPanasonicHandlers() {
+	r.SetDevicePropValue(ip.DPC_Panasonic_ISO, le(uint32(200)))
+	r.SetDevicePropValue(ip.DPC_Panasonic_ShutterSpeed, le(ip.SS_Panasonic_Fraction|uint32(125000)))
+	r.SetDevicePropValue(ip.DPC_Panasonic_Aperture, le(uint16(56)))
+	r.SetDevicePropValue(ip.DPC_Panasonic_ExposureCompensation, le(int16(0)))
+
+	r.Handle(ip.OC_Panasonic_GetProperty, r.handlePanasonicGetProperty)
+	r.Handle(ip.OC_Panasonic_SetProperty, r.handlePanasonicSetProperty)
+	r.Handle(ip.OC_Panasonic_InitiateCapture, Reply(OK()))
+	r.Handle(ip.OC_Panasonic_LiveView, r.handlePanasonicLiveView)
+	r.Handle(ip.OC_Panasonic_LiveViewImage, r.handlePanasonicLiveViewImage)
+}
+
+// panasonicDevicePropCode converts the 32 bit property code passed as first parameter to a ptp.DevicePropCode.
+func panasonicDevicePropCode(req *Request) (ptp.DevicePropCode, bool) {
+	p := req.Parameter(1)
+	if p&0xFFFF0000 != ip.PM_Panasonic_CameraSettings {
+		return 0, false
+	}
+
+	return ptp.DevicePropCode(p), true
+}
+
+// handlePanasonicGetProperty returns the 32 bit property code and the size of the value followed by the value.
+func (r *Responder) handlePanasonicGetProperty(req *Request) *Response {
+	code, ok := panasonicDevicePropCode(req)
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+
+	v, ok := r.DevicePropValue(code)
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+
+	return Data(append(le(req.Parameter(1), uint32(len(v))), v...))
+}
+
+// handlePanasonicSetProperty stores the value received in the data out phase. The size of the value must match the
+// size of the current value.
+func (r *Responder) handlePanasonicSetProperty(req *Request) *Response {
+	code, ok := panasonicDevicePropCode(req)
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+
+	cur, ok := r.DevicePropValue(code)
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+	if len(req.Data) < 8 || binary.LittleEndian.Uint32(req.Data[0:4]) != req.Parameter(1) {
+		return Fail(ptp.RC_InvalidParameter)
+	}
+	size := binary.LittleEndian.Uint32(req.Data[4:8])
+	if int(size) != len(cur) || int(size) != len(req.Data)-8 {
+		return Fail(ptp.RC_InvalidDevicePropFormat)
+	}
+	r.SetDevicePropValue(code, append([]byte{}, req.Data[8:]...))
+
+	return OK()
+}
+
+// handlePanasonicLiveView starts or stops live view.
+func (r *Responder) handlePanasonicLiveView(req *Request) *Response {
+	switch req.Parameter(1) {
+	case ip.PM_Panasonic_LiveViewStart:
+		r.setPanasonicLiveView(true)
+	case ip.PM_Panasonic_LiveViewStop:
+		r.setPanasonicLiveView(false)
+	default:
+		return Fail(ptp.RC_InvalidParameter)
+	}
+
+	return OK()
+}
+
+func (r *Responder) setPanasonicLiveView(en bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.panasonicLV = en
+}
+
+// handlePanasonicLiveViewImage returns the capture preview preceded by a header. The request fails with
+// ptp.RC_DeviceBusy when live view has not been started or when there is no capture preview.
+func (r *Responder) handlePanasonicLiveViewImage(_ *Request) *Response {
+	r.mu.Lock()
+	en := r.panasonicLV
+	r.mu.Unlock()
+
+	img := r.capturePreview()
+	if !en || img == nil {
+		return Fail(ptp.RC_DeviceBusy)
+	}
+
+	return Data(append(make([]byte, panasonicLiveViewHeaderSize), img...))
+}
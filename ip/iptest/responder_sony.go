@@ -0,0 +1,166 @@
+package iptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"sort"
+)
+
+// Sony devices speak the generic PTP/IP protocol but need an SDIO handshake and report all device properties in a
+// single OC_Sony_SDIO_GetAllExtDevicePropInfo operation.
+
+const (
+	// sonyLiveViewHeaderSize is the size of the information preceding the JPEG image returned for
+	// ip.PM_Sony_LiveViewHandle.
+	sonyLiveViewHeaderSize = 0x88
+	// sonyCaptureHandle is the object handle used by Sony devices for a captured image.
+	sonyCaptureHandle uint32 = 0xFFFFC001
+)
+
+func (r *Responder) registerSonyHandlers() {
+	r.setSonyDevicePropDesc(&ptp.DevicePropDesc{
+		DevicePropertyCode:  ip.DPC_Sony_ShutterSpeed,
+		DataType:            ptp.DTC_UINT32,
+		GetSet:              ptp.DPD_GetSet,
+		FactoryDefaultValue: le(uint32(0x000100FA)),
+		CurrentValue:        le(uint32(0x000100FA)),
+	})
+	r.setSonyDevicePropDesc(&ptp.DevicePropDesc{
+		DevicePropertyCode:  ip.DPC_Sony_ISO,
+		DataType:            ptp.DTC_UINT32,
+		GetSet:              ptp.DPD_GetSet,
+		FactoryDefaultValue: le(ip.ISO_Sony_Auto),
+		CurrentValue:        le(uint32(100)),
+	})
+	r.setSonyDevicePropDesc(&ptp.DevicePropDesc{
+		DevicePropertyCode:  ip.DPC_Sony_BatteryLevel,
+		DataType:            ptp.DTC_INT8,
+		GetSet:              ptp.DPD_Get,
+		FactoryDefaultValue: le(int8(-1)),
+		CurrentValue:        le(int8(80)),
+	})
+
+	r.Handle(ip.OC_Sony_SDIO_Connect, Reply(Data(make([]byte, 8))))
+	r.Handle(ip.OC_Sony_SDIO_GetExtDeviceInfo, r.handleSonyGetExtDeviceInfo)
+	r.Handle(ip.OC_Sony_SDIO_GetAllExtDevicePropInfo, r.handleSonyGetAllExtDevicePropInfo)
+	r.Handle(ip.OC_Sony_SDIO_SetExtDevicePropValue, r.handleSonySetExtDevicePropValue)
+	r.Handle(ip.OC_Sony_SDIO_ControlDevice, r.handleSonyControlDevice)
+	r.Handle(ptp.OC_GetObject, r.handleSonyGetObject)
+}
+
+// setSonyDevicePropDesc stores the device property description to be returned by
+// OC_Sony_SDIO_GetAllExtDevicePropInfo.
+func (r *Responder) setSonyDevicePropDesc(dpd *ptp.DevicePropDesc) {
+	b, err := dpd.MarshalPTP()
+	if err != nil {
+		panic(err)
+	}
+	r.SetDevicePropDesc(dpd.DevicePropertyCode, b)
+}
+
+// handleSonyGetExtDeviceInfo returns the SDIO protocol version followed by the list of supported device properties.
+func (r *Responder) handleSonyGetExtDeviceInfo(_ *Request) *Response {
+	codes := r.sonyDevicePropCodes()
+
+	b := le(uint16(ip.PM_Sony_SDIO_ProtocolVersion), uint32(len(codes)))
+	for _, code := range codes {
+		b = append(b, le(uint16(code))...)
+	}
+
+	return Data(b)
+}
+
+// sonyDevicePropCodes returns the codes of all device properties that have a description, in ascending order.
+func (r *Responder) sonyDevicePropCodes() []ptp.DevicePropCode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make([]ptp.DevicePropCode, 0, len(r.propDescs))
+	for code := range r.propDescs {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	return codes
+}
+
+// handleSonyGetAllExtDevicePropInfo returns the number of properties followed by all property descriptions. Each
+// description is a standard description with the enabled flag inserted after the GetSet field.
+func (r *Responder) handleSonyGetAllExtDevicePropInfo(_ *Request) *Response {
+	codes := r.sonyDevicePropCodes()
+
+	var b bytes.Buffer
+	b.Write(le(uint64(len(codes))))
+	r.mu.Lock()
+	for _, code := range codes {
+		d := r.propDescs[code]
+		b.Write(d[:5])
+		b.WriteByte(0x01)
+		b.Write(d[5:])
+	}
+	r.mu.Unlock()
+
+	return Data(b.Bytes())
+}
+
+// handleSonySetExtDevicePropValue stores the value received in the data out phase as the current value of the device
+// property passed as first parameter.
+func (r *Responder) handleSonySetExtDevicePropValue(req *Request) *Response {
+	code := ptp.DevicePropCode(req.Parameter(1))
+
+	r.mu.Lock()
+	d, ok := r.propDescs[code]
+	r.mu.Unlock()
+	if !ok {
+		return Fail(ptp.RC_DevicePropNotSupported)
+	}
+
+	dpd, err := ptp.ReadDevicePropDesc(bytes.NewReader(d))
+	if err != nil || dpd.SizeOfValueInBytes() != len(req.Data) {
+		return Fail(ptp.RC_InvalidDevicePropValue)
+	}
+	dpd.CurrentValue = append([]byte{}, req.Data...)
+	r.setSonyDevicePropDesc(dpd)
+
+	return &Response{
+		Code:   ptp.RC_OK,
+		Events: []*Event{{Code: ip.EC_Sony_PropertyChanged}},
+	}
+}
+
+// handleSonyControlDevice sends an EC_Sony_ObjectAdded event when the shutter button is pressed all the way.
+func (r *Responder) handleSonyControlDevice(req *Request) *Response {
+	if len(req.Data) != 2 {
+		return Fail(ptp.RC_InvalidParameter)
+	}
+
+	res := OK()
+	if ptp.DevicePropCode(req.Parameter(1)) == ip.DPC_Sony_S2Button &&
+		ip.SonyButtonState(binary.LittleEndian.Uint16(req.Data)) == ip.BTN_Sony_Down {
+		res.Events = []*Event{{Code: ip.EC_Sony_ObjectAdded, Parameters: []uint32{sonyCaptureHandle}}}
+	}
+
+	return res
+}
+
+// handleSonyGetObject returns the capture preview as live view frame when ip.PM_Sony_LiveViewHandle is requested. When
+// there is no capture preview, the request fails with ptp.RC_AccessDenied just like a real device does when no frame is
+// available.
+func (r *Responder) handleSonyGetObject(req *Request) *Response {
+	if req.Parameter(1) != ip.PM_Sony_LiveViewHandle {
+		return Fail(ptp.RC_InvalidObjectHandle)
+	}
+
+	img := r.capturePreview()
+	if img == nil {
+		return Fail(ptp.RC_AccessDenied)
+	}
+
+	b := make([]byte, sonyLiveViewHeaderSize)
+	binary.LittleEndian.PutUint32(b[0:4], sonyLiveViewHeaderSize)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(img)))
+
+	return Data(append(b, img...))
+}
@@ -0,0 +1,216 @@
+package iptest
+
+import (
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+	"time"
+)
+
+func dialFuji(t *testing.T, r *Responder) *ip.Client {
+	c, err := r.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNewResponder(t *testing.T) {
+	r := NewResponder(ip.DefaultVendor)
+	defer r.Close()
+
+	if r.IpAddress() != "127.0.0.1" {
+		t.Errorf("IpAddress() got = %s; want 127.0.0.1", r.IpAddress())
+	}
+	if r.CommandDataPort() == 0 {
+		t.Errorf("CommandDataPort() got = %d; want random port", r.CommandDataPort())
+	}
+	if r.EventPort() != r.CommandDataPort() {
+		t.Errorf("EventPort() got = %d; want %d", r.EventPort(), r.CommandDataPort())
+	}
+
+	f := NewResponder("fuji")
+	defer f.Close()
+
+	if f.EventPort() == f.CommandDataPort() {
+		t.Errorf("EventPort() got = %d; want port different from %d", f.EventPort(), f.CommandDataPort())
+	}
+	if f.StreamerPort() == f.CommandDataPort() || f.StreamerPort() == f.EventPort() {
+		t.Errorf("StreamerPort() got = %d; want separate port", f.StreamerPort())
+	}
+}
+
+func TestResponder_Initiator(t *testing.T) {
+	r := NewResponder("fuji")
+	defer r.Close()
+
+	if r.Initiator() != nil {
+		t.Errorf("Initiator() got = %v; want <nil>", r.Initiator())
+	}
+
+	c := dialFuji(t, r)
+	defer c.Close()
+
+	got := r.Initiator()
+	if got == nil {
+		t.Fatal("Initiator() got = <nil>; want *ip.Initiator")
+	}
+	if got.FriendlyName != "testèr" {
+		t.Errorf("Initiator() FriendlyName = %s; want testèr", got.FriendlyName)
+	}
+	if got.GUID.String() != "67bace55-e7a4-4fbc-8e31-5122ee73a17c" {
+		t.Errorf("Initiator() GUID = %s; want 67bace55-e7a4-4fbc-8e31-5122ee73a17c", got.GUID)
+	}
+	if c.ResponderFriendlyName() != ResponderFriendlyName {
+		t.Errorf("ResponderFriendlyName() got = %s; want %s", c.ResponderFriendlyName(), ResponderFriendlyName)
+	}
+}
+
+func TestResponder_FailInit(t *testing.T) {
+	r := NewResponder(ip.DefaultVendor)
+	defer r.Close()
+	r.FailInit(ip.FR_FailBusy)
+
+	c, err := r.NewClient("", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Dial()
+	want := "command data connection: busy: too many active connections"
+	if err == nil || err.Error() != want {
+		t.Errorf("Dial() err = %v; want %s", err, want)
+	}
+
+	r.FailInit(0)
+	c, err = r.NewClient("", "", ip.LevelSilent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Dial(); err != nil {
+		t.Errorf("Dial() err = %s; want <nil>", err)
+	}
+}
+
+func TestResponder_Handle(t *testing.T) {
+	r := NewResponder("fuji")
+	defer r.Close()
+
+	c := dialFuji(t, r)
+	defer c.Close()
+
+	r.Handle(ptp.OC_GetDevicePropValue, func(req *Request) *Response {
+		return Data(le(req.Parameter(1) + 1))
+	})
+
+	got, err := c.GetDevicePropertyValue(ip.DPC_Fuji_FilmSimulation)
+	if err != nil {
+		t.Errorf("GetDevicePropertyValue() err = %s; want <nil>", err)
+	}
+	want := uint32(ip.DPC_Fuji_FilmSimulation) + 1
+	if got != want {
+		t.Errorf("GetDevicePropertyValue() got = %#x; want %#x", got, want)
+	}
+
+	r.Handle(ptp.OC_GetDevicePropValue, Reply(Fail(ptp.RC_DevicePropNotSupported)))
+	if _, err := c.GetDevicePropertyValue(ip.DPC_Fuji_FilmSimulation); err == nil {
+		t.Errorf("GetDevicePropertyValue() err = <nil>; want %s", ptp.OperationResponseCodeAsError(ptp.RC_DevicePropNotSupported))
+	}
+}
+
+func TestResponder_RequestsFor(t *testing.T) {
+	r := NewResponder("fuji")
+	defer r.Close()
+
+	c := dialFuji(t, r)
+	defer c.Close()
+
+	if err := c.SetDeviceProperty(ip.DPC_Fuji_FilmSimulation, uint32(ip.FS_Fuji_Astia)); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := r.RequestsFor(ptp.OC_SetDevicePropValue)
+	// Two requests to set properties during the init sequence and one request from us.
+	if len(reqs) != 3 {
+		t.Fatalf("RequestsFor() got = %d requests; want 3", len(reqs))
+	}
+
+	req := reqs[2]
+	if req.TransactionID != c.TransactionId() {
+		t.Errorf("RequestsFor() TransactionID = %d; want %d", req.TransactionID, c.TransactionId())
+	}
+	if ptp.DevicePropCode(req.Parameter(1)) != ip.DPC_Fuji_FilmSimulation {
+		t.Errorf("RequestsFor() Parameter1 = %#x; want %#x", req.Parameter(1), ip.DPC_Fuji_FilmSimulation)
+	}
+	if binary.LittleEndian.Uint32(req.Data) != uint32(ip.FS_Fuji_Astia) {
+		t.Errorf("RequestsFor() Data = %#x; want %#x", req.Data, ip.FS_Fuji_Astia)
+	}
+
+	v, ok := r.DevicePropValue(ip.DPC_Fuji_FilmSimulation)
+	if !ok || binary.LittleEndian.Uint32(v) != uint32(ip.FS_Fuji_Astia) {
+		t.Errorf("DevicePropValue() got = %#x; want %#x", v, ip.FS_Fuji_Astia)
+	}
+
+	if !r.Received(ptp.OC_InitiateOpenCapture) {
+		t.Errorf("Received() got = false; want true")
+	}
+	if r.Received(ptp.OC_InitiateCapture) {
+		t.Errorf("Received() got = true; want false")
+	}
+}
+
+func TestResponder_Timeout(t *testing.T) {
+	r := NewResponder("fuji")
+	defer r.Close()
+
+	c := dialFuji(t, r)
+	defer c.Close()
+
+	r.Timeout(ptp.OC_GetDevicePropValue)
+
+	resCh, err := ip.FujiSendOperationRequest(c, ptp.OC_GetDevicePropValue, uint32(ip.DPC_Fuji_AppVersion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-resCh:
+		t.Errorf("FujiSendOperationRequest() got = %#x; want no response", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if n := len(r.RequestsFor(ptp.OC_GetDevicePropValue)); n != 2 {
+		t.Errorf("RequestsFor() got = %d requests; want 2", n)
+	}
+}
+
+func TestResponder_SendEvent(t *testing.T) {
+	r := NewResponder("fuji")
+	defer r.Close()
+
+	if err := r.SendEvent(&Event{Code: ptp.EC_DevicePropChanged}); err != ip.NotConnectedError {
+		t.Errorf("SendEvent() err = %v; want %s", err, ip.NotConnectedError)
+	}
+
+	c := dialFuji(t, r)
+	defer c.Close()
+
+	if err := r.SendEvent(&Event{Code: ptp.EC_DevicePropChanged}); err != nil {
+		t.Errorf("SendEvent() err = %s; want <nil>", err)
+	}
+
+	r.Disconnect()
+
+	if err := r.SendEvent(&Event{Code: ptp.EC_DevicePropChanged}); err != ip.NotConnectedError {
+		t.Errorf("SendEvent() err = %v; want %s", err, ip.NotConnectedError)
+	}
+}
@@ -2,8 +2,13 @@ package ip
 
 import (
 	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
 	"io"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 const (
@@ -69,81 +74,84 @@ type Logger interface {
 	Warnln(v ...interface{})
 }
 
-// StdLogger is the standard logger, a wrapper around the golang log package.
+// StdLogger is the standard logger, a wrapper around the golang log package. Next to the leveled Printf style methods,
+// it implements the StructuredLogger interface allowing the log level to be set per Subsystem.
 type StdLogger struct {
-	level LogLevel
+	level    LogLevel
+	levels   SubsystemLevels
+	levelsMu sync.RWMutex
 	*log.Logger
 }
 
 func (sl *StdLogger) Debug(v ...interface{}) {
 	if sl.level >= LevelDebug {
-		log.Print(v...)
+		sl.Logger.Print(v...)
 	}
 }
 
 func (sl *StdLogger) Debugf(format string, v ...interface{}) {
 	if sl.level >= LevelDebug {
-		log.Printf(format, v...)
+		sl.Logger.Printf(format, v...)
 	}
 }
 
 func (sl *StdLogger) Debugln(v ...interface{}) {
 	if sl.level >= LevelDebug {
-		log.Println(v...)
+		sl.Logger.Println(v...)
 	}
 }
 
 func (sl *StdLogger) Error(v ...interface{}) {
 	if sl.level > LevelSilent {
-		log.Print(v...)
+		sl.Logger.Print(v...)
 	}
 }
 
 func (sl *StdLogger) Errorf(format string, v ...interface{}) {
 	if sl.level > LevelSilent {
-		log.Printf(format, v...)
+		sl.Logger.Printf(format, v...)
 	}
 }
 
 func (sl *StdLogger) Errorln(v ...interface{}) {
 	if sl.level > LevelSilent {
-		log.Println(v...)
+		sl.Logger.Println(v...)
 	}
 }
 
 func (sl *StdLogger) Info(v ...interface{}) {
 	if sl.level >= LevelVeryVerbose {
-		log.Print(v...)
+		sl.Logger.Print(v...)
 	}
 }
 
 func (sl *StdLogger) Infof(format string, v ...interface{}) {
 	if sl.level >= LevelVeryVerbose {
-		log.Printf(format, v...)
+		sl.Logger.Printf(format, v...)
 	}
 }
 
 func (sl *StdLogger) Infoln(v ...interface{}) {
 	if sl.level >= LevelVeryVerbose {
-		log.Println(v...)
+		sl.Logger.Println(v...)
 	}
 }
 
 func (sl *StdLogger) Warn(v ...interface{}) {
 	if sl.level >= LevelVerbose {
-		log.Print(v...)
+		sl.Logger.Print(v...)
 	}
 }
 
 func (sl *StdLogger) Warnf(format string, v ...interface{}) {
 	if sl.level >= LevelVerbose {
-		log.Printf(format, v...)
+		sl.Logger.Printf(format, v...)
 	}
 }
 
 func (sl *StdLogger) Warnln(v ...interface{}) {
 	if sl.level >= LevelVerbose {
-		log.Println(v...)
+		sl.Logger.Println(v...)
 	}
 }
 
@@ -158,3 +166,257 @@ func NewLogger(level LogLevel, out io.Writer, prefix string, flag int) Logger {
 		Logger: log.New(out, prefix, flag),
 	}
 }
+
+// Subsystem identifies the part of the client a structured log message originates from. The log level can be set per
+// subsystem so you can, for example, debug the event connection without being flooded by live view frames.
+type Subsystem string
+
+const (
+	// SubsystemCmdData covers the command/data connection.
+	SubsystemCmdData Subsystem = "cmddata"
+	// SubsystemEvent covers the event connection.
+	SubsystemEvent Subsystem = "event"
+	// SubsystemStream covers the streamer or 'live view' connection.
+	SubsystemStream Subsystem = "stream"
+	// SubsystemVendor covers vendor specific logic such as initialisation sequences and device property handling.
+	SubsystemVendor Subsystem = "vendor"
+)
+
+// Subsystems lists all known subsystems.
+var Subsystems = []Subsystem{SubsystemCmdData, SubsystemEvent, SubsystemStream, SubsystemVendor}
+
+// The keys used for the fields of a structured log message.
+const (
+	FieldTransactionID = "tid"
+	FieldOperationCode = "opcode"
+	FieldChannel       = "channel"
+	FieldBytes         = "bytes"
+)
+
+// SubsystemLevels holds a log level per subsystem.
+type SubsystemLevels map[Subsystem]LogLevel
+
+// Set() implements flags.Value interface. It expects a comma separated list of subsystem=level pairs, e.g.
+// "cmddata=vvv,stream=v".
+func (sls *SubsystemLevels) Set(s string) error {
+	if *sls == nil {
+		*sls = make(SubsystemLevels)
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid subsystem log level '%s': expected subsystem=level", pair)
+		}
+		sub := Subsystem(kv[0])
+		if !isSubsystem(sub) {
+			return fmt.Errorf("unknown subsystem '%s'", kv[0])
+		}
+		var l LogLevel
+		if err := l.Set(kv[1]); err != nil {
+			return err
+		}
+		(*sls)[sub] = l
+	}
+
+	return nil
+}
+
+// String() implement flags.Value interface.
+func (sls *SubsystemLevels) String() string {
+	if sls == nil {
+		return ""
+	}
+
+	var pairs []string
+	for _, sub := range Subsystems {
+		if l, ok := (*sls)[sub]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", sub, l.String()))
+		}
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func isSubsystem(s Subsystem) bool {
+	for _, sub := range Subsystems {
+		if s == sub {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StructuredLogger can optionally be implemented by a custom Logger to receive log messages as key/value pairs instead
+// of preformatted strings. The keysAndValues are passed in alternating order, e.g. "tid", 5, "bytes", 12. When the
+// Logger does not implement this interface, the client will fall back to the Printf style methods using a logfmt like
+// message.
+type StructuredLogger interface {
+	Debugw(s Subsystem, msg string, keysAndValues ...interface{})
+	Errorw(s Subsystem, msg string, keysAndValues ...interface{})
+	Infow(s Subsystem, msg string, keysAndValues ...interface{})
+	Warnw(s Subsystem, msg string, keysAndValues ...interface{})
+}
+
+// SetSubsystemLevel overrides the log level of the given subsystem. Subsystems without an override use the level the
+// logger was created with.
+func (sl *StdLogger) SetSubsystemLevel(s Subsystem, level LogLevel) {
+	sl.levelsMu.Lock()
+	if sl.levels == nil {
+		sl.levels = make(SubsystemLevels)
+	}
+	sl.levels[s] = level
+	sl.levelsMu.Unlock()
+}
+
+// SubsystemLevel returns the log level for the given subsystem.
+func (sl *StdLogger) SubsystemLevel(s Subsystem) LogLevel {
+	sl.levelsMu.RLock()
+	defer sl.levelsMu.RUnlock()
+
+	if l, ok := sl.levels[s]; ok {
+		return l
+	}
+
+	return sl.level
+}
+
+func (sl *StdLogger) Debugw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if sl.SubsystemLevel(s) >= LevelDebug {
+		sl.Logger.Print("level=debug " + formatFields(s, msg, keysAndValues))
+	}
+}
+
+func (sl *StdLogger) Errorw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if sl.SubsystemLevel(s) > LevelSilent {
+		sl.Logger.Print("level=error " + formatFields(s, msg, keysAndValues))
+	}
+}
+
+func (sl *StdLogger) Infow(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if sl.SubsystemLevel(s) >= LevelVeryVerbose {
+		sl.Logger.Print("level=info " + formatFields(s, msg, keysAndValues))
+	}
+}
+
+func (sl *StdLogger) Warnw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if sl.SubsystemLevel(s) >= LevelVerbose {
+		sl.Logger.Print("level=warn " + formatFields(s, msg, keysAndValues))
+	}
+}
+
+// formatFields formats a structured log message as logfmt, e.g.:
+//
+//	subsystem=cmddata msg="sending packet" tid=5 opcode=0x1014 channel=cmd bytes=30
+func formatFields(s Subsystem, msg string, keysAndValues []interface{}) string {
+	var b strings.Builder
+	b.WriteString("subsystem=")
+	b.WriteString(string(s))
+	b.WriteString(" msg=")
+	b.WriteString(formatValue(msg))
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(keysAndValues[i]))
+		b.WriteByte('=')
+		if i+1 < len(keysAndValues) {
+			b.WriteString(formatValue(keysAndValues[i+1]))
+		} else {
+			b.WriteString("(MISSING)")
+		}
+	}
+
+	return b.String()
+}
+
+// formatValue formats a single value of a structured log message. PTP codes are formatted as hexadecimal numbers since
+// that is how they are listed in the specifications.
+func formatValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case error:
+		s = t.Error()
+	case ptp.OperationCode, ptp.OperationResponseCode, ptp.EventCode, ptp.DevicePropCode:
+		s = fmt.Sprintf("%#04x", t)
+	case fmt.Stringer:
+		s = t.String()
+	default:
+		s = fmt.Sprint(t)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \"=\t\n") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// SetSubsystemLogLevel sets the log level for the given subsystem. This only has effect when the Logger supports it,
+// which the default StdLogger does.
+func (c *Client) SetSubsystemLogLevel(s Subsystem, level LogLevel) {
+	if l, ok := c.Logger.(interface{ SetSubsystemLevel(Subsystem, LogLevel) }); ok {
+		l.SetSubsystemLevel(s, level)
+	}
+}
+
+func (c *Client) debugw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if l, ok := c.Logger.(StructuredLogger); ok {
+		l.Debugw(s, msg, keysAndValues...)
+		return
+	}
+	c.Debug(formatFields(s, msg, keysAndValues))
+}
+
+func (c *Client) errorw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if c.metrics != nil {
+		c.metrics.setLastError(errorMessage(msg, keysAndValues))
+	}
+
+	if l, ok := c.Logger.(StructuredLogger); ok {
+		l.Errorw(s, msg, keysAndValues...)
+		return
+	}
+	c.Error(formatFields(s, msg, keysAndValues))
+}
+
+func (c *Client) infow(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if l, ok := c.Logger.(StructuredLogger); ok {
+		l.Infow(s, msg, keysAndValues...)
+		return
+	}
+	c.Info(formatFields(s, msg, keysAndValues))
+}
+
+func (c *Client) warnw(s Subsystem, msg string, keysAndValues ...interface{}) {
+	if l, ok := c.Logger.(StructuredLogger); ok {
+		l.Warnw(s, msg, keysAndValues...)
+		return
+	}
+	c.Warn(formatFields(s, msg, keysAndValues))
+}
+
+// errorMessage returns the message with the value of the "error" field appended to it, if there is one.
+func errorMessage(msg string, keysAndValues []interface{}) string {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if keysAndValues[i] == "error" {
+			return fmt.Sprintf("%s: %v", msg, keysAndValues[i+1])
+		}
+	}
+
+	return msg
+}
+
+// subsystemFor maps a connection to its logging subsystem.
+func subsystemFor(ct connectionType) Subsystem {
+	switch ct {
+	case eventConnection:
+		return SubsystemEvent
+	case streamConnection:
+		return SubsystemStream
+	}
+
+	return SubsystemCmdData
+}
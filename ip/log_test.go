@@ -0,0 +1,86 @@
+package ip
+
+import (
+	"bytes"
+	"errors"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strings"
+	"testing"
+)
+
+func TestSubsystemLevels_Set(t *testing.T) {
+	var sls SubsystemLevels
+	if err := sls.Set("cmddata=vvv, stream=v"); err != nil {
+		t.Errorf("SubsystemLevels.Set() err = %s; want <nil>", err)
+	}
+
+	want := "cmddata=vvv,stream=v"
+	if got := sls.String(); got != want {
+		t.Errorf("SubsystemLevels.String() = %s; want %s", got, want)
+	}
+
+	for _, s := range []string{"cmddata", "video=v", "event=vvvv"} {
+		if err := sls.Set(s); err == nil {
+			t.Errorf("SubsystemLevels.Set(%s) err = <nil>; want error", s)
+		}
+	}
+}
+
+func TestStdLogger_SubsystemLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(LevelVerbose, &buf, "", 0).(*StdLogger)
+	l.SetSubsystemLevel(SubsystemEvent, LevelDebug)
+
+	l.Debugw(SubsystemCmdData, "not logged")
+	l.Infow(SubsystemVendor, "not logged either")
+	l.Debugw(SubsystemEvent, "publishing new event", "code", ptp.EC_DevicePropChanged, FieldBytes, 12)
+	l.Warnw(SubsystemStream, "dropping frame", "error", errors.New("queue full"))
+
+	got := buf.String()
+	want := "level=debug subsystem=event msg=\"publishing new event\" code=0x4006 bytes=12\n" +
+		"level=warn subsystem=stream msg=\"dropping frame\" error=\"queue full\"\n"
+	if got != want {
+		t.Errorf("StdLogger output = %q; want %q", got, want)
+	}
+
+	// The plain methods must keep honouring the global level.
+	buf.Reset()
+	l.Debug("not logged")
+	l.Warn("logged")
+	if got := buf.String(); got != "logged\n" {
+		t.Errorf("StdLogger output = %q; want %q", got, "logged\n")
+	}
+}
+
+func TestFormatFields(t *testing.T) {
+	got := formatFields(SubsystemCmdData, "sending packet", []interface{}{FieldTransactionID, ptp.TransactionID(5), FieldOperationCode, ptp.OC_GetDevicePropValue, FieldChannel, cmdDataConnection, "odd"})
+	want := "subsystem=cmddata msg=\"sending packet\" tid=5 opcode=0x1015 channel=cmd odd=(MISSING)"
+	if got != want {
+		t.Errorf("formatFields() = %s; want %s", got, want)
+	}
+}
+
+// plainLogger only implements Logger, not StructuredLogger.
+type plainLogger struct {
+	Logger
+	buf *bytes.Buffer
+}
+
+func (pl plainLogger) Info(v ...interface{}) {
+	pl.buf.WriteString(v[0].(string))
+}
+
+func TestClient_infowFallback(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "logger", "", logLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	c.SetLogger(plainLogger{buf: &buf})
+	c.infow(SubsystemVendor, "requesting device info", "responder", "X-T1")
+
+	if got := buf.String(); !strings.Contains(got, "subsystem=vendor msg=\"requesting device info\" responder=X-T1") {
+		t.Errorf("infow() = %s; want logfmt message", got)
+	}
+}
@@ -0,0 +1,265 @@
+package ip
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicateCamera = errors.New("duplicate camera name")
+	ErrUnknownCamera   = errors.New("unknown camera")
+)
+
+// Manager owns multiple clients, each identified by a unique name, to control a rig of several cameras at once.
+// Operations are broadcast to all cameras in parallel and the results are reported per camera in the order the cameras
+// were added.
+type Manager struct {
+	mu      sync.RWMutex
+	names   []string
+	clients map[string]*Client
+}
+
+// Result holds the outcome of an operation on a single camera.
+type Result struct {
+	// Name is the name of the camera.
+	Name string
+	// Err is nil when the operation succeeded.
+	Err error
+	// Duration is the time it took the operation to complete.
+	Duration time.Duration
+}
+
+// CaptureResult holds the outcome of a synchronised capture on a single camera.
+type CaptureResult struct {
+	Result
+	// Preview holds the preview of the captured image if the Responder returns one.
+	Preview []byte
+	// Released is the time the shutter release request was sent to the camera. It is the zero time when the release time
+	// is unknown, e.g. because the capture failed before the request was sent.
+	Released time.Time
+	// Skew is the time between the earliest shutter release of all cameras and the shutter release of this camera. It is
+	// only meaningful when Released is known.
+	Skew time.Duration
+}
+
+// NewManager returns a manager without any clients.
+func NewManager() *Manager {
+	return &Manager{
+		clients: make(map[string]*Client),
+	}
+}
+
+// Add adds a client to the manager under the given name. Each client should use its own initiator GUID and ports as
+// required by the camera it is connecting to.
+func (m *Manager) Add(name string, c *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.clients[name]; ok {
+		return fmt.Errorf("%w '%s'", ErrDuplicateCamera, name)
+	}
+	m.names = append(m.names, name)
+	m.clients[name] = c
+
+	return nil
+}
+
+// Client returns the client with the given name.
+func (m *Manager) Client(name string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownCamera, name)
+	}
+
+	return c, nil
+}
+
+// Names returns the names of all cameras in the order they were added.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]string(nil), m.names...)
+}
+
+// Len returns the amount of cameras.
+func (m *Manager) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.names)
+}
+
+// Broadcast calls f for every camera in parallel and waits for all of them to return.
+func (m *Manager) Broadcast(f func(name string, c *Client) error) []Result {
+	names, clients := m.snapshot()
+
+	return broadcast(names, clients, f)
+}
+
+// snapshot returns a copy of the names and the corresponding clients so no lock must be held while broadcasting.
+func (m *Manager) snapshot() ([]string, []*Client) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := append([]string(nil), m.names...)
+	clients := make([]*Client, len(names))
+	for i, n := range names {
+		clients[i] = m.clients[n]
+	}
+
+	return names, clients
+}
+
+func broadcast(names []string, clients []*Client, f func(name string, c *Client) error) []Result {
+	res := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			err := f(names[i], clients[i])
+			res[i] = Result{Name: names[i], Err: err, Duration: time.Since(start)}
+		}(i)
+	}
+	wg.Wait()
+
+	return res
+}
+
+// Dial dials all cameras in parallel.
+func (m *Manager) Dial() []Result {
+	return m.Broadcast(func(_ string, c *Client) error {
+		return c.Dial()
+	})
+}
+
+// Close closes the connections of all cameras and returns the first error encountered, if any.
+func (m *Manager) Close() error {
+	var err error
+	for _, r := range m.Broadcast(func(_ string, c *Client) error {
+		return c.Close()
+	}) {
+		if r.Err != nil && err == nil {
+			err = fmt.Errorf("%s: %w", r.Name, r.Err)
+		}
+	}
+
+	return err
+}
+
+// SetDeviceProperty sets the given device property to the specified value on all cameras.
+func (m *Manager) SetDeviceProperty(code ptp.DevicePropCode, val uint32) []Result {
+	return m.Broadcast(func(_ string, c *Client) error {
+		return c.SetDeviceProperty(code, val)
+	})
+}
+
+// Capture releases the shutter of all cameras as close to simultaneously as possible. This is done on a best effort
+// basis: all cameras wait for each other at a barrier right before sending the shutter release request. The timing skew
+// between the cameras is reported per camera.
+func (m *Manager) Capture() []CaptureResult {
+	names, clients := m.snapshot()
+	if len(names) == 0 {
+		return nil
+	}
+
+	var (
+		ready sync.WaitGroup
+		fire  = make(chan struct{})
+		fired time.Time
+	)
+	ready.Add(len(names))
+	go func() {
+		ready.Wait()
+		fired = time.Now()
+		close(fire)
+	}()
+
+	index := make(map[string]int, len(names))
+	for i, n := range names {
+		index[n] = i
+	}
+	previews := make([][]byte, len(names))
+	res := broadcast(names, clients, func(name string, c *Client) error {
+		ready.Done()
+		<-fire
+		img, err := c.InitiateCapture()
+		previews[index[name]] = img
+		return err
+	})
+
+	crs := make([]CaptureResult, len(res))
+	var first time.Time
+	for i, r := range res {
+		crs[i] = CaptureResult{Result: r, Preview: previews[i]}
+		// A release time from before firing belongs to a previous capture: the request never made it out this time.
+		if rel := clients[i].CaptureSentAt(); !rel.Before(fired) {
+			crs[i].Released = rel
+			if first.IsZero() || rel.Before(first) {
+				first = rel
+			}
+		}
+	}
+	for i := range crs {
+		if !crs[i].Released.IsZero() {
+			crs[i].Skew = crs[i].Released.Sub(first)
+		}
+	}
+
+	return crs
+}
+
+// MaxSkew returns the largest timing skew of the given capture results.
+func MaxSkew(crs []CaptureResult) time.Duration {
+	var max time.Duration
+	for _, cr := range crs {
+		if cr.Skew > max {
+			max = cr.Skew
+		}
+	}
+
+	return max
+}
+
+// WritePrometheus writes the metrics of all cameras that have metrics enabled to w in the Prometheus text exposition
+// format. The series of each camera are told apart by a camera label.
+func (m *Manager) WritePrometheus(w io.Writer) error {
+	return writePrometheus(w, m.labelledSnapshots())
+}
+
+// String returns the metrics of all cameras that have metrics enabled as a JSON object keyed by camera name. This
+// allows publishing the manager as an expvar.
+func (m *Manager) String() string {
+	snapshots := make(map[string]MetricsSnapshot)
+	for _, ls := range m.labelledSnapshots() {
+		snapshots[ls.name] = ls.snapshot
+	}
+	b, err := json.Marshal(snapshots)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+func (m *Manager) labelledSnapshots() []labelledSnapshot {
+	names, clients := m.snapshot()
+	lss := make([]labelledSnapshot, 0, len(names))
+	for i, c := range clients {
+		if mt := c.Metrics(); mt != nil {
+			lss = append(lss, labelledSnapshot{name: names[i], label: promPair("camera", names[i]), snapshot: mt.Snapshot()})
+		}
+	}
+
+	return lss
+}
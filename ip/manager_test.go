@@ -0,0 +1,193 @@
+package ip_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRig starts a fake camera for each name and adds a client for it to a new manager.
+func newRig(t *testing.T, names ...string) (*ip.Manager, map[string]*iptest.Responder) {
+	m := ip.NewManager()
+	rs := make(map[string]*iptest.Responder)
+	for _, n := range names {
+		r := iptest.NewResponder("fuji")
+		rs[n] = r
+
+		c, err := r.NewClient("rig "+n, "", ip.LogLevelUnderTest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Add(n, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return m, rs
+}
+
+func closeRig(m *ip.Manager, rs map[string]*iptest.Responder) {
+	m.Close()
+	for _, r := range rs {
+		r.Close()
+	}
+}
+
+func TestManager_Add(t *testing.T) {
+	m := ip.NewManager()
+	c, err := ip.NewClient("fuji", ip.DefaultIpAddress, ip.DefaultPort, "", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Add("left", c); err != nil {
+		t.Errorf("Add() err = %s; want <nil>", err)
+	}
+	if err := m.Add("left", c); !errors.Is(err, ip.ErrDuplicateCamera) {
+		t.Errorf("Add() err = %v; want %s", err, ip.ErrDuplicateCamera)
+	}
+	if _, err := m.Client("right"); !errors.Is(err, ip.ErrUnknownCamera) {
+		t.Errorf("Client() err = %v; want %s", err, ip.ErrUnknownCamera)
+	}
+	if got, err := m.Client("left"); err != nil || got != c {
+		t.Errorf("Client() got = %p, %v; want %p, <nil>", got, err, c)
+	}
+}
+
+func TestManager_Dial(t *testing.T) {
+	m, rs := newRig(t, "left", "centre", "right")
+	defer closeRig(m, rs)
+
+	res := m.Dial()
+	for i, want := range []string{"left", "centre", "right"} {
+		if res[i].Name != want {
+			t.Errorf("Dial() result %d name = %s; want %s", i, res[i].Name, want)
+		}
+		if res[i].Err != nil {
+			t.Errorf("Dial() %s err = %s; want <nil>", res[i].Name, res[i].Err)
+		}
+	}
+}
+
+func TestManager_SetDeviceProperty(t *testing.T) {
+	m, rs := newRig(t, "left", "right")
+	defer closeRig(m, rs)
+
+	for _, r := range m.Dial() {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+
+	for _, r := range m.SetDeviceProperty(ptp.DPC_WhiteBalance, 0x0004) {
+		if r.Err != nil {
+			t.Errorf("SetDeviceProperty() %s err = %s; want <nil>", r.Name, r.Err)
+		}
+		got, _ := rs[r.Name].DevicePropValue(ptp.DPC_WhiteBalance)
+		want := []byte{0x04, 0x00, 0x00, 0x00}
+		if !bytes.Equal(got, want) {
+			t.Errorf("SetDeviceProperty() %s value = %#x; want %#x", r.Name, got, want)
+		}
+	}
+}
+
+func TestManager_Capture(t *testing.T) {
+	m, rs := newRig(t, "left", "right")
+	defer closeRig(m, rs)
+
+	for _, r := range m.Dial() {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+	for n, r := range rs {
+		r.SetCapturePreview([]byte(n))
+	}
+
+	crs := m.Capture()
+	if len(crs) != 2 {
+		t.Fatalf("Capture() results = %d; want 2", len(crs))
+	}
+
+	var zeroSkew int
+	for _, cr := range crs {
+		if cr.Err != nil {
+			t.Errorf("Capture() %s err = %s; want <nil>", cr.Name, cr.Err)
+		}
+		if string(cr.Preview) != cr.Name {
+			t.Errorf("Capture() %s preview = %s; want %s", cr.Name, cr.Preview, cr.Name)
+		}
+		if cr.Released.IsZero() {
+			t.Errorf("Capture() %s released = %s; want a time", cr.Name, cr.Released)
+		}
+		if cr.Skew == 0 {
+			zeroSkew++
+		}
+	}
+	if zeroSkew == 0 {
+		t.Errorf("Capture() skews = %s, %s; want at least one to be 0", crs[0].Skew, crs[1].Skew)
+	}
+	if got := ip.MaxSkew(crs); got < crs[0].Skew || got < crs[1].Skew {
+		t.Errorf("MaxSkew() = %s; want the largest skew", got)
+	}
+}
+
+func TestClient_CaptureSentAt(t *testing.T) {
+	for _, vendor := range []string{"fuji", "canon", "nikon", "sony", "panasonic"} {
+		res := iptest.NewResponder(vendor)
+		c := res.DialClient(t, "rig "+vendor, "", ip.LogLevelUnderTest())
+
+		if got := c.CaptureSentAt(); !got.IsZero() {
+			t.Errorf("CaptureSentAt() %s before capture = %s; want zero time", vendor, got)
+		}
+		before := time.Now()
+		if _, err := c.InitiateCapture(); err != nil {
+			t.Errorf("InitiateCapture() %s err = %s; want <nil>", vendor, err)
+		}
+		if got := c.CaptureSentAt(); got.Before(before) {
+			t.Errorf("CaptureSentAt() %s = %s; want a time after %s", vendor, got, before)
+		}
+
+		c.Close()
+		res.Close()
+	}
+}
+
+func TestManager_WritePrometheus(t *testing.T) {
+	m := ip.NewManager()
+	for _, n := range []string{"left", "right"} {
+		c, err := ip.NewClient("fuji", ip.DefaultIpAddress, ip.DefaultPort, "", "", ip.LogLevelUnderTest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetMetrics(ip.NewMetrics())
+		m.Add(n, c)
+	}
+
+	var b bytes.Buffer
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"ptpip_packets_sent_total{camera=\"left\",channel=\"cmd\"} 0\n",
+		"ptpip_packets_sent_total{camera=\"right\",channel=\"cmd\"} 0\n",
+		"ptpip_stream_fps{camera=\"right\"} 0\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WritePrometheus() got = %s; want it to contain %q", got, want)
+		}
+	}
+	if n := strings.Count(got, "# TYPE ptpip_packets_sent_total counter"); n != 1 {
+		t.Errorf("WritePrometheus() TYPE lines = %d; want 1", n)
+	}
+
+	if got := m.String(); !strings.Contains(got, `"left":{`) || !strings.Contains(got, `"right":{`) {
+		t.Errorf("String() got = %s; want both cameras", got)
+	}
+}
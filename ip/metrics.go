@@ -0,0 +1,443 @@
+package ip
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the transaction latency histogram buckets.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// maxPendingTransactions is the amount of pending transactions at which stale ones are pruned.
+const maxPendingTransactions = 256
+
+// channels lists the connections in the order they appear in the metrics.
+var channels = [...]connectionType{cmdDataConnection, eventConnection, streamConnection}
+
+func channelIndex(ct connectionType) int {
+	switch ct {
+	case eventConnection:
+		return 1
+	case streamConnection:
+		return 2
+	}
+
+	return 0
+}
+
+type channelCounters struct {
+	packetsSent     uint64
+	packetsReceived uint64
+	bytesSent       uint64
+	bytesReceived   uint64
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+type pendingTransaction struct {
+	code  ptp.OperationCode
+	start time.Time
+}
+
+// Metrics collects statistics about a client: packets and bytes per channel, transaction latencies per operation code,
+// timeouts, reconnects and live view frames. Metrics are disabled by default, enable them by passing a Metrics to
+// Client.SetMetrics() before calling Dial().
+// Metrics implements the expvar.Var interface so it can be published as is using expvar.Publish(). WritePrometheus()
+// outputs the Prometheus text exposition format.
+type Metrics struct {
+	// The counters are accessed atomically and must remain at the top of the struct to guarantee 64-bit alignment.
+	channels      [len(channels)]channelCounters
+	timeouts      uint64
+	reconnects    uint64
+	frames        uint64
+	droppedFrames uint64
+	dials         uint64
+
+	mu        sync.Mutex
+	pending   map[ptp.TransactionID]pendingTransaction
+	latencies map[ptp.OperationCode]*histogram
+	fps       float64
+	fpsFrames int
+	fpsStart  time.Time
+	lastFrame time.Time
+	lastErr   string
+	lastErrAt time.Time
+}
+
+// NewMetrics returns an empty metrics collector.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		pending:   make(map[ptp.TransactionID]pendingTransaction),
+		latencies: make(map[ptp.OperationCode]*histogram),
+	}
+}
+
+// SetMetrics enables collecting metrics for the client. Pass nil to disable collecting metrics again. Must be called
+// before calling Dial().
+func (c *Client) SetMetrics(m *Metrics) {
+	c.metrics = m
+}
+
+// Metrics returns the metrics collector of the client or nil when metrics are not enabled.
+func (c *Client) Metrics() *Metrics {
+	return c.metrics
+}
+
+// All methods below are safe to call on a nil *Metrics so the client does not need to check if metrics are enabled.
+
+func (m *Metrics) packetSent(ct connectionType, p PacketOut, n int) {
+	if m == nil {
+		return
+	}
+
+	cc := &m.channels[channelIndex(ct)]
+	atomic.AddUint64(&cc.packetsSent, 1)
+	atomic.AddUint64(&cc.bytesSent, uint64(n))
+
+	var tid ptp.TransactionID
+	var code ptp.OperationCode
+	switch pkt := p.(type) {
+	case *OperationRequestPacket:
+		tid, code = pkt.TransactionID, pkt.OperationCode
+	case *FujiOperationRequestPacket:
+		tid, code = pkt.TransactionID, pkt.OperationCode
+	default:
+		return
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	// Not every request gets a response, e.g. when the connection is lost, so make sure the pending transactions do not
+	// pile up.
+	if len(m.pending) >= maxPendingTransactions {
+		for t, pt := range m.pending {
+			if now.Sub(pt.start) > DefaultReadTimeout {
+				delete(m.pending, t)
+			}
+		}
+	}
+	m.pending[tid] = pendingTransaction{code: code, start: now}
+	m.mu.Unlock()
+}
+
+func (m *Metrics) packetReceived(ct connectionType, n int) {
+	if m == nil {
+		return
+	}
+
+	cc := &m.channels[channelIndex(ct)]
+	atomic.AddUint64(&cc.packetsReceived, 1)
+	atomic.AddUint64(&cc.bytesReceived, uint64(n))
+}
+
+// transactionDone records the latency of the transaction, measured from sending the operation request until the first
+// packet of the response arrives.
+func (m *Metrics) transactionDone(tid ptp.TransactionID) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pt, ok := m.pending[tid]
+	if !ok {
+		return
+	}
+	delete(m.pending, tid)
+
+	h, ok := m.latencies[pt.code]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latencies[pt.code] = h
+	}
+	h.observe(time.Since(pt.start).Seconds())
+}
+
+func (m *Metrics) timeout() {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.timeouts, 1)
+}
+
+// dialed counts the reconnects: every successful dial after the first one.
+func (m *Metrics) dialed() {
+	if m == nil {
+		return
+	}
+
+	if atomic.AddUint64(&m.dials, 1) > 1 {
+		atomic.AddUint64(&m.reconnects, 1)
+	}
+}
+
+func (m *Metrics) frameReceived() {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.frames, 1)
+
+	now := time.Now()
+	m.mu.Lock()
+	m.lastFrame = now
+	m.fpsFrames++
+	if m.fpsStart.IsZero() {
+		m.fpsStart = now
+	} else if elapsed := now.Sub(m.fpsStart); elapsed >= time.Second {
+		m.fps = float64(m.fpsFrames) / elapsed.Seconds()
+		m.fpsFrames = 0
+		m.fpsStart = now
+	}
+	m.mu.Unlock()
+}
+
+func (m *Metrics) frameDropped() {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.droppedFrames, 1)
+}
+
+func (m *Metrics) setLastError(msg string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.lastErr = msg
+	m.lastErrAt = time.Now()
+	m.mu.Unlock()
+}
+
+// ChannelMetrics holds the packet and byte counters of a single connection.
+type ChannelMetrics struct {
+	PacketsSent     uint64 `json:"packets_sent"`
+	PacketsReceived uint64 `json:"packets_received"`
+	BytesSent       uint64 `json:"bytes_sent"`
+	BytesReceived   uint64 `json:"bytes_received"`
+}
+
+// LatencyHistogram holds the transaction latencies of a single operation code. Buckets holds the cumulative count per
+// upper bound in UpperBounds, expressed in seconds.
+type LatencyHistogram struct {
+	UpperBounds []float64 `json:"upper_bounds"`
+	Buckets     []uint64  `json:"buckets"`
+	Count       uint64    `json:"count"`
+	Sum         float64   `json:"sum"`
+}
+
+// MetricsSnapshot is a point in time copy of the collected metrics.
+type MetricsSnapshot struct {
+	// Channels is keyed by the connection: "cmd", "event" or "stream".
+	Channels map[string]ChannelMetrics `json:"channels"`
+	// Transactions is keyed by the operation code in hexadecimal notation.
+	Transactions  map[string]LatencyHistogram `json:"transactions"`
+	Timeouts      uint64                      `json:"timeouts"`
+	Reconnects    uint64                      `json:"reconnects"`
+	StreamFrames  uint64                      `json:"stream_frames"`
+	StreamFPS     float64                     `json:"stream_fps"`
+	DroppedFrames uint64                      `json:"dropped_frames"`
+	LastError     string                      `json:"last_error,omitempty"`
+	LastErrorTime time.Time                   `json:"last_error_time"`
+}
+
+// Snapshot returns a copy of the metrics collected so far.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Channels:     make(map[string]ChannelMetrics),
+		Transactions: make(map[string]LatencyHistogram),
+	}
+	if m == nil {
+		return s
+	}
+
+	for i, ct := range channels {
+		cc := &m.channels[i]
+		s.Channels[string(ct)] = ChannelMetrics{
+			PacketsSent:     atomic.LoadUint64(&cc.packetsSent),
+			PacketsReceived: atomic.LoadUint64(&cc.packetsReceived),
+			BytesSent:       atomic.LoadUint64(&cc.bytesSent),
+			BytesReceived:   atomic.LoadUint64(&cc.bytesReceived),
+		}
+	}
+	s.Timeouts = atomic.LoadUint64(&m.timeouts)
+	s.Reconnects = atomic.LoadUint64(&m.reconnects)
+	s.StreamFrames = atomic.LoadUint64(&m.frames)
+	s.DroppedFrames = atomic.LoadUint64(&m.droppedFrames)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for code, h := range m.latencies {
+		s.Transactions[fmt.Sprintf("%#04x", code)] = LatencyHistogram{
+			UpperBounds: latencyBuckets,
+			Buckets:     append([]uint64(nil), h.counts...),
+			Count:       h.count,
+			Sum:         h.sum,
+		}
+	}
+	// Do not keep reporting the last known frame rate when live view has stopped.
+	if time.Since(m.lastFrame) < 2*time.Second {
+		s.StreamFPS = m.fps
+	}
+	s.LastError = m.lastErr
+	s.LastErrorTime = m.lastErrAt
+
+	return s
+}
+
+// String returns the metrics in JSON format, satisfying the expvar.Var interface.
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	return writePrometheus(w, []labelledSnapshot{{snapshot: m.Snapshot()}})
+}
+
+// labelledSnapshot is a snapshot with an optional label added to all of its series, e.g. to tell cameras apart.
+type labelledSnapshot struct {
+	name     string
+	label    string
+	snapshot MetricsSnapshot
+}
+
+// labels joins the label of the snapshot with the given labels into a Prometheus label set.
+func (ls labelledSnapshot) labels(labels ...string) string {
+	if ls.label != "" {
+		labels = append([]string{ls.label}, labels...)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func writePrometheus(w io.Writer, lss []labelledSnapshot) error {
+	pw := &promWriter{w: w}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(ChannelMetrics) uint64
+	}{
+		{"ptpip_packets_sent_total", "Packets sent to the responder.", func(cm ChannelMetrics) uint64 { return cm.PacketsSent }},
+		{"ptpip_packets_received_total", "Packets received from the responder.", func(cm ChannelMetrics) uint64 { return cm.PacketsReceived }},
+		{"ptpip_bytes_sent_total", "Bytes sent to the responder.", func(cm ChannelMetrics) uint64 { return cm.BytesSent }},
+		{"ptpip_bytes_received_total", "Bytes received from the responder.", func(cm ChannelMetrics) uint64 { return cm.BytesReceived }},
+	}
+	for _, c := range counters {
+		pw.header(c.name, c.help, "counter")
+		for _, ls := range lss {
+			for _, ct := range channels {
+				pw.printf("%s%s %d\n", c.name, ls.labels(promPair("channel", string(ct))), c.value(ls.snapshot.Channels[string(ct)]))
+			}
+		}
+	}
+
+	name := "ptpip_transaction_duration_seconds"
+	pw.header(name, "Time between sending an operation request and receiving the first response packet.", "histogram")
+	for _, ls := range lss {
+		codes := make([]string, 0, len(ls.snapshot.Transactions))
+		for code := range ls.snapshot.Transactions {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		for _, code := range codes {
+			h := ls.snapshot.Transactions[code]
+			op := promPair("opcode", code)
+			for i, le := range h.UpperBounds {
+				pw.printf("%s_bucket%s %d\n", name, ls.labels(op, promPair("le", strconv.FormatFloat(le, 'g', -1, 64))), h.Buckets[i])
+			}
+			pw.printf("%s_bucket%s %d\n", name, ls.labels(op, promPair("le", "+Inf")), h.Count)
+			pw.printf("%s_sum%s %s\n", name, ls.labels(op), strconv.FormatFloat(h.Sum, 'g', -1, 64))
+			pw.printf("%s_count%s %d\n", name, ls.labels(op), h.Count)
+		}
+	}
+
+	singles := []struct {
+		name  string
+		help  string
+		typ   string
+		value func(MetricsSnapshot) interface{}
+	}{
+		{"ptpip_timeouts_total", "Transactions that timed out waiting for a response.", "counter", func(s MetricsSnapshot) interface{} { return s.Timeouts }},
+		{"ptpip_reconnects_total", "Successful dials after the first one.", "counter", func(s MetricsSnapshot) interface{} { return s.Reconnects }},
+		{"ptpip_stream_frames_total", "Live view frames received from the responder.", "counter", func(s MetricsSnapshot) interface{} { return s.StreamFrames }},
+		{"ptpip_stream_dropped_frames_total", "Live view frames dropped because the consumer could not keep up.", "counter", func(s MetricsSnapshot) interface{} { return s.DroppedFrames }},
+		{"ptpip_stream_fps", "Live view frames received per second.", "gauge", func(s MetricsSnapshot) interface{} { return s.StreamFPS }},
+	}
+	for _, single := range singles {
+		pw.header(single.name, single.help, single.typ)
+		for _, ls := range lss {
+			pw.printf("%s%s %v\n", single.name, ls.labels(), single.value(ls.snapshot))
+		}
+	}
+
+	name = "ptpip_last_error_timestamp_seconds"
+	pw.header(name, "Time of the last error, the error message is in the error label.", "gauge")
+	for _, ls := range lss {
+		if ls.snapshot.LastError != "" {
+			pw.printf("%s%s %d\n", name, ls.labels(promPair("error", ls.snapshot.LastError)), ls.snapshot.LastErrorTime.Unix())
+		}
+	}
+
+	return pw.err
+}
+
+// promWriter remembers the first error so the caller only has to check it once.
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) printf(format string, v ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, v...)
+	}
+}
+
+func (pw *promWriter) header(name, help, typ string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// promPair formats a Prometheus label, quoting the value as required by the text format.
+func promPair(name, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	return name + `="` + r.Replace(value) + `"`
+}
@@ -0,0 +1,87 @@
+package ip_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetMetrics(ip.NewMetrics())
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ip.FujiGetDevicePropertyValue(c, ip.DPC_Fuji_AppVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	s := c.Metrics().Snapshot()
+	cmd := s.Channels["cmd"]
+	if cmd.PacketsSent == 0 || cmd.BytesSent == 0 {
+		t.Errorf("Snapshot() cmd sent = %d packets, %d bytes; want > 0", cmd.PacketsSent, cmd.BytesSent)
+	}
+	if cmd.PacketsReceived == 0 || cmd.BytesReceived == 0 {
+		t.Errorf("Snapshot() cmd received = %d packets, %d bytes; want > 0", cmd.PacketsReceived, cmd.BytesReceived)
+	}
+	if ev := s.Channels["event"]; ev.PacketsSent != 0 {
+		t.Errorf("Snapshot() event packets sent = %d; want 0", ev.PacketsSent)
+	}
+
+	h, ok := s.Transactions["0x1015"]
+	if !ok {
+		t.Fatalf("Snapshot() transactions = %v; want 0x1015", s.Transactions)
+	}
+	if h.Count == 0 || h.Buckets[len(h.Buckets)-1] != h.Count {
+		t.Errorf("Snapshot() 0x1015 histogram = %+v; want all observations in the last bucket", h)
+	}
+
+	var got ip.MetricsSnapshot
+	if err := json.Unmarshal([]byte(c.Metrics().String()), &got); err != nil {
+		t.Errorf("String() err = %s; want valid json", err)
+	}
+	if got.Channels["cmd"] != cmd {
+		t.Errorf("String() cmd = %+v; want %+v", got.Channels["cmd"], cmd)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Metrics().WritePrometheus(&buf); err != nil {
+		t.Errorf("WritePrometheus() err = %s; want <nil>", err)
+	}
+	for _, want := range []string{
+		"# TYPE ptpip_packets_sent_total counter\n",
+		`ptpip_packets_sent_total{channel="event"} 0` + "\n",
+		`ptpip_transaction_duration_seconds_bucket{opcode="0x1015",le="+Inf"} `,
+		`ptpip_transaction_duration_seconds_bucket{opcode="0x1015",le="0.005"} `,
+		"ptpip_stream_fps 0\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WritePrometheus() output does not contain %q", want)
+		}
+	}
+}
+
+func TestMetricsNil(t *testing.T) {
+	var m *ip.Metrics
+
+	if got := m.String(); got == "" {
+		t.Errorf("String() on nil metrics = %q; want json", got)
+	}
+	var buf bytes.Buffer
+	if err := m.WritePrometheus(&buf); err != nil {
+		t.Errorf("WritePrometheus() on nil metrics err = %s; want <nil>", err)
+	}
+}
@@ -0,0 +1,82 @@
+package ip
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+// GetObjectPropsSupported returns the MTP object properties supported by the Responder for the given object format.
+func (c *Client) GetObjectPropsSupported(ofc ptp.ObjectFormatCode) ([]ptp.ObjectPropCode, error) {
+	c.infow(SubsystemCmdData, "requesting supported object properties", "responder", c.ResponderFriendlyName(), "format", ofc)
+	xs, err := c.vendorExtensions.operationRequestDataIn(c, ptp.OC_MTP_GetObjectPropsSupported, []uint32{uint32(ofc)})
+	if err != nil {
+		return nil, err
+	}
+
+	pv, err := ptp.UnmarshalPropertyValue(xs, ptp.DTC_AUINT16)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := pv.Value.([]uint16)
+	list := make([]ptp.ObjectPropCode, len(codes))
+	for i, code := range codes {
+		list[i] = ptp.ObjectPropCode(code)
+	}
+
+	return list, nil
+}
+
+// GetObjectPropDesc returns the description of the given MTP object property for the given object format.
+func (c *Client) GetObjectPropDesc(opc ptp.ObjectPropCode, ofc ptp.ObjectFormatCode) (*ptp.ObjectPropDesc, error) {
+	c.infow(SubsystemCmdData, "requesting object property description", "responder", c.ResponderFriendlyName(), "property", opc, "format", ofc)
+	xs, err := c.vendorExtensions.operationRequestDataIn(c, ptp.OC_MTP_GetObjectPropDesc, []uint32{uint32(opc), uint32(ofc)})
+	if err != nil {
+		return nil, err
+	}
+
+	return ptp.ReadObjectPropDesc(bytes.NewReader(xs))
+}
+
+// GetObjectPropValue returns the value of the given MTP object property of the given object. The data type of the
+// property can be obtained using GetObjectPropDesc().
+func (c *Client) GetObjectPropValue(h ptp.ObjectHandle, opc ptp.ObjectPropCode, dt ptp.DataTypeCode) (ptp.PropertyValue, error) {
+	c.infow(SubsystemCmdData, "requesting object property value", "responder", c.ResponderFriendlyName(), "object", h, "property", opc)
+	xs, err := c.vendorExtensions.operationRequestDataIn(c, ptp.OC_MTP_GetObjectPropValue, []uint32{uint32(h), uint32(opc)})
+	if err != nil {
+		return ptp.PropertyValue{}, err
+	}
+
+	return ptp.UnmarshalPropertyValue(xs, dt)
+}
+
+// SetObjectPropValue sets the given MTP object property of the given object to the specified value.
+func (c *Client) SetObjectPropValue(h ptp.ObjectHandle, opc ptp.ObjectPropCode, pv ptp.PropertyValue) error {
+	c.infow(SubsystemCmdData, "setting object property value", "responder", c.ResponderFriendlyName(), "object", h, "property", opc)
+	b, err := pv.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return c.vendorExtensions.operationRequestDataOut(c, ptp.OC_MTP_SetObjectPropValue, []uint32{uint32(h), uint32(opc)}, b)
+}
+
+// GetObjectPropList returns any number of object property values for any number of objects in a single operation.
+// Use ptp.PM_MTP_AllObjects as handle together with a depth of 0 to select all objects on the Responder, filtered by
+// the given object format, ofc, when it is not 0. Use ptp.PM_MTP_AllProperties as opc to get all properties or set opc
+// to ptp.PM_MTP_ByGroup to select the properties by group code. Fetching the filename and size of all objects thus
+// only takes two operations instead of one GetObjectInfo operation per object.
+func (c *Client) GetObjectPropList(h ptp.ObjectHandle, ofc ptp.ObjectFormatCode, opc uint32, group uint32, depth uint32) (ptp.ObjectPropList, error) {
+	c.infow(SubsystemCmdData, "requesting object property list", "responder", c.ResponderFriendlyName(), "object", h, "property", opc)
+	xs, err := c.vendorExtensions.operationRequestDataIn(c, ptp.OC_MTP_GetObjectPropList, []uint32{uint32(h), uint32(ofc), opc, group, depth})
+	if err != nil {
+		return nil, err
+	}
+
+	var l ptp.ObjectPropList
+	if err := l.UnmarshalPTP(xs); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,131 @@
+package ip_test
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetObjectPropsSupported(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	res.Handle(ptp.OC_MTP_GetObjectPropsSupported, iptest.Reply(iptest.Data([]byte{
+		0x03, 0x00, 0x00, 0x00, 0x01, 0xdc, 0x04, 0xdc, 0x07, 0xdc,
+	})))
+
+	c := res.DialClient(t, "tèster", "0f7a4bd4-3f4b-4a8f-9a2d-3d8b6a0b5c21", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	got, err := c.GetObjectPropsSupported(ptp.OFC_EXIF_JPEG)
+	if err != nil {
+		t.Fatalf("GetObjectPropsSupported() error = %s; want <nil>", err)
+	}
+	want := []ptp.ObjectPropCode{ptp.OPC_StorageID, ptp.OPC_ObjectSize, ptp.OPC_ObjectFileName}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObjectPropsSupported() got = %v; want %v", got, want)
+	}
+
+	if p := res.RequestsFor(ptp.OC_MTP_GetObjectPropsSupported)[0].Parameter(1); p != uint32(ptp.OFC_EXIF_JPEG) {
+		t.Errorf("GetObjectPropsSupported() parameter got = %#x; want %#x", p, ptp.OFC_EXIF_JPEG)
+	}
+}
+
+func TestClient_GetObjectPropDesc(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	res.Handle(ptp.OC_MTP_GetObjectPropDesc, iptest.Reply(iptest.Data([]byte{
+		0x04, 0xdc, 0x08, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00,
+	})))
+
+	c := res.DialClient(t, "tèster", "0f7a4bd4-3f4b-4a8f-9a2d-3d8b6a0b5c21", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	got, err := c.GetObjectPropDesc(ptp.OPC_ObjectSize, ptp.OFC_EXIF_JPEG)
+	if err != nil {
+		t.Fatalf("GetObjectPropDesc() error = %s; want <nil>", err)
+	}
+	if got.ObjectPropertyCode != ptp.OPC_ObjectSize || got.DataType != ptp.DTC_UINT64 {
+		t.Errorf("GetObjectPropDesc() got = %#v; want object size of type UINT64", got)
+	}
+
+	req := res.RequestsFor(ptp.OC_MTP_GetObjectPropDesc)[0]
+	if req.Parameter(1) != uint32(ptp.OPC_ObjectSize) || req.Parameter(2) != uint32(ptp.OFC_EXIF_JPEG) {
+		t.Errorf("GetObjectPropDesc() parameters got = %#x; want [%#x %#x]", req.Parameters, ptp.OPC_ObjectSize, ptp.OFC_EXIF_JPEG)
+	}
+}
+
+func TestClient_GetObjectPropList(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	list := ptp.ObjectPropList{
+		{ObjectHandle: 1, PropertyCode: ptp.OPC_ObjectFileName, Value: ptp.PropertyValue{DataType: ptp.DTC_STR, Value: "DSCF0001.JPG"}},
+		{ObjectHandle: 1, PropertyCode: ptp.OPC_ObjectSize, Value: ptp.PropertyValue{DataType: ptp.DTC_UINT64, Value: uint64(7028736)}},
+		{ObjectHandle: 2, PropertyCode: ptp.OPC_ObjectFileName, Value: ptp.PropertyValue{DataType: ptp.DTC_STR, Value: "DSCF0002.RAF"}},
+	}
+	b, err := list.MarshalPTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Handle(ptp.OC_MTP_GetObjectPropList, iptest.Reply(iptest.Data(b)))
+
+	c := res.DialClient(t, "tèster", "0f7a4bd4-3f4b-4a8f-9a2d-3d8b6a0b5c21", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	got, err := c.GetObjectPropList(ptp.PM_MTP_AllObjects, 0, ptp.PM_MTP_AllProperties, 0, 0)
+	if err != nil {
+		t.Fatalf("GetObjectPropList() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("GetObjectPropList() got = %v; want %v", got, list)
+	}
+
+	req := res.RequestsFor(ptp.OC_MTP_GetObjectPropList)[0]
+	want := []uint32{uint32(ptp.PM_MTP_AllObjects), 0, ptp.PM_MTP_AllProperties, 0, 0}
+	if !reflect.DeepEqual(req.Parameters, want) {
+		t.Errorf("GetObjectPropList() parameters got = %#x; want %#x", req.Parameters, want)
+	}
+}
+
+func TestClient_SetObjectPropValue(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	res.Handle(ptp.OC_MTP_SetObjectPropValue, iptest.Reply(iptest.OK()))
+
+	c := res.DialClient(t, "tèster", "0f7a4bd4-3f4b-4a8f-9a2d-3d8b6a0b5c21", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	pv := ptp.PropertyValue{DataType: ptp.DTC_STR, Value: "A.JPG"}
+	if err := c.SetObjectPropValue(3, ptp.OPC_ObjectFileName, pv); err != nil {
+		t.Fatalf("SetObjectPropValue() error = %s; want <nil>", err)
+	}
+
+	req := res.RequestsFor(ptp.OC_MTP_SetObjectPropValue)[0]
+	if req.DataPhase != ip.DP_DataOut {
+		t.Errorf("SetObjectPropValue() data phase got = %#x; want %#x", req.DataPhase, ip.DP_DataOut)
+	}
+	want, _ := pv.MarshalBinary()
+	if !bytes.Equal(req.Data, want) {
+		t.Errorf("SetObjectPropValue() data got = %#v; want %#v", req.Data, want)
+	}
+}
+
+func TestClient_GetObjectPropValueFail(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	res.Handle(ptp.OC_MTP_GetObjectPropValue, iptest.Reply(iptest.Fail(ptp.RC_MTP_InvalidObjectPropCode)))
+
+	c := res.DialClient(t, "tèster", "0f7a4bd4-3f4b-4a8f-9a2d-3d8b6a0b5c21", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	_, err := c.GetObjectPropValue(1, 0xdcff, ptp.DTC_UINT8)
+	if err == nil || err.Error() != "invalid object property code" {
+		t.Errorf("GetObjectPropValue() error = %v; want invalid object property code", err)
+	}
+}
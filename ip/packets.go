@@ -58,6 +58,10 @@ const (
 )
 
 var (
+	// ErrUnknownPacketType is wrapped by errors caused by a packet type that is not known to us. Use errors.Is() to check
+	// for it.
+	ErrUnknownPacketType = errors.New("unknown packet type")
+	// Deprecated: use ErrUnknownPacketType instead.
 	UnknownPacketType = errors.New("unknown packet type %#x")
 )
 
@@ -468,7 +472,7 @@ func NewPacketOutFromPacketType(pt PacketType) (PacketOut, error) {
 		return p, nil
 	}
 
-	return nil, fmt.Errorf(UnknownPacketType.Error(), pt)
+	return nil, fmt.Errorf("%w %#x", ErrUnknownPacketType, pt)
 }
 
 // NewPacketInFromPacketType creates an new packet struct based on the given packet type. All fields will be left
@@ -505,5 +509,5 @@ func NewPacketInFromPacketType(pt PacketType) (PacketIn, error) {
 		return p, nil
 	}
 
-	return nil, fmt.Errorf(UnknownPacketType.Error(), pt)
+	return nil, fmt.Errorf("%w %#x", ErrUnknownPacketType, pt)
 }
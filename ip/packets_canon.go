@@ -0,0 +1,502 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"time"
+)
+
+type CanonEVFOutputDevice uint32
+
+const (
+	OC_Canon_EOS_GetStorageIDs          ptp.OperationCode = 0x9101
+	OC_Canon_EOS_GetStorageInfo         ptp.OperationCode = 0x9102
+	OC_Canon_EOS_GetObjectInfo          ptp.OperationCode = 0x9103
+	OC_Canon_EOS_GetObject              ptp.OperationCode = 0x9104
+	OC_Canon_EOS_DeleteObject           ptp.OperationCode = 0x9105
+	OC_Canon_EOS_FormatStore            ptp.OperationCode = 0x9106
+	OC_Canon_EOS_GetPartialObject       ptp.OperationCode = 0x9107
+	OC_Canon_EOS_GetDeviceInfoEx        ptp.OperationCode = 0x9108
+	OC_Canon_EOS_GetObjectInfoEx        ptp.OperationCode = 0x9109
+	OC_Canon_EOS_GetThumbEx             ptp.OperationCode = 0x910A
+	OC_Canon_EOS_RemoteRelease          ptp.OperationCode = 0x910F
+	OC_Canon_EOS_SetDevicePropValueEx   ptp.OperationCode = 0x9110
+	OC_Canon_EOS_GetRemoteMode          ptp.OperationCode = 0x9113
+	OC_Canon_EOS_SetRemoteMode          ptp.OperationCode = 0x9114
+	OC_Canon_EOS_SetEventMode           ptp.OperationCode = 0x9115
+	OC_Canon_EOS_GetEvent               ptp.OperationCode = 0x9116
+	OC_Canon_EOS_TransferComplete       ptp.OperationCode = 0x9117
+	OC_Canon_EOS_KeepDeviceOn           ptp.OperationCode = 0x911D
+	OC_Canon_EOS_BulbStart              ptp.OperationCode = 0x9125
+	OC_Canon_EOS_BulbEnd                ptp.OperationCode = 0x9126
+	OC_Canon_EOS_RequestDevicePropValue ptp.OperationCode = 0x9127
+	OC_Canon_EOS_RemoteReleaseOn        ptp.OperationCode = 0x9128
+	OC_Canon_EOS_RemoteReleaseOff       ptp.OperationCode = 0x9129
+	OC_Canon_EOS_InitiateViewfinder     ptp.OperationCode = 0x9151
+	OC_Canon_EOS_TerminateViewfinder    ptp.OperationCode = 0x9152
+	OC_Canon_EOS_GetViewFinderData      ptp.OperationCode = 0x9153
+	OC_Canon_EOS_DoAf                   ptp.OperationCode = 0x9154
+	OC_Canon_EOS_AfCancel               ptp.OperationCode = 0x9160
+
+	RC_Canon_UnknownCommand   ptp.OperationResponseCode = 0xA001
+	RC_Canon_OperationRefused ptp.OperationResponseCode = 0xA005
+	RC_Canon_LensCover        ptp.OperationResponseCode = 0xA006
+	RC_Canon_BatteryLow       ptp.OperationResponseCode = 0xA101
+	// RC_Canon_NotReady is returned by OC_Canon_EOS_GetViewFinderData when there is no live view frame available yet.
+	RC_Canon_NotReady ptp.OperationResponseCode = 0xA102
+
+	EC_Canon_EOS_RequestGetEvent        ptp.EventCode = 0xC101
+	EC_Canon_EOS_ObjectAddedEx          ptp.EventCode = 0xC181
+	EC_Canon_EOS_ObjectRemoved          ptp.EventCode = 0xC182
+	EC_Canon_EOS_RequestGetObjectInfoEx ptp.EventCode = 0xC183
+	EC_Canon_EOS_StorageStatusChanged   ptp.EventCode = 0xC184
+	EC_Canon_EOS_StorageInfoChanged     ptp.EventCode = 0xC185
+	EC_Canon_EOS_RequestObjectTransfer  ptp.EventCode = 0xC186
+	EC_Canon_EOS_ObjectInfoChangedEx    ptp.EventCode = 0xC187
+	EC_Canon_EOS_ObjectContentChanged   ptp.EventCode = 0xC188
+	EC_Canon_EOS_PropValueChanged       ptp.EventCode = 0xC189
+	EC_Canon_EOS_AvailListChanged       ptp.EventCode = 0xC18A
+	EC_Canon_EOS_CameraStatusChanged    ptp.EventCode = 0xC18B
+	EC_Canon_EOS_WillSoonShutdown       ptp.EventCode = 0xC18D
+	EC_Canon_EOS_ShutdownTimerUpdated   ptp.EventCode = 0xC18E
+	EC_Canon_EOS_BulbExposureTime       ptp.EventCode = 0xC194
+
+	DPC_Canon_EOS_Aperture           ptp.DevicePropCode = 0xD101
+	DPC_Canon_EOS_ShutterSpeed       ptp.DevicePropCode = 0xD102
+	DPC_Canon_EOS_ISOSpeed           ptp.DevicePropCode = 0xD103
+	DPC_Canon_EOS_ExpCompensation    ptp.DevicePropCode = 0xD104
+	DPC_Canon_EOS_AutoExposureMode   ptp.DevicePropCode = 0xD105
+	DPC_Canon_EOS_DriveMode          ptp.DevicePropCode = 0xD106
+	DPC_Canon_EOS_MeteringMode       ptp.DevicePropCode = 0xD107
+	DPC_Canon_EOS_FocusMode          ptp.DevicePropCode = 0xD108
+	DPC_Canon_EOS_WhiteBalance       ptp.DevicePropCode = 0xD109
+	DPC_Canon_EOS_ColorTemperature   ptp.DevicePropCode = 0xD10A
+	DPC_Canon_EOS_PictureStyle       ptp.DevicePropCode = 0xD110
+	DPC_Canon_EOS_BatteryPower       ptp.DevicePropCode = 0xD111
+	DPC_Canon_EOS_AvailableShots     ptp.DevicePropCode = 0xD11B
+	DPC_Canon_EOS_CaptureDestination ptp.DevicePropCode = 0xD11C
+	DPC_Canon_EOS_ImageFormat        ptp.DevicePropCode = 0xD120
+	// DPC_Canon_EOS_EVFOutputDevice selects where the live view image is sent to. Setting it to EVF_Canon_PC makes the
+	// frames available through OC_Canon_EOS_GetViewFinderData.
+	DPC_Canon_EOS_EVFOutputDevice ptp.DevicePropCode = 0xD1B0
+	DPC_Canon_EOS_EVFMode         ptp.DevicePropCode = 0xD1B1
+
+	EVF_Canon_None CanonEVFOutputDevice = 0x00000000
+	EVF_Canon_TFT  CanonEVFOutputDevice = 0x00000001
+	EVF_Canon_PC   CanonEVFOutputDevice = 0x00000002
+
+	// PM_Canon_EOS_RemoteMode is the parameter to OC_Canon_EOS_SetRemoteMode enabling remote control.
+	PM_Canon_EOS_RemoteMode uint32 = 0x00000001
+	// PM_Canon_EOS_EventMode is the parameter to OC_Canon_EOS_SetEventMode enabling the GetEvent polling model.
+	PM_Canon_EOS_EventMode uint32 = 0x00000001
+	// PM_Canon_EOS_ShutterHalf presses the shutter button halfway when passed to OC_Canon_EOS_RemoteReleaseOn.
+	PM_Canon_EOS_ShutterHalf uint32 = 0x00000001
+	// PM_Canon_EOS_ShutterFull presses the shutter button all the way when passed to OC_Canon_EOS_RemoteReleaseOn.
+	PM_Canon_EOS_ShutterFull uint32 = 0x00000003
+	// PM_Canon_EOS_AF makes OC_Canon_EOS_RemoteReleaseOn focus before releasing the shutter.
+	PM_Canon_EOS_AF uint32 = 0x00000000
+	// PM_Canon_EOS_NoAF makes OC_Canon_EOS_RemoteReleaseOn release the shutter without focusing.
+	PM_Canon_EOS_NoAF uint32 = 0x00000001
+	// PM_Canon_EOS_ViewFinderData is the first parameter to OC_Canon_EOS_GetViewFinderData.
+	PM_Canon_EOS_ViewFinderData uint32 = 0x00100000
+
+	// canonRecordHeaderSize is the size of the length and type fields heading each record returned by
+	// OC_Canon_EOS_GetEvent and OC_Canon_EOS_GetViewFinderData.
+	canonRecordHeaderSize = 8
+	// canonViewFinderImage is the record type holding the JPEG image returned by OC_Canon_EOS_GetViewFinderData.
+	canonViewFinderImage uint32 = 0x00000001
+	// canonFormEnumeration is the form type of an EC_Canon_EOS_AvailListChanged record listing the allowed values.
+	canonFormEnumeration uint32 = 0x00000003
+	// canonAvailListValueSize is the size taken by each allowed value in an EC_Canon_EOS_AvailListChanged record,
+	// whatever the data type of the property.
+	canonAvailListValueSize = 4
+)
+
+// canonDevicePropDataTypes holds the data type of the Canon device properties. The Responder does not report them so
+// they are taken from libgphoto2 (camlibs/ptp2/ptp-pack.c, ptp_unpack_CANON_changes()). DPC_Canon_EOS_ImageFormat is
+// missing on purpose: its values are not integers but variable sized structures.
+var canonDevicePropDataTypes = map[ptp.DevicePropCode]ptp.DataTypeCode{
+	DPC_Canon_EOS_Aperture:           ptp.DTC_UINT16,
+	DPC_Canon_EOS_ShutterSpeed:       ptp.DTC_UINT16,
+	DPC_Canon_EOS_ISOSpeed:           ptp.DTC_UINT16,
+	DPC_Canon_EOS_ExpCompensation:    ptp.DTC_UINT8,
+	DPC_Canon_EOS_AutoExposureMode:   ptp.DTC_UINT16,
+	DPC_Canon_EOS_DriveMode:          ptp.DTC_UINT16,
+	DPC_Canon_EOS_MeteringMode:       ptp.DTC_UINT8,
+	DPC_Canon_EOS_FocusMode:          ptp.DTC_UINT16,
+	DPC_Canon_EOS_WhiteBalance:       ptp.DTC_UINT8,
+	DPC_Canon_EOS_ColorTemperature:   ptp.DTC_UINT32,
+	DPC_Canon_EOS_PictureStyle:       ptp.DTC_UINT8,
+	DPC_Canon_EOS_BatteryPower:       ptp.DTC_UINT16,
+	DPC_Canon_EOS_AvailableShots:     ptp.DTC_UINT32,
+	DPC_Canon_EOS_CaptureDestination: ptp.DTC_UINT32,
+	DPC_Canon_EOS_EVFOutputDevice:    ptp.DTC_UINT16,
+	DPC_Canon_EOS_EVFMode:            ptp.DTC_UINT16,
+}
+
+// ErrCanonNotReady is returned by CanonGetViewFinderData() when there is no live view frame available.
+var ErrCanonNotReady = errors.New("no view finder data available")
+
+// CanonViewFinderInterval is the time waited between two OC_Canon_EOS_GetViewFinderData requests when live view is
+// enabled on a Canon device.
+var CanonViewFinderInterval = 50 * time.Millisecond
+
+// CanonEvent is a single record returned by OC_Canon_EOS_GetEvent. Canon EOS devices do not report property changes
+// or new objects on the event connection, they need to be polled for them instead.
+type CanonEvent struct {
+	Code ptp.EventCode
+	// Property is the device property that changed for EC_Canon_EOS_PropValueChanged and EC_Canon_EOS_AvailListChanged.
+	Property ptp.DevicePropCode
+	// Value holds the raw new value of the property for EC_Canon_EOS_PropValueChanged.
+	Value []byte
+	// DataType is the data type of the property for EC_Canon_EOS_AvailListChanged. It is ptp.DTC_UNDEF when the data
+	// type of the property is not known.
+	DataType ptp.DataTypeCode
+	// Values holds the raw allowed values of the property for EC_Canon_EOS_AvailListChanged, each one sized according
+	// to DataType. It is nil when the values are not listed as an enumeration or when DataType is ptp.DTC_UNDEF.
+	Values [][]byte
+	// ObjectHandle is the object that was added or removed for EC_Canon_EOS_ObjectAddedEx and
+	// EC_Canon_EOS_ObjectRemoved.
+	ObjectHandle ptp.ObjectHandle
+	// StorageID, ObjectFormat, ObjectSize, ParentObject and Filename describe the object for
+	// EC_Canon_EOS_ObjectAddedEx.
+	StorageID    uint32
+	ObjectFormat ptp.ObjectFormatCode
+	ObjectSize   uint32
+	ParentObject ptp.ObjectHandle
+	Filename     string
+	// Data holds the raw record payload following the length and type fields.
+	Data []byte
+}
+
+// CanonInitEventConn initialises the event connection following the PTP/IP standard and then performs the EOS
+// handshake:
+//  1. Open a session.
+//  2. Set the remote mode so that the Responder hands over control to the Initiator.
+//  3. Set the event mode so that the Responder starts collecting the events to be retrieved using CanonGetEvent().
+//
+// The first call to CanonGetEvent() after the handshake will return the current value of all device properties.
+func CanonInitEventConn(c *Client) error {
+	if err := GenericInitEventConn(c); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "opening a session")
+	if _, err := GenericOperationRequestDataIn(c, ptp.OC_OpenSession, []uint32{0x00000001}); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "enabling remote mode")
+	if _, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_SetRemoteMode, []uint32{PM_Canon_EOS_RemoteMode}); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "enabling event mode")
+	if _, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_SetEventMode, []uint32{PM_Canon_EOS_EventMode}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CanonGetEvent polls the Responder for the events that occurred since the previous poll. An empty list is returned
+// when nothing happened.
+func CanonGetEvent(c *Client) ([]CanonEvent, error) {
+	c.debugw(SubsystemVendor, "polling for events", "responder", c.ResponderFriendlyName())
+	xs, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_GetEvent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonParseEvents(xs)
+}
+
+// canonParseEvents splits the data returned by OC_Canon_EOS_GetEvent into events. Each record starts with its length,
+// which includes the length field itself, followed by its type. The list is terminated by an empty record of type 0.
+func canonParseEvents(b []byte) ([]CanonEvent, error) {
+	// Do not allocate the list up front since the number of events comes straight from the network.
+	var list []CanonEvent
+	for len(b) >= canonRecordHeaderSize {
+		l := binary.LittleEndian.Uint32(b[0:4])
+		t := binary.LittleEndian.Uint32(b[4:8])
+		if l == canonRecordHeaderSize && t == 0 {
+			break
+		}
+		if l < canonRecordHeaderSize || uint64(l) > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: event record length %d with %d bytes left", InvalidPacketError, l, len(b))
+		}
+
+		e, err := canonParseEvent(ptp.EventCode(t), b[canonRecordHeaderSize:l])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		b = b[l:]
+	}
+
+	return list, nil
+}
+
+// canonParseEvent decodes the payload of a single event record. Unknown events only have their raw Data set.
+func canonParseEvent(code ptp.EventCode, d []byte) (CanonEvent, error) {
+	e := CanonEvent{Code: code, Data: d}
+
+	switch code {
+	case EC_Canon_EOS_PropValueChanged:
+		if len(d) < 4 {
+			return e, internal.ShortPacketError(len(d), 4)
+		}
+		e.Property = ptp.DevicePropCode(binary.LittleEndian.Uint32(d[0:4]))
+		e.Value = d[4:]
+	case EC_Canon_EOS_AvailListChanged:
+		// The property code is followed by the form type and the number of values. Each value takes up 4 bytes, the
+		// data type of the property determines how many of those are significant.
+		if len(d) < 12 {
+			return e, internal.ShortPacketError(len(d), 12)
+		}
+		e.Property = ptp.DevicePropCode(binary.LittleEndian.Uint32(d[0:4]))
+		e.DataType = canonDevicePropDataTypes[e.Property]
+		form := binary.LittleEndian.Uint32(d[4:8])
+		n := int(binary.LittleEndian.Uint32(d[8:12]))
+		s := e.DataType.ElementSize()
+		if form != canonFormEnumeration || s <= 0 || s > canonAvailListValueSize || n > (len(d)-12)/canonAvailListValueSize {
+			break
+		}
+		for i := 0; i < n; i++ {
+			o := 12 + i*canonAvailListValueSize
+			e.Values = append(e.Values, d[o:o+s])
+		}
+	case EC_Canon_EOS_ObjectAddedEx:
+		if len(d) < 0x20 {
+			return e, internal.ShortPacketError(len(d), 0x20)
+		}
+		e.ObjectHandle = ptp.ObjectHandle(binary.LittleEndian.Uint32(d[0x00:0x04]))
+		e.StorageID = binary.LittleEndian.Uint32(d[0x04:0x08])
+		e.ObjectFormat = ptp.ObjectFormatCode(binary.LittleEndian.Uint16(d[0x08:0x0A]))
+		e.ObjectSize = binary.LittleEndian.Uint32(d[0x14:0x18])
+		e.ParentObject = ptp.ObjectHandle(binary.LittleEndian.Uint32(d[0x18:0x1C]))
+		// The filename is a null terminated ASCII string.
+		if fn := d[0x20:]; len(fn) > 0 {
+			if i := bytes.IndexByte(fn, 0); i >= 0 {
+				fn = fn[:i]
+			}
+			e.Filename = string(fn)
+		}
+	case EC_Canon_EOS_ObjectRemoved:
+		if len(d) < 4 {
+			return e, internal.ShortPacketError(len(d), 4)
+		}
+		e.ObjectHandle = ptp.ObjectHandle(binary.LittleEndian.Uint32(d[0:4]))
+	}
+
+	return e, nil
+}
+
+// CanonPollEvents calls CanonGetEvent() every interval and sends the events received on the returned channel until
+// done is closed. The channel is closed when polling stops, which also happens when the connection to the Responder is
+// lost.
+func CanonPollEvents(c *Client, interval time.Duration, done <-chan struct{}) <-chan CanonEvent {
+	ch := make(chan CanonEvent, 10)
+
+	go func() {
+		defer close(ch)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+
+			list, err := CanonGetEvent(c)
+			if err != nil {
+				if err == WaitForResponseError {
+					continue
+				}
+				c.errorw(SubsystemVendor, "event polling stopped", "error", err)
+				return
+			}
+			for _, e := range list {
+				select {
+				case ch <- e:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// CanonGetDeviceState polls the Responder for events and returns the properties that changed since the previous poll
+// with their current value. Right after the EOS handshake, this will return all properties. The data type of a
+// property is not reported by the Responder: values of 1, 2 or 4 bytes are returned as unsigned integers, any other
+// value as ptp.DTC_UNDEF.
+func CanonGetDeviceState(c *Client) (interface{}, error) {
+	c.infow(SubsystemVendor, "requesting device state", "responder", c.ResponderFriendlyName())
+	list, err := CanonGetEvent(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var props []*ptp.DevicePropDesc
+	for _, e := range list {
+		if e.Code != EC_Canon_EOS_PropValueChanged {
+			continue
+		}
+
+		dpd := &ptp.DevicePropDesc{
+			DevicePropertyCode: e.Property,
+			DataType:           ptp.DTC_UNDEF,
+			CurrentValue:       e.Value,
+		}
+		switch len(e.Value) {
+		case 1:
+			dpd.DataType = ptp.DTC_UINT8
+		case 2:
+			dpd.DataType = ptp.DTC_UINT16
+		case 4:
+			dpd.DataType = ptp.DTC_UINT32
+		}
+		props = append(props, dpd)
+	}
+
+	return props, nil
+}
+
+// CanonSetDeviceProperty sets a device property to the given value using OC_Canon_EOS_SetDevicePropValueEx. The data
+// out phase holds the size of the data, including the size field itself, followed by the property code and the value.
+func CanonSetDeviceProperty(c *Client, code ptp.DevicePropCode, val uint32) error {
+	c.infow(SubsystemVendor, "setting device property", "responder", c.ResponderFriendlyName(), "property", code, "value", fmt.Sprintf("%#x", val))
+	data := make([]byte, 12)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(code))
+	binary.LittleEndian.PutUint32(data[8:12], val)
+
+	return GenericOperationRequestDataOut(c, OC_Canon_EOS_SetDevicePropValueEx, nil, data)
+}
+
+// CanonRemoteRelease releases the shutter using OC_Canon_EOS_RemoteRelease which is supported by older EOS devices.
+func CanonRemoteRelease(c *Client) error {
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	_, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_RemoteRelease, nil)
+
+	return err
+}
+
+// CanonRemoteReleaseOn presses the shutter button. Pass PM_Canon_EOS_ShutterHalf or PM_Canon_EOS_ShutterFull as button
+// and PM_Canon_EOS_AF or PM_Canon_EOS_NoAF as af. The button must be released again using CanonRemoteReleaseOff().
+func CanonRemoteReleaseOn(c *Client, button uint32, af uint32) error {
+	_, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_RemoteReleaseOn, []uint32{button, af})
+
+	return err
+}
+
+// CanonRemoteReleaseOff releases the shutter button pressed using CanonRemoteReleaseOn().
+func CanonRemoteReleaseOff(c *Client, button uint32) error {
+	_, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_RemoteReleaseOff, []uint32{button})
+
+	return err
+}
+
+// CanonInitiateCapture focuses and releases the shutter by fully pressing and releasing the shutter button. Canon does
+// not return a capture preview: the object that was added is reported by CanonGetEvent() as an
+// EC_Canon_EOS_ObjectAddedEx event.
+func CanonInitiateCapture(c *Client) ([]byte, error) {
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	c.markCaptureSent()
+	if err := CanonRemoteReleaseOn(c, PM_Canon_EOS_ShutterFull, PM_Canon_EOS_AF); err != nil {
+		return nil, err
+	}
+	if err := CanonRemoteReleaseOff(c, PM_Canon_EOS_ShutterFull); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// CanonBulbCapture opens the shutter using OC_Canon_EOS_BulbStart and closes it using OC_Canon_EOS_BulbEnd once the
+// given duration has passed, as libgphoto2 does. The Responder must be set to bulb exposure. The Responder is probed
+// using ptp.OC_GetDeviceInfo in the meantime to keep the session alive, which leaves the events to be retrieved using
+// CanonGetEvent() untouched. Canon does not return a capture preview: the object that was added is reported by
+// CanonGetEvent() as an EC_Canon_EOS_ObjectAddedEx event.
+func CanonBulbCapture(c *Client, d time.Duration) ([]byte, error) {
+	c.infow(SubsystemVendor, "opening shutter", "responder", c.ResponderFriendlyName(), "duration", d)
+	start := time.Now()
+	if _, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_BulbStart, nil); err != nil {
+		return nil, err
+	}
+
+	c.holdOpenCapture(start, d, func(c *Client) error {
+		_, err := GenericOperationRequestDataIn(c, ptp.OC_GetDeviceInfo, nil)
+		return err
+	})
+
+	c.infow(SubsystemVendor, "closing shutter", "responder", c.ResponderFriendlyName())
+	if _, err := GenericOperationRequestDataIn(c, OC_Canon_EOS_BulbEnd, nil); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// CanonGetViewFinderData fetches a single live view frame. Live view must be enabled first by setting
+// DPC_Canon_EOS_EVFOutputDevice to EVF_Canon_PC. When the Responder has no frame available yet, the error returned
+// will be ErrCanonNotReady.
+func CanonGetViewFinderData(c *Client) ([]byte, error) {
+	xs, rc, err := genericOperationRequestDataIn(c, OC_Canon_EOS_GetViewFinderData, []uint32{PM_Canon_EOS_ViewFinderData, 0, 0})
+	if err != nil {
+		return nil, err
+	}
+	switch rc {
+	case ptp.RC_OK:
+	case RC_Canon_NotReady:
+		return nil, ErrCanonNotReady
+	default:
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
+
+	// The data holds several records, only the one holding the JPEG image is of interest here.
+	for b := xs; len(b) >= canonRecordHeaderSize; {
+		l := binary.LittleEndian.Uint32(b[0:4])
+		if l < canonRecordHeaderSize || uint64(l) > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: view finder record length %d with %d bytes left", InvalidPacketError, l, len(b))
+		}
+		if binary.LittleEndian.Uint32(b[4:8]) == canonViewFinderImage {
+			return b[canonRecordHeaderSize:l], nil
+		}
+		b = b[l:]
+	}
+
+	return nil, ErrCanonNotReady
+}
+
+// CanonToggleLiveView enables or disables live view. Canon devices do not have a streamer connection, so when enabled,
+// the frames are fetched using CanonGetViewFinderData() every CanonViewFinderInterval and queued on the StreamChan.
+func CanonToggleLiveView(c *Client, en bool) error {
+	if !en {
+		c.stopStream()
+		return CanonSetDeviceProperty(c, DPC_Canon_EOS_EVFOutputDevice, uint32(EVF_Canon_None))
+	}
+
+	if c.streaming() {
+		return nil
+	}
+	if err := CanonSetDeviceProperty(c, DPC_Canon_EOS_EVFOutputDevice, uint32(EVF_Canon_PC)); err != nil {
+		return err
+	}
+
+	c.startPolledStream(CanonViewFinderInterval, CanonGetViewFinderData, func(err error) bool {
+		return errors.Is(err, ErrCanonNotReady)
+	})
+
+	return nil
+}
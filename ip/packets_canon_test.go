@@ -0,0 +1,329 @@
+package ip_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCanonInitEventConn(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	var got []ptp.OperationCode
+	for _, req := range res.Requests() {
+		got = append(got, req.OperationCode)
+	}
+	want := []ptp.OperationCode{ptp.OC_OpenSession, ip.OC_Canon_EOS_SetRemoteMode, ip.OC_Canon_EOS_SetEventMode}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dial() operations = %#x; want %#x", got, want)
+	}
+
+	if p := res.RequestsFor(ip.OC_Canon_EOS_SetRemoteMode)[0].Parameter(1); p != ip.PM_Canon_EOS_RemoteMode {
+		t.Errorf("Dial() remote mode = %#x; want %#x", p, ip.PM_Canon_EOS_RemoteMode)
+	}
+}
+
+func TestCanonGetDeviceState(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	s, err := c.GetDeviceState()
+	if err != nil {
+		t.Fatalf("GetDeviceState() error = %s; want <nil>", err)
+	}
+
+	got := make(map[ptp.DevicePropCode]int64)
+	for _, dpd := range s.([]*ptp.DevicePropDesc) {
+		if dpd.DataType != ptp.DTC_UINT32 {
+			t.Errorf("GetDeviceState() %#x DataType = %#x; want %#x", dpd.DevicePropertyCode, dpd.DataType, ptp.DTC_UINT32)
+		}
+		got[dpd.DevicePropertyCode] = dpd.CurrentValueAsInt64()
+	}
+	want := map[ptp.DevicePropCode]int64{
+		ip.DPC_Canon_EOS_Aperture:        0x30,
+		ip.DPC_Canon_EOS_ShutterSpeed:    0x6d,
+		ip.DPC_Canon_EOS_ISOSpeed:        0x48,
+		ip.DPC_Canon_EOS_EVFOutputDevice: 0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeviceState() got = %#v; want %#v", got, want)
+	}
+
+	// The Responder only reports changes after the first poll.
+	s, err = c.GetDeviceState()
+	if err != nil {
+		t.Fatalf("GetDeviceState() error = %s; want <nil>", err)
+	}
+	if l := len(s.([]*ptp.DevicePropDesc)); l != 0 {
+		t.Errorf("GetDeviceState() got %d properties; want 0", l)
+	}
+}
+
+func TestCanonSetDeviceProperty(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.CanonGetEvent(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetDeviceProperty(ip.DPC_Canon_EOS_ISOSpeed, 0x58); err != nil {
+		t.Fatalf("SetDeviceProperty() error = %s; want <nil>", err)
+	}
+
+	req := res.RequestsFor(ip.OC_Canon_EOS_SetDevicePropValueEx)[0]
+	want := []byte{0x0c, 0x00, 0x00, 0x00, 0x03, 0xd1, 0x00, 0x00, 0x58, 0x00, 0x00, 0x00}
+	if !bytes.Equal(req.Data, want) {
+		t.Errorf("SetDeviceProperty() data = %#v; want %#v", req.Data, want)
+	}
+
+	got, err := ip.CanonGetEvent(c)
+	if err != nil {
+		t.Fatalf("CanonGetEvent() error = %s; want <nil>", err)
+	}
+	wantEvents := []ip.CanonEvent{{
+		Code:     ip.EC_Canon_EOS_PropValueChanged,
+		Property: ip.DPC_Canon_EOS_ISOSpeed,
+		Value:    []byte{0x58, 0x00, 0x00, 0x00},
+		Data:     []byte{0x03, 0xd1, 0x00, 0x00, 0x58, 0x00, 0x00, 0x00},
+	}}
+	if !reflect.DeepEqual(got, wantEvents) {
+		t.Errorf("CanonGetEvent() got = %#v; want %#v", got, wantEvents)
+	}
+}
+
+func TestCanonGetEvent(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.CanonGetEvent(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// No capture of a Canon device is available: the available list records follow the layout parsed by libgphoto2
+	// (camlibs/ptp2/ptp-pack.c, ptp_unpack_CANON_changes()), i.e. the property code, the form type (3 being an
+	// enumeration) and the number of values, each of which takes up 4 bytes.
+	res.QueueCanonEvent(ip.EC_Canon_EOS_AvailListChanged, []byte{
+		0x03, 0xd1, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+		0x48, 0x00, 0x00, 0x00, 0x58, 0x00, 0x00, 0x00,
+	})
+	res.QueueCanonEvent(ip.EC_Canon_EOS_AvailListChanged, []byte{
+		0x04, 0xd1, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+		0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+	})
+	res.QueueCanonEvent(ip.EC_Canon_EOS_AvailListChanged, []byte{
+		0x01, 0xd1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	})
+	res.QueueCanonEvent(ip.EC_Canon_EOS_AvailListChanged, []byte{
+		0xff, 0xd1, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+	})
+	res.QueueCanonEvent(ip.EC_Canon_EOS_ObjectRemoved, []byte{0x2a, 0x00, 0x00, 0x00})
+	res.QueueCanonEvent(0xc1ff, []byte{0x01})
+
+	got, err := ip.CanonGetEvent(c)
+	if err != nil {
+		t.Fatalf("CanonGetEvent() error = %s; want <nil>", err)
+	}
+	if len(got) != 6 {
+		t.Fatalf("CanonGetEvent() got %d events; want 6", len(got))
+	}
+	if got[0].Property != ip.DPC_Canon_EOS_ISOSpeed || got[0].DataType != ptp.DTC_UINT16 ||
+		!reflect.DeepEqual(got[0].Values, [][]byte{{0x48, 0x00}, {0x58, 0x00}}) {
+		t.Errorf("CanonGetEvent() available list = %#v", got[0])
+	}
+	if got[1].Property != ip.DPC_Canon_EOS_ExpCompensation || got[1].DataType != ptp.DTC_UINT8 ||
+		!reflect.DeepEqual(got[1].Values, [][]byte{{0xf8}, {0x00}, {0x08}}) {
+		t.Errorf("CanonGetEvent() available list = %#v", got[1])
+	}
+	if got[2].Property != ip.DPC_Canon_EOS_Aperture || got[2].Values != nil {
+		t.Errorf("CanonGetEvent() available list without enumeration = %#v", got[2])
+	}
+	if got[3].Property != 0xd1ff || got[3].DataType != ptp.DTC_UNDEF || got[3].Values != nil {
+		t.Errorf("CanonGetEvent() available list of unknown property = %#v", got[3])
+	}
+	if got[4].Code != ip.EC_Canon_EOS_ObjectRemoved || got[4].ObjectHandle != 0x2a {
+		t.Errorf("CanonGetEvent() object removed = %#v", got[4])
+	}
+	if got[5].Code != 0xc1ff || !bytes.Equal(got[5].Data, []byte{0x01}) {
+		t.Errorf("CanonGetEvent() unknown event = %#v", got[5])
+	}
+}
+
+func TestCanonInitiateCapture(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+	res.SetCapturePreview([]byte{0xff, 0xd8, 0xff, 0xd9})
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.CanonGetEvent(c); err != nil {
+		t.Fatal(err)
+	}
+
+	pv, err := c.InitiateCapture()
+	if err != nil {
+		t.Fatalf("InitiateCapture() error = %s; want <nil>", err)
+	}
+	if pv != nil {
+		t.Errorf("InitiateCapture() preview = %#v; want <nil>", pv)
+	}
+
+	on := res.RequestsFor(ip.OC_Canon_EOS_RemoteReleaseOn)
+	off := res.RequestsFor(ip.OC_Canon_EOS_RemoteReleaseOff)
+	if len(on) != 1 || len(off) != 1 || on[0].Parameter(1) != ip.PM_Canon_EOS_ShutterFull {
+		t.Fatalf("InitiateCapture() did not fully press and release the shutter button")
+	}
+
+	got, err := ip.CanonGetEvent(c)
+	if err != nil {
+		t.Fatalf("CanonGetEvent() error = %s; want <nil>", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("CanonGetEvent() got %d events; want 1", len(got))
+	}
+	e := got[0]
+	if e.Code != ip.EC_Canon_EOS_ObjectAddedEx || e.ObjectHandle != ptp.ObjectHandle(on[0].TransactionID) ||
+		e.ObjectFormat != ptp.OFC_EXIF_JPEG || e.ObjectSize != 4 || e.Filename != "IMG_0001.JPG" {
+		t.Errorf("CanonGetEvent() object added = %#v", e)
+	}
+}
+
+func TestCanonBulbCapture(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	interval := ip.BulbProbeInterval
+	ip.BulbProbeInterval = 20 * time.Millisecond
+	defer func() { ip.BulbProbeInterval = interval }()
+
+	d := 100 * time.Millisecond
+	start := time.Now()
+	if _, err := c.BulbCapture(d); err != nil {
+		t.Fatalf("BulbCapture() error = %s; want <nil>", err)
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Errorf("BulbCapture() returned after %s; want at least %s", elapsed, d)
+	}
+
+	bs := res.RequestsFor(ip.OC_Canon_EOS_BulbStart)
+	be := res.RequestsFor(ip.OC_Canon_EOS_BulbEnd)
+	if len(bs) != 1 || len(be) != 1 || be[0].TransactionID < bs[0].TransactionID {
+		t.Fatalf("BulbCapture() bulb start/end requests = %d/%d; want 1/1 in that order", len(bs), len(be))
+	}
+	if res.Received(ptp.OC_InitiateOpenCapture) {
+		t.Error("BulbCapture() sent ptp.OC_InitiateOpenCapture; want OC_Canon_EOS_BulbStart only")
+	}
+	if len(res.RequestsFor(ptp.OC_GetDeviceInfo)) == 0 {
+		t.Error("BulbCapture() sent no probes")
+	}
+}
+
+func TestCanonPollEvents(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	done := make(chan struct{})
+	ch := ip.CanonPollEvents(c, 10*time.Millisecond, done)
+
+	got := make(map[ptp.DevicePropCode]bool)
+	for len(got) < 4 {
+		select {
+		case e := <-ch:
+			got[e.Property] = true
+		case <-time.After(time.Second):
+			t.Fatalf("CanonPollEvents() got %d properties; want 4", len(got))
+		}
+	}
+
+	close(done)
+	for range ch {
+	}
+}
+
+func TestCanonGetViewFinderData(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x01, 0x02, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.CanonGetViewFinderData(c); err != ip.ErrCanonNotReady {
+		t.Errorf("CanonGetViewFinderData() error = %v; want %s", err, ip.ErrCanonNotReady)
+	}
+
+	if err := ip.CanonSetDeviceProperty(c, ip.DPC_Canon_EOS_EVFOutputDevice, uint32(ip.EVF_Canon_PC)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ip.CanonGetViewFinderData(c)
+	if err != nil {
+		t.Fatalf("CanonGetViewFinderData() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, img) {
+		t.Errorf("CanonGetViewFinderData() got = %#v; want %#v", got, img)
+	}
+}
+
+func TestCanonToggleLiveView(t *testing.T) {
+	res := iptest.NewResponder("canon")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x03, 0x04, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+
+	for i := uint32(0); i < 2; i++ {
+		select {
+		case f := <-c.StreamChan:
+			if !bytes.Equal(f.Data, img) {
+				t.Errorf("ToggleLiveView() frame = %#v; want %#v", f.Data, img)
+			}
+			if f.Counter != i {
+				t.Errorf("ToggleLiveView() counter = %d; want %d", f.Counter, i)
+			}
+			f.Release()
+		case <-time.After(time.Second):
+			t.Fatal("ToggleLiveView() no frame received")
+		}
+	}
+
+	if err := c.ToggleLiveView(false); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+	v, _ := res.DevicePropValue(ip.DPC_Canon_EOS_EVFOutputDevice)
+	if got := ip.CanonEVFOutputDevice(binary.LittleEndian.Uint32(v)); got != ip.EVF_Canon_None {
+		t.Errorf("ToggleLiveView() output device = %#x; want %#x", got, ip.EVF_Canon_None)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/malc0mn/ptp-ip/ip/internal"
 	"github.com/malc0mn/ptp-ip/ptp"
 	"io"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -162,7 +164,7 @@ const (
 	EC_Fuji_PreviewAvailable ptp.EventCode = 0xC001
 	// EC_Fuji_ObjectAdded is the first event sent during the ptp.OC_InitiateCapture operation informing the initiator
 	// of a new object having been added to the device. Sadly none of the parameters hold the object handle allowing
-	// the initiator to retrieve the full object.
+	// the initiator to retrieve the full object. Use FujiCapture() to have the handles resolved.
 	EC_Fuji_ObjectAdded ptp.EventCode = 0xC004
 
 	// FR_Fuji_DeviceBusy is returned in the following cases:
@@ -181,8 +183,10 @@ const (
 	// EC_Fuji_PreviewAvailable event to empty the preview buffer, thereby triggering the camera to sent the
 	// ptp.EC_CaptureComplete event after which a new capture can be executed.
 	OC_Fuji_GetCapturePreview ptp.OperationCode = 0x9022
-	OC_Fuji_SetFocusPoint     ptp.OperationCode = 0x9026
-	OC_Fuji_ResetFocusPoint   ptp.OperationCode = 0x9027
+	// OC_Fuji_SetFocusPoint moves the focus point. The parameter is the encoded FujiFocusPoint.
+	OC_Fuji_SetFocusPoint ptp.OperationCode = 0x9026
+	// OC_Fuji_ResetFocusPoint moves the focus point back to the centre of the focus point grid.
+	OC_Fuji_ResetFocusPoint ptp.OperationCode = 0x9027
 
 	// OC_Fuji_GetDeviceInfo returns a list of DevicePropDesc structs so it is not at all the same as OC_GetDeviceInfo.
 	OC_Fuji_GetDeviceInfo ptp.OperationCode = 0x902B
@@ -206,14 +210,18 @@ const (
 	//   - 0x00000005 hits the sweet spot and the init sequence we use completes nicely.
 	// Could it maybe be that this is not so much 'init sequence' as "operation mode'? There is a mode for image
 	// transfers over Wi-Fi as well, but this was not investigated deeper just yet...
+	// This is the value used by the X-T1; other models can use a different one through their FujiProfile.
 	PM_Fuji_InitSequence = 0x00000005
 	// PM_Fuji_AppVersion defines the minimal supported app version by the Responder.
 	// When this parameter is 'too low', the camera will also complain about the application version being 'the previous
 	// version' and requests to 'upgrade the app'. However, it does NOT affect the initialisation sequence at all.
 	// The value here is that of the X-T1 on firmware version v5.51. We're not using it through this fixed value
 	// anymore, but we now get it from the camera and confirm it by setting it to what the camera reports in the hope
-	// that this will be future proof and we do not need to to adjust it ever again.
+	// that this will be future proof and we do not need to to adjust it ever again. A FujiProfile can require a higher
+	// version for models that do not report the version they expect.
 	PM_Fuji_AppVersion = 0x00020001
+	// PM_Fuji_AllStorages is the parameter to ptp.OC_GetObjectHandles selecting the objects on all storages.
+	PM_Fuji_AllStorages = 0xFFFFFFFF
 
 	// PV_Fuji is the Fuji Protocol Version required to construct a valid InitCommandRequestPacket.
 	PV_Fuji ProtocolVersion = 0x8F53E4F2
@@ -227,6 +235,9 @@ const (
 	RC_Fuji_GetDeviceInfo = ptp.OperationResponseCode(OC_Fuji_GetDeviceInfo)
 	// RC_Fuji_GetCapturePreview is the response code to OC_Fuji_GetCapturePreview
 	RC_Fuji_GetCapturePreview = ptp.OperationResponseCode(OC_Fuji_GetCapturePreview)
+
+	// fujiStreamHeaderSize is the size of the header that precedes the image data in a streamer connection packet.
+	fujiStreamHeaderSize = 18
 )
 
 // FujiInitCommandRequestPacket is the Fuji version of the PTP/IP InitCommandRequestPacket which deviates from the
@@ -431,46 +442,50 @@ func NewFujiEventPacket() EventPacket {
 // FujiExtractTransactionId extracts the transaction ID from a full raw inbound packet. This packet must include the
 // full header containing length and packet type.
 func FujiExtractTransactionId(p []byte, ct connectionType) (ptp.TransactionID, error) {
-	errFmt := "packet too small: got length %d"
-
-	var data []byte
+	var offset int
 	switch ct {
 	case cmdDataConnection:
-		if len(p) < 8 {
-			return 0, fmt.Errorf(errFmt, len(p))
-		}
-
-		data = p[8:12]
+		offset = 8
 	case eventConnection:
-		if len(p) < 12 {
-			return 0, fmt.Errorf(errFmt, len(p))
-		}
+		offset = 12
+	default:
+		return 0, fmt.Errorf("no transaction ID on %s connection", ct)
+	}
 
-		data = p[12:16]
+	if len(p) < offset+4 {
+		return 0, internal.ShortPacketError(len(p), offset+4)
 	}
 
-	return ptp.TransactionID(binary.LittleEndian.Uint32(data)), nil
+	return ptp.TransactionID(binary.LittleEndian.Uint32(p[offset : offset+4])), nil
 }
 
+// FujiPairingPromptDelay is the time waited for the Responder to acknowledge the init sequence before it is considered
+// to be prompting the user to accept the connection.
+var FujiPairingPromptDelay = 2 * time.Second
+
 // FujiInitCommandDataConn initialises the Fuji command/data connection. It expects an open TCP connection to the
 // command/data port to be present.
 // The PTP/IP protocol specifies how to set up the command/data connection which should immediately be followed by
 // setting up the event connection. However Fuji wants additional communications before it is satisfied that the
 // command/data connection is properly setup. This additional initialisation is performed here.
+// The values used depend on the model: the FujiProfile is selected from the Responder friendly name, see
+// FujiProfileForName().
 // The sequence is as follows:
 //   1. Open a session.
-//   2. Set device property DPC_Fuji_InitSequence to the correct number of the init sequence being used by the
-//      Initiator.
+//   2. Set device property DPC_Fuji_InitSequence to the init sequence of the profile.
 //   3. If the client name differs from the one stored, the Responder will now prompt the user to acknowledge the client
-//      connection, displaying the client name that was communicated using the InitCommandRequestPacket.
+//      connection, displaying the client name that was communicated using the InitCommandRequestPacket. Use
+//      Client.SetIdentityStore() to reuse the identity the Responder was paired with and avoid the prompt.
 //   4. We will wait for 30 seconds for an acknowledgement from the Responder which means the user has pressed the 'OK'
-//      button on the camera.
+//      button on the camera. When the acknowledgement takes longer than FujiPairingPromptDelay, PairingPending is
+//      reported to the pairing handler set using Client.SetPairingHandler().
 //   5. Next we will request the value of device property DPC_Fuji_AppVersion which holds the current minimal
 //      application version supported by the Responder and we will simply acknowledge it by setting it to the same
-//      value.
+//      value, unless the profile requires a higher version.
 //      This way we will always support any future versions as required by the firmware; unless of course a newer init
 //      sequence should be required.
-//   6. Finally, we send the operation request OC_InitiateOpenCapture which makes the Responder hand over control to the
+//   6. When the profile has an Init hook, it is called now.
+//   7. Finally, we send the operation request OC_InitiateOpenCapture which makes the Responder hand over control to the
 //      Initiator. This also opens up the event connection port 55741 used by Fuji so we can connect to it and complete
 //      the init sequence there.
 func FujiInitCommandDataConn(c *Client) error {
@@ -479,28 +494,39 @@ func FujiInitCommandDataConn(c *Client) error {
 		return err
 	}
 
-	c.Info("Opening a session...")
+	p := fujiSelectProfile(c)
+
+	c.infow(SubsystemVendor, "opening a session")
 	if err := FujiSendOperationRequestIgnoreResponse(c, ptp.OC_OpenSession, 0x00000001, 0); err != nil {
 		return err
 	}
 
-	c.Info("Setting correct init sequence number...")
-	c.Infof("Should you be prompted, please accept the new connection request on the %s.", c.ResponderFriendlyName())
-	if err := FujiSetDeviceProperty(c, DPC_Fuji_InitSequence, PM_Fuji_InitSequence); err != nil {
+	c.infow(SubsystemVendor, "setting correct init sequence number", "sequence", p.InitSequence)
+	if err := fujiSetInitSequence(c, p.InitSequence); err != nil {
 		return err
 	}
 
-	c.Info("Getting current minimum application version...")
+	c.infow(SubsystemVendor, "getting current minimum application version")
 	val, err := FujiGetDevicePropertyValue(c, DPC_Fuji_AppVersion)
 	if err != nil {
 		return err
 	}
-	c.Infof("Acknowledging current minimal application version as communicated by the %s: %#x", c.ResponderFriendlyName(), val)
+	if p.AppVersion > val {
+		val = p.AppVersion
+	}
+	c.infow(SubsystemVendor, "acknowledging current minimal application version", "responder", c.ResponderFriendlyName(), "version", fmt.Sprintf("%#x", val))
 	if err := FujiSetDeviceProperty(c, DPC_Fuji_AppVersion, val); err != nil {
 		return err
 	}
 
-	c.Info("Initiating open capture...")
+	if p.Init != nil {
+		c.infow(SubsystemVendor, "running profile init", "profile", p.Name)
+		if err := p.Init(c); err != nil {
+			return err
+		}
+	}
+
+	c.infow(SubsystemVendor, "initiating open capture")
 	if err := FujiSendOperationRequestIgnoreResponse(c, ptp.OC_InitiateOpenCapture, PM_Fuji_NoParam, 0); err != nil {
 		return err
 	}
@@ -508,33 +534,104 @@ func FujiInitCommandDataConn(c *Client) error {
 	return nil
 }
 
-// FujiProcessStreamData reads raw image data from the incoming stream and sends them to the streamer channel.
+// fujiSetInitSequence sets DPC_Fuji_InitSequence to the given sequence and reports the pairing state when the Responder
+// does not respond within FujiPairingPromptDelay, which means it is prompting the user to accept the connection.
+func fujiSetInitSequence(c *Client, seq uint32) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- FujiSetDeviceProperty(c, DPC_Fuji_InitSequence, seq)
+	}()
+
+	t := time.NewTimer(FujiPairingPromptDelay)
+	defer t.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+	}
+
+	c.infow(SubsystemVendor, "please accept the new connection request on the responder", "responder", c.ResponderFriendlyName(), "initiator", c.InitiatorFriendlyName())
+	c.pairing(PairingPending)
+	if err := <-done; err != nil {
+		c.pairing(PairingFailed)
+		return err
+	}
+	c.pairing(PairingAccepted)
+
+	return nil
+}
+
+// FujiProcessStreamData reads raw image data from the incoming stream and queues them as frames on the streamer channel.
+// The image data is not copied: each frame's data points straight into its pooled read buffer. The decoded header is
+// available as the frame's Meta. The frame counter is used to detect missed frames, see Client.StreamStats(), and frames
+// arriving out of order are discarded. The StreamChan is closed when the streamer connection is lost.
 func FujiProcessStreamData(c *Client) error {
+	ch, done := c.streamChannels()
 	go func() {
-		c.Info("[fujiStreamListener] subscribing stream listener to streamer connection...")
+		c.infow(SubsystemStream, "subscribing stream listener to streamer connection")
+		defer func() {
+			close(ch)
+			c.streamMu.Lock()
+			if c.StreamChan == ch {
+				c.StreamChan = nil
+			}
+			c.streamMu.Unlock()
+		}()
+
+		var (
+			last uint8
+			seen bool
+		)
 		for {
 			select {
-			case <-c.closeStreamChan:
-				c.Info("[fujiStreamListener] stopping stream listener.")
-				close(c.StreamChan)
-				c.StreamChan = nil
+			case <-done:
+				c.infow(SubsystemStream, "stopping stream listener")
 				return
 			default:
-				if data, err := c.ReadRawFromStreamConn(); err == nil {
-					// As always, packet length first.
-					l := binary.LittleEndian.Uint32(data[:4])
-					c.Debugf("[fujiStreamListener] Packet length %d", l)
-
-					// Four bytes always zero followed by what is clearly a counter which resets on 0xff, so one byte
-					// only.
-					count := binary.LittleEndian.Uint16(append(data[8:9], 0))
-					c.Debugf("[fujiStreamListener] Image number %d", count)
-
-					// Unknown what the next 9 bytes are, but they always END in two bytes with unknown significance
-					// (seen 0xff, 0xff as well as 0x5e, 0x49 and 0x4b, 0xbf) after which the image data begins, filling
-					// the rest of the packet.
-					c.StreamChan <- data[18:]
+				f := newFrame()
+				if err := c.readRawFromStreamConnTo(&f.buf); err != nil {
+					f.Release()
+					if strings.Contains(err.Error(), "i/o timeout") {
+						continue
+					}
+					c.errorw(SubsystemStream, "stream listener stopped", FieldChannel, streamConnection, "error", err)
+					return
 				}
+				f.Received = time.Now()
+
+				sf, err := FujiParseStreamFrame(f.buf.Bytes())
+				if err != nil {
+					c.errorw(SubsystemStream, "dropping stream packet", FieldChannel, streamConnection, FieldBytes, f.buf.Len(), "error", err)
+					f.Release()
+					continue
+				}
+				c.debugw(SubsystemStream, "received frame", FieldChannel, streamConnection, FieldBytes, sf.Length, "frame", sf.Counter)
+
+				if seen && sf.Counter == last {
+					// The counter only tells frames apart, it does not tell whether the image is repeated, so the
+					// frame is delivered like any other.
+					c.warnw(SubsystemStream, "duplicate frame counter", "frame", sf.Counter)
+					c.streamStats.frameDuplicate()
+				} else if seen {
+					gap := fujiFrameGap(last, sf.Counter)
+					if gap < 0 {
+						c.warnw(SubsystemStream, "discarding out of order frame", "frame", sf.Counter, "last", last)
+						c.streamStats.frameOutOfOrder()
+						f.Release()
+						continue
+					}
+					if gap > 0 {
+						c.warnw(SubsystemStream, "missed frames", "count", gap, "frame", sf.Counter, "last", last)
+						c.streamStats.framesMissed(gap)
+					}
+				}
+				last, seen = sf.Counter, true
+
+				f.Data = sf.Image
+				f.Counter = uint32(sf.Counter)
+				f.Meta = sf
+				c.queueFrame(ch, f)
 			}
 		}
 	}()
@@ -542,6 +639,57 @@ func FujiProcessStreamData(c *Client) error {
 	return nil
 }
 
+// FujiStreamFrame is a single packet received on the streamer connection, decoded into the known header fields and the
+// JPEG image data. The layout of the 18 byte header is as follows:
+//   - 4 bytes holding the packet length
+//   - 4 bytes that are always zero
+//   - 1 byte holding the frame counter
+//   - 7 bytes of unknown significance, always zero so far
+//   - 2 trailing bytes of unknown significance, seen 0xff, 0xff as well as 0x5e, 0x49 and 0x4b, 0xbf
+type FujiStreamFrame struct {
+	// Length is the length of the packet including the header.
+	Length uint32
+	// Counter is the frame counter which wraps around after 0xff.
+	Counter uint8
+	// Trailer holds the last two bytes of the header.
+	Trailer [2]byte
+	// Image holds the JPEG image data filling the rest of the packet.
+	Image []byte
+}
+
+// FujiParseStreamFrame decodes a raw packet received on the streamer connection. The Image of the returned frame refers
+// to the given data, it is not copied.
+func FujiParseStreamFrame(data []byte) (FujiStreamFrame, error) {
+	if len(data) < fujiStreamHeaderSize {
+		return FujiStreamFrame{}, internal.ShortPacketError(len(data), fujiStreamHeaderSize)
+	}
+
+	sf := FujiStreamFrame{
+		// As always, packet length first.
+		Length: binary.LittleEndian.Uint32(data[:4]),
+		// Four bytes always zero followed by what is clearly a counter which resets on 0xff, so one byte only.
+		Counter: data[8],
+		Image:   data[fujiStreamHeaderSize:],
+	}
+	copy(sf.Trailer[:], data[fujiStreamHeaderSize-2:fujiStreamHeaderSize])
+
+	return sf, nil
+}
+
+// fujiFrameGap compares the counter of a frame to the counter of the frame received before it and returns the number
+// of frames that went missing in between. A negative value is returned when the frame is not more recent than the
+// previous one, which includes a frame carrying the same counter: the caller must check for those first. The counter
+// only uses a single byte, so a difference of more than half its range is considered to be a late frame rather than a
+// gap.
+func fujiFrameGap(last, cur uint8) int {
+	d := cur - last
+	if d == 0 || d > 0x7f {
+		return -1
+	}
+
+	return int(d) - 1
+}
+
 // FujiSetDeviceProperty sets a device property to the given value.
 func FujiSetDeviceProperty(c *Client, code ptp.DevicePropCode, val uint32) error {
 	tid := c.incrementTransactionId()
@@ -737,13 +885,70 @@ func FujiSendOperationRequestAndGetRawResponse(c *Client, code ptp.OperationCode
 	return raw, err
 }
 
+// FujiOperationRequestDataIn is not supported: the Fuji operation requests only hold a single parameter.
+func FujiOperationRequestDataIn(_ *Client, _ ptp.OperationCode, _ []uint32) ([]byte, error) {
+	return nil, errors.New("command not supported")
+}
+
+// FujiOperationRequestDataOut is not supported: the Fuji operation requests only hold a single parameter.
+func FujiOperationRequestDataOut(_ *Client, _ ptp.OperationCode, _ []uint32, _ []byte) error {
+	return errors.New("command not supported")
+}
+
+// FujiTransact performs the given operation request using the Fuji packets. The data sent by the Responder in the data
+// in phase, if any, is returned together with the operation response. The Fuji operation requests only hold a single
+// parameter and a data out phase holding arbitrary data is not supported.
+func FujiTransact(c *Client, req ptp.OperationRequest, dataOut []byte) (*ptp.OperationResponse, []byte, error) {
+	if dataOut != nil || req.Parameter2 != 0 || req.Parameter3 != 0 || req.Parameter4 != 0 || req.Parameter5 != 0 {
+		return nil, nil, errors.New("command not supported")
+	}
+
+	resCh := make(chan []byte, 2)
+	if err := c.subscribe(req.TransactionID, resCh); err != nil {
+		return nil, nil, err
+	}
+	defer c.unsubscribe(req.TransactionID)
+
+	if err := c.SendPacketToCmdDataConn(&FujiOperationRequestPacket{
+		DataPhaseInfo: uint16(DP_NoDataOrDataIn),
+		OperationCode: req.OperationCode,
+		TransactionID: req.TransactionID,
+		Parameter1:    req.Parameter1,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	for {
+		p, err := c.WaitForRawPacketFromCommandDataSubscriber(resCh)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Length, data phase, operation response code and transaction ID.
+		if len(p) < 12 {
+			return nil, nil, internal.ShortPacketError(len(p), 12)
+		}
+		// The data is sent in a packet announcing a data out phase, followed by the actual response.
+		if binary.LittleEndian.Uint16(p[4:6]) == uint16(DP_DataOut) {
+			data = append(data, p[12:]...)
+			continue
+		}
+
+		return &ptp.OperationResponse{
+			ResponseCode:  ptp.OperationResponseCode(binary.LittleEndian.Uint16(p[6:8])),
+			TransactionID: req.TransactionID,
+		}, data, nil
+	}
+}
+
 // FujiGetDevicePropDesc retrieves the description for the given device property code. Beware that this method can
 // return no error and at the same time return nil for *ptp.DevicePropDesc! This means that the requested device
 // property cannot be described: the camera gave a response but returned no property data.
 // With the Fuji implementation one cannot be sure if the property does not exist or cannot be described as there is no
 // clear error being returned.
 func FujiGetDevicePropertyDesc(c *Client, code ptp.DevicePropCode) (*ptp.DevicePropDesc, error) {
-	c.Infof("Requesting %s device property description for %#x...", c.ResponderFriendlyName(), code)
+	c.infow(SubsystemVendor, "requesting device property description", "responder", c.ResponderFriendlyName(), "property", code)
 	_, xs, err := FujiSendOperationRequestAndGetResponse(c, ptp.OC_GetDevicePropDesc, uint32(code), 0)
 	if err != nil {
 		return nil, err
@@ -765,16 +970,17 @@ func FujiGetDevicePropertyDesc(c *Client, code ptp.DevicePropCode) (*ptp.DeviceP
 // in the PTP/IP specification, but it is more of a GetDevicePropDescList call that simply does not exist in the PTP/IP
 // specification.
 func FujiGetDeviceInfo(c *Client) (interface{}, error) {
-	c.Infof("Requesting %s device info...", c.ResponderFriendlyName())
+	c.infow(SubsystemVendor, "requesting device info", "responder", c.ResponderFriendlyName())
 	numProps, xs, err := FujiSendOperationRequestAndGetResponse(c, OC_Fuji_GetDeviceInfo, PM_Fuji_NoParam, 4)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Debugf("Number of properties returned: %d", numProps)
+	c.debugw(SubsystemVendor, "number of properties returned", "properties", numProps)
 
 	r := bytes.NewReader(xs)
-	list := make([]*ptp.DevicePropDesc, numProps)
+	// Do not allocate the list up front since the number of properties comes straight from the network.
+	var list []*ptp.DevicePropDesc
 
 	for i := 0; i < int(numProps); i++ {
 		var l uint32
@@ -782,97 +988,31 @@ func FujiGetDeviceInfo(c *Client) (interface{}, error) {
 			return nil, err
 		}
 
-		c.Debugf("Property length: %d", l)
+		c.debugw(SubsystemVendor, "property length", FieldBytes, l)
 
 		dpd, err := fujiReadDevicePropDesc(c, r)
 		if err != nil {
 			return nil, err
 		}
 
-		list[i] = dpd
+		list = append(list, dpd)
 	}
 
 	return list, nil
 }
 
 func fujiReadDevicePropDesc(c *Client, r io.Reader) (*ptp.DevicePropDesc, error) {
-	dpd := new(ptp.DevicePropDesc)
-	if err := binary.Read(r, binary.LittleEndian, &dpd.DevicePropertyCode); err != nil {
-		return nil, err
-	}
-	if err := binary.Read(r, binary.LittleEndian, &dpd.DataType); err != nil {
-		return nil, err
-	}
-	if err := binary.Read(r, binary.LittleEndian, &dpd.GetSet); err != nil {
-		return nil, err
-	}
-
-	c.Debugf("Size of property values in bytes: %d", dpd.SizeOfValueInBytes())
-
-	// We now know the DataTypeCode so we know what to expect next.
-	dpd.FactoryDefaultValue = make([]byte, dpd.SizeOfValueInBytes())
-	if err := binary.Read(r, binary.LittleEndian, dpd.FactoryDefaultValue); err != nil {
-		return nil, err
-	}
-
-	dpd.CurrentValue = make([]byte, dpd.SizeOfValueInBytes())
-	if err := binary.Read(r, binary.LittleEndian, dpd.CurrentValue); err != nil {
-		return nil, err
-	}
-
-	// Read the type of form that will follow.
-	if err := binary.Read(r, binary.LittleEndian, &dpd.FormFlag); err != nil {
+	dpd, err := ptp.ReadDevicePropDesc(r)
+	if err != nil {
 		return nil, err
 	}
 
+	c.debugw(SubsystemVendor, "property data type", "property", dpd.DevicePropertyCode, "type", dpd.DataType)
 	switch dpd.FormFlag {
 	case ptp.DPF_FormFlag_Range:
-		form := new(ptp.RangeForm)
-		c.Debug("Property is a range type, filling range form...")
-
-		form.SetDevicePropDesc(dpd)
-
-		// Minimum possible value.
-		form.MinimumValue = make([]byte, dpd.SizeOfValueInBytes())
-		if err := binary.Read(r, binary.LittleEndian, form.MinimumValue); err != nil {
-			return nil, err
-		}
-
-		// Maximum possible value.
-		form.MaximumValue = make([]byte, dpd.SizeOfValueInBytes())
-		if err := binary.Read(r, binary.LittleEndian, form.MaximumValue); err != nil {
-			return nil, err
-		}
-
-		// Stepper value.
-		form.StepSize = make([]byte, dpd.SizeOfValueInBytes())
-		if err := binary.Read(r, binary.LittleEndian, form.StepSize); err != nil {
-			return nil, err
-		}
-
-		dpd.Form = form
+		c.debugw(SubsystemVendor, "property is a range type, filled range form")
 	case ptp.DPF_FormFlag_Enum:
-		form := new(ptp.EnumerationForm)
-		c.Debug("Property is an enum type, filling enum form...")
-
-		form.SetDevicePropDesc(dpd)
-
-		// First read the number of values that will follow.
-		var num uint16
-		if err := binary.Read(r, binary.LittleEndian, &num); err != nil {
-			return nil, err
-		}
-		form.NumberOfValues = int(num)
-
-		// Now fill the enumeration form with the actual values.
-		for i := 0; i < form.NumberOfValues; i++ {
-			v := make([]byte, dpd.SizeOfValueInBytes())
-			if err := binary.Read(r, binary.LittleEndian, v); err != nil {
-				return nil, err
-			}
-			form.SupportedValues = append(form.SupportedValues, v)
-		}
-		dpd.Form = form
+		c.debugw(SubsystemVendor, "property is an enum type, filled enum form")
 	}
 
 	return dpd, nil
@@ -882,13 +1022,13 @@ func fujiReadDevicePropDesc(c *Client, r io.Reader) (*ptp.DevicePropDesc, error)
 // the exposure program mode of the camera: it will change if the camera is in aperture priority, shutter priority,
 // manual or auto.
 func FujiGetDeviceState(c *Client) (interface{}, error) {
-	c.Infof("Requesting %s device state...", c.ResponderFriendlyName())
+	c.infow(SubsystemVendor, "requesting device state", "responder", c.ResponderFriendlyName())
 	numProps, xs, err := FujiSendOperationRequestAndGetResponse(c, ptp.OC_GetDevicePropValue, uint32(DPC_Fuji_CurrentState), 2)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Debugf("Number of properties returned: %d", numProps)
+	c.debugw(SubsystemVendor, "number of properties returned", "properties", numProps)
 
 	r := bytes.NewReader(xs)
 	list := make([]*ptp.DevicePropDesc, numProps)
@@ -898,14 +1038,14 @@ func FujiGetDeviceState(c *Client) (interface{}, error) {
 		if err := binary.Read(r, binary.LittleEndian, &dpd.DevicePropertyCode); err != nil {
 			return nil, err
 		}
-		c.Debugf("Property code: %#x", dpd.DevicePropertyCode)
+		c.debugw(SubsystemVendor, "property code", "property", dpd.DevicePropertyCode)
 
 		dpd.DataType = ptp.DTC_UINT32
 		dpd.CurrentValue = make([]byte, dpd.SizeOfValueInBytes())
 		if err := binary.Read(r, binary.LittleEndian, dpd.CurrentValue); err != nil {
 			return nil, err
 		}
-		c.Debugf("Property value: %#x", dpd.CurrentValue)
+		c.debugw(SubsystemVendor, "property value", "property", dpd.DevicePropertyCode, "value", fmt.Sprintf("%#x", dpd.CurrentValue))
 
 		list[i] = dpd
 	}
@@ -920,65 +1060,383 @@ func FujiGetDeviceState(c *Client) (interface{}, error) {
 // Failing to do this, will not allow the client to release the shutter again. The operation request will be accepted
 // but no further actions will be taken by the camera.
 func FujiInitiateCapture(c *Client) ([]byte, error) {
-	c.Infof("Releasing %s shutter...", c.ResponderFriendlyName())
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	c.markCaptureSent()
 	if err := FujiSendOperationRequestIgnoreResponse(c, ptp.OC_InitiateCapture, PM_Fuji_NoParam, 0); err != nil {
 		return nil, err
 	}
 
-	var pvSize int
+	_, img, err := fujiCaptureComplete(c, DefaultReadTimeout)
+
+	return img, err
+}
+
+// FujiBulbCapture holds the shutter open for the given duration using ptp.OC_InitiateOpenCapture and
+// ptp.OC_TerminateOpenCapture. The shutter speed must be set to bulb on the camera. The Responder is probed by
+// requesting DPC_Fuji_CurrentState every BulbProbeInterval to keep the session alive. Once the shutter is closed, the
+// capture preview is fetched in the same way as FujiInitiateCapture() does.
+func FujiBulbCapture(c *Client, d time.Duration) ([]byte, error) {
+	c.infow(SubsystemVendor, "opening shutter", "responder", c.ResponderFriendlyName(), "duration", d)
+	start := time.Now()
+	tid, err := fujiInitiateOpenCapture(c)
+	if err != nil {
+		return nil, err
+	}
+
+	c.holdOpenCapture(start, d, func(c *Client) error {
+		_, err := FujiGetDevicePropertyValue(c, DPC_Fuji_CurrentState)
+		return err
+	})
+
+	c.infow(SubsystemVendor, "closing shutter", "responder", c.ResponderFriendlyName())
+	if err := FujiSendOperationRequestIgnoreResponse(c, ptp.OC_TerminateOpenCapture, uint32(tid), 0); err != nil {
+		return nil, err
+	}
+
+	// Long exposure noise reduction can take as long as the exposure itself before the object is added.
+	_, img, err := fujiCaptureComplete(c, DefaultReadTimeout+d)
+
+	return img, err
+}
+
+// fujiInitiateOpenCapture sends ptp.OC_InitiateOpenCapture and returns its transaction ID, which must be passed to
+// ptp.OC_TerminateOpenCapture.
+func fujiInitiateOpenCapture(c *Client) (ptp.TransactionID, error) {
+	resCh := make(chan []byte, 2)
+	tid, err := FujiSendOperationRequestWithChan(c, ptp.OC_InitiateOpenCapture, PM_Fuji_NoParam, resCh)
+	if err != nil {
+		return 0, err
+	}
+	defer c.unsubscribe(tid)
+
+	p := new(FujiOperationResponsePacket)
+	if _, _, err := c.WaitForPacketFromCommandDataSubscriber(resCh, p); err != nil {
+		return 0, err
+	}
+	if !p.WasSuccessful(0) {
+		return 0, p.ReasonAsError()
+	}
+
+	return tid, nil
+}
+
+// fujiCaptureComplete waits for the events sent out after a capture and fetches the capture preview, which in turn
+// makes the Responder send out the ptp.EC_CaptureComplete event. The EC_Fuji_ObjectAdded event is returned together
+// with the preview. The timeout applies to waiting for the first event.
+func fujiCaptureComplete(c *Client, timeout time.Duration) (*FujiEventPacket, []byte, error) {
+	var (
+		added  *FujiEventPacket
+		pvSize int
+	)
 	invalidEvent := "invalid event received, expected '%#x' got '%#x'"
 	for _, ec := range []ptp.EventCode{EC_Fuji_ObjectAdded, EC_Fuji_PreviewAvailable} {
 		select {
 		case msg := <-c.eventChan:
 			if msg.GetEventCode() != ec {
-				return nil, fmt.Errorf(invalidEvent, ec, msg.GetEventCode())
+				return nil, nil, fmt.Errorf(invalidEvent, ec, msg.GetEventCode())
 			}
-			var txt string
-			var extra string
 			switch ec {
 			case EC_Fuji_ObjectAdded:
-				txt = "object added"
+				added = msg.(*FujiEventPacket)
+				c.debugw(SubsystemEvent, "received object added event", "code", msg.GetEventCode())
 			case EC_Fuji_PreviewAvailable:
-				txt = "preview available"
 				pvSize = int(msg.(*FujiEventPacket).Parameter2)
-				extra = fmt.Sprintf(": preview size is %d bytes", pvSize)
+				c.debugw(SubsystemEvent, "received preview available event", "code", msg.GetEventCode(), FieldBytes, pvSize)
 			}
-			c.Debugf("Received %s event (%#x)%s.", txt, msg.GetEventCode(), extra)
-		case <-time.After(DefaultReadTimeout):
-			return nil, WaitForEventError
+		case <-time.After(timeout):
+			return nil, nil, WaitForEventError
 		}
+		timeout = DefaultReadTimeout
 	}
 
 	raw, err := FujiSendOperationRequestAndGetRawResponse(c, OC_Fuji_GetCapturePreview, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	select {
 	case msg := <-c.eventChan:
 		if msg.GetEventCode() != ptp.EC_CaptureComplete {
-			return nil, fmt.Errorf("invalid event received, expected '%#x' got '%#x'", ptp.EC_CaptureComplete, msg.GetEventCode())
+			return nil, nil, fmt.Errorf("invalid event received, expected '%#x' got '%#x'", ptp.EC_CaptureComplete, msg.GetEventCode())
 		}
-		c.Debugf("Received capture complete event (%#x).", msg.GetEventCode())
+		c.debugw(SubsystemEvent, "received capture complete event", "code", msg.GetEventCode())
 	case <-time.After(DefaultReadTimeout):
-		return nil, WaitForEventError
+		return nil, nil, WaitForEventError
+	}
+
+	img, err := fujiRawResponseData(raw, OC_Fuji_GetCapturePreview)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(img) != pvSize {
+		c.warnw(SubsystemVendor, "preview size mismatch, returning possibly malformed data nonetheless", "expected", pvSize, FieldBytes, len(img))
 	}
 
-	var img []byte
+	return added, img, nil
+}
+
+// fujiRawResponseData concatenates the data held by the raw packets returned by
+// FujiSendOperationRequestAndGetRawResponse(). When sending data, the Responder uses the operation code as response
+// code, any other response code than ptp.RC_OK is returned as an error.
+func fujiRawResponseData(raw [][]byte, code ptp.OperationCode) ([]byte, error) {
+	var data []byte
 	for _, pkt := range raw {
-		code := binary.LittleEndian.Uint16(pkt[6:8])
+		// Length, data phase, response code and transaction ID.
+		if len(pkt) < 12 {
+			return nil, internal.ShortPacketError(len(pkt), 12)
+		}
+		rc := binary.LittleEndian.Uint16(pkt[6:8])
 		switch {
-		case code == uint16(ptp.RC_OK):
+		case rc == uint16(ptp.RC_OK):
 			break
-		case code != uint16(OC_Fuji_GetCapturePreview):
-			return nil, errors.New("failed reading image data")
+		case rc != uint16(code):
+			return nil, ptp.OperationResponseCodeAsError(ptp.OperationResponseCode(rc))
 		}
-		img = append(img, pkt[12:]...)
+		data = append(data, pkt[12:]...)
 	}
 
-	if len(img) != pvSize {
-		c.Warnf("Preview size mismatch: expected %d, got %d. Returning possibly malformed data nonetheless.", pvSize, len(img))
+	return data, nil
+}
+
+// FujiFocusPoint is the decoded value of DPC_Fuji_FocusMeteringMode. The value is packed in 4 bytes: the 2 most
+// significant bytes describe the focus point grid, followed by the X and Y coordinates of the focus point on that grid.
+// E.g. 0x03020602 is focus point 6x2 on grid 0x0302.
+type FujiFocusPoint struct {
+	// GridSize identifies the focus point grid of the camera. The exact meaning of the individual bytes is unknown so
+	// it is best to pass back the value reported by the camera unchanged.
+	GridSize uint16
+	X        uint8
+	Y        uint8
+}
+
+// NewFujiFocusPoint decodes a DPC_Fuji_FocusMeteringMode value.
+func NewFujiFocusPoint(v uint32) FujiFocusPoint {
+	return FujiFocusPoint{
+		GridSize: uint16(v >> 16),
+		X:        uint8(v >> 8),
+		Y:        uint8(v),
+	}
+}
+
+// Uint32 encodes the focus point as a DPC_Fuji_FocusMeteringMode value.
+func (fp FujiFocusPoint) Uint32() uint32 {
+	return uint32(fp.GridSize)<<16 | uint32(fp.X)<<8 | uint32(fp.Y)
+}
+
+// String returns the focus point coordinates as displayed by the camera, e.g. "6x2".
+func (fp FujiFocusPoint) String() string {
+	return fmt.Sprintf("%dx%d", fp.X, fp.Y)
+}
+
+// FujiGetFocusPoint returns the current focus point.
+func FujiGetFocusPoint(c *Client) (FujiFocusPoint, error) {
+	v, err := FujiGetDevicePropertyValue(c, DPC_Fuji_FocusMeteringMode)
+	if err != nil {
+		return FujiFocusPoint{}, err
+	}
+
+	return NewFujiFocusPoint(v), nil
+}
+
+// FujiSetFocusPoint moves the focus point to the given position using OC_Fuji_SetFocusPoint.
+func FujiSetFocusPoint(c *Client, fp FujiFocusPoint) error {
+	c.infow(SubsystemVendor, "setting focus point", "responder", c.ResponderFriendlyName(), "point", fp.String(), "value", fmt.Sprintf("%#x", fp.Uint32()))
+	return FujiSendOperationRequestIgnoreResponse(c, OC_Fuji_SetFocusPoint, fp.Uint32(), 0)
+}
+
+// FujiMoveFocusPoint moves the focus point to the given coordinates on the focus point grid currently in use by the
+// camera.
+func FujiMoveFocusPoint(c *Client, x, y uint8) error {
+	fp, err := FujiGetFocusPoint(c)
+	if err != nil {
+		return err
+	}
+	fp.X = x
+	fp.Y = y
+
+	return FujiSetFocusPoint(c, fp)
+}
+
+// FujiResetFocusPoint moves the focus point back to the centre of the focus point grid using
+// OC_Fuji_ResetFocusPoint.
+func FujiResetFocusPoint(c *Client) error {
+	c.infow(SubsystemVendor, "resetting focus point", "responder", c.ResponderFriendlyName())
+	return FujiSendOperationRequestIgnoreResponse(c, OC_Fuji_ResetFocusPoint, PM_Fuji_NoParam, 0)
+}
+
+// FujiGetMovieRemainingTime returns the length of the movie the storage can still hold based on the current movie
+// settings. Poll this to monitor a recording started on the camera.
+func FujiGetMovieRemainingTime(c *Client) (time.Duration, error) {
+	v, err := FujiGetDevicePropertyValue(c, DPC_Fuji_MovieRemainingTime)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(v) * time.Second, nil
+}
+
+// FujiObject is an object stored on the camera.
+type FujiObject struct {
+	Handle ptp.ObjectHandle
+	// Format is the object format as reported in the ObjectInfo dataset of the object.
+	Format ptp.ObjectFormatCode
+	// Data holds the contents of the object once it has been downloaded.
+	Data []byte
+}
+
+// Extension returns the file extension matching the contents of the object: ".JPG" for a JPEG image, ".RAF" for a
+// Fuji RAW file or an empty string when the contents are unknown. An object that has not been downloaded only gets an
+// extension when its format is ptp.OFC_EXIF_JPEG.
+func (o *FujiObject) Extension() string {
+	switch {
+	case bytes.HasPrefix(o.Data, []byte{0xFF, 0xD8}), o.Data == nil && o.Format == ptp.OFC_EXIF_JPEG:
+		return ".JPG"
+	case bytes.HasPrefix(o.Data, []byte("FUJIFILMCCD-RAW")):
+		return ".RAF"
+	default:
+		return ""
+	}
+}
+
+// FujiCaptureResult is the result of FujiCapture().
+type FujiCaptureResult struct {
+	// ObjectAdded is the EC_Fuji_ObjectAdded event sent out by the camera.
+	ObjectAdded *FujiEventPacket
+	// Preview holds the capture preview.
+	Preview []byte
+	// Objects holds the objects added to the storage by the capture: a single JPEG image or, when shooting RAW + JPEG,
+	// the JPEG image followed by the RAF file.
+	Objects []*FujiObject
+}
+
+// FujiCapture releases the shutter in the same way FujiInitiateCapture() does but also resolves the handles of the
+// objects that were added to the storage. Since the EC_Fuji_ObjectAdded event does not hold the object handle, the
+// newest handles on the storage are taken: two when the image quality is set to RAW + JPEG, one otherwise. The format
+// of each object is checked to tell the JPEG image from the RAW file, whatever the order of their handles. When
+// download is true, the full objects are fetched right after the capture has completed.
+func FujiCapture(c *Client, download bool) (*FujiCaptureResult, error) {
+	iq, err := FujiGetDevicePropertyValue(c, DPC_Fuji_ImageQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	c.markCaptureSent()
+	if err := FujiSendOperationRequestIgnoreResponse(c, ptp.OC_InitiateCapture, PM_Fuji_NoParam, 0); err != nil {
+		return nil, err
+	}
+
+	added, img, err := fujiCaptureComplete(c, DefaultReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	res := &FujiCaptureResult{
+		ObjectAdded: added,
+		Preview:     img,
+	}
+
+	n := 1
+	switch FujiImageQuality(iq) {
+	case IQ_Fuji_FineAndRAW, IQ_Fuji_NormalAndRAW:
+		n = 2
+	}
+	handles, err := FujiGetObjectHandles(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(handles) < n {
+		return nil, fmt.Errorf("expected at least %d objects on the storage, found %d", n, len(handles))
+	}
+	var jpg, raw *FujiObject
+	for _, h := range handles[len(handles)-n:] {
+		oi, err := FujiGetObjectInfo(c, h)
+		if err != nil {
+			return nil, err
+		}
+		o := &FujiObject{Handle: h, Format: oi.ObjectFormat}
+		switch {
+		case o.Format == ptp.OFC_EXIF_JPEG && jpg == nil:
+			jpg = o
+		case o.Format != ptp.OFC_EXIF_JPEG && raw == nil && n == 2:
+			raw = o
+		default:
+			return nil, fmt.Errorf("unexpected object %#x with format %#x", h, o.Format)
+		}
+	}
+	if jpg == nil {
+		return nil, errors.New("no JPEG image found for the capture")
+	}
+	res.Objects = append(res.Objects, jpg)
+	if raw != nil {
+		res.Objects = append(res.Objects, raw)
+	}
+
+	if download {
+		for _, o := range res.Objects {
+			if o.Data, err = FujiGetObject(c, o.Handle); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// FujiGetObjectInfo returns the ObjectInfo dataset of the object with the given handle.
+func FujiGetObjectInfo(c *Client, h ptp.ObjectHandle) (*ptp.ObjectInfo, error) {
+	c.infow(SubsystemVendor, "requesting object info", "responder", c.ResponderFriendlyName(), "object", h)
+	raw, err := FujiSendOperationRequestAndGetRawResponse(c, ptp.OC_GetObjectInfo, []uint32{uint32(h)})
+	if err != nil {
+		return nil, err
+	}
+	xs, err := fujiRawResponseData(raw, ptp.OC_GetObjectInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	oi := new(ptp.ObjectInfo)
+	if err := oi.UnmarshalPTP(xs); err != nil {
+		return nil, err
+	}
+
+	return oi, nil
+}
+
+// FujiGetObjectHandles returns the handles of all objects on all storages of the camera in ascending order.
+func FujiGetObjectHandles(c *Client) ([]ptp.ObjectHandle, error) {
+	c.infow(SubsystemVendor, "requesting object handles", "responder", c.ResponderFriendlyName())
+	raw, err := FujiSendOperationRequestAndGetRawResponse(c, ptp.OC_GetObjectHandles, []uint32{PM_Fuji_AllStorages})
+	if err != nil {
+		return nil, err
+	}
+	xs, err := fujiRawResponseData(raw, ptp.OC_GetObjectHandles)
+	if err != nil {
+		return nil, err
+	}
+
+	pv, err := ptp.UnmarshalPropertyValue(xs, ptp.DTC_AUINT32)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := pv.Value.([]uint32)
+	handles := make([]ptp.ObjectHandle, len(vals))
+	for i, h := range vals {
+		handles[i] = ptp.ObjectHandle(h)
+	}
+	sort.Slice(handles, func(i, j int) bool { return handles[i] < handles[j] })
+
+	return handles, nil
+}
+
+// FujiGetObject downloads the object with the given handle.
+func FujiGetObject(c *Client, h ptp.ObjectHandle) ([]byte, error) {
+	c.infow(SubsystemVendor, "requesting object", "responder", c.ResponderFriendlyName(), "object", h)
+	raw, err := FujiSendOperationRequestAndGetRawResponse(c, ptp.OC_GetObject, []uint32{uint32(h)})
+	if err != nil {
+		return nil, err
 	}
 
-	return img, nil
+	return fujiRawResponseData(raw, ptp.OC_GetObject)
 }
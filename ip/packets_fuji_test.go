@@ -1,50 +1,53 @@
-package ip
+package ip_test
 
 import (
 	"bytes"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
 	"github.com/malc0mn/ptp-ip/ptp"
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewFujiInitCommandRequestPacket(t *testing.T) {
 	uuid, _ := uuid.NewRandom()
-	got := NewFujiInitCommandRequestPacket(uuid, "têst")
+	got := ip.NewFujiInitCommandRequestPacket(uuid, "têst")
 	want := "têst"
 
 	if got.GetFriendlyName() != want {
 		t.Errorf("NewFujiInitCommandRequestPacket() FriendlyName = %s; want %s", got.GetFriendlyName(), want)
 	}
-	if got.GetProtocolVersion() != PV_Fuji {
-		t.Errorf("NewFujiInitCommandRequestPacket() ProtocolVersion = %#x; want %#x", got.GetProtocolVersion(), PV_Fuji)
+	if got.GetProtocolVersion() != ip.PV_Fuji {
+		t.Errorf("NewFujiInitCommandRequestPacket() ProtocolVersion = %#x; want %#x", got.GetProtocolVersion(), ip.PV_Fuji)
 	}
 }
 
 func TestNewFujiInitCommandRequestPacketForClient(t *testing.T) {
-	c, err := NewClient("fuji", DefaultIpAddress, DefaultPort, "test", "", logLevel)
+	c, err := ip.NewClient("fuji", ip.DefaultIpAddress, ip.DefaultPort, "test", "", ip.LogLevelUnderTest())
 	if err != nil {
 		t.Errorf("NewClient() err = %s; want <nil>", err)
 	}
 
-	got := NewFujiInitCommandRequestPacketForClient(c)
+	got := ip.NewFujiInitCommandRequestPacketForClient(c)
 	want := "test"
 
 	if got.GetFriendlyName() != want {
 		t.Errorf("NewFujiInitCommandRequestPacketForClient() FriendlyName = %s; want %s", got.GetFriendlyName(), want)
 	}
-	if got.GetProtocolVersion() != PV_Fuji {
-		t.Errorf("NewFujiInitCommandRequestPacketForClient() ProtocolVersion = %#x; want %#x", got.GetProtocolVersion(), PV_Fuji)
+	if got.GetProtocolVersion() != ip.PV_Fuji {
+		t.Errorf("NewFujiInitCommandRequestPacketForClient() ProtocolVersion = %#x; want %#x", got.GetProtocolVersion(), ip.PV_Fuji)
 	}
 }
 
 func TestNewFujiInitCommandRequestPacketWithVersion(t *testing.T) {
 	uuid, _ := uuid.NewRandom()
-	got := NewFujiInitCommandRequestPacketWithVersion(uuid, "versíon", 0x00020005)
+	got := ip.NewFujiInitCommandRequestPacketWithVersion(uuid, "versíon", 0x00020005)
 	wantName := "versíon"
-	wantVersion := ProtocolVersion(0x00020005)
+	wantVersion := ip.ProtocolVersion(0x00020005)
 
 	if got.GetFriendlyName() != wantName {
 		t.Errorf("NewFujiInitCommandRequestPacketWithVersion() FriendlyName = %s; want %s", got.GetFriendlyName(), wantName)
@@ -55,11 +58,11 @@ func TestNewFujiInitCommandRequestPacketWithVersion(t *testing.T) {
 }
 
 func TestFujiOperationRequestPacket_Payload(t *testing.T) {
-	oreq := &FujiOperationRequestPacket{
-		DataPhaseInfo: uint16(DP_NoDataOrDataIn),
+	oreq := &ip.FujiOperationRequestPacket{
+		DataPhaseInfo: uint16(ip.DP_NoDataOrDataIn),
 		OperationCode: ptp.OC_GetDevicePropValue,
 		TransactionID: 1,
-		Parameter1:    uint32(DPC_Fuji_FilmSimulation),
+		Parameter1:    uint32(ip.DPC_Fuji_FilmSimulation),
 	}
 
 	pl := oreq.Payload()
@@ -71,12 +74,15 @@ func TestFujiOperationRequestPacket_Payload(t *testing.T) {
 }
 
 func TestFujiInitCommandDataConn(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = c.initCommandDataConn()
+	err = c.InitCommandDataConn()
 	if err != nil {
 		t.Errorf("FujiInitCommandDataConn() error = %s; want <nil>", err)
 	}
@@ -89,7 +95,10 @@ func TestFujiInitCommandDataConn(t *testing.T) {
 }
 
 func TestFujiSetDeviceProperty(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -100,7 +109,7 @@ func TestFujiSetDeviceProperty(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = FujiSetDeviceProperty(c, DPC_Fuji_FilmSimulation, uint32(FS_Fuji_Astia))
+	err = ip.FujiSetDeviceProperty(c, ip.DPC_Fuji_FilmSimulation, uint32(ip.FS_Fuji_Astia))
 	if err != nil {
 		t.Errorf("FujiSetDeviceProperty() error = %s; want <nil>", err)
 	}
@@ -113,12 +122,15 @@ func TestFujiSetDeviceProperty(t *testing.T) {
 }
 
 func TestFujiSetDevicePropertyFail(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = FujiSetDeviceProperty(c, DPC_Fuji_FilmSimulation, uint32(FS_Fuji_Astia))
+	err = ip.FujiSetDeviceProperty(c, ip.DPC_Fuji_FilmSimulation, uint32(ip.FS_Fuji_Astia))
 	want := "not connected"
 	if err.Error() != want {
 		t.Errorf("FujiSetDeviceProperty() error = %s; want %s", err, want)
@@ -126,7 +138,10 @@ func TestFujiSetDevicePropertyFail(t *testing.T) {
 }
 
 func TestFujiGetDevicePropertyValue(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -137,19 +152,22 @@ func TestFujiGetDevicePropertyValue(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := FujiGetDevicePropertyValue(c, DPC_Fuji_AppVersion)
+	got, err := ip.FujiGetDevicePropertyValue(c, ip.DPC_Fuji_AppVersion)
 	if err != nil {
 		t.Errorf("FujiGetDevicePropertyValue() error = %s; want <nil>", err)
 	}
 
-	want := uint32(PM_Fuji_AppVersion)
+	want := uint32(ip.PM_Fuji_AppVersion)
 	if got != want {
 		t.Errorf("FujiGetDevicePropertyValue() got = %#x; want %#x", got, want)
 	}
 }
 
 func TestFujiSendOperationRequest(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -160,8 +178,8 @@ func TestFujiSendOperationRequest(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// We use close session here because our fuji mock will not respond to it.
-	resCh, err := FujiSendOperationRequest(c, ptp.OC_CloseSession, PM_Fuji_NoParam)
+	// We use close session here because the fuji responder will not respond to it.
+	resCh, err := ip.FujiSendOperationRequest(c, ptp.OC_CloseSession, ip.PM_Fuji_NoParam)
 	defer close(resCh)
 	if err != nil {
 		t.Errorf("FujiSendOperationRequest() error = %s; want <nil>", err)
@@ -169,7 +187,10 @@ func TestFujiSendOperationRequest(t *testing.T) {
 }
 
 func TestFujiSendOperationRequestAndGetResponse(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -180,7 +201,7 @@ func TestFujiSendOperationRequestAndGetResponse(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	gotPar, xs, err := FujiSendOperationRequestAndGetResponse(c, ptp.OC_GetDevicePropValue, uint32(DPC_Fuji_AppVersion), 4)
+	gotPar, xs, err := ip.FujiSendOperationRequestAndGetResponse(c, ptp.OC_GetDevicePropValue, uint32(ip.DPC_Fuji_AppVersion), 4)
 	if len(xs) > 0 {
 		t.Errorf("FujiSendOperationRequestAndGetResponse() excess bytes = %d; want <nil>", len(xs))
 	}
@@ -188,14 +209,17 @@ func TestFujiSendOperationRequestAndGetResponse(t *testing.T) {
 		t.Errorf("FujiSendOperationRequestAndGetResponse() error = %s; want <nil>", err)
 	}
 
-	wantPar := uint32(PM_Fuji_AppVersion)
+	wantPar := uint32(ip.PM_Fuji_AppVersion)
 	if gotPar != wantPar {
 		t.Errorf("FujiSendOperationRequestAndGetResponse() got = %#x; want %#x", gotPar, wantPar)
 	}
 }
 
 func TestFujiSendOperationRequestAndGetRawResponse(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -206,7 +230,7 @@ func TestFujiSendOperationRequestAndGetRawResponse(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := FujiSendOperationRequestAndGetRawResponse(c, ptp.OC_GetDevicePropDesc, []uint32{uint32(DPC_Fuji_FilmSimulation)})
+	got, err := ip.FujiSendOperationRequestAndGetRawResponse(c, ptp.OC_GetDevicePropDesc, []uint32{uint32(ip.DPC_Fuji_FilmSimulation)})
 	if err != nil {
 		t.Errorf("FujiSendOperationRequestAndGetRawResponse() error = %s; want <nil>", err)
 	}
@@ -226,7 +250,10 @@ func TestFujiSendOperationRequestAndGetRawResponse(t *testing.T) {
 }
 
 func TestFujiGetDevicePropertyDesc(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -237,7 +264,7 @@ func TestFujiGetDevicePropertyDesc(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := FujiGetDevicePropertyDesc(c, ptp.DPC_WhiteBalance)
+	got, err := ip.FujiGetDevicePropertyDesc(c, ptp.DPC_WhiteBalance)
 	if err != nil {
 		t.Errorf("FujiGetDevicePropertyDesc() error = %s; want <nil>", err)
 	}
@@ -263,13 +290,13 @@ func TestFujiGetDevicePropertyDesc(t *testing.T) {
 		t.Errorf("FujiGetDevicePropertyDesc() got = %#v; want %#v", got, want)
 	}
 
-	got, err = FujiGetDevicePropertyDesc(c, DPC_Fuji_FocusMeteringMode)
+	got, err = ip.FujiGetDevicePropertyDesc(c, ip.DPC_Fuji_FocusMeteringMode)
 	if err != nil {
 		t.Errorf("FujiGetDevicePropertyDesc() error = %s; want <nil>", err)
 	}
 
 	want = &ptp.DevicePropDesc{
-		DevicePropertyCode:  DPC_Fuji_FocusMeteringMode,
+		DevicePropertyCode:  ip.DPC_Fuji_FocusMeteringMode,
 		DataType:            ptp.DTC_UINT32,
 		GetSet:              ptp.DPD_GetSet,
 		FactoryDefaultValue: []uint8{0x0, 0x0, 0x0, 0x0},
@@ -289,7 +316,10 @@ func TestFujiGetDevicePropertyDesc(t *testing.T) {
 }
 
 func TestFujiGetDeviceInfo(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -300,7 +330,7 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := FujiGetDeviceInfo(c)
+	got, err := ip.FujiGetDeviceInfo(c)
 	if err != nil {
 		t.Errorf("FujiGetDeviceInfo() error = %s; want <nil>", err)
 	}
@@ -366,7 +396,7 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 			},
 		},
 		{
-			DevicePropertyCode:  DPC_Fuji_FilmSimulation,
+			DevicePropertyCode:  ip.DPC_Fuji_FilmSimulation,
 			DataType:            ptp.DTC_UINT16,
 			GetSet:              ptp.DPD_GetSet,
 			FactoryDefaultValue: []uint8{0x1, 0x0},
@@ -381,7 +411,7 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 			},
 		},
 		{
-			DevicePropertyCode:  DPC_Fuji_ExposureIndex,
+			DevicePropertyCode:  ip.DPC_Fuji_ExposureIndex,
 			DataType:            ptp.DTC_UINT32,
 			GetSet:              ptp.DPD_GetSet,
 			FactoryDefaultValue: []uint8{0xff, 0xff, 0xff, 0xff},
@@ -401,7 +431,7 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 			},
 		},
 		{
-			DevicePropertyCode:  DPC_Fuji_RecMode,
+			DevicePropertyCode:  ip.DPC_Fuji_RecMode,
 			DataType:            ptp.DTC_UINT16,
 			GetSet:              ptp.DPD_GetSet,
 			FactoryDefaultValue: []uint8{0x1, 0x0},
@@ -415,7 +445,7 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 			},
 		},
 		{
-			DevicePropertyCode:  DPC_Fuji_FocusMeteringMode,
+			DevicePropertyCode:  ip.DPC_Fuji_FocusMeteringMode,
 			DataType:            ptp.DTC_UINT32,
 			GetSet:              ptp.DPD_GetSet,
 			FactoryDefaultValue: []uint8{0x0, 0x0, 0x0, 0x0},
@@ -442,7 +472,10 @@ func TestFujiGetDeviceInfo(t *testing.T) {
 }
 
 func TestFujiGetDeviceState(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -453,29 +486,29 @@ func TestFujiGetDeviceState(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := FujiGetDeviceState(c)
+	got, err := ip.FujiGetDeviceState(c)
 	if err != nil {
 		t.Errorf("FujiGetDeviceState() error = %s; want <nil>", err)
 	}
 
 	want := []*ptp.DevicePropDesc{
 		{DevicePropertyCode: ptp.DPC_BatteryLevel, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_ImageAspectRatio, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x0a, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_ImageAspectRatio, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x0a, 0x00, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_WhiteBalance, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x00, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_FocusMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x01, 0x80, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_FlashMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x0a, 0x80, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_ExposureProgramMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x00, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_ExposureBiasCompensation, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0xb3, 0xfe, 0x00, 0x00}},
 		{DevicePropertyCode: ptp.DPC_CaptureDelay, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_FilmSimulation, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_ImageQuality, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x04, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_CommandDialMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_ExposureIndex, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x19, 0x00, 0x80}},
-		{DevicePropertyCode: DPC_Fuji_FocusMeteringMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x07, 0x02, 0x03}},
-		{DevicePropertyCode: DPC_Fuji_FocusLock, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_DeviceError, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_CapturesRemaining, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0xd6, 0x05, 0x00, 0x00}},
-		{DevicePropertyCode: DPC_Fuji_MovieRemainingTime, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x8f, 0x06, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_FilmSimulation, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_ImageQuality, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x04, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_CommandDialMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_ExposureIndex, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x19, 0x00, 0x80}},
+		{DevicePropertyCode: ip.DPC_Fuji_FocusMeteringMode, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x02, 0x07, 0x02, 0x03}},
+		{DevicePropertyCode: ip.DPC_Fuji_FocusLock, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_DeviceError, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x00, 0x00, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_CapturesRemaining, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0xd6, 0x05, 0x00, 0x00}},
+		{DevicePropertyCode: ip.DPC_Fuji_MovieRemainingTime, DataType: ptp.DTC_UINT32, CurrentValue: []uint8{0x8f, 0x06, 0x00, 0x00}},
 	}
 
 	for i, g := range got.([]*ptp.DevicePropDesc) {
@@ -487,9 +520,10 @@ func TestFujiGetDeviceState(t *testing.T) {
 }
 
 func TestFujiInitiateCapture(t *testing.T) {
-	c, err := NewClient("fuji", address, fujiCmdPort, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", logLevel)
-	c.SetEventPort(fujiEvtPort)
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
 
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
 	defer c.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -504,7 +538,9 @@ func TestFujiInitiateCapture(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	got, err := FujiInitiateCapture(c)
+	res.SetCapturePreview(want)
+
+	got, err := ip.FujiInitiateCapture(c)
 	if err != nil {
 		t.Errorf("FujiInitiateCapture() error = %s; want <nil>", err)
 	}
@@ -513,3 +549,433 @@ func TestFujiInitiateCapture(t *testing.T) {
 		t.Errorf("FujiInitiateCapture() imgdata = %#v; want %#v", got, want)
 	}
 }
+
+func TestFujiCapture(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pv, err := ioutil.ReadFile("testdata/preview.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.SetCapturePreview(pv)
+	jpg := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}
+	raf := []byte("FUJIFILMCCD-RAW 0201FF129502")
+	// The RAW file deliberately gets the lower handle: the objects must be told apart by their format.
+	res.SetObject(0x0006, jpg)
+	res.SetObject(0x0001, []byte{0x00})
+	res.SetObject(0x0005, raf)
+	res.SetDevicePropValue(ip.DPC_Fuji_ImageQuality, []byte{byte(ip.IQ_Fuji_FineAndRAW), 0x00})
+
+	got, err := ip.FujiCapture(c, true)
+	if err != nil {
+		t.Fatalf("FujiCapture() error = %s; want <nil>", err)
+	}
+	if bytes.Compare(got.Preview, pv) != 0 {
+		t.Errorf("FujiCapture() preview = %#v; want %#v", got.Preview, pv)
+	}
+	if got.ObjectAdded == nil || got.ObjectAdded.EventCode != ip.EC_Fuji_ObjectAdded {
+		t.Errorf("FujiCapture() object added = %v; want event %#x", got.ObjectAdded, ip.EC_Fuji_ObjectAdded)
+	}
+	want := []struct {
+		h   ptp.ObjectHandle
+		ext string
+		b   []byte
+	}{
+		{0x0006, ".JPG", jpg},
+		{0x0005, ".RAF", raf},
+	}
+	if len(got.Objects) != len(want) {
+		t.Fatalf("FujiCapture() objects = %d; want %d", len(got.Objects), len(want))
+	}
+	for i, w := range want {
+		o := got.Objects[i]
+		if o.Handle != w.h {
+			t.Errorf("FujiCapture() object %d handle = %#x; want %#x", i, o.Handle, w.h)
+		}
+		if o.Extension() != w.ext {
+			t.Errorf("FujiCapture() object %d extension = %s; want %s", i, o.Extension(), w.ext)
+		}
+		if bytes.Compare(o.Data, w.b) != 0 {
+			t.Errorf("FujiCapture() object %d data = %#v; want %#v", i, o.Data, w.b)
+		}
+	}
+
+	res.SetDevicePropValue(ip.DPC_Fuji_ImageQuality, []byte{byte(ip.IQ_Fuji_Fine), 0x00})
+	got, err = ip.FujiCapture(c, false)
+	if err != nil {
+		t.Fatalf("FujiCapture() error = %s; want <nil>", err)
+	}
+	if len(got.Objects) != 1 || got.Objects[0].Handle != 0x0006 || got.Objects[0].Data != nil {
+		t.Errorf("FujiCapture() objects = %v; want handle 0x6 without data", got.Objects)
+	}
+	if ext := got.Objects[0].Extension(); ext != ".JPG" {
+		t.Errorf("FujiCapture() object extension = %s; want .JPG", ext)
+	}
+
+	res.SetObject(0x0007, raf)
+	_, err = ip.FujiCapture(c, false)
+	if err == nil || err.Error() != "unexpected object 0x7 with format 0x3000" {
+		t.Errorf("FujiCapture() error = %v; want unexpected object 0x7 with format 0x3000", err)
+	}
+
+	_, err = ip.FujiGetObject(c, 0x0002)
+	if err == nil || err.Error() != "invalid object handle" {
+		t.Errorf("FujiGetObject() error = %v; want invalid object handle", err)
+	}
+}
+
+func TestFujiBulbCapture(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ioutil.ReadFile("testdata/preview.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.SetCapturePreview(want)
+
+	interval := ip.BulbProbeInterval
+	ip.BulbProbeInterval = 20 * time.Millisecond
+	defer func() { ip.BulbProbeInterval = interval }()
+
+	d := 200 * time.Millisecond
+	start := time.Now()
+	got, err := c.BulbCapture(d)
+	if err != nil {
+		t.Fatalf("BulbCapture() error = %s; want <nil>", err)
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Errorf("BulbCapture() returned after %s; want at least %s", elapsed, d)
+	}
+	if bytes.Compare(got, want) != 0 {
+		t.Errorf("BulbCapture() imgdata = %#v; want %#v", got, want)
+	}
+
+	// The first open capture is sent by Dial().
+	open := res.RequestsFor(ptp.OC_InitiateOpenCapture)
+	if len(open) != 2 {
+		t.Fatalf("BulbCapture() open captures = %d; want 2", len(open))
+	}
+	term := res.RequestsFor(ptp.OC_TerminateOpenCapture)
+	if len(term) != 1 {
+		t.Fatalf("BulbCapture() terminate requests = %d; want 1", len(term))
+	}
+	if p := ptp.TransactionID(term[0].Parameter(1)); p != open[1].TransactionID {
+		t.Errorf("BulbCapture() terminated transaction = %d; want %d", p, open[1].TransactionID)
+	}
+	if len(res.RequestsFor(ptp.OC_GetDevicePropValue)) == 0 {
+		t.Errorf("BulbCapture() sent no probes")
+	}
+
+	_, err = c.BulbCapture(0)
+	if err == nil || err.Error() != "invalid bulb duration 0s" {
+		t.Errorf("BulbCapture() error = %v; want invalid bulb duration 0s", err)
+	}
+}
+
+func TestFujiGetMovieRemainingTime(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	got, err := ip.FujiGetMovieRemainingTime(c)
+	if err != nil {
+		t.Errorf("FujiGetMovieRemainingTime() error = %s; want <nil>", err)
+	}
+	want := 27*time.Minute + 59*time.Second
+	if got != want {
+		t.Errorf("FujiGetMovieRemainingTime() got = %s; want %s", got, want)
+	}
+}
+
+func TestFujiPairing(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+	res.PromptPairing(200 * time.Millisecond)
+
+	delay := ip.FujiPairingPromptDelay
+	ip.FujiPairingPromptDelay = 50 * time.Millisecond
+	defer func() { ip.FujiPairingPromptDelay = delay }()
+
+	s, cleanup := tempIdentityStore(t)
+	defer cleanup()
+
+	dial := func() []ip.PairingState {
+		c, err := res.NewClient("testèr", "", ip.LogLevelUnderTest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		var got []ip.PairingState
+		c.SetIdentityStore(s)
+		c.SetPairingHandler(func(ps ip.PairingState) {
+			got = append(got, ps)
+		})
+		if err := c.Dial(); err != nil {
+			t.Fatal(err)
+		}
+
+		return got
+	}
+
+	want := []ip.PairingState{ip.PairingPending, ip.PairingAccepted}
+	if got := dial(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dial() pairing states = %v; want %v", got, want)
+	}
+
+	// The stored identity is reused so the user must not be prompted again.
+	if got := dial(); got != nil {
+		t.Errorf("Dial() pairing states = %v; want none", got)
+	}
+}
+
+func TestFujiProcessStreamData(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatal(err)
+	}
+	defer c.ToggleLiveView(false)
+
+	img, err := ioutil.ReadFile("testdata/preview.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream header minus the length field which the responder will prepend.
+	header := []byte{0x00, 0x00, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x5e, 0x49}
+	// A truncated packet must be dropped without bringing down the stream listener.
+	if err := res.SendStreamData(header[:4]); err != nil {
+		t.Fatal(err)
+	}
+	if err := res.SendStreamData(append(header, img...)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case f := <-c.StreamChan:
+		if f.Counter != 0x2a {
+			t.Errorf("FujiProcessStreamData() Counter = %#x; want 0x2a", f.Counter)
+		}
+		if !bytes.Equal(f.Data, img) {
+			t.Errorf("FujiProcessStreamData() Data length = %d; want %d", len(f.Data), len(img))
+		}
+		if f.Received.IsZero() {
+			t.Error("FujiProcessStreamData() Received = zero time; want receive time")
+		}
+		if f.Dropped != 0 {
+			t.Errorf("FujiProcessStreamData() Dropped = %d; want 0", f.Dropped)
+		}
+		if sf, ok := f.Meta.(ip.FujiStreamFrame); !ok || sf.Trailer != [2]byte{0x5e, 0x49} {
+			t.Errorf("FujiProcessStreamData() Meta = %#v; want FujiStreamFrame with trailer 0x5e, 0x49", f.Meta)
+		}
+		f.Release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("FujiProcessStreamData() got no frame; want frame")
+	}
+
+	// Two frames go missing before 0x2d arrives, 0x2c arrives late and must be discarded. 0x2e arrives twice and both
+	// frames must be delivered.
+	for _, count := range []byte{0x2d, 0x2c, 0x2e, 0x2e} {
+		header[4] = count
+		if err := res.SendStreamData(append(header, img...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []uint32{0x2d, 0x2e, 0x2e} {
+		select {
+		case f := <-c.StreamChan:
+			if f.Counter != want {
+				t.Errorf("FujiProcessStreamData() Counter = %#x; want %#x", f.Counter, want)
+			}
+			f.Release()
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FujiProcessStreamData() got no frame; want frame %#x", want)
+		}
+	}
+
+	s := c.StreamStats()
+	if s.Frames != 4 || s.Missed != 2 || s.OutOfOrder != 1 || s.Duplicates != 1 || s.Dropped != 0 {
+		t.Errorf("StreamStats() frames = %d, missed = %d, out of order = %d, duplicates = %d, dropped = %d; want 4, 2, 1, 1, 0", s.Frames, s.Missed, s.OutOfOrder, s.Duplicates, s.Dropped)
+	}
+	if s.AverageSize != len(img) {
+		t.Errorf("StreamStats() AverageSize = %d; want %d", s.AverageSize, len(img))
+	}
+}
+
+func TestFujiProcessStreamData_connectionLost(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatal(err)
+	}
+	ch := c.StreamChan
+	// Deliver a frame first to be sure the streamer connection has been accepted before dropping it.
+	header := []byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff}
+	if err := res.SendStreamData(append(header, 0xff, 0xd8)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case f := <-ch:
+		f.Release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("FujiProcessStreamData() got no frame; want frame")
+	}
+	res.Disconnect()
+
+	select {
+	case f, ok := <-ch:
+		if ok {
+			f.Release()
+			t.Error("FujiProcessStreamData() got frame; want closed StreamChan")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("FujiProcessStreamData() StreamChan still open; want closed after losing the connection")
+	}
+}
+
+func TestFujiParseStreamFrame(t *testing.T) {
+	img := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}
+	// Hand-built packets following the header layout documented for FujiStreamFrame, no recorded frames are available.
+	// The trailers are the values noted in that documentation.
+	check := []struct {
+		header []byte
+		want   ip.FujiStreamFrame
+	}{
+		{
+			header: []byte{0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff},
+			want:   ip.FujiStreamFrame{Length: 0x18, Counter: 0x2a, Trailer: [2]byte{0xff, 0xff}, Image: img},
+		},
+		{
+			header: []byte{0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x5e, 0x49},
+			want:   ip.FujiStreamFrame{Length: 0x18, Counter: 0xff, Trailer: [2]byte{0x5e, 0x49}, Image: img},
+		},
+		{
+			header: []byte{0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4b, 0xbf},
+			want:   ip.FujiStreamFrame{Length: 0x18, Counter: 0x00, Trailer: [2]byte{0x4b, 0xbf}, Image: img},
+		},
+	}
+
+	for _, tt := range check {
+		got, err := ip.FujiParseStreamFrame(append(tt.header, img...))
+		if err != nil {
+			t.Errorf("FujiParseStreamFrame() error = %s; want <nil>", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FujiParseStreamFrame() got = %#v; want %#v", got, tt.want)
+		}
+	}
+
+	if _, err := ip.FujiParseStreamFrame(img); err == nil {
+		t.Error("FujiParseStreamFrame() error = <nil>; want short packet error")
+	}
+}
+
+func TestFujiFocusPoint(t *testing.T) {
+	check := map[uint32]ip.FujiFocusPoint{
+		0x03020602: {GridSize: 0x0302, X: 6, Y: 2},
+		0x10090707: {GridSize: 0x1009, X: 7, Y: 7},
+		0x00000000: {},
+	}
+
+	for v, want := range check {
+		got := ip.NewFujiFocusPoint(v)
+		if got != want {
+			t.Errorf("NewFujiFocusPoint() got = %#v; want %#v", got, want)
+		}
+		if enc := got.Uint32(); enc != v {
+			t.Errorf("Uint32() got = %#x; want %#x", enc, v)
+		}
+	}
+
+	if got := ip.NewFujiFocusPoint(0x03020602).String(); got != "6x2" {
+		t.Errorf("String() got = %s; want 6x2", got)
+	}
+}
+
+func TestFujiMoveFocusPoint(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ip.FujiMoveFocusPoint(c, 6, 2)
+	if err != nil {
+		t.Errorf("FujiMoveFocusPoint() error = %s; want <nil>", err)
+	}
+
+	reqs := res.RequestsFor(ip.OC_Fuji_SetFocusPoint)
+	if len(reqs) != 1 {
+		t.Fatalf("FujiMoveFocusPoint() requests = %d; want 1", len(reqs))
+	}
+	want := uint32(0x03020602)
+	if got := reqs[0].Parameter(1); got != want {
+		t.Errorf("FujiMoveFocusPoint() parameter = %#x; want %#x", got, want)
+	}
+
+	err = ip.FujiResetFocusPoint(c)
+	if err != nil {
+		t.Errorf("FujiResetFocusPoint() error = %s; want <nil>", err)
+	}
+
+	got, err := ip.FujiGetFocusPoint(c)
+	if err != nil {
+		t.Errorf("FujiGetFocusPoint() error = %s; want <nil>", err)
+	}
+	wantFp := ip.FujiFocusPoint{GridSize: 0x0302, X: 4, Y: 4}
+	if got != wantFp {
+		t.Errorf("FujiGetFocusPoint() got = %#v; want %#v", got, wantFp)
+	}
+}
@@ -0,0 +1,396 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"time"
+)
+
+type NikonAutofocusMode uint8
+type NikonLiveViewStatus uint8
+type NikonRecordingMedia uint8
+
+const (
+	OC_Nikon_GetProfileAllData         ptp.OperationCode = 0x9006
+	OC_Nikon_InitiateCaptureRecInSdram ptp.OperationCode = 0x90C0
+	OC_Nikon_AfDrive                   ptp.OperationCode = 0x90C1
+	OC_Nikon_ChangeCameraMode          ptp.OperationCode = 0x90C2
+	OC_Nikon_DelImageSdram             ptp.OperationCode = 0x90C3
+	OC_Nikon_GetLargeThumb             ptp.OperationCode = 0x90C4
+	OC_Nikon_CheckEvent                ptp.OperationCode = 0x90C7
+	OC_Nikon_DeviceReady               ptp.OperationCode = 0x90C8
+	OC_Nikon_SetPreWbData              ptp.OperationCode = 0x90C9
+	OC_Nikon_GetVendorPropCodes        ptp.OperationCode = 0x90CA
+	OC_Nikon_AfAndCaptureRecInSdram    ptp.OperationCode = 0x90CB
+	OC_Nikon_GetPicCtrlData            ptp.OperationCode = 0x90CC
+	OC_Nikon_SetPicCtrlData            ptp.OperationCode = 0x90CD
+	OC_Nikon_StartLiveView             ptp.OperationCode = 0x9201
+	OC_Nikon_EndLiveView               ptp.OperationCode = 0x9202
+	OC_Nikon_GetLiveViewImg            ptp.OperationCode = 0x9203
+	OC_Nikon_MfDrive                   ptp.OperationCode = 0x9204
+	OC_Nikon_ChangeAfArea              ptp.OperationCode = 0x9205
+	OC_Nikon_AfDriveCancel             ptp.OperationCode = 0x9206
+	OC_Nikon_InitiateCaptureRecInMedia ptp.OperationCode = 0x9207
+
+	RC_Nikon_HardwareError          ptp.OperationResponseCode = 0xA001
+	RC_Nikon_OutOfFocus             ptp.OperationResponseCode = 0xA002
+	RC_Nikon_ChangeCameraModeFailed ptp.OperationResponseCode = 0xA003
+	RC_Nikon_InvalidStatus          ptp.OperationResponseCode = 0xA004
+	RC_Nikon_SetPropertyNotSupport  ptp.OperationResponseCode = 0xA005
+	RC_Nikon_WbResetError           ptp.OperationResponseCode = 0xA006
+	RC_Nikon_DustReferenceError     ptp.OperationResponseCode = 0xA007
+	RC_Nikon_ShutterSpeedBulb       ptp.OperationResponseCode = 0xA008
+	RC_Nikon_MirrorUpSequence       ptp.OperationResponseCode = 0xA009
+	RC_Nikon_CameraModeNotAdjustF   ptp.OperationResponseCode = 0xA00A
+	// RC_Nikon_NotLiveView is returned by OC_Nikon_GetLiveViewImg and OC_Nikon_EndLiveView when live view is not
+	// active.
+	RC_Nikon_NotLiveView      ptp.OperationResponseCode = 0xA00B
+	RC_Nikon_MfDriveStepEnd   ptp.OperationResponseCode = 0xA00C
+	RC_Nikon_MfDriveStepShort ptp.OperationResponseCode = 0xA00E
+	RC_Nikon_AdvancedTransfer ptp.OperationResponseCode = 0xA022
+
+	EC_Nikon_ObjectAddedInSdram        ptp.EventCode = 0xC101
+	EC_Nikon_CaptureCompleteRecInSdram ptp.EventCode = 0xC102
+	EC_Nikon_AdvancedTransfer          ptp.EventCode = 0xC103
+	EC_Nikon_PreviewImageAdded         ptp.EventCode = 0xC104
+
+	DPC_Nikon_ShootingBank          ptp.DevicePropCode = 0xD010
+	DPC_Nikon_LensID                ptp.DevicePropCode = 0xD0E0
+	DPC_Nikon_FocalLengthMin        ptp.DevicePropCode = 0xD0E3
+	DPC_Nikon_FocalLengthMax        ptp.DevicePropCode = 0xD0E4
+	DPC_Nikon_MaxApAtMinFocalLength ptp.DevicePropCode = 0xD0E5
+	DPC_Nikon_MaxApAtMaxFocalLength ptp.DevicePropCode = 0xD0E6
+	// DPC_Nikon_ExposureTime is the Nikon equivalent of ptp.DPC_ExposureTime. The upper 16 bits of the value hold the
+	// numerator, the lower 16 bits the denominator.
+	DPC_Nikon_ExposureTime  ptp.DevicePropCode = 0xD100
+	DPC_Nikon_ACPower       ptp.DevicePropCode = 0xD101
+	DPC_Nikon_WarningStatus ptp.DevicePropCode = 0xD102
+	DPC_Nikon_MaximumShots  ptp.DevicePropCode = 0xD103
+	DPC_Nikon_AFLockStatus  ptp.DevicePropCode = 0xD104
+	DPC_Nikon_AELockStatus  ptp.DevicePropCode = 0xD105
+	DPC_Nikon_FVLockStatus  ptp.DevicePropCode = 0xD106
+	// DPC_Nikon_RecordingMedia selects where captures are stored. Set it to RM_Nikon_SDRAM to keep captures in the
+	// device's memory only.
+	DPC_Nikon_RecordingMedia            ptp.DevicePropCode = 0xD10B
+	DPC_Nikon_CameraOrientation         ptp.DevicePropCode = 0xD10E
+	DPC_Nikon_AutofocusMode             ptp.DevicePropCode = 0xD161
+	DPC_Nikon_LiveViewStatus            ptp.DevicePropCode = 0xD1A2
+	DPC_Nikon_LiveViewImageZoomRatio    ptp.DevicePropCode = 0xD1A3
+	DPC_Nikon_LiveViewProhibitCondition ptp.DevicePropCode = 0xD1A4
+	DPC_Nikon_ActivePicCtrlItem         ptp.DevicePropCode = 0xD200
+
+	AFM_Nikon_Single      NikonAutofocusMode = 0x00
+	AFM_Nikon_Continuous  NikonAutofocusMode = 0x01
+	AFM_Nikon_Automatic   NikonAutofocusMode = 0x02
+	AFM_Nikon_ManualFixed NikonAutofocusMode = 0x03
+	AFM_Nikon_Manual      NikonAutofocusMode = 0x04
+
+	LVS_Nikon_Off NikonLiveViewStatus = 0x00
+	LVS_Nikon_On  NikonLiveViewStatus = 0x01
+
+	RM_Nikon_Card  NikonRecordingMedia = 0x00
+	RM_Nikon_SDRAM NikonRecordingMedia = 0x01
+
+	// ET_Nikon_Bulb is the DPC_Nikon_ExposureTime value indicating bulb mode.
+	ET_Nikon_Bulb uint32 = 0xFFFFFFFF
+	// ET_Nikon_Time is the DPC_Nikon_ExposureTime value indicating time mode.
+	ET_Nikon_Time uint32 = 0xFFFFFFFD
+
+	// PM_Nikon_NoAF is the parameter to OC_Nikon_InitiateCaptureRecInSdram to release the shutter without focusing.
+	PM_Nikon_NoAF uint32 = 0xFFFFFFFF
+
+	// nikonEventSize is the size of a single event returned by OC_Nikon_CheckEvent: a 16 bit event code followed by a
+	// 32 bit parameter.
+	nikonEventSize = 6
+)
+
+// nikonLiveViewHeaderSizes lists the known sizes of the header preceding the JPEG image returned by
+// OC_Nikon_GetLiveViewImg. The size depends on the device model.
+var nikonLiveViewHeaderSizes = []int{0x80, 0x180, 0x200, 0x300}
+
+// nikonLiveViewAreasSize is the size of the part of the live view header that is common to all models: twelve big
+// endian 16 bit values holding the sizes of the JPEG image, the whole image, the display area and the AF area, and the
+// centers of the display and AF areas. The layout matches the one logged by libgphoto2 for Nikon live view frames.
+const nikonLiveViewAreasSize = 24
+
+var (
+	// ErrNikonDeviceBusy is returned when the device is still busy processing a previous operation.
+	ErrNikonDeviceBusy = errors.New("device busy")
+	// ErrNikonNotLiveView is returned by NikonGetLiveViewImg() when live view is not active.
+	ErrNikonNotLiveView = errors.New("live view not active")
+	// ErrNikonOutOfFocus is returned when the device failed to focus.
+	ErrNikonOutOfFocus = errors.New("out of focus")
+)
+
+// NikonDeviceReadyInterval is the time waited between two OC_Nikon_DeviceReady requests while the device is busy.
+var NikonDeviceReadyInterval = 20 * time.Millisecond
+
+// NikonDeviceReadyTimeout is the maximum time to wait for a busy device to become ready again.
+var NikonDeviceReadyTimeout = 10 * time.Second
+
+// NikonLiveViewInterval is the time waited between two OC_Nikon_GetLiveViewImg requests when live view is enabled on a
+// Nikon device.
+var NikonLiveViewInterval = 50 * time.Millisecond
+
+// NikonEvent is a single event returned by OC_Nikon_CheckEvent.
+type NikonEvent struct {
+	Code      ptp.EventCode
+	Parameter uint32
+}
+
+// NikonLiveViewArea is a rectangular area of a live view frame. The coordinates are relative to the whole image.
+type NikonLiveViewArea struct {
+	Width   uint16
+	Height  uint16
+	CenterX uint16
+	CenterY uint16
+}
+
+// NikonLiveViewImage is a single live view frame returned by OC_Nikon_GetLiveViewImg.
+type NikonLiveViewImage struct {
+	// Width and Height are the size of the JPEG image.
+	Width  uint16
+	Height uint16
+	// WholeWidth and WholeHeight are the size of the whole image the JPEG image is taken from.
+	WholeWidth  uint16
+	WholeHeight uint16
+	// DisplayArea is the part of the whole image shown in the JPEG image, which is smaller than the whole image when
+	// zoomed in.
+	DisplayArea NikonLiveViewArea
+	// AFArea is the position of the AF area.
+	AFArea NikonLiveViewArea
+	// Header holds the undecoded header. Beyond the areas decoded above, its contents depend on the model and are not
+	// interpreted.
+	Header []byte
+	// Image holds the JPEG image.
+	Image []byte
+}
+
+// NikonInitEventConn initialises the event connection following the PTP/IP standard and then opens a session, which
+// is required for all Nikon vendor operations.
+func NikonInitEventConn(c *Client) error {
+	if err := GenericInitEventConn(c); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "opening a session")
+	if _, err := GenericOperationRequestDataIn(c, ptp.OC_OpenSession, []uint32{0x00000001}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NikonDeviceReady checks if the Responder is ready to accept a new operation. The returned response code will be
+// ptp.RC_DeviceBusy when the Responder is still processing the previous operation.
+func NikonDeviceReady(c *Client) (ptp.OperationResponseCode, error) {
+	_, rc, err := genericOperationRequestDataIn(c, OC_Nikon_DeviceReady, nil)
+
+	return rc, err
+}
+
+// nikonWaitDeviceReady calls NikonDeviceReady() every NikonDeviceReadyInterval until the Responder is no longer busy
+// or NikonDeviceReadyTimeout has passed.
+func nikonWaitDeviceReady(c *Client) error {
+	timeout := time.Now().Add(NikonDeviceReadyTimeout)
+	for {
+		rc, err := NikonDeviceReady(c)
+		if err != nil {
+			return err
+		}
+
+		switch rc {
+		case ptp.RC_OK:
+			return nil
+		case ptp.RC_DeviceBusy:
+			if time.Now().After(timeout) {
+				return ErrNikonDeviceBusy
+			}
+			time.Sleep(NikonDeviceReadyInterval)
+		default:
+			return nikonResponseCodeAsError(rc)
+		}
+	}
+}
+
+// nikonResponseCodeAsError converts the Nikon response codes that need to be handled by the caller to an error. Any
+// other code is handled by ptp.OperationResponseCodeAsError().
+func nikonResponseCodeAsError(rc ptp.OperationResponseCode) error {
+	switch rc {
+	case ptp.RC_DeviceBusy:
+		return ErrNikonDeviceBusy
+	case RC_Nikon_NotLiveView:
+		return ErrNikonNotLiveView
+	case RC_Nikon_OutOfFocus:
+		return ErrNikonOutOfFocus
+	default:
+		return ptp.OperationResponseCodeAsError(rc)
+	}
+}
+
+// nikonOperationRequest sends an operation request without a data phase and waits for the Responder to become ready
+// again.
+func nikonOperationRequest(c *Client, code ptp.OperationCode, params []uint32) error {
+	_, rc, err := genericOperationRequestDataIn(c, code, params)
+	if err != nil {
+		return err
+	}
+	if rc != ptp.RC_OK {
+		return nikonResponseCodeAsError(rc)
+	}
+
+	return nikonWaitDeviceReady(c)
+}
+
+// NikonCheckEvent polls the Responder for the events that occurred since the previous poll. An empty list is returned
+// when nothing happened.
+func NikonCheckEvent(c *Client) ([]NikonEvent, error) {
+	c.debugw(SubsystemVendor, "checking for events", "responder", c.ResponderFriendlyName())
+	xs, err := GenericOperationRequestDataIn(c, OC_Nikon_CheckEvent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return nikonParseEvents(xs)
+}
+
+// nikonParseEvents decodes the data returned by OC_Nikon_CheckEvent: the number of events followed by the events.
+func nikonParseEvents(b []byte) ([]NikonEvent, error) {
+	if len(b) < 2 {
+		return nil, nil
+	}
+
+	n := int(binary.LittleEndian.Uint16(b[0:2]))
+	b = b[2:]
+	if n*nikonEventSize > len(b) {
+		return nil, fmt.Errorf("%w: %d events with %d bytes left", InvalidPacketError, n, len(b))
+	}
+
+	list := make([]NikonEvent, n)
+	for i := range list {
+		e := b[i*nikonEventSize : (i+1)*nikonEventSize]
+		list[i] = NikonEvent{
+			Code:      ptp.EventCode(binary.LittleEndian.Uint16(e[0:2])),
+			Parameter: binary.LittleEndian.Uint32(e[2:6]),
+		}
+	}
+
+	return list, nil
+}
+
+// NikonAfDrive makes the Responder focus and waits for it to finish. When the Responder failed to focus, the error
+// returned will be ErrNikonOutOfFocus.
+func NikonAfDrive(c *Client) error {
+	c.infow(SubsystemVendor, "focusing", "responder", c.ResponderFriendlyName())
+	return nikonOperationRequest(c, OC_Nikon_AfDrive, nil)
+}
+
+// NikonInitiateCapture focuses using NikonAfDrive() and then releases the shutter using
+// OC_Nikon_InitiateCaptureRecInSdram. Nikon does not return a capture preview: the object that was added is reported
+// by NikonCheckEvent() as an EC_Nikon_ObjectAddedInSdram or ptp.EC_ObjectAdded event depending on
+// DPC_Nikon_RecordingMedia.
+func NikonInitiateCapture(c *Client) ([]byte, error) {
+	if err := NikonAfDrive(c); err != nil {
+		return nil, err
+	}
+
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	c.markCaptureSent()
+	if err := nikonOperationRequest(c, OC_Nikon_InitiateCaptureRecInSdram, []uint32{PM_Nikon_NoAF}); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// NikonGetLiveViewImg fetches a single live view frame. Live view must be started first using
+// OC_Nikon_StartLiveView. When live view is not active, the error returned will be ErrNikonNotLiveView. When the
+// Responder has no frame available yet, the error returned will be ErrNikonDeviceBusy.
+func NikonGetLiveViewImg(c *Client) (*NikonLiveViewImage, error) {
+	xs, rc, err := genericOperationRequestDataIn(c, OC_Nikon_GetLiveViewImg, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rc != ptp.RC_OK {
+		return nil, nikonResponseCodeAsError(rc)
+	}
+
+	return nikonParseLiveViewImg(xs)
+}
+
+// nikonParseLiveViewImg splits the data returned by OC_Nikon_GetLiveViewImg into the header and the JPEG image. The
+// known header sizes are tried first, after which the data is searched for the JPEG start of image marker.
+func nikonParseLiveViewImg(b []byte) (*NikonLiveViewImage, error) {
+	soi := []byte{0xFF, 0xD8}
+
+	i := -1
+	for _, s := range nikonLiveViewHeaderSizes {
+		if len(b) >= s+len(soi) && bytes.Equal(b[s:s+len(soi)], soi) {
+			i = s
+			break
+		}
+	}
+	if i < 0 {
+		i = bytes.Index(b, soi)
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no JPEG image found in live view data", InvalidPacketError)
+	}
+
+	lv := &NikonLiveViewImage{Header: b[:i], Image: b[i:]}
+	if i < nikonLiveViewAreasSize {
+		return lv, nil
+	}
+
+	v := make([]uint16, nikonLiveViewAreasSize/2)
+	if err := binary.Read(bytes.NewReader(b[:nikonLiveViewAreasSize]), binary.BigEndian, v); err != nil {
+		return nil, err
+	}
+	lv.Width, lv.Height = v[0], v[1]
+	lv.WholeWidth, lv.WholeHeight = v[2], v[3]
+	lv.DisplayArea = NikonLiveViewArea{Width: v[4], Height: v[5], CenterX: v[6], CenterY: v[7]}
+	lv.AFArea = NikonLiveViewArea{Width: v[8], Height: v[9], CenterX: v[10], CenterY: v[11]}
+
+	return lv, nil
+}
+
+// NikonToggleLiveView enables or disables live view. Nikon devices do not have a streamer connection, so when enabled,
+// the frames are fetched using NikonGetLiveViewImg() every NikonLiveViewInterval and queued on the StreamChan.
+func NikonToggleLiveView(c *Client, en bool) error {
+	if !en {
+		c.stopStream()
+		c.infow(SubsystemVendor, "ending live view", "responder", c.ResponderFriendlyName())
+		err := nikonOperationRequest(c, OC_Nikon_EndLiveView, nil)
+		if errors.Is(err, ErrNikonNotLiveView) {
+			return nil
+		}
+		return err
+	}
+
+	if c.streaming() {
+		return nil
+	}
+	c.infow(SubsystemVendor, "starting live view", "responder", c.ResponderFriendlyName())
+	if err := nikonOperationRequest(c, OC_Nikon_StartLiveView, nil); err != nil {
+		return err
+	}
+
+	c.startPolledStream(NikonLiveViewInterval, nikonLiveViewFrame, func(err error) bool {
+		return errors.Is(err, ErrNikonDeviceBusy)
+	})
+
+	return nil
+}
+
+// nikonLiveViewFrame returns the JPEG image of a single live view frame.
+func nikonLiveViewFrame(c *Client) ([]byte, error) {
+	lv, err := NikonGetLiveViewImg(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return lv.Image, nil
+}
@@ -0,0 +1,194 @@
+package ip_test
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNikonInitEventConn(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	var got []ptp.OperationCode
+	for _, req := range res.Requests() {
+		got = append(got, req.OperationCode)
+	}
+	want := []ptp.OperationCode{ptp.OC_OpenSession}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dial() operations = %#x; want %#x", got, want)
+	}
+}
+
+func TestNikonCheckEvent(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	got, err := ip.NikonCheckEvent(c)
+	if err != nil {
+		t.Fatalf("NikonCheckEvent() error = %s; want <nil>", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("NikonCheckEvent() got %d events; want 0", len(got))
+	}
+
+	res.QueueNikonEvent(ptp.EC_DevicePropChanged, uint32(ip.DPC_Nikon_ExposureTime))
+	res.QueueNikonEvent(ptp.EC_ObjectAdded, 0x2a)
+
+	got, err = ip.NikonCheckEvent(c)
+	if err != nil {
+		t.Fatalf("NikonCheckEvent() error = %s; want <nil>", err)
+	}
+	want := []ip.NikonEvent{
+		{Code: ptp.EC_DevicePropChanged, Parameter: uint32(ip.DPC_Nikon_ExposureTime)},
+		{Code: ptp.EC_ObjectAdded, Parameter: 0x2a},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NikonCheckEvent() got = %#v; want %#v", got, want)
+	}
+}
+
+func TestNikonInitiateCapture(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	pv, err := c.InitiateCapture()
+	if err != nil {
+		t.Fatalf("InitiateCapture() error = %s; want <nil>", err)
+	}
+	if pv != nil {
+		t.Errorf("InitiateCapture() preview = %#v; want <nil>", pv)
+	}
+
+	var got []ptp.OperationCode
+	for _, req := range res.Requests()[1:] {
+		got = append(got, req.OperationCode)
+	}
+	want := []ptp.OperationCode{
+		ip.OC_Nikon_AfDrive, ip.OC_Nikon_DeviceReady, ip.OC_Nikon_DeviceReady,
+		ip.OC_Nikon_InitiateCaptureRecInSdram, ip.OC_Nikon_DeviceReady, ip.OC_Nikon_DeviceReady, ip.OC_Nikon_DeviceReady,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InitiateCapture() operations = %#x; want %#x", got, want)
+	}
+	if p := res.RequestsFor(ip.OC_Nikon_InitiateCaptureRecInSdram)[0].Parameter(1); p != ip.PM_Nikon_NoAF {
+		t.Errorf("InitiateCapture() parameter = %#x; want %#x", p, ip.PM_Nikon_NoAF)
+	}
+
+	events, err := ip.NikonCheckEvent(c)
+	if err != nil {
+		t.Fatalf("NikonCheckEvent() error = %s; want <nil>", err)
+	}
+	if len(events) != 2 || events[0].Code != ip.EC_Nikon_ObjectAddedInSdram ||
+		events[1].Code != ip.EC_Nikon_CaptureCompleteRecInSdram {
+		t.Errorf("NikonCheckEvent() got = %#v; want object added and capture complete", events)
+	}
+}
+
+func TestNikonInitiateCaptureOutOfFocus(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+	res.Handle(ip.OC_Nikon_DeviceReady, iptest.Reply(iptest.Fail(ip.RC_Nikon_OutOfFocus)))
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := c.InitiateCapture(); err != ip.ErrNikonOutOfFocus {
+		t.Errorf("InitiateCapture() error = %v; want %s", err, ip.ErrNikonOutOfFocus)
+	}
+	if res.Received(ip.OC_Nikon_InitiateCaptureRecInSdram) {
+		t.Errorf("InitiateCapture() released the shutter while out of focus")
+	}
+}
+
+func TestNikonGetLiveViewImg(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x01, 0x02, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.NikonGetLiveViewImg(c); err != ip.ErrNikonNotLiveView {
+		t.Errorf("NikonGetLiveViewImg() error = %v; want %s", err, ip.ErrNikonNotLiveView)
+	}
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatal(err)
+	}
+	defer c.ToggleLiveView(false)
+
+	got, err := ip.NikonGetLiveViewImg(c)
+	if err != nil {
+		t.Fatalf("NikonGetLiveViewImg() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got.Image, img) {
+		t.Errorf("NikonGetLiveViewImg() image = %#v; want %#v", got.Image, img)
+	}
+	if l := len(got.Header); l != 0x180 {
+		t.Errorf("NikonGetLiveViewImg() header length = %d; want %d", l, 0x180)
+	}
+	if got.Width != 640 || got.Height != 424 {
+		t.Errorf("NikonGetLiveViewImg() size = %dx%d; want 640x424", got.Width, got.Height)
+	}
+	if got.WholeWidth != 6000 || got.WholeHeight != 4000 {
+		t.Errorf("NikonGetLiveViewImg() whole size = %dx%d; want 6000x4000", got.WholeWidth, got.WholeHeight)
+	}
+	if want := (ip.NikonLiveViewArea{Width: 6000, Height: 4000, CenterX: 3000, CenterY: 2000}); got.DisplayArea != want {
+		t.Errorf("NikonGetLiveViewImg() display area = %+v; want %+v", got.DisplayArea, want)
+	}
+	if want := (ip.NikonLiveViewArea{Width: 300, Height: 200, CenterX: 3000, CenterY: 2000}); got.AFArea != want {
+		t.Errorf("NikonGetLiveViewImg() AF area = %+v; want %+v", got.AFArea, want)
+	}
+}
+
+func TestNikonToggleLiveView(t *testing.T) {
+	res := iptest.NewResponder("nikon")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x03, 0x04, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "tëster", "2d3e5a7c-94b1-4c0e-b6f8-1a9d0e7c3b52", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+
+	for i := uint32(0); i < 2; i++ {
+		select {
+		case f := <-c.StreamChan:
+			if !bytes.Equal(f.Data, img) {
+				t.Errorf("ToggleLiveView() frame = %#v; want %#v", f.Data, img)
+			}
+			if f.Counter != i {
+				t.Errorf("ToggleLiveView() counter = %d; want %d", f.Counter, i)
+			}
+			f.Release()
+		case <-time.After(time.Second):
+			t.Fatal("ToggleLiveView() no frame received")
+		}
+	}
+
+	if err := c.ToggleLiveView(false); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+	v, _ := res.DevicePropValue(ip.DPC_Nikon_LiveViewStatus)
+	if got := ip.NikonLiveViewStatus(v[0]); got != ip.LVS_Nikon_Off {
+		t.Errorf("ToggleLiveView() live view status = %#x; want %#x", got, ip.LVS_Nikon_Off)
+	}
+}
@@ -0,0 +1,273 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"time"
+)
+
+const (
+	OC_Panasonic_ListProperty    ptp.OperationCode = 0x9108
+	OC_Panasonic_GetProperty     ptp.OperationCode = 0x9402
+	OC_Panasonic_SetProperty     ptp.OperationCode = 0x9403
+	OC_Panasonic_InitiateCapture ptp.OperationCode = 0x9404
+	OC_Panasonic_LiveView        ptp.OperationCode = 0x9412
+	OC_Panasonic_LiveViewImage   ptp.OperationCode = 0x9706
+
+	// Panasonic uses 32 bit property codes of which the upper 16 bits select the property group. All properties listed
+	// here belong to PM_Panasonic_CameraSettings, which is added when talking to the Responder, so they can be used as
+	// a regular ptp.DevicePropCode.
+	DPC_Panasonic_ISO          ptp.DevicePropCode = 0x0020
+	DPC_Panasonic_ShutterSpeed ptp.DevicePropCode = 0x0030
+	// DPC_Panasonic_Aperture holds the f-number multiplied by 10.
+	DPC_Panasonic_Aperture     ptp.DevicePropCode = 0x0040
+	DPC_Panasonic_WhiteBalance ptp.DevicePropCode = 0x0050
+	// DPC_Panasonic_ExposureCompensation holds the compensation in thirds of a stop as a signed 16 bit integer.
+	DPC_Panasonic_ExposureCompensation ptp.DevicePropCode = 0x0060
+
+	// ISO_Panasonic_Auto is the DPC_Panasonic_ISO value indicating auto ISO.
+	ISO_Panasonic_Auto uint32 = 0xFFFFFFFF
+	// SS_Panasonic_Bulb is the DPC_Panasonic_ShutterSpeed value indicating bulb mode.
+	SS_Panasonic_Bulb uint32 = 0xFFFFFFFF
+	// SS_Panasonic_Fraction is set on a DPC_Panasonic_ShutterSpeed value holding the denominator, multiplied by 1000,
+	// of a fraction of a second. Without it, the value holds the number of seconds multiplied by 1000.
+	SS_Panasonic_Fraction uint32 = 0x80000000
+
+	// PM_Panasonic_CameraSettings is the property group holding the camera settings.
+	PM_Panasonic_CameraSettings uint32 = 0x02000000
+	// PM_Panasonic_CaptureStill is the parameter to OC_Panasonic_InitiateCapture to capture a still image.
+	PM_Panasonic_CaptureStill uint32 = 0x03000011
+	// PM_Panasonic_LiveViewStart is the parameter to OC_Panasonic_LiveView to start live view.
+	PM_Panasonic_LiveViewStart uint32 = 0x0D000010
+	// PM_Panasonic_LiveViewStop is the parameter to OC_Panasonic_LiveView to stop live view.
+	PM_Panasonic_LiveViewStop uint32 = 0x0D000011
+
+	// panasonicPropHeaderSize is the size of the property code and value size fields preceding a property value.
+	panasonicPropHeaderSize = 8
+)
+
+// panasonicProperties lists the properties returned by PanasonicGetDeviceState().
+var panasonicProperties = []ptp.DevicePropCode{
+	DPC_Panasonic_ISO,
+	DPC_Panasonic_ShutterSpeed,
+	DPC_Panasonic_Aperture,
+	DPC_Panasonic_WhiteBalance,
+	DPC_Panasonic_ExposureCompensation,
+}
+
+// ErrPanasonicPropNotSupported is returned when the Responder does not support the requested property.
+var ErrPanasonicPropNotSupported = errors.New("device property not supported")
+
+// ErrPanasonicNotReady is returned by PanasonicGetLiveViewImg() when there is no live view frame available.
+var ErrPanasonicNotReady = errors.New("no live view image available")
+
+// PanasonicLiveViewInterval is the time waited between two OC_Panasonic_LiveViewImage requests when live view is
+// enabled on a Panasonic device.
+var PanasonicLiveViewInterval = 50 * time.Millisecond
+
+// PanasonicInitEventConn initialises the event connection following the PTP/IP standard and then opens a session,
+// which is required for all Panasonic vendor operations.
+func PanasonicInitEventConn(c *Client) error {
+	if err := GenericInitEventConn(c); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "opening a session")
+	if _, err := GenericOperationRequestDataIn(c, ptp.OC_OpenSession, []uint32{0x00000001}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// panasonicPropCode returns the 32 bit property code as used by the Responder.
+func panasonicPropCode(code ptp.DevicePropCode) uint32 {
+	return PM_Panasonic_CameraSettings | uint32(code)
+}
+
+// panasonicGetProperty returns the raw value of the given property. The data returned by OC_Panasonic_GetProperty
+// holds the 32 bit property code and the size of the value followed by the value.
+func panasonicGetProperty(c *Client, code ptp.DevicePropCode) ([]byte, error) {
+	xs, rc, err := genericOperationRequestDataIn(c, OC_Panasonic_GetProperty, []uint32{panasonicPropCode(code)})
+	if err != nil {
+		return nil, err
+	}
+	switch rc {
+	case ptp.RC_OK:
+	case ptp.RC_DevicePropNotSupported:
+		return nil, ErrPanasonicPropNotSupported
+	default:
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
+	if len(xs) < panasonicPropHeaderSize {
+		return nil, internal.ShortPacketError(len(xs), panasonicPropHeaderSize)
+	}
+
+	size := uint64(binary.LittleEndian.Uint32(xs[4:8]))
+	if size > uint64(len(xs)-panasonicPropHeaderSize) {
+		return nil, fmt.Errorf("%w: property value size %d with %d bytes left", InvalidPacketError, size, len(xs)-panasonicPropHeaderSize)
+	}
+
+	return xs[panasonicPropHeaderSize : panasonicPropHeaderSize+size], nil
+}
+
+// panasonicDevicePropDataTypes lists the data types of the properties that do not hold an unsigned integer.
+var panasonicDevicePropDataTypes = map[ptp.DevicePropCode]ptp.DataTypeCode{
+	DPC_Panasonic_ExposureCompensation: ptp.DTC_INT16,
+}
+
+// panasonicDevicePropDesc returns a device property description holding the current value of the given property. The
+// data type of a property is not reported by the Responder: the types in panasonicDevicePropDataTypes are used for the
+// properties listed there, other values of 1, 2 or 4 bytes are returned as unsigned integers and any other value as
+// ptp.DTC_UNDEF.
+func panasonicDevicePropDesc(c *Client, code ptp.DevicePropCode) (*ptp.DevicePropDesc, error) {
+	v, err := panasonicGetProperty(c, code)
+	if err != nil {
+		return nil, err
+	}
+
+	dpd := &ptp.DevicePropDesc{
+		DevicePropertyCode: code,
+		DataType:           ptp.DTC_UNDEF,
+		CurrentValue:       v,
+	}
+	switch len(v) {
+	case 1:
+		dpd.DataType = ptp.DTC_UINT8
+	case 2:
+		dpd.DataType = ptp.DTC_UINT16
+	case 4:
+		dpd.DataType = ptp.DTC_UINT32
+	}
+	if dt, ok := panasonicDevicePropDataTypes[code]; ok && dt.ElementSize() == len(v) {
+		dpd.DataType = dt
+	}
+
+	return dpd, nil
+}
+
+// PanasonicGetDeviceState returns the current value of the known properties the Responder supports. Panasonic does
+// not support requesting all properties at once, so each property is requested separately.
+func PanasonicGetDeviceState(c *Client) (interface{}, error) {
+	c.infow(SubsystemVendor, "requesting device state", "responder", c.ResponderFriendlyName())
+
+	var list []*ptp.DevicePropDesc
+	for _, code := range panasonicProperties {
+		dpd, err := panasonicDevicePropDesc(c, code)
+		if errors.Is(err, ErrPanasonicPropNotSupported) {
+			c.debugw(SubsystemVendor, "skipping unsupported property", "property", code)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, dpd)
+	}
+
+	return list, nil
+}
+
+// PanasonicGetDevicePropertyDesc returns a description of the given property holding its current value only.
+func PanasonicGetDevicePropertyDesc(c *Client, code ptp.DevicePropCode) (*ptp.DevicePropDesc, error) {
+	c.infow(SubsystemVendor, "requesting device property description", "responder", c.ResponderFriendlyName(), "property", code)
+	return panasonicDevicePropDesc(c, code)
+}
+
+// PanasonicGetDevicePropertyValue returns the current value of the given property.
+func PanasonicGetDevicePropertyValue(c *Client, code ptp.DevicePropCode) (uint32, error) {
+	c.infow(SubsystemVendor, "requesting device property value", "responder", c.ResponderFriendlyName(), "property", code)
+	dpd, err := panasonicDevicePropDesc(c, code)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(dpd.CurrentValueAsInt64()), nil
+}
+
+// PanasonicSetDeviceProperty sets a device property to the given value using OC_Panasonic_SetProperty. The data out
+// phase holds the 32 bit property code and the size of the value followed by the value. The size of the value depends
+// on the property, which is why the current value is requested first.
+func PanasonicSetDeviceProperty(c *Client, code ptp.DevicePropCode, val uint32) error {
+	cur, err := panasonicGetProperty(c, code)
+	if err != nil {
+		return err
+	}
+	if len(cur) == 0 || len(cur) > 4 {
+		return ptp.OperationResponseCodeAsError(ptp.RC_InvalidDevicePropFormat)
+	}
+
+	c.infow(SubsystemVendor, "setting device property", "responder", c.ResponderFriendlyName(), "property", code, "value", fmt.Sprintf("%#x", val))
+	data := make([]byte, panasonicPropHeaderSize+4)
+	binary.LittleEndian.PutUint32(data[0:4], panasonicPropCode(code))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(cur)))
+	binary.LittleEndian.PutUint32(data[8:12], val)
+
+	return GenericOperationRequestDataOut(c, OC_Panasonic_SetProperty, []uint32{panasonicPropCode(code)}, data[:panasonicPropHeaderSize+len(cur)])
+}
+
+// PanasonicInitiateCapture releases the shutter using OC_Panasonic_InitiateCapture. Panasonic does not return a capture
+// preview: the object that was added is reported on the event connection.
+func PanasonicInitiateCapture(c *Client) ([]byte, error) {
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	c.markCaptureSent()
+	if _, err := GenericOperationRequestDataIn(c, OC_Panasonic_InitiateCapture, []uint32{PM_Panasonic_CaptureStill}); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// PanasonicGetLiveViewImg fetches a single live view frame. Live view must be started first using
+// OC_Panasonic_LiveView. The JPEG image is preceded by a header of which the size varies, so the JPEG start of image
+// marker is searched for. When the Responder has no frame available yet, the error returned will be
+// ErrPanasonicNotReady.
+func PanasonicGetLiveViewImg(c *Client) ([]byte, error) {
+	xs, rc, err := genericOperationRequestDataIn(c, OC_Panasonic_LiveViewImage, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch rc {
+	case ptp.RC_OK:
+	case ptp.RC_DeviceBusy:
+		return nil, ErrPanasonicNotReady
+	default:
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
+
+	i := bytes.Index(xs, []byte{0xFF, 0xD8})
+	if i < 0 {
+		return nil, ErrPanasonicNotReady
+	}
+
+	return xs[i:], nil
+}
+
+// PanasonicToggleLiveView starts or stops live view. Panasonic devices do not have a streamer connection, so when
+// enabled, the frames are fetched using PanasonicGetLiveViewImg() every PanasonicLiveViewInterval and queued on the
+// StreamChan.
+func PanasonicToggleLiveView(c *Client, en bool) error {
+	if !en {
+		c.stopStream()
+		c.infow(SubsystemVendor, "stopping live view", "responder", c.ResponderFriendlyName())
+		_, err := GenericOperationRequestDataIn(c, OC_Panasonic_LiveView, []uint32{PM_Panasonic_LiveViewStop})
+		return err
+	}
+
+	if c.streaming() {
+		return nil
+	}
+	c.infow(SubsystemVendor, "starting live view", "responder", c.ResponderFriendlyName())
+	if _, err := GenericOperationRequestDataIn(c, OC_Panasonic_LiveView, []uint32{PM_Panasonic_LiveViewStart}); err != nil {
+		return err
+	}
+
+	c.startPolledStream(PanasonicLiveViewInterval, PanasonicGetLiveViewImg, func(err error) bool {
+		return errors.Is(err, ErrPanasonicNotReady)
+	})
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package ip_test
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPanasonicInitEventConn(t *testing.T) {
+	res := iptest.NewResponder("panasonic")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "8b6e4d29-31c7-4f0a-9e5d-6c2a1b8f7e04", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	var got []ptp.OperationCode
+	for _, req := range res.Requests() {
+		got = append(got, req.OperationCode)
+	}
+	want := []ptp.OperationCode{ptp.OC_OpenSession}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dial() operations = %#x; want %#x", got, want)
+	}
+}
+
+func TestPanasonicGetDeviceState(t *testing.T) {
+	res := iptest.NewResponder("panasonic")
+	defer res.Close()
+	// Minus one stop.
+	res.SetDevicePropValue(ip.DPC_Panasonic_ExposureCompensation, []byte{0xfd, 0xff})
+
+	c := res.DialClient(t, "tëster", "8b6e4d29-31c7-4f0a-9e5d-6c2a1b8f7e04", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	state, err := c.GetDeviceState()
+	if err != nil {
+		t.Fatalf("GetDeviceState() error = %s; want <nil>", err)
+	}
+	list, ok := state.([]*ptp.DevicePropDesc)
+	if !ok {
+		t.Fatalf("GetDeviceState() type = %T; want []*ptp.DevicePropDesc", state)
+	}
+
+	// The white balance is not supported by the Responder and must be skipped.
+	got := make(map[ptp.DevicePropCode]ptp.DataTypeCode)
+	for _, dpd := range list {
+		got[dpd.DevicePropertyCode] = dpd.DataType
+	}
+	want := map[ptp.DevicePropCode]ptp.DataTypeCode{
+		ip.DPC_Panasonic_ISO:                  ptp.DTC_UINT32,
+		ip.DPC_Panasonic_ShutterSpeed:         ptp.DTC_UINT32,
+		ip.DPC_Panasonic_Aperture:             ptp.DTC_UINT16,
+		ip.DPC_Panasonic_ExposureCompensation: ptp.DTC_INT16,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeviceState() properties = %#v; want %#v", got, want)
+	}
+	for _, dpd := range list {
+		if dpd.DevicePropertyCode != ip.DPC_Panasonic_ExposureCompensation {
+			continue
+		}
+		pv, err := dpd.Current()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := pv.Int64(); v != -3 {
+			t.Errorf("GetDeviceState() exposure compensation = %d; want %d", v, -3)
+		}
+	}
+
+	req := res.RequestsFor(ip.OC_Panasonic_GetProperty)[0]
+	if p := req.Parameter(1); p != ip.PM_Panasonic_CameraSettings|uint32(ip.DPC_Panasonic_ISO) {
+		t.Errorf("GetDeviceState() parameter = %#x; want %#x", p, ip.PM_Panasonic_CameraSettings|uint32(ip.DPC_Panasonic_ISO))
+	}
+}
+
+func TestPanasonicSetDeviceProperty(t *testing.T) {
+	res := iptest.NewResponder("panasonic")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "8b6e4d29-31c7-4f0a-9e5d-6c2a1b8f7e04", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.SetDeviceProperty(ip.DPC_Panasonic_Aperture, 80); err != nil {
+		t.Fatalf("SetDeviceProperty() error = %s; want <nil>", err)
+	}
+	got, err := c.GetDevicePropertyValue(ip.DPC_Panasonic_Aperture)
+	if err != nil {
+		t.Fatalf("GetDevicePropertyValue() error = %s; want <nil>", err)
+	}
+	if got != 80 {
+		t.Errorf("GetDevicePropertyValue() return = %d; want %d", got, 80)
+	}
+
+	if err := c.SetDeviceProperty(ip.DPC_Panasonic_WhiteBalance, 1); err != ip.ErrPanasonicPropNotSupported {
+		t.Errorf("SetDeviceProperty() error = %v; want %s", err, ip.ErrPanasonicPropNotSupported)
+	}
+}
+
+func TestPanasonicInitiateCapture(t *testing.T) {
+	res := iptest.NewResponder("panasonic")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "8b6e4d29-31c7-4f0a-9e5d-6c2a1b8f7e04", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := c.InitiateCapture(); err != nil {
+		t.Fatalf("InitiateCapture() error = %s; want <nil>", err)
+	}
+	if p := res.RequestsFor(ip.OC_Panasonic_InitiateCapture)[0].Parameter(1); p != ip.PM_Panasonic_CaptureStill {
+		t.Errorf("InitiateCapture() parameter = %#x; want %#x", p, ip.PM_Panasonic_CaptureStill)
+	}
+}
+
+func TestPanasonicToggleLiveView(t *testing.T) {
+	res := iptest.NewResponder("panasonic")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x03, 0x04, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "tëster", "8b6e4d29-31c7-4f0a-9e5d-6c2a1b8f7e04", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.PanasonicGetLiveViewImg(c); err != ip.ErrPanasonicNotReady {
+		t.Errorf("PanasonicGetLiveViewImg() error = %v; want %s", err, ip.ErrPanasonicNotReady)
+	}
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+
+	for i := uint32(0); i < 2; i++ {
+		select {
+		case f := <-c.StreamChan:
+			if !bytes.Equal(f.Data, img) {
+				t.Errorf("ToggleLiveView() frame = %#v; want %#v", f.Data, img)
+			}
+			if f.Counter != i {
+				t.Errorf("ToggleLiveView() counter = %d; want %d", f.Counter, i)
+			}
+			f.Release()
+		case <-time.After(time.Second):
+			t.Fatal("ToggleLiveView() no frame received")
+		}
+	}
+
+	if err := c.ToggleLiveView(false); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+	var got []uint32
+	for _, req := range res.RequestsFor(ip.OC_Panasonic_LiveView) {
+		got = append(got, req.Parameter(1))
+	}
+	want := []uint32{ip.PM_Panasonic_LiveViewStart, ip.PM_Panasonic_LiveViewStop}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToggleLiveView() parameters = %#x; want %#x", got, want)
+	}
+}
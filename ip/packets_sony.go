@@ -0,0 +1,283 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"io"
+	"time"
+)
+
+type SonyButtonState uint16
+
+const (
+	OC_Sony_SDIO_Connect                 ptp.OperationCode = 0x9201
+	OC_Sony_SDIO_GetExtDeviceInfo        ptp.OperationCode = 0x9202
+	OC_Sony_SDIO_SetExtDevicePropValue   ptp.OperationCode = 0x9205
+	OC_Sony_SDIO_ControlDevice           ptp.OperationCode = 0x9207
+	OC_Sony_SDIO_GetAllExtDevicePropInfo ptp.OperationCode = 0x9209
+
+	EC_Sony_ObjectAdded     ptp.EventCode = 0xC201
+	EC_Sony_ObjectRemoved   ptp.EventCode = 0xC202
+	EC_Sony_PropertyChanged ptp.EventCode = 0xC203
+
+	DPC_Sony_DRangeOptimize ptp.DevicePropCode = 0xD201
+	DPC_Sony_ImageSize      ptp.DevicePropCode = 0xD203
+	// DPC_Sony_ShutterSpeed is the Sony equivalent of ptp.DPC_ExposureTime. The upper 16 bits of the value hold the
+	// numerator, the lower 16 bits the denominator.
+	DPC_Sony_ShutterSpeed     ptp.DevicePropCode = 0xD20D
+	DPC_Sony_ColorTemperature ptp.DevicePropCode = 0xD20F
+	DPC_Sony_AspectRatio      ptp.DevicePropCode = 0xD211
+	DPC_Sony_FocusIndication  ptp.DevicePropCode = 0xD213
+	DPC_Sony_ObjectInMemory   ptp.DevicePropCode = 0xD215
+	DPC_Sony_BatteryLevel     ptp.DevicePropCode = 0xD218
+	DPC_Sony_PictureEffect    ptp.DevicePropCode = 0xD21B
+	// DPC_Sony_ISO is the Sony equivalent of ptp.DPC_ExposureIndex.
+	DPC_Sony_ISO ptp.DevicePropCode = 0xD21E
+	// DPC_Sony_S1Button is the control passed to OC_Sony_SDIO_ControlDevice to press the shutter button halfway.
+	DPC_Sony_S1Button ptp.DevicePropCode = 0xD2C1
+	// DPC_Sony_S2Button is the control passed to OC_Sony_SDIO_ControlDevice to press the shutter button all the way.
+	DPC_Sony_S2Button    ptp.DevicePropCode = 0xD2C2
+	DPC_Sony_MovieButton ptp.DevicePropCode = 0xD2C8
+
+	BTN_Sony_Up   SonyButtonState = 0x0001
+	BTN_Sony_Down SonyButtonState = 0x0002
+
+	// ISO_Sony_Auto is the DPC_Sony_ISO value indicating auto ISO.
+	ISO_Sony_Auto uint32 = 0x00FFFFFF
+	// SS_Sony_Bulb is the DPC_Sony_ShutterSpeed value indicating bulb mode.
+	SS_Sony_Bulb uint32 = 0x00000000
+
+	// PM_Sony_SDIO_ProtocolVersion is the SDIO protocol version passed to OC_Sony_SDIO_GetExtDeviceInfo.
+	PM_Sony_SDIO_ProtocolVersion uint32 = 0x000000C8
+	// PM_Sony_LiveViewHandle is the object handle to pass to ptp.OC_GetObject to retrieve a live view frame.
+	PM_Sony_LiveViewHandle uint32 = 0xFFFFC002
+)
+
+// ErrSonyNotReady is returned by SonyGetLiveViewImg() when there is no live view frame available.
+var ErrSonyNotReady = errors.New("no live view image available")
+
+// SonyLiveViewInterval is the time waited between two live view frame requests when live view is enabled on a Sony
+// device.
+var SonyLiveViewInterval = 50 * time.Millisecond
+
+// SonyInitEventConn initialises the event connection following the PTP/IP standard and then performs the SDIO
+// handshake which puts the Responder in PC remote mode:
+//  1. Open a session.
+//  2. Perform SDIO connect phase 1 and 2.
+//  3. Request the extended device info passing the SDIO protocol version.
+//  4. Perform SDIO connect phase 3.
+func SonyInitEventConn(c *Client) error {
+	if err := GenericInitEventConn(c); err != nil {
+		return err
+	}
+
+	c.infow(SubsystemVendor, "opening a session")
+	if _, err := GenericOperationRequestDataIn(c, ptp.OC_OpenSession, []uint32{0x00000001}); err != nil {
+		return err
+	}
+
+	for _, step := range []struct {
+		code  ptp.OperationCode
+		param uint32
+	}{
+		{OC_Sony_SDIO_Connect, 1},
+		{OC_Sony_SDIO_Connect, 2},
+		{OC_Sony_SDIO_GetExtDeviceInfo, PM_Sony_SDIO_ProtocolVersion},
+		{OC_Sony_SDIO_Connect, 3},
+	} {
+		c.infow(SubsystemVendor, "performing SDIO handshake", "operation", step.code, "parameter", step.param)
+		if _, err := GenericOperationRequestDataIn(c, step.code, []uint32{step.param, 0, 0}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SonyGetDeviceState returns the description of all device properties, including their current value, using a single
+// OC_Sony_SDIO_GetAllExtDevicePropInfo operation.
+func SonyGetDeviceState(c *Client) (interface{}, error) {
+	c.infow(SubsystemVendor, "requesting device state", "responder", c.ResponderFriendlyName())
+	return sonyGetAllDevicePropDesc(c)
+}
+
+func sonyGetAllDevicePropDesc(c *Client) ([]*ptp.DevicePropDesc, error) {
+	xs, err := GenericOperationRequestDataIn(c, OC_Sony_SDIO_GetAllExtDevicePropInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(xs) < 8 {
+		return nil, internal.ShortPacketError(len(xs), 8)
+	}
+
+	numProps := binary.LittleEndian.Uint64(xs[0:8])
+	c.debugw(SubsystemVendor, "number of properties returned", "properties", numProps)
+
+	r := bytes.NewReader(xs[8:])
+	// Do not allocate the list up front since the number of properties comes straight from the network.
+	var list []*ptp.DevicePropDesc
+	for i := uint64(0); i < numProps; i++ {
+		dpd, err := sonyReadDevicePropDesc(r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, dpd)
+	}
+
+	return list, nil
+}
+
+// sonyReadDevicePropDesc reads a single device property description as returned by
+// OC_Sony_SDIO_GetAllExtDevicePropInfo. It is a standard device property description with an additional byte,
+// indicating if the property is currently enabled, following the GetSet field.
+func sonyReadDevicePropDesc(r io.Reader) (*ptp.DevicePropDesc, error) {
+	// Property code (2), data type (2), GetSet (1) and the enabled flag (1).
+	h := make([]byte, 6)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+
+	return ptp.ReadDevicePropDesc(io.MultiReader(bytes.NewReader(h[:5]), r))
+}
+
+// SonyGetDevicePropertyDesc returns the description of the given property. Sony does not support requesting the
+// description of a single property, so all properties are requested and nil is returned when the Responder does not
+// know the property.
+func SonyGetDevicePropertyDesc(c *Client, code ptp.DevicePropCode) (*ptp.DevicePropDesc, error) {
+	c.infow(SubsystemVendor, "requesting device property description", "responder", c.ResponderFriendlyName(), "property", code)
+	list, err := sonyGetAllDevicePropDesc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dpd := range list {
+		if dpd.DevicePropertyCode == code {
+			return dpd, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SonyGetDevicePropertyValue returns the current value of the given property.
+func SonyGetDevicePropertyValue(c *Client, code ptp.DevicePropCode) (uint32, error) {
+	dpd, err := SonyGetDevicePropertyDesc(c, code)
+	if err != nil {
+		return 0, err
+	}
+	if dpd == nil {
+		return 0, ptp.OperationResponseCodeAsError(ptp.RC_DevicePropNotSupported)
+	}
+
+	return uint32(dpd.CurrentValueAsInt64()), nil
+}
+
+// SonySetDeviceProperty sets a device property to the given value using OC_Sony_SDIO_SetExtDevicePropValue. The size
+// of the value sent depends on the data type of the property, which is why the property description is requested
+// first.
+func SonySetDeviceProperty(c *Client, code ptp.DevicePropCode, val uint32) error {
+	dpd, err := SonyGetDevicePropertyDesc(c, code)
+	if err != nil {
+		return err
+	}
+	if dpd == nil {
+		return ptp.OperationResponseCodeAsError(ptp.RC_DevicePropNotSupported)
+	}
+	// Only integer values can be set.
+	size := dpd.SizeOfValueInBytes()
+	if size == 0 || size > 8 {
+		return ptp.OperationResponseCodeAsError(ptp.RC_InvalidDevicePropFormat)
+	}
+
+	c.infow(SubsystemVendor, "setting device property", "responder", c.ResponderFriendlyName(), "property", code, "value", fmt.Sprintf("%#x", val))
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, uint64(val))
+
+	return GenericOperationRequestDataOut(c, OC_Sony_SDIO_SetExtDevicePropValue, []uint32{uint32(code)}, data[:size])
+}
+
+// SonyControlDevice changes the state of the given button, such as DPC_Sony_S1Button, using
+// OC_Sony_SDIO_ControlDevice.
+func SonyControlDevice(c *Client, button ptp.DevicePropCode, state SonyButtonState) error {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data, uint16(state))
+
+	return GenericOperationRequestDataOut(c, OC_Sony_SDIO_ControlDevice, []uint32{uint32(button)}, data)
+}
+
+// SonyInitiateCapture focuses and releases the shutter by pressing the shutter button halfway, then all the way and
+// releasing it again. Sony does not return a capture preview: the object that was added is reported on the event
+// connection as an EC_Sony_ObjectAdded event.
+func SonyInitiateCapture(c *Client) ([]byte, error) {
+	c.infow(SubsystemVendor, "releasing shutter", "responder", c.ResponderFriendlyName())
+	for _, step := range []struct {
+		button ptp.DevicePropCode
+		state  SonyButtonState
+	}{
+		{DPC_Sony_S1Button, BTN_Sony_Down},
+		{DPC_Sony_S2Button, BTN_Sony_Down},
+		{DPC_Sony_S2Button, BTN_Sony_Up},
+		{DPC_Sony_S1Button, BTN_Sony_Up},
+	} {
+		// Fully pressing the shutter button is what releases the shutter.
+		if step.button == DPC_Sony_S2Button && step.state == BTN_Sony_Down {
+			c.markCaptureSent()
+		}
+		if err := SonyControlDevice(c, step.button, step.state); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// SonyGetLiveViewImg fetches a single live view frame by requesting the object PM_Sony_LiveViewHandle. The data
+// returned starts with the offset and the size of the JPEG image followed by information about the frame. When the
+// Responder has no frame available yet, the error returned will be ErrSonyNotReady.
+func SonyGetLiveViewImg(c *Client) ([]byte, error) {
+	xs, rc, err := genericOperationRequestDataIn(c, ptp.OC_GetObject, []uint32{PM_Sony_LiveViewHandle})
+	if err != nil {
+		return nil, err
+	}
+	switch rc {
+	case ptp.RC_OK:
+	case ptp.RC_AccessDenied:
+		return nil, ErrSonyNotReady
+	default:
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
+
+	if len(xs) < 8 {
+		return nil, internal.ShortPacketError(len(xs), 8)
+	}
+	offset := uint64(binary.LittleEndian.Uint32(xs[0:4]))
+	size := uint64(binary.LittleEndian.Uint32(xs[4:8]))
+	if offset < 8 || offset+size > uint64(len(xs)) {
+		return nil, fmt.Errorf("%w: live view image at offset %d of size %d with %d bytes", InvalidPacketError, offset, size, len(xs))
+	}
+
+	return xs[offset : offset+size], nil
+}
+
+// SonyToggleLiveView enables or disables live view. Sony devices do not have a streamer connection, so when enabled,
+// the frames are fetched using SonyGetLiveViewImg() every SonyLiveViewInterval and queued on the StreamChan.
+func SonyToggleLiveView(c *Client, en bool) error {
+	if !en {
+		c.stopStream()
+		return nil
+	}
+
+	if c.streaming() {
+		return nil
+	}
+
+	c.startPolledStream(SonyLiveViewInterval, SonyGetLiveViewImg, func(err error) bool {
+		return errors.Is(err, ErrSonyNotReady)
+	})
+
+	return nil
+}
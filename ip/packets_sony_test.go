@@ -0,0 +1,170 @@
+package ip_test
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSonyInitEventConn(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	var got [][]uint32
+	for _, req := range res.Requests() {
+		got = append(got, append([]uint32{uint32(req.OperationCode)}, req.Parameter(1)))
+	}
+	want := [][]uint32{
+		{uint32(ptp.OC_OpenSession), 1},
+		{uint32(ip.OC_Sony_SDIO_Connect), 1},
+		{uint32(ip.OC_Sony_SDIO_Connect), 2},
+		{uint32(ip.OC_Sony_SDIO_GetExtDeviceInfo), ip.PM_Sony_SDIO_ProtocolVersion},
+		{uint32(ip.OC_Sony_SDIO_Connect), 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dial() operations = %#x; want %#x", got, want)
+	}
+}
+
+func TestSonyGetDeviceState(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	state, err := c.GetDeviceState()
+	if err != nil {
+		t.Fatalf("GetDeviceState() error = %s; want <nil>", err)
+	}
+	list, ok := state.([]*ptp.DevicePropDesc)
+	if !ok {
+		t.Fatalf("GetDeviceState() type = %T; want []*ptp.DevicePropDesc", state)
+	}
+
+	var got []ptp.DevicePropCode
+	for _, dpd := range list {
+		got = append(got, dpd.DevicePropertyCode)
+	}
+	want := []ptp.DevicePropCode{ip.DPC_Sony_ShutterSpeed, ip.DPC_Sony_BatteryLevel, ip.DPC_Sony_ISO}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeviceState() properties = %#x; want %#x", got, want)
+	}
+	if v := list[1].CurrentValueAsInt64(); v != 80 {
+		t.Errorf("GetDeviceState() battery level = %d; want %d", v, 80)
+	}
+}
+
+func TestSonySetDeviceProperty(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.SetDeviceProperty(ip.DPC_Sony_ISO, 400); err != nil {
+		t.Fatalf("SetDeviceProperty() error = %s; want <nil>", err)
+	}
+	got, err := c.GetDevicePropertyValue(ip.DPC_Sony_ISO)
+	if err != nil {
+		t.Fatalf("GetDevicePropertyValue() error = %s; want <nil>", err)
+	}
+	if got != 400 {
+		t.Errorf("GetDevicePropertyValue() return = %d; want %d", got, 400)
+	}
+
+	req := res.RequestsFor(ip.OC_Sony_SDIO_SetExtDevicePropValue)[0]
+	if p := ptp.DevicePropCode(req.Parameter(1)); p != ip.DPC_Sony_ISO {
+		t.Errorf("SetDeviceProperty() parameter = %#x; want %#x", p, ip.DPC_Sony_ISO)
+	}
+	if !bytes.Equal(req.Data, []byte{0x90, 0x01, 0x00, 0x00}) {
+		t.Errorf("SetDeviceProperty() data = %#v; want %#v", req.Data, []byte{0x90, 0x01, 0x00, 0x00})
+	}
+
+	if _, err := c.GetDevicePropertyValue(ip.DPC_Sony_AspectRatio); err == nil {
+		t.Errorf("GetDevicePropertyValue() error = <nil>; want not supported error")
+	}
+}
+
+func TestSonyInitiateCapture(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := c.InitiateCapture(); err != nil {
+		t.Fatalf("InitiateCapture() error = %s; want <nil>", err)
+	}
+
+	var got [][]byte
+	for _, req := range res.RequestsFor(ip.OC_Sony_SDIO_ControlDevice) {
+		got = append(got, append([]byte{byte(req.Parameter(1) & 0xFF)}, req.Data...))
+	}
+	want := [][]byte{
+		{byte(ip.DPC_Sony_S1Button & 0xFF), byte(ip.BTN_Sony_Down), 0x00},
+		{byte(ip.DPC_Sony_S2Button & 0xFF), byte(ip.BTN_Sony_Down), 0x00},
+		{byte(ip.DPC_Sony_S2Button & 0xFF), byte(ip.BTN_Sony_Up), 0x00},
+		{byte(ip.DPC_Sony_S1Button & 0xFF), byte(ip.BTN_Sony_Up), 0x00},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InitiateCapture() button presses = %#x; want %#x", got, want)
+	}
+}
+
+func TestSonyGetLiveViewImg(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if _, err := ip.SonyGetLiveViewImg(c); err != ip.ErrSonyNotReady {
+		t.Errorf("SonyGetLiveViewImg() error = %v; want %s", err, ip.ErrSonyNotReady)
+	}
+
+	img := []byte{0xff, 0xd8, 0x01, 0x02, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+	got, err := ip.SonyGetLiveViewImg(c)
+	if err != nil {
+		t.Fatalf("SonyGetLiveViewImg() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, img) {
+		t.Errorf("SonyGetLiveViewImg() return = %#v; want %#v", got, img)
+	}
+}
+
+func TestSonyToggleLiveView(t *testing.T) {
+	res := iptest.NewResponder("sony")
+	defer res.Close()
+	img := []byte{0xff, 0xd8, 0x03, 0x04, 0xff, 0xd9}
+	res.SetCapturePreview(img)
+
+	c := res.DialClient(t, "tëster", "5f0c2b1e-7a3d-4e96-8c41-d2b07e9a6f13", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := c.ToggleLiveView(true); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+
+	select {
+	case f := <-c.StreamChan:
+		if !bytes.Equal(f.Data, img) {
+			t.Errorf("ToggleLiveView() frame = %#v; want %#v", f.Data, img)
+		}
+		f.Release()
+	case <-time.After(time.Second):
+		t.Fatal("ToggleLiveView() no frame received")
+	}
+
+	if err := c.ToggleLiveView(false); err != nil {
+		t.Fatalf("ToggleLiveView() error = %s; want <nil>", err)
+	}
+}
@@ -1,6 +1,7 @@
 package ip
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/malc0mn/ptp-ip/ptp"
@@ -121,8 +122,8 @@ func TestNewPacketOutFromPacketTypeFail(t *testing.T) {
 	}
 	for _, typ := range types {
 		got, err := NewPacketOutFromPacketType(typ)
-		if err == nil {
-			t.Errorf("NewPacketOutFromPacketType() err = %s; want unknown packet type %#x", err, typ)
+		if !errors.Is(err, ErrUnknownPacketType) {
+			t.Errorf("NewPacketOutFromPacketType() err = %v; want %s %#x", err, ErrUnknownPacketType, typ)
 		}
 		if got != nil {
 			t.Errorf("NewPacketOutFromPacketType() got = %T; want <nil>", got)
@@ -171,8 +172,8 @@ func TestNewPacketInFromPacketTypeFail(t *testing.T) {
 	}
 	for _, typ := range types {
 		got, err := NewPacketInFromPacketType(typ)
-		if err == nil {
-			t.Errorf("NewPacketInFromPacketType() err = %s; want unknown packet type %#x", err, typ)
+		if !errors.Is(err, ErrUnknownPacketType) {
+			t.Errorf("NewPacketInFromPacketType() err = %v; want %s %#x", err, ErrUnknownPacketType, typ)
 		}
 		if got != nil {
 			t.Errorf("NewPacketInFromPacketType() got = %T; want <nil>", got)
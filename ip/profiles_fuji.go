@@ -0,0 +1,228 @@
+package ip
+
+import (
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FujiProfile describes the differences between Fuji camera models. Support for a new model is added by registering a
+// profile using RegisterFujiProfile().
+type FujiProfile struct {
+	// Name is the model name, e.g. "X-T1".
+	Name string
+	// Models lists the names used to select the profile from the Responder friendly name. A name matches when the
+	// friendly name starts with it, ignoring case, and is not followed by a letter or a digit: "X-T1" matches "X-T1"
+	// and "X-T1 Studio" but not "X-T10".
+	Models []string
+	// InitSequence is the value set for DPC_Fuji_InitSequence when initialising the command/data connection. See
+	// PM_Fuji_InitSequence.
+	InitSequence uint32
+	// AppVersion is the minimal application version the Initiator reports in DPC_Fuji_AppVersion. When the Responder
+	// requires a higher version, the version required by the Responder is reported instead. See PM_Fuji_AppVersion.
+	AppVersion uint32
+	// Properties lists the device properties supported by the model. It is used to select the profile from the device
+	// info when the friendly name does not match any profile.
+	Properties []ptp.DevicePropCode
+	// StreamerPort is the port of the streamer connection. It is only used when the streamer port of the client was not
+	// set to a port differing from the command/data port.
+	StreamerPort uint16
+	// Viewfinder is the name of the viewfinder layout to use in the live view, see the viewfinder package.
+	Viewfinder string
+	// Init, when not nil, is called when initialising the command/data connection after the application version has
+	// been acknowledged and before the Responder hands over control to the Initiator. This allows for additional
+	// operations required by the model.
+	Init func(c *Client) error
+}
+
+// Supports returns true when the model supports the given device property.
+func (p *FujiProfile) Supports(code ptp.DevicePropCode) bool {
+	for _, pc := range p.Properties {
+		if pc == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesName returns true when one of the model names matches the given friendly name.
+func (p *FujiProfile) matchesName(name string) bool {
+	for _, m := range p.Models {
+		if len(name) < len(m) || !strings.EqualFold(name[:len(m)], m) {
+			continue
+		}
+		if rest := name[len(m):]; rest == "" || !unicode.IsLetter(rune(rest[0])) && !unicode.IsDigit(rune(rest[0])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FujiXT1Profile is the profile of the X-T1 on firmware version 5.51.
+var FujiXT1Profile = &FujiProfile{
+	Name:         "X-T1",
+	Models:       []string{"X-T1"},
+	InitSequence: PM_Fuji_InitSequence,
+	AppVersion:   PM_Fuji_AppVersion,
+	Properties: []ptp.DevicePropCode{
+		ptp.DPC_BatteryLevel,
+		ptp.DPC_WhiteBalance,
+		ptp.DPC_FNumber,
+		ptp.DPC_FocusMode,
+		ptp.DPC_FlashMode,
+		ptp.DPC_ExposureProgramMode,
+		ptp.DPC_ExposureBiasCompensation,
+		ptp.DPC_CaptureDelay,
+		DPC_Fuji_FilmSimulation,
+		DPC_Fuji_ImageQuality,
+		DPC_Fuji_RecMode,
+		DPC_Fuji_CommandDialMode,
+		DPC_Fuji_ExposureIndex,
+		DPC_Fuji_MovieISO,
+		DPC_Fuji_ImageSize,
+		DPC_Fuji_FocusMeteringMode,
+		DPC_Fuji_FocusLock,
+		DPC_Fuji_CurrentState,
+		DPC_Fuji_DeviceError,
+		DPC_Fuji_CapturesRemaining,
+		DPC_Fuji_MovieRemainingTime,
+		DPC_Fuji_ShutterSpeed,
+		DPC_Fuji_ImageAspectRatio,
+		DPC_Fuji_BatteryLevel,
+		DPC_Fuji_InitSequence,
+		DPC_Fuji_AppVersion,
+	},
+	StreamerPort: 55742,
+	Viewfinder:   "fuji-x-t1",
+}
+
+// DefaultFujiProfile is the profile used when no registered profile matches the Responder.
+var DefaultFujiProfile = FujiXT1Profile
+
+var (
+	fujiProfiles   = []*FujiProfile{FujiXT1Profile}
+	fujiProfilesMu sync.RWMutex
+)
+
+// RegisterFujiProfile adds a profile to the registry. Profiles are matched in the order they were registered.
+func RegisterFujiProfile(p *FujiProfile) {
+	fujiProfilesMu.Lock()
+	defer fujiProfilesMu.Unlock()
+
+	fujiProfiles = append(fujiProfiles, p)
+}
+
+// FujiProfiles returns all registered profiles.
+func FujiProfiles() []*FujiProfile {
+	fujiProfilesMu.RLock()
+	defer fujiProfilesMu.RUnlock()
+
+	return append([]*FujiProfile(nil), fujiProfiles...)
+}
+
+// FujiProfileForName returns the first registered profile matching the given Responder friendly name or nil when none
+// matches.
+func FujiProfileForName(name string) *FujiProfile {
+	for _, p := range FujiProfiles() {
+		if p.matchesName(name) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// FujiProfileForDeviceInfo returns the registered profile supporting all device properties in the given device info,
+// as returned by FujiGetDeviceInfo(), or nil when there is none. When several profiles qualify, the one supporting the
+// least properties is the closest match.
+func FujiProfileForDeviceInfo(list []*ptp.DevicePropDesc) *FujiProfile {
+	if len(list) == 0 {
+		return nil
+	}
+
+	var found *FujiProfile
+
+profiles:
+	for _, p := range FujiProfiles() {
+		for _, dpd := range list {
+			if !p.Supports(dpd.DevicePropertyCode) {
+				continue profiles
+			}
+		}
+		if found == nil || len(p.Properties) < len(found.Properties) {
+			found = p
+		}
+	}
+
+	return found
+}
+
+// FujiGetProfile returns the profile in use by the client. Before the profile has been selected, DefaultFujiProfile is
+// returned.
+func FujiGetProfile(c *Client) *FujiProfile {
+	if c.fujiProfile == nil {
+		return DefaultFujiProfile
+	}
+
+	return c.fujiProfile
+}
+
+// FujiSetProfile forces the client to use the given profile instead of selecting one. Must be called before calling
+// Dial().
+func FujiSetProfile(c *Client, p *FujiProfile) {
+	c.fujiProfile = p
+	fujiApplyProfile(c, p)
+}
+
+// FujiDetectProfile requests the device info and selects the profile supporting all device properties listed in it.
+// The profile in use is not changed when there is no such profile. Use this after calling Dial() when no profile matched
+// the Responder friendly name; see FujiProfileForName(). Note that the streamer port of the detected profile will only
+// be used the next time the client dials the Responder.
+func FujiDetectProfile(c *Client) (*FujiProfile, error) {
+	di, err := FujiGetDeviceInfo(c)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := di.([]*ptp.DevicePropDesc)
+	if !ok {
+		return nil, fmt.Errorf("unexpected device info type %T", di)
+	}
+
+	p := FujiProfileForDeviceInfo(list)
+	if p != nil {
+		c.infow(SubsystemVendor, "selected profile from device info", "responder", c.ResponderFriendlyName(), "profile", p.Name)
+		FujiSetProfile(c, p)
+	}
+
+	return p, nil
+}
+
+// fujiSelectProfile selects the profile matching the Responder friendly name, unless a profile is in use already. It
+// returns the profile to initialise the command/data connection with, which is DefaultFujiProfile when no profile
+// matches. In that case the client's profile remains unset so that it can be detected from the device info later on.
+func fujiSelectProfile(c *Client) *FujiProfile {
+	if c.fujiProfile != nil {
+		return c.fujiProfile
+	}
+
+	if p := FujiProfileForName(c.ResponderFriendlyName()); p != nil {
+		c.infow(SubsystemVendor, "selected profile from friendly name", "responder", c.ResponderFriendlyName(), "profile", p.Name)
+		FujiSetProfile(c, p)
+		return p
+	}
+
+	c.infow(SubsystemVendor, "no profile matches friendly name, using default profile", "responder", c.ResponderFriendlyName(), "profile", DefaultFujiProfile.Name)
+	return DefaultFujiProfile
+}
+
+// fujiApplyProfile applies the streamer port of the profile when the client does not use a separate streamer port yet.
+func fujiApplyProfile(c *Client, p *FujiProfile) {
+	if p.StreamerPort != 0 && c.responder.StreamerPort == c.responder.CommandDataPort {
+		c.responder.StreamerPort = p.StreamerPort
+	}
+}
@@ -0,0 +1,162 @@
+package ip_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strings"
+	"testing"
+)
+
+func TestFujiProfileForName(t *testing.T) {
+	check := map[string]*ip.FujiProfile{
+		"X-T1":          ip.FujiXT1Profile,
+		"x-t1 studio":   ip.FujiXT1Profile,
+		"X-T1-1234":     ip.FujiXT1Profile,
+		"X-T10":         nil,
+		"X-T":           nil,
+		"FUJIFILM X-T1": nil,
+		"":              nil,
+	}
+
+	for name, want := range check {
+		if got := ip.FujiProfileForName(name); got != want {
+			t.Errorf("FujiProfileForName(%q) got = %v; want %v", name, got, want)
+		}
+	}
+}
+
+func TestFujiProfileForDeviceInfo(t *testing.T) {
+	small := &ip.FujiProfile{Name: "small", Properties: []ptp.DevicePropCode{ptp.DPC_BatteryLevel}}
+	defer ip.SetFujiProfiles([]*ip.FujiProfile{ip.FujiXT1Profile, small})()
+
+	list := func(codes ...ptp.DevicePropCode) []*ptp.DevicePropDesc {
+		var l []*ptp.DevicePropDesc
+		for _, c := range codes {
+			l = append(l, &ptp.DevicePropDesc{DevicePropertyCode: c})
+		}
+		return l
+	}
+
+	if got := ip.FujiProfileForDeviceInfo(list(ptp.DPC_BatteryLevel)); got != small {
+		t.Errorf("FujiProfileForDeviceInfo() got = %v; want %v", got, small)
+	}
+	if got := ip.FujiProfileForDeviceInfo(list(ptp.DPC_BatteryLevel, ip.DPC_Fuji_FilmSimulation)); got != ip.FujiXT1Profile {
+		t.Errorf("FujiProfileForDeviceInfo() got = %v; want %v", got, ip.FujiXT1Profile)
+	}
+	if got := ip.FujiProfileForDeviceInfo(list(ptp.DPC_BatteryLevel, 0xD999)); got != nil {
+		t.Errorf("FujiProfileForDeviceInfo() got = %v; want <nil>", got)
+	}
+	if got := ip.FujiProfileForDeviceInfo(nil); got != nil {
+		t.Errorf("FujiProfileForDeviceInfo() got = %v; want <nil>", got)
+	}
+}
+
+func TestFujiProfile_Supports(t *testing.T) {
+	if !ip.FujiXT1Profile.Supports(ip.DPC_Fuji_FilmSimulation) {
+		t.Errorf("Supports() got = false; want true")
+	}
+	if ip.FujiXT1Profile.Supports(0xD999) {
+		t.Errorf("Supports() got = true; want false")
+	}
+}
+
+func TestFujiProfileDial(t *testing.T) {
+	var called bool
+	p := &ip.FujiProfile{
+		Name:         "iptest",
+		Models:       []string{"iptest"},
+		InitSequence: 0x00000006,
+		AppVersion:   0x00030000,
+		Init: func(c *ip.Client) error {
+			called = true
+			return nil
+		},
+	}
+	defer ip.SetFujiProfiles([]*ip.FujiProfile{ip.FujiXT1Profile, p})()
+
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ip.FujiGetProfile(c); got != p {
+		t.Errorf("FujiGetProfile() got = %v; want %v", got, p)
+	}
+	if !called {
+		t.Error("Dial() did not call the profile init hook")
+	}
+	for code, want := range map[ptp.DevicePropCode]uint32{ip.DPC_Fuji_InitSequence: p.InitSequence, ip.DPC_Fuji_AppVersion: p.AppVersion} {
+		b, ok := res.DevicePropValue(code)
+		if !ok || len(b) < 4 {
+			t.Fatalf("DevicePropValue(%#x) got = %v; want 4 bytes", code, b)
+		}
+		if got := binary.LittleEndian.Uint32(b); got != want {
+			t.Errorf("DevicePropValue(%#x) got = %#x; want %#x", code, got, want)
+		}
+	}
+}
+
+func TestFujiProfileDialInitError(t *testing.T) {
+	want := errors.New("init failed")
+	p := &ip.FujiProfile{
+		Name:         "iptest",
+		Models:       []string{"iptest"},
+		InitSequence: ip.PM_Fuji_InitSequence,
+		Init: func(c *ip.Client) error {
+			return want
+		},
+	}
+	defer ip.SetFujiProfiles([]*ip.FujiProfile{p})()
+
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err == nil || !strings.HasSuffix(err.Error(), want.Error()) {
+		t.Errorf("Dial() error = %v; want %s", err, want)
+	}
+}
+
+func TestFujiDetectProfile(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The friendly name of the responder does not match any profile.
+	if res.Received(ip.OC_Fuji_GetDeviceInfo) {
+		t.Error("Dial() requested the device info; want it to use the default profile")
+	}
+
+	got, err := ip.FujiDetectProfile(c)
+	if err != nil {
+		t.Fatalf("FujiDetectProfile() error = %s; want <nil>", err)
+	}
+	if got != ip.FujiXT1Profile {
+		t.Errorf("FujiDetectProfile() got = %v; want %v", got, ip.FujiXT1Profile)
+	}
+	if got := ip.FujiGetProfile(c); got != ip.FujiXT1Profile {
+		t.Errorf("FujiGetProfile() got = %v; want %v", got, ip.FujiXT1Profile)
+	}
+}
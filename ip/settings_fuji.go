@@ -0,0 +1,134 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ptp"
+)
+
+// ErrFujiValueNotSupported is returned when setting a device property to a value that is not listed in the enumeration
+// form of the property description returned by the Responder.
+var ErrFujiValueNotSupported = errors.New("value not supported by the camera")
+
+// FujiISO is the decoded value of DPC_Fuji_ExposureIndex.
+type FujiISO struct {
+	// Auto indicates the camera selects the sensitivity. When Value is not 0, it is the maximum sensitivity the camera
+	// will select, e.g. 'S6400'.
+	Auto bool
+	// Extended indicates an extended sensitivity outside of the native range of the sensor, e.g. 'L100' or 'H25600'.
+	Extended bool
+	// Value is the sensitivity, e.g. 400.
+	Value uint16
+}
+
+// NewFujiISO decodes the given value of DPC_Fuji_ExposureIndex using the EDX_Fuji_ flags.
+func NewFujiISO(edx FujiExposureIndex) FujiISO {
+	if edx == EDX_Fuji_Auto {
+		return FujiISO{Auto: true}
+	}
+
+	flags := uint16(edx >> 16)
+
+	return FujiISO{
+		Auto:     flags&EDX_Fuji_MaxSensitivity != 0,
+		Extended: flags&EDX_Fuji_Extended != 0,
+		Value:    uint16(edx),
+	}
+}
+
+// ExposureIndex encodes the ISO as value of DPC_Fuji_ExposureIndex.
+func (iso FujiISO) ExposureIndex() FujiExposureIndex {
+	if iso.Auto && iso.Value == 0 {
+		return EDX_Fuji_Auto
+	}
+
+	var flags uint16
+	if iso.Auto {
+		flags |= EDX_Fuji_MaxSensitivity
+	}
+	if iso.Extended {
+		flags |= EDX_Fuji_Extended
+	}
+
+	return FujiExposureIndex(uint32(flags)<<16 | uint32(iso.Value))
+}
+
+// fujiSetEnumProperty validates the given value against the enumeration form of the device property description before
+// setting it. When the Responder does not describe the property or does not use an enumeration form, the value is set
+// without validation.
+func fujiSetEnumProperty(c *Client, code ptp.DevicePropCode, val uint32) error {
+	dpd, err := FujiGetDevicePropertyDesc(c, code)
+	if err != nil {
+		return err
+	}
+
+	if dpd == nil {
+		c.warnw(SubsystemVendor, "property cannot be described, not validating value", "responder", c.ResponderFriendlyName(), "property", code, "value", fmt.Sprintf("%#x", val))
+	} else if form, ok := dpd.Form.(*ptp.EnumerationForm); ok {
+		supported := false
+		for _, v := range form.SupportedValuesAsInt64Array() {
+			if v == int64(val) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return fmt.Errorf("%w: %#x for property %#x", ErrFujiValueNotSupported, val, uint16(code))
+		}
+	}
+
+	return FujiSetDeviceProperty(c, code, val)
+}
+
+// FujiGetFilmSimulation returns the current film simulation.
+func FujiGetFilmSimulation(c *Client) (FujiFilmSimulation, error) {
+	v, err := FujiGetDevicePropertyValue(c, DPC_Fuji_FilmSimulation)
+
+	return FujiFilmSimulation(v), err
+}
+
+// FujiSetFilmSimulation sets the film simulation, returning ErrFujiValueNotSupported when the camera does not support
+// it.
+func FujiSetFilmSimulation(c *Client, fs FujiFilmSimulation) error {
+	return fujiSetEnumProperty(c, DPC_Fuji_FilmSimulation, uint32(fs))
+}
+
+// FujiGetISO returns the current ISO setting.
+func FujiGetISO(c *Client) (FujiISO, error) {
+	v, err := FujiGetDevicePropertyValue(c, DPC_Fuji_ExposureIndex)
+	if err != nil {
+		return FujiISO{}, err
+	}
+
+	return NewFujiISO(FujiExposureIndex(v)), nil
+}
+
+// FujiSetISO sets the ISO, returning ErrFujiValueNotSupported when the camera does not support it.
+func FujiSetISO(c *Client, iso FujiISO) error {
+	return fujiSetEnumProperty(c, DPC_Fuji_ExposureIndex, uint32(iso.ExposureIndex()))
+}
+
+// FujiGetImageQuality returns the current image quality.
+func FujiGetImageQuality(c *Client) (FujiImageQuality, error) {
+	v, err := FujiGetDevicePropertyValue(c, DPC_Fuji_ImageQuality)
+
+	return FujiImageQuality(v), err
+}
+
+// FujiSetImageQuality sets the image quality, returning ErrFujiValueNotSupported when the camera does not support it.
+func FujiSetImageQuality(c *Client, iq FujiImageQuality) error {
+	return fujiSetEnumProperty(c, DPC_Fuji_ImageQuality, uint32(iq))
+}
+
+// FujiGetSelfTimer returns the current self-timer setting.
+func FujiGetSelfTimer(c *Client) (FujiSelfTimer, error) {
+	v, err := FujiGetDevicePropertyValue(c, ptp.DPC_CaptureDelay)
+
+	return FujiSelfTimer(v), err
+}
+
+// FujiSetSelfTimer sets the self-timer, returning ErrFujiValueNotSupported when the camera does not support the given
+// delay.
+func FujiSetSelfTimer(c *Client, st FujiSelfTimer) error {
+	return fujiSetEnumProperty(c, ptp.DPC_CaptureDelay, uint32(st))
+}
@@ -0,0 +1,116 @@
+package ip_test
+
+import (
+	"errors"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"testing"
+)
+
+func TestNewFujiISO(t *testing.T) {
+	check := map[ip.FujiExposureIndex]ip.FujiISO{
+		ip.EDX_Fuji_Auto: {Auto: true},
+		0x80001900:       {Auto: true, Value: 6400},
+		0x40000064:       {Extended: true, Value: 100},
+		0x40006400:       {Extended: true, Value: 25600},
+		0x00000190:       {Value: 400},
+	}
+
+	for edx, want := range check {
+		got := ip.NewFujiISO(edx)
+		if got != want {
+			t.Errorf("NewFujiISO(%#x) got = %+v; want %+v", uint32(edx), got, want)
+		}
+		if got := want.ExposureIndex(); got != edx {
+			t.Errorf("ExposureIndex() got = %#x; want %#x", uint32(got), uint32(edx))
+		}
+	}
+}
+
+func TestFujiSetFilmSimulation(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := ip.FujiSetFilmSimulation(c, ip.FS_Fuji_ClassicChrome); err != nil {
+		t.Fatalf("FujiSetFilmSimulation() error = %s; want <nil>", err)
+	}
+	if got, err := ip.FujiGetFilmSimulation(c); err != nil || got != ip.FS_Fuji_ClassicChrome {
+		t.Errorf("FujiGetFilmSimulation() got = %#x, error = %v; want %#x, <nil>", got, err, ip.FS_Fuji_ClassicChrome)
+	}
+
+	n := len(res.RequestsFor(ptp.OC_SetDevicePropValue))
+	if err := ip.FujiSetFilmSimulation(c, ip.FS_Fuji_ETERNA); !errors.Is(err, ip.ErrFujiValueNotSupported) {
+		t.Errorf("FujiSetFilmSimulation() error = %v; want %s", err, ip.ErrFujiValueNotSupported)
+	}
+	if got := len(res.RequestsFor(ptp.OC_SetDevicePropValue)); got != n {
+		t.Errorf("FujiSetFilmSimulation() sent %d requests; want none", got-n)
+	}
+}
+
+func TestFujiSetISO(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	for _, iso := range []ip.FujiISO{{Auto: true, Value: 3200}, {Extended: true, Value: 100}, {Value: 800}} {
+		if err := ip.FujiSetISO(c, iso); err != nil {
+			t.Fatalf("FujiSetISO(%+v) error = %s; want <nil>", iso, err)
+		}
+		if got, err := ip.FujiGetISO(c); err != nil || got != iso {
+			t.Errorf("FujiGetISO() got = %+v, error = %v; want %+v, <nil>", got, err, iso)
+		}
+	}
+
+	// The X-T1 only offers automatic ISO with a maximum sensitivity.
+	for _, iso := range []ip.FujiISO{{Value: 123}, {Auto: true}} {
+		if err := ip.FujiSetISO(c, iso); !errors.Is(err, ip.ErrFujiValueNotSupported) {
+			t.Errorf("FujiSetISO(%+v) error = %v; want %s", iso, err, ip.ErrFujiValueNotSupported)
+		}
+	}
+}
+
+func TestFujiSetImageQuality(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if got, err := ip.FujiGetImageQuality(c); err != nil || got != ip.IQ_Fuji_Fine {
+		t.Errorf("FujiGetImageQuality() got = %#x, error = %v; want %#x, <nil>", got, err, ip.IQ_Fuji_Fine)
+	}
+	if err := ip.FujiSetImageQuality(c, ip.IQ_Fuji_FineAndRAW); err != nil {
+		t.Fatalf("FujiSetImageQuality() error = %s; want <nil>", err)
+	}
+	if got, err := ip.FujiGetImageQuality(c); err != nil || got != ip.IQ_Fuji_FineAndRAW {
+		t.Errorf("FujiGetImageQuality() got = %#x, error = %v; want %#x, <nil>", got, err, ip.IQ_Fuji_FineAndRAW)
+	}
+	if err := ip.FujiSetImageQuality(c, ip.FujiImageQuality(1)); !errors.Is(err, ip.ErrFujiValueNotSupported) {
+		t.Errorf("FujiSetImageQuality() error = %v; want %s", err, ip.ErrFujiValueNotSupported)
+	}
+}
+
+func TestFujiSetSelfTimer(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c := res.DialClient(t, "testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	defer c.Close()
+
+	if err := ip.FujiSetSelfTimer(c, ip.ST_Fuji_10Sec); err != nil {
+		t.Fatalf("FujiSetSelfTimer() error = %s; want <nil>", err)
+	}
+	if got, err := ip.FujiGetSelfTimer(c); err != nil || got != ip.ST_Fuji_10Sec {
+		t.Errorf("FujiGetSelfTimer() got = %#x, error = %v; want %#x, <nil>", got, err, ip.ST_Fuji_10Sec)
+	}
+	// The X-T1 only offers a 2 and 10 second delay.
+	if err := ip.FujiSetSelfTimer(c, ip.ST_Fuji_1Sec); !errors.Is(err, ip.ErrFujiValueNotSupported) {
+		t.Errorf("FujiSetSelfTimer() error = %v; want %s", err, ip.ErrFujiValueNotSupported)
+	}
+}
@@ -0,0 +1,169 @@
+package ip
+
+import (
+	"github.com/malc0mn/ptp-ip/ptp"
+	"time"
+)
+
+// FujiStateChangeKind indicates what a FujiStateChange is about.
+type FujiStateChangeKind int
+
+const (
+	// FujiPropertyChanged reports a property change not covered by any of the other kinds.
+	FujiPropertyChanged FujiStateChangeKind = iota
+	// FujiDeviceErrorRaised reports DPC_Fuji_DeviceError changing to a value other than DE_Fuji_None.
+	FujiDeviceErrorRaised
+	// FujiDeviceErrorCleared reports DPC_Fuji_DeviceError changing back to DE_Fuji_None.
+	FujiDeviceErrorCleared
+	// FujiBatteryLevelDropped reports the battery level decreasing.
+	FujiBatteryLevelDropped
+	// FujiCapturesExhausted reports DPC_Fuji_CapturesRemaining reaching zero: the storage cannot hold another capture.
+	FujiCapturesExhausted
+	// FujiCommandDialModeChanged reports a change of DPC_Fuji_CommandDialMode.
+	FujiCommandDialModeChanged
+	// FujiExposureProgramModeChanged reports a change of ptp.DPC_ExposureProgramMode, e.g. by turning the mode dial.
+	FujiExposureProgramModeChanged
+)
+
+func (k FujiStateChangeKind) String() string {
+	switch k {
+	case FujiPropertyChanged:
+		return "property changed"
+	case FujiDeviceErrorRaised:
+		return "device error raised"
+	case FujiDeviceErrorCleared:
+		return "device error cleared"
+	case FujiBatteryLevelDropped:
+		return "battery level dropped"
+	case FujiCapturesExhausted:
+		return "captures exhausted"
+	case FujiCommandDialModeChanged:
+		return "command dial mode changed"
+	case FujiExposureProgramModeChanged:
+		return "exposure program mode changed"
+	}
+
+	return "unknown"
+}
+
+// FujiStateChange is a change of a property in the device state as returned by FujiGetDeviceState().
+type FujiStateChange struct {
+	Kind     FujiStateChangeKind
+	Property ptp.DevicePropCode
+	Previous int64
+	Current  int64
+}
+
+// DeviceError returns the current value as FujiDeviceError. Use this for FujiDeviceErrorRaised; for
+// FujiDeviceErrorCleared the error that was cleared is held by Previous. The meaning of the values other than
+// DE_Fuji_None is unknown, so the raw value is all there is to report.
+func (sc FujiStateChange) DeviceError() FujiDeviceError {
+	return FujiDeviceError(sc.Current)
+}
+
+// BatteryLevel returns the current value as FujiBatteryLevel.
+func (sc FujiStateChange) BatteryLevel() FujiBatteryLevel {
+	return FujiBatteryLevel(sc.Current)
+}
+
+// CommandDialMode returns the current value as FujiCommandDialMode.
+func (sc FujiStateChange) CommandDialMode() FujiCommandDialMode {
+	return FujiCommandDialMode(sc.Current)
+}
+
+// FujiDiffState compares two device states as returned by FujiGetDeviceState() and returns the changes in the order
+// the properties appear in the current state. Properties missing from the previous state are not reported as changed:
+// which properties are returned depends on the exposure program mode.
+func FujiDiffState(prev, cur []*ptp.DevicePropDesc) []FujiStateChange {
+	old := make(map[ptp.DevicePropCode]int64, len(prev))
+	for _, dpd := range prev {
+		old[dpd.DevicePropertyCode] = dpd.CurrentValueAsInt64()
+	}
+
+	var changes []FujiStateChange
+	for _, dpd := range cur {
+		p, ok := old[dpd.DevicePropertyCode]
+		v := dpd.CurrentValueAsInt64()
+		if !ok || p == v {
+			continue
+		}
+		changes = append(changes, FujiStateChange{
+			Kind:     fujiStateChangeKind(dpd.DevicePropertyCode, p, v),
+			Property: dpd.DevicePropertyCode,
+			Previous: p,
+			Current:  v,
+		})
+	}
+
+	return changes
+}
+
+// fujiStateChangeKind determines the kind of change of the given property from the previous to the current value.
+func fujiStateChangeKind(code ptp.DevicePropCode, prev, cur int64) FujiStateChangeKind {
+	switch code {
+	case DPC_Fuji_DeviceError:
+		if FujiDeviceError(cur) == DE_Fuji_None {
+			return FujiDeviceErrorCleared
+		}
+		return FujiDeviceErrorRaised
+	case ptp.DPC_BatteryLevel, DPC_Fuji_BatteryLevel:
+		if cur < prev {
+			return FujiBatteryLevelDropped
+		}
+	case DPC_Fuji_CapturesRemaining:
+		if cur == 0 {
+			return FujiCapturesExhausted
+		}
+	case DPC_Fuji_CommandDialMode:
+		return FujiCommandDialModeChanged
+	case ptp.DPC_ExposureProgramMode:
+		return FujiExposureProgramModeChanged
+	}
+
+	return FujiPropertyChanged
+}
+
+// FujiWatchState calls FujiGetDeviceState() every interval and sends the changes compared to the previous call on the
+// returned channel until done is closed. Fuji does not report property changes over the event connection, which is why
+// the state is polled. The first call only serves as the initial snapshot so no changes are reported for it. The
+// channel is closed when watching stops, which also happens when the connection to the Responder is lost.
+func FujiWatchState(c *Client, interval time.Duration, done <-chan struct{}) <-chan FujiStateChange {
+	ch := make(chan FujiStateChange, 10)
+
+	go func() {
+		defer close(ch)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		var prev []*ptp.DevicePropDesc
+		for {
+			s, err := FujiGetDeviceState(c)
+			if err != nil {
+				if err != WaitForResponseError {
+					c.errorw(SubsystemVendor, "state watcher stopped", "error", err)
+					return
+				}
+			} else {
+				cur := s.([]*ptp.DevicePropDesc)
+				for _, sc := range FujiDiffState(prev, cur) {
+					c.debugw(SubsystemVendor, "state changed", "kind", sc.Kind, "property", sc.Property, "previous", sc.Previous, "current", sc.Current)
+					select {
+					case ch <- sc:
+					case <-done:
+						return
+					}
+				}
+				prev = cur
+			}
+
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+
+	return ch
+}
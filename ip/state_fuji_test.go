@@ -0,0 +1,149 @@
+package ip_test
+
+import (
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fujiState(values map[ptp.DevicePropCode]uint32) []*ptp.DevicePropDesc {
+	var list []*ptp.DevicePropDesc
+	for _, code := range []ptp.DevicePropCode{ptp.DPC_BatteryLevel, ptp.DPC_ExposureProgramMode, ip.DPC_Fuji_CommandDialMode, ip.DPC_Fuji_DeviceError, ip.DPC_Fuji_CapturesRemaining, ip.DPC_Fuji_FilmSimulation} {
+		v, ok := values[code]
+		if !ok {
+			continue
+		}
+		list = append(list, &ptp.DevicePropDesc{
+			DevicePropertyCode: code,
+			DataType:           ptp.DTC_UINT32,
+			CurrentValue:       []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)},
+		})
+	}
+
+	return list
+}
+
+func TestFujiDiffState(t *testing.T) {
+	prev := fujiState(map[ptp.DevicePropCode]uint32{
+		ptp.DPC_BatteryLevel:          uint32(ip.BAT_Fuji_3bFull),
+		ptp.DPC_ExposureProgramMode:   uint32(ptp.EPM_Manual),
+		ip.DPC_Fuji_CommandDialMode:   uint32(ip.CMD_Fuji_Both),
+		ip.DPC_Fuji_DeviceError:       uint32(ip.DE_Fuji_None),
+		ip.DPC_Fuji_CapturesRemaining: 1,
+		ip.DPC_Fuji_FilmSimulation:    uint32(ip.FS_Fuji_Provia),
+	})
+
+	cur := fujiState(map[ptp.DevicePropCode]uint32{
+		ptp.DPC_BatteryLevel:          uint32(ip.BAT_Fuji_3bTwo),
+		ptp.DPC_ExposureProgramMode:   uint32(ptp.EPM_AperturePriority),
+		ip.DPC_Fuji_CommandDialMode:   uint32(ip.CMD_Fuji_None),
+		ip.DPC_Fuji_DeviceError:       uint32(ip.FujiDeviceError(0x3)),
+		ip.DPC_Fuji_CapturesRemaining: 0,
+		ip.DPC_Fuji_FilmSimulation:    uint32(ip.FS_Fuji_Velvia),
+	})
+	want := []ip.FujiStateChange{
+		{Kind: ip.FujiBatteryLevelDropped, Property: ptp.DPC_BatteryLevel, Previous: int64(ip.BAT_Fuji_3bFull), Current: int64(ip.BAT_Fuji_3bTwo)},
+		{Kind: ip.FujiExposureProgramModeChanged, Property: ptp.DPC_ExposureProgramMode, Previous: int64(ptp.EPM_Manual), Current: int64(ptp.EPM_AperturePriority)},
+		{Kind: ip.FujiCommandDialModeChanged, Property: ip.DPC_Fuji_CommandDialMode, Previous: int64(ip.CMD_Fuji_Both), Current: int64(ip.CMD_Fuji_None)},
+		{Kind: ip.FujiDeviceErrorRaised, Property: ip.DPC_Fuji_DeviceError, Previous: int64(ip.DE_Fuji_None), Current: int64(ip.FujiDeviceError(0x3))},
+		{Kind: ip.FujiCapturesExhausted, Property: ip.DPC_Fuji_CapturesRemaining, Previous: 1, Current: 0},
+		{Kind: ip.FujiPropertyChanged, Property: ip.DPC_Fuji_FilmSimulation, Previous: int64(ip.FS_Fuji_Provia), Current: int64(ip.FS_Fuji_Velvia)},
+	}
+	if got := ip.FujiDiffState(prev, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("FujiDiffState() got = %v; want %v", got, want)
+	}
+
+	// Charging the battery, clearing the error and freeing up space.
+	want = []ip.FujiStateChange{
+		{Kind: ip.FujiPropertyChanged, Property: ptp.DPC_BatteryLevel, Previous: int64(ip.BAT_Fuji_3bTwo), Current: int64(ip.BAT_Fuji_3bFull)},
+		{Kind: ip.FujiExposureProgramModeChanged, Property: ptp.DPC_ExposureProgramMode, Previous: int64(ptp.EPM_AperturePriority), Current: int64(ptp.EPM_Manual)},
+		{Kind: ip.FujiCommandDialModeChanged, Property: ip.DPC_Fuji_CommandDialMode, Previous: int64(ip.CMD_Fuji_None), Current: int64(ip.CMD_Fuji_Both)},
+		{Kind: ip.FujiDeviceErrorCleared, Property: ip.DPC_Fuji_DeviceError, Previous: int64(ip.FujiDeviceError(0x3)), Current: int64(ip.DE_Fuji_None)},
+		{Kind: ip.FujiPropertyChanged, Property: ip.DPC_Fuji_CapturesRemaining, Previous: 0, Current: 1},
+		{Kind: ip.FujiPropertyChanged, Property: ip.DPC_Fuji_FilmSimulation, Previous: int64(ip.FS_Fuji_Velvia), Current: int64(ip.FS_Fuji_Provia)},
+	}
+	if got := ip.FujiDiffState(cur, prev); !reflect.DeepEqual(got, want) {
+		t.Errorf("FujiDiffState() got = %v; want %v", got, want)
+	}
+
+	if got := ip.FujiDiffState(prev, prev); got != nil {
+		t.Errorf("FujiDiffState() got = %v; want <nil>", got)
+	}
+	if got := ip.FujiDiffState(nil, cur); got != nil {
+		t.Errorf("FujiDiffState() got = %v; want <nil>", got)
+	}
+}
+
+func TestFujiStateChange_DeviceError(t *testing.T) {
+	sc := ip.FujiStateChange{Kind: ip.FujiDeviceErrorRaised, Property: ip.DPC_Fuji_DeviceError, Current: int64(ip.FujiDeviceError(0x5))}
+	if got := sc.DeviceError(); got != ip.FujiDeviceError(0x5) {
+		t.Errorf("DeviceError() got = %#x; want %#x", got, ip.FujiDeviceError(0x5))
+	}
+}
+
+func TestFujiStateChangeKind_String(t *testing.T) {
+	check := map[ip.FujiStateChangeKind]string{
+		ip.FujiPropertyChanged:            "property changed",
+		ip.FujiDeviceErrorRaised:          "device error raised",
+		ip.FujiDeviceErrorCleared:         "device error cleared",
+		ip.FujiBatteryLevelDropped:        "battery level dropped",
+		ip.FujiCapturesExhausted:          "captures exhausted",
+		ip.FujiCommandDialModeChanged:     "command dial mode changed",
+		ip.FujiExposureProgramModeChanged: "exposure program mode changed",
+		ip.FujiStateChangeKind(99):        "unknown",
+	}
+
+	for k, want := range check {
+		if got := k.String(); got != want {
+			t.Errorf("String() got = %s; want %s", got, want)
+		}
+	}
+}
+
+func TestFujiWatchState(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+
+	c, err := res.NewClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	ch := ip.FujiWatchState(c, 20*time.Millisecond, done)
+
+	// Wait for the second poll to make sure the initial snapshot has been taken.
+	polls := func() int {
+		n := 0
+		for _, req := range res.RequestsFor(ptp.OC_GetDevicePropValue) {
+			if ptp.DevicePropCode(req.Parameter(1)) == ip.DPC_Fuji_CurrentState {
+				n++
+			}
+		}
+		return n
+	}
+	for polls() < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	res.SetFujiStateValue(ip.DPC_Fuji_DeviceError, uint32(ip.FujiDeviceError(0x6)))
+
+	select {
+	case sc := <-ch:
+		if sc.Kind != ip.FujiDeviceErrorRaised || sc.DeviceError() != ip.FujiDeviceError(0x6) {
+			t.Errorf("FujiWatchState() got = %v; want %s %#x", sc, ip.FujiDeviceErrorRaised, ip.FujiDeviceError(0x6))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("FujiWatchState() timed out waiting for a change")
+	}
+
+	close(done)
+	for range ch {
+	}
+}
@@ -0,0 +1,315 @@
+package ip
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStreamBufferSize is the default amount of frames that can be queued on the StreamChan before the
+// FrameDropPolicy kicks in.
+const DefaultStreamBufferSize = 50
+
+// FrameDropPolicy determines which frame is dropped when the consumer of the StreamChan cannot keep up with the frames
+// coming in from the Responder.
+type FrameDropPolicy int
+
+const (
+	// DropOldest discards the oldest frame in the queue to make room for the frame that was just received. This keeps
+	// the latency as low as possible and is the default.
+	DropOldest FrameDropPolicy = iota
+	// DropNewest discards the frame that was just received when the queue is full.
+	DropNewest
+)
+
+var framePool = sync.Pool{
+	New: func() interface{} {
+		return new(Frame)
+	},
+}
+
+// Frame is a single frame received from the Responder's streamer connection. Frames are pooled to avoid allocating a
+// new buffer for every frame, so the consumer must call Release() once it is done with the frame. The Frame, including
+// its Data, must not be used after calling Release().
+type Frame struct {
+	// Data holds the raw image data.
+	Data []byte
+	// Counter is the frame counter as sent by the Responder. It will wrap around depending on the vendor's
+	// implementation, e.g. Fuji only uses a single byte.
+	Counter uint32
+	// Received is the time the frame was read from the streamer connection.
+	Received time.Time
+	// Dropped is the total number of frames that were dropped by the client since live view was enabled, at the time
+	// this frame was queued.
+	Dropped uint64
+	// Meta holds the vendor specific metadata sent along with the frame, e.g. a FujiStreamFrame for Fuji devices, or
+	// nil when the vendor sends none.
+	Meta interface{}
+
+	buf bytes.Buffer
+}
+
+// newFrame returns an empty frame from the pool.
+func newFrame() *Frame {
+	f := framePool.Get().(*Frame)
+	f.buf.Reset()
+
+	return f
+}
+
+// Release hands the frame back to the pool.
+func (f *Frame) Release() {
+	f.Data = nil
+	f.Counter = 0
+	f.Received = time.Time{}
+	f.Dropped = 0
+	f.Meta = nil
+	framePool.Put(f)
+}
+
+// SetStreamDropPolicy sets the policy to use when the StreamChan is full. Must be called before enabling live view.
+func (c *Client) SetStreamDropPolicy(p FrameDropPolicy) {
+	c.streamDropPolicy = p
+}
+
+// SetStreamBufferSize sets the amount of frames that can be queued on the StreamChan. A small buffer means low latency
+// but more dropped frames when the consumer cannot keep up. The size must be at least 1. Must be called before enabling
+// live view.
+func (c *Client) SetStreamBufferSize(size int) error {
+	if size < 1 {
+		return fmt.Errorf("invalid stream buffer size %d: must be at least 1", size)
+	}
+	c.streamBufferSize = size
+
+	return nil
+}
+
+// DroppedFrames returns the total number of frames that were dropped since live view was enabled.
+func (c *Client) DroppedFrames() uint64 {
+	return atomic.LoadUint64(&c.droppedFrames)
+}
+
+// StreamStats holds statistics about the live view stream since it was last enabled.
+type StreamStats struct {
+	// Frames is the number of frames received from the Responder.
+	Frames uint64
+	// FPS is the number of frames received per second, measured over the last second. It is zero when no frame was
+	// received for the last two seconds.
+	FPS float64
+	// Dropped is the number of frames dropped by the client because the consumer of the StreamChan could not keep up.
+	Dropped uint64
+	// Missed is the number of frames that never arrived, detected using the frame counter sent by the Responder.
+	Missed uint64
+	// OutOfOrder is the number of frames that were discarded because they arrived after a more recent frame.
+	OutOfOrder uint64
+	// Duplicates is the number of frames carrying the same counter as the frame received before them. These frames are
+	// not discarded.
+	Duplicates uint64
+	// AverageSize is the average size of the image data in bytes.
+	AverageSize int
+}
+
+// streamStats collects the StreamStats of a client.
+type streamStats struct {
+	mu         sync.Mutex
+	frames     uint64
+	bytes      uint64
+	missed     uint64
+	outOfOrder uint64
+	duplicates uint64
+	fps        float64
+	fpsFrames  int
+	fpsStart   time.Time
+	lastFrame  time.Time
+}
+
+// reset clears the statistics.
+func (s *streamStats) reset() {
+	s.mu.Lock()
+	s.frames, s.bytes, s.missed, s.outOfOrder, s.duplicates = 0, 0, 0, 0, 0
+	s.fps, s.fpsFrames = 0, 0
+	s.fpsStart, s.lastFrame = time.Time{}, time.Time{}
+	s.mu.Unlock()
+}
+
+// frameReceived records a frame holding size bytes of image data, received at the given time.
+func (s *streamStats) frameReceived(size int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames++
+	s.bytes += uint64(size)
+	s.lastFrame = at
+	s.fpsFrames++
+	if s.fpsStart.IsZero() {
+		s.fpsStart = at
+	} else if elapsed := at.Sub(s.fpsStart); elapsed >= time.Second {
+		s.fps = float64(s.fpsFrames) / elapsed.Seconds()
+		s.fpsFrames = 0
+		s.fpsStart = at
+	}
+}
+
+// framesMissed records frames that never arrived.
+func (s *streamStats) framesMissed(n int) {
+	s.mu.Lock()
+	s.missed += uint64(n)
+	s.mu.Unlock()
+}
+
+// frameOutOfOrder records a frame that arrived after a more recent frame.
+func (s *streamStats) frameOutOfOrder() {
+	s.mu.Lock()
+	s.outOfOrder++
+	s.mu.Unlock()
+}
+
+// frameDuplicate records a frame carrying the same counter as the frame received before it.
+func (s *streamStats) frameDuplicate() {
+	s.mu.Lock()
+	s.duplicates++
+	s.mu.Unlock()
+}
+
+// StreamStats returns the statistics of the live view stream since it was last enabled.
+func (c *Client) StreamStats() StreamStats {
+	s := &c.streamStats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := StreamStats{
+		Frames:     s.frames,
+		Dropped:    c.DroppedFrames(),
+		Missed:     s.missed,
+		OutOfOrder: s.outOfOrder,
+		Duplicates: s.duplicates,
+	}
+	// Do not keep reporting the last known frame rate when live view has stopped.
+	if time.Since(s.lastFrame) < 2*time.Second {
+		st.FPS = s.fps
+	}
+	if s.frames > 0 {
+		st.AverageSize = int(s.bytes / s.frames)
+	}
+
+	return st
+}
+
+// resetStream clears the dropped frames counter and the stream statistics when live view is enabled.
+func (c *Client) resetStream() {
+	atomic.StoreUint64(&c.droppedFrames, 0)
+	c.streamStats.reset()
+}
+
+// queueFrame queues the frame on ch, which is the StreamChan the frame was received for, without ever blocking the
+// reader of the streamer connection. When the queue is full, a frame is dropped according to the FrameDropPolicy.
+func (c *Client) queueFrame(ch chan *Frame, f *Frame) {
+	c.metrics.frameReceived()
+	c.streamStats.frameReceived(len(f.Data), f.Received)
+	for {
+		f.Dropped = atomic.LoadUint64(&c.droppedFrames)
+		select {
+		case ch <- f:
+			return
+		default:
+		}
+
+		if c.streamDropPolicy == DropNewest {
+			atomic.AddUint64(&c.droppedFrames, 1)
+			c.metrics.frameDropped()
+			f.Release()
+			return
+		}
+
+		select {
+		case old := <-ch:
+			atomic.AddUint64(&c.droppedFrames, 1)
+			c.metrics.frameDropped()
+			old.Release()
+		default:
+		}
+	}
+}
+
+// startPolledStream creates the StreamChan for devices that do not have a streamer connection and calls fetch every
+// interval to poll the Responder for a live view frame. Errors for which retry returns true are ignored, any other
+// error stops the polling. The frame counter is maintained by the Initiator since such devices do not send one.
+// Nothing is done when the stream is already running.
+func (c *Client) startPolledStream(interval time.Duration, fetch func(*Client) ([]byte, error), retry func(error) bool) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	if c.closeStreamChan != nil {
+		return
+	}
+	ch, done := make(chan *Frame, c.streamBufferSize), make(chan struct{})
+	c.StreamChan, c.closeStreamChan = ch, done
+	c.resetStream()
+	go c.pollStream(ch, done, interval, fetch, retry)
+}
+
+// streaming indicates if frames are being queued on the StreamChan.
+func (c *Client) streaming() bool {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	return c.closeStreamChan != nil
+}
+
+// streamChannels returns the StreamChan and the channel signalling the stream to stop.
+func (c *Client) streamChannels() (chan *Frame, chan struct{}) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	return c.StreamChan, c.closeStreamChan
+}
+
+// stopStream signals the goroutine queueing frames on the StreamChan to stop, if there is one.
+func (c *Client) stopStream() {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	if c.closeStreamChan != nil {
+		close(c.closeStreamChan)
+		c.closeStreamChan = nil
+	}
+}
+
+// pollStream queues live view frames on ch until done is closed or the connection to the Responder is lost.
+func (c *Client) pollStream(ch chan *Frame, done chan struct{}, interval time.Duration, fetch func(*Client) ([]byte, error), retry func(error) bool) {
+	c.infow(SubsystemStream, "polling live view frames")
+	defer close(ch)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var count uint32
+	for {
+		select {
+		case <-done:
+			c.infow(SubsystemStream, "stopping live view polling")
+			return
+		case <-t.C:
+		}
+
+		img, err := fetch(c)
+		if err != nil {
+			if retry(err) || err == WaitForResponseError {
+				continue
+			}
+			c.errorw(SubsystemStream, "live view polling stopped", "error", err)
+			return
+		}
+
+		f := newFrame()
+		f.buf.Write(img)
+		f.Data = f.buf.Bytes()
+		f.Counter = count
+		f.Received = time.Now()
+		count++
+		c.debugw(SubsystemStream, "received frame", FieldBytes, len(img), "frame", f.Counter)
+		c.queueFrame(ch, f)
+	}
+}
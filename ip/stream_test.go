@@ -0,0 +1,177 @@
+package ip
+
+import (
+	"testing"
+	"time"
+)
+
+func queuedCounters(c *Client) []uint32 {
+	var got []uint32
+	for len(c.StreamChan) > 0 {
+		f := <-c.StreamChan
+		got = append(got, f.Counter)
+		f.Release()
+	}
+
+	return got
+}
+
+func TestClient_queueFrame(t *testing.T) {
+	tests := []struct {
+		policy FrameDropPolicy
+		want   []uint32
+	}{
+		{DropOldest, []uint32{3, 4}},
+		{DropNewest, []uint32{1, 2}},
+	}
+
+	for _, tt := range tests {
+		c := &Client{StreamChan: make(chan *Frame, 2)}
+		c.SetStreamDropPolicy(tt.policy)
+		c.SetMetrics(NewMetrics())
+
+		var last *Frame
+		for i := uint32(1); i <= 4; i++ {
+			last = newFrame()
+			last.Counter = i
+			c.queueFrame(c.StreamChan, last)
+		}
+
+		if c.DroppedFrames() != 2 {
+			t.Errorf("DroppedFrames() got = %d; want 2", c.DroppedFrames())
+		}
+		if s := c.Metrics().Snapshot(); s.DroppedFrames != 2 || s.StreamFrames != 4 {
+			t.Errorf("Metrics() dropped = %d, frames = %d; want 2, 4", s.DroppedFrames, s.StreamFrames)
+		}
+		if tt.policy == DropOldest && last.Dropped != 2 {
+			t.Errorf("queueFrame() Dropped = %d; want 2", last.Dropped)
+		}
+
+		got := queuedCounters(c)
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("queueFrame() policy %d frames = %v; want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestClient_SetStreamBufferSize(t *testing.T) {
+	c := &Client{streamBufferSize: DefaultStreamBufferSize}
+
+	for _, size := range []int{0, -1} {
+		if err := c.SetStreamBufferSize(size); err == nil {
+			t.Errorf("SetStreamBufferSize(%d) error = <nil>; want error", size)
+		}
+		if c.streamBufferSize != DefaultStreamBufferSize {
+			t.Errorf("SetStreamBufferSize(%d) size = %d; want %d", size, c.streamBufferSize, DefaultStreamBufferSize)
+		}
+	}
+
+	if err := c.SetStreamBufferSize(1); err != nil {
+		t.Errorf("SetStreamBufferSize(1) error = %s; want <nil>", err)
+	}
+	if c.streamBufferSize != 1 {
+		t.Errorf("SetStreamBufferSize(1) size = %d; want 1", c.streamBufferSize)
+	}
+}
+
+func TestFrame_Release(t *testing.T) {
+	f := newFrame()
+	f.buf.WriteString("image data")
+	f.Data = f.buf.Bytes()
+	f.Counter = 5
+	f.Dropped = 1
+	f.Release()
+
+	if f.Data != nil || f.Counter != 0 || f.Dropped != 0 || !f.Received.IsZero() {
+		t.Errorf("Release() frame = %+v; want zero value", f)
+	}
+
+	if n := newFrame(); n.buf.Len() != 0 {
+		t.Errorf("newFrame() buffer length = %d; want 0", n.buf.Len())
+	}
+}
+
+func TestClient_StreamStats(t *testing.T) {
+	c := &Client{StreamChan: make(chan *Frame, 1)}
+
+	start := time.Now().Add(-time.Second)
+	for i, size := range []int{100, 200, 300} {
+		f := newFrame()
+		f.buf.Write(make([]byte, size))
+		f.Data = f.buf.Bytes()
+		f.Received = start.Add(time.Duration(i) * 500 * time.Millisecond)
+		c.queueFrame(c.StreamChan, f)
+	}
+	c.streamStats.framesMissed(3)
+	c.streamStats.frameOutOfOrder()
+	c.streamStats.frameDuplicate()
+
+	got := c.StreamStats()
+	want := StreamStats{Frames: 3, FPS: 3, Dropped: 2, Missed: 3, OutOfOrder: 1, Duplicates: 1, AverageSize: 200}
+	if got != want {
+		t.Errorf("StreamStats() got = %+v; want %+v", got, want)
+	}
+
+	c.resetStream()
+	if got := c.StreamStats(); got != (StreamStats{}) {
+		t.Errorf("StreamStats() after reset got = %+v; want zero value", got)
+	}
+}
+
+func TestFujiFrameGap(t *testing.T) {
+	check := []struct {
+		last, cur uint8
+		want      int
+	}{
+		{0x2a, 0x2b, 0},
+		{0x2a, 0x2d, 2},
+		{0xff, 0x00, 0},
+		{0xfe, 0x01, 2},
+		{0x2a, 0x2a, -1},
+		{0x2a, 0x29, -1},
+		{0x01, 0xfe, -1},
+	}
+
+	for _, tt := range check {
+		if got := fujiFrameGap(tt.last, tt.cur); got != tt.want {
+			t.Errorf("fujiFrameGap(%#x, %#x) got = %d; want %d", tt.last, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestClient_CloseStopsPolledStream(t *testing.T) {
+	c, err := NewClient(DefaultVendor, "127.0.0.1", DefaultPort, "", "", logLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.startPolledStream(time.Millisecond, func(*Client) ([]byte, error) {
+		return []byte{0xff, 0xd8, 0xff, 0xd9}, nil
+	}, func(error) bool {
+		return false
+	})
+	if !c.streaming() {
+		t.Fatal("streaming() = false; want true")
+	}
+	ch := c.StreamChan
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %s; want <nil>", err)
+	}
+	if c.streaming() {
+		t.Error("streaming() after Close() = true; want false")
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case f, ok := <-ch:
+			if !ok {
+				return
+			}
+			f.Release()
+		case <-timeout:
+			t.Fatal("Close() did not stop the polled stream")
+		}
+	}
+}
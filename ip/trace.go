@@ -0,0 +1,126 @@
+package ip
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTraceStreamBytes is the default amount of bytes dumped for each packet received on the streamer connection.
+const DefaultTraceStreamBytes = 64
+
+// PacketTrace writes an annotated hex dump of every packet sent and received by the client to a separate writer. This
+// allows diagnosing issues at the protocol level without flooding the log output. A dump looks like this:
+//
+//	2020-11-14T21:42:53.123456Z > cmd OperationRequestPacket bytes=38 tid=5 opcode=0x1015
+//	00000000  26 00 00 00 06 00 00 00  01 00 00 00 15 10 05 00  |&...............|
+//	00000010  00 00 12 d2 00 00 00 00  00 00 00 00 00 00 00 00  |................|
+//	00000020  00 00 00 00 00 00                                 |......|
+//
+// where '>' indicates a packet sent to the Responder and '<' a packet received from the Responder.
+type PacketTrace struct {
+	// StreamBytes is the maximum amount of bytes dumped for packets received on the streamer connection. These
+	// packets mostly contain image data, so dumping them in full would make the trace unreadable. Set to a negative
+	// value to dump them in full anyway.
+	StreamBytes int
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewPacketTrace returns a packet trace writing to w.
+func NewPacketTrace(w io.Writer) *PacketTrace {
+	return &PacketTrace{
+		StreamBytes: DefaultTraceStreamBytes,
+		w:           w,
+	}
+}
+
+// dump writes the annotated hex dump of p to the trace. The out parameter indicates the direction of the packet.
+// Additional annotations are passed as alternating keys and values.
+func (pt *PacketTrace) dump(out bool, ct connectionType, desc string, p []byte, keysAndValues ...interface{}) {
+	dir := "<"
+	if out {
+		dir = ">"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"), dir, ct)
+	if desc != "" {
+		b.WriteByte(' ')
+		b.WriteString(desc)
+	}
+	fmt.Fprintf(&b, " %s=%d", FieldBytes, len(p))
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fmt.Fprintf(&b, " %s=%s", keysAndValues[i], formatValue(keysAndValues[i+1]))
+	}
+
+	if ct == streamConnection && pt.StreamBytes >= 0 && len(p) > pt.StreamBytes {
+		fmt.Fprintf(&b, " truncated=%d", pt.StreamBytes)
+		p = p[:pt.StreamBytes]
+	}
+	b.WriteByte('\n')
+	b.WriteString(hex.Dump(p))
+	b.WriteByte('\n')
+
+	pt.mu.Lock()
+	io.WriteString(pt.w, b.String())
+	pt.mu.Unlock()
+}
+
+// SetPacketTrace enables tracing of all packets sent and received by the client. Pass nil to disable tracing again.
+// Must be called before calling Dial().
+func (c *Client) SetPacketTrace(pt *PacketTrace) {
+	c.trace = pt
+}
+
+// traceIn traces a packet received on the given connection. The transaction ID is added when the vendor allows
+// extracting it from the packet.
+func (c *Client) traceIn(ct connectionType, p []byte) {
+	if c.trace == nil || len(p) == 0 {
+		return
+	}
+
+	var kv []interface{}
+	if ct != streamConnection {
+		if tid, err := c.vendorExtensions.extractTransactionId(p, ct); err == nil {
+			kv = append(kv, FieldTransactionID, tid)
+		}
+	}
+	c.trace.dump(false, ct, "", p, kv...)
+}
+
+// traceOut traces a packet sent on the given connection.
+func (c *Client) traceOut(ct connectionType, p PacketOut, raw []byte) {
+	if c.trace == nil {
+		return
+	}
+
+	c.trace.dump(true, ct, packetName(p), raw, requestFields(p)...)
+}
+
+// packetName returns the type name of the packet without the package name.
+func packetName(p PacketOut) string {
+	n := fmt.Sprintf("%T", p)
+	if i := strings.LastIndex(n, "."); i >= 0 {
+		n = n[i+1:]
+	}
+
+	return n
+}
+
+// requestFields returns the transaction ID and operation code of the packet as structured log fields, if the packet
+// carries them.
+func requestFields(p PacketOut) []interface{} {
+	switch pkt := p.(type) {
+	case *OperationRequestPacket:
+		return []interface{}{FieldTransactionID, pkt.TransactionID, FieldOperationCode, pkt.OperationCode}
+	case *FujiOperationRequestPacket:
+		return []interface{}{FieldTransactionID, pkt.TransactionID, FieldOperationCode, pkt.OperationCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package ip
+
+import (
+	"bytes"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestClient_sendPacketTrace(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "tracèr", "b2f3c1b8-3f3e-4d0a-8e4b-1d7a9c0b5e61", logLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trace bytes.Buffer
+	c.SetPacketTrace(NewPacketTrace(&trace))
+
+	var buf bytes.Buffer
+	p := &OperationRequestPacket{
+		DataPhaseInfo:    DP_NoDataOrDataIn,
+		OperationRequest: ptp.GetDeviceInfo(5),
+	}
+	if err := c.sendPacket(&buf, p); err != nil {
+		t.Errorf("sendPacket() err = %s; want <nil>", err)
+	}
+
+	lines := strings.Split(trace.String(), "\n")
+	want := "> cmd OperationRequestPacket bytes=38 tid=5 opcode=0x1001"
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("sendPacket() trace annotation = %s; want suffix %s", lines[0], want)
+	}
+	want = "00000000  26 00 00 00 06 00 00 00  01 00 00 00 01 10 05 00  |&...............|"
+	if lines[1] != want {
+		t.Errorf("sendPacket() trace dump = %s; want %s", lines[1], want)
+	}
+}
+
+func TestClient_traceInStream(t *testing.T) {
+	c, err := NewClient("fuji", DefaultIpAddress, DefaultPort, "tracèr", "", logLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trace bytes.Buffer
+	pt := NewPacketTrace(&trace)
+	pt.StreamBytes = 4
+	c.SetPacketTrace(pt)
+
+	c.traceIn(streamConnection, make([]byte, 100))
+
+	lines := strings.Split(trace.String(), "\n")
+	want := "< stream bytes=100 truncated=4"
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("traceIn() trace annotation = %s; want suffix %s", lines[0], want)
+	}
+	want = "00000000  00 00 00 00                                       |....|"
+	if lines[1] != want {
+		t.Errorf("traceIn() trace dump = %s; want %s", lines[1], want)
+	}
+}
+
+func TestClient_readResponseFrom(t *testing.T) {
+	c, err := NewClient(DefaultVendor, DefaultIpAddress, DefaultPort, "tracèr", "", logLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trace bytes.Buffer
+	c.SetPacketTrace(NewPacketTrace(&trace))
+
+	b := packetBuffer(&OperationResponsePacket{OperationResponse: ptp.OperationResponse{ResponseCode: ptp.RC_OK, TransactionID: 9}})
+	l := b.Len()
+	if _, _, err := c.readResponseFrom(cmdDataConnection, b, nil); err != nil {
+		t.Errorf("readResponseFrom() err = %s; want <nil>", err)
+	}
+
+	first := strings.SplitN(trace.String(), "\n", 2)[0]
+	want := "< cmd bytes=" + strconv.Itoa(l) + " tid=9"
+	if !strings.HasSuffix(first, want) {
+		t.Errorf("readResponseFrom() trace annotation = %s; want suffix %s", first, want)
+	}
+}
@@ -0,0 +1,260 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/malc0mn/ptp-ip/ip/internal"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidLocalAddress = errors.New("invalid local address")
+	ErrNoInterfaceAddress  = errors.New("no matching address on interface")
+	// ErrChannelNotSupported is returned by a Transport when it does not offer the requested channel.
+	ErrChannelNotSupported = errors.New("channel not supported by transport")
+)
+
+// Channel identifies one of the PTP/IP connections between the Initiator and the Responder.
+type Channel string
+
+const (
+	// CommandDataChannel carries the operation request, data and response packets.
+	CommandDataChannel Channel = Channel(cmdDataConnection)
+	// EventChannel carries the event packets sent by the Responder.
+	EventChannel Channel = Channel(eventConnection)
+	// StreamChannel carries vendor specific streaming data such as live view images. Not all vendors offer it.
+	StreamChannel Channel = Channel(streamConnection)
+)
+
+// Conn is an open channel to the Responder carrying PTP/IP packets.
+type Conn interface {
+	io.ReadWriteCloser
+	// SetReadDeadline sets the deadline for future Read calls. A zero value for t means Read will not time out.
+	SetReadDeadline(t time.Time) error
+}
+
+// Transport opens the channels carrying the PTP/IP packets to the Responder. This allows the packets to be carried by
+// something else than TCP connections, e.g. an in-memory pipe for testing.
+// Transports not using PTP/IP packets at all, such as PTP over USB, implement ptp.Transport instead.
+type Transport interface {
+	// Open opens the given channel to the Responder.
+	Open(ch Channel) (Conn, error)
+}
+
+// TransportFunc is an adapter to allow the use of an ordinary function as a Transport.
+type TransportFunc func(ch Channel) (Conn, error)
+
+// Open calls f(ch).
+func (f TransportFunc) Open(ch Channel) (Conn, error) {
+	return f(ch)
+}
+
+// TCPTransport is the PTP/IP transport: each channel is a separate TCP connection to the Responder. Dialing is retried
+// when the Responder refuses the connection.
+// The Responder's host can be an IPv4 address, an IPv6 address optionally including a zone, e.g. fe80::1%wlan0, or a
+// host name.
+type TCPTransport struct {
+	// Resolver is used to look up the Responder's host name. The default resolver is used when nil.
+	Resolver *net.Resolver
+	// LocalAddr is the local IP address to connect from. The system picks one when empty.
+	LocalAddr string
+	// Interface is the name of the local network interface to connect from, which is useful when several Wi-Fi
+	// adapters are present. Its address matching the address family of the Responder is used and it is added as the
+	// zone to link-local IPv6 addresses lacking one. Ignored when LocalAddr is set.
+	Interface string
+
+	responder *Responder
+}
+
+// NewTCPTransport returns a PTP/IP transport connecting to the addresses of the given Responder. The addresses are
+// looked up when a channel is opened, so any port changes made afterwards are taken into account.
+func NewTCPTransport(r *Responder) *TCPTransport {
+	return &TCPTransport{
+		responder: r,
+	}
+}
+
+// Open dials the TCP connection for the given channel.
+func (t *TCPTransport) Open(ch Channel) (Conn, error) {
+	var addr string
+	switch ch {
+	case CommandDataChannel:
+		addr = t.responder.CommandDataAddress()
+	case EventChannel:
+		addr = t.responder.EventAddress()
+	case StreamChannel:
+		addr = t.responder.StreamerAddress()
+	default:
+		return nil, ErrChannelNotSupported
+	}
+
+	d, addr, err := t.dialer(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := internal.RetryDialer(d, t.responder.Network(), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// dialer returns the dialer to use for the given address. The address is returned with the zone added to it when
+// needed.
+func (t *TCPTransport) dialer(addr string) (*net.Dialer, string, error) {
+	d := &net.Dialer{
+		Timeout:  DefaultDialTimeout,
+		Resolver: t.Resolver,
+	}
+
+	switch {
+	case t.LocalAddr != "":
+		ip, zone := splitZone(t.LocalAddr)
+		if ip == nil {
+			return nil, "", fmt.Errorf("%w '%s'", ErrInvalidLocalAddress, t.LocalAddr)
+		}
+		d.LocalAddr = &net.TCPAddr{IP: ip, Zone: zone}
+	case t.Interface != "":
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, "", err
+		}
+		remote, err := t.lookup(host)
+		if err != nil {
+			return nil, "", err
+		}
+		local, err := interfaceAddr(t.Interface, remote.IP)
+		if err != nil {
+			return nil, "", err
+		}
+		la := &net.TCPAddr{IP: local}
+		if isIPv6LinkLocal(remote.IP) {
+			if remote.Zone == "" {
+				remote.Zone = t.Interface
+			}
+			la.Zone = t.Interface
+		}
+		d.LocalAddr = la
+		addr = net.JoinHostPort(remote.String(), port)
+	}
+
+	return d, addr, nil
+}
+
+// lookup returns the first address of the given host.
+func (t *TCPTransport) lookup(host string) (net.IPAddr, error) {
+	r := t.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDialTimeout)
+	defer cancel()
+
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return net.IPAddr{}, err
+	}
+
+	return addrs[0], nil
+}
+
+// interfaceAddr returns the address of the named interface that is of the same address family as remote. Link-local
+// IPv6 addresses are preferred for link-local remotes and avoided otherwise.
+func interfaceAddr(name string, remote net.IP) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var match net.IP
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || (ipn.IP.To4() == nil) != (remote.To4() == nil) {
+			continue
+		}
+		if isIPv6LinkLocal(ipn.IP) == isIPv6LinkLocal(remote) {
+			return ipn.IP, nil
+		}
+		if match == nil {
+			match = ipn.IP
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w %s for %s", ErrNoInterfaceAddress, name, remote)
+	}
+
+	return match, nil
+}
+
+// splitZone parses an IP address optionally including a zone, e.g. fe80::1%wlan0.
+func splitZone(s string) (net.IP, string) {
+	var zone string
+	if i := strings.LastIndexByte(s, '%'); i >= 0 {
+		s, zone = s[:i], s[i+1:]
+	}
+
+	return net.ParseIP(strings.Trim(s, "[]")), zone
+}
+
+func isIPv6LinkLocal(ip net.IP) bool {
+	return ip.To4() == nil && ip.IsLinkLocalUnicast()
+}
+
+// SetTransport replaces the transport used to open the channels to the Responder, which is a TCPTransport by default.
+// Must be called before calling Dial().
+func (c *Client) SetTransport(t Transport) {
+	c.transport = t
+}
+
+// openConn opens the channel for the given connection type using the client's transport.
+func (c *Client) openConn(ct connectionType) (Conn, error) {
+	conn, err := c.transport.Open(Channel(ct))
+	if err != nil {
+		return nil, err
+	}
+	c.configureTcpConn(ct, conn)
+
+	return conn, nil
+}
+
+// tcpTransport returns the client's transport when it is a TCPTransport.
+func (c *Client) tcpTransport() *TCPTransport {
+	t, _ := c.transport.(*TCPTransport)
+
+	return t
+}
+
+// SetResolver sets the resolver used to look up the Responder's host name. Has no effect when a custom transport was
+// set using SetTransport().
+func (c *Client) SetResolver(r *net.Resolver) {
+	if t := c.tcpTransport(); t != nil {
+		t.Resolver = r
+	}
+}
+
+// SetLocalAddress binds the client to the given local IP address, optionally including a zone. Has no effect when a
+// custom transport was set using SetTransport().
+func (c *Client) SetLocalAddress(addr string) {
+	if t := c.tcpTransport(); t != nil {
+		t.LocalAddr = addr
+	}
+}
+
+// SetInterface binds the client to the named local network interface. Has no effect when a custom transport was set
+// using SetTransport().
+func (c *Client) SetInterface(name string) {
+	if t := c.tcpTransport(); t != nil {
+		t.Interface = name
+	}
+}
@@ -0,0 +1,166 @@
+package ip_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/malc0mn/ptp-ip/ip"
+	"github.com/malc0mn/ptp-ip/ip/iptest"
+	"github.com/malc0mn/ptp-ip/ptp"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// newTCPTransport returns a transport connecting to the command/data port of res using the given host.
+func newTCPTransport(res *iptest.Responder, host string) *ip.TCPTransport {
+	return ip.NewTCPTransport(ip.NewResponder(ip.DefaultVendor, host, res.CommandDataPort(), 0, 0))
+}
+
+func TestTCPTransport_OpenHostName(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	tr := newTCPTransport(res, "localhost")
+	tr.Resolver = &net.Resolver{PreferGo: true}
+	conn, err := tr.Open(ip.CommandDataChannel)
+	if err != nil {
+		t.Fatalf("Open() err = %s; want <nil>", err)
+	}
+	conn.Close()
+}
+
+func TestTCPTransport_OpenLocalAddr(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	tr := newTCPTransport(res, res.IpAddress())
+	tr.LocalAddr = "127.0.0.1"
+	conn, err := tr.Open(ip.CommandDataChannel)
+	if err != nil {
+		t.Fatalf("Open() err = %s; want <nil>", err)
+	}
+	got, _, _ := net.SplitHostPort(conn.(net.Conn).LocalAddr().String())
+	if got != tr.LocalAddr {
+		t.Errorf("Open() local address = %s; want %s", got, tr.LocalAddr)
+	}
+	conn.Close()
+
+	tr.LocalAddr = "not-an-ip"
+	if _, err := tr.Open(ip.CommandDataChannel); !errors.Is(err, ip.ErrInvalidLocalAddress) {
+		t.Errorf("Open() err = %v; want %s", err, ip.ErrInvalidLocalAddress)
+	}
+}
+
+func TestTCPTransport_OpenInterface(t *testing.T) {
+	lo := loopbackInterface(t)
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+
+	tr := newTCPTransport(res, res.IpAddress())
+	tr.Interface = lo
+	conn, err := tr.Open(ip.CommandDataChannel)
+	if err != nil {
+		t.Fatalf("Open() err = %s; want <nil>", err)
+	}
+	conn.Close()
+
+	tr.Interface = "does-not-exist"
+	if _, err := tr.Open(ip.CommandDataChannel); err == nil {
+		t.Errorf("Open() err = <nil>; want an error")
+	}
+}
+
+func loopbackInterface(t *testing.T) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+
+	return ""
+}
+
+func TestClient_Transact(t *testing.T) {
+	res := iptest.NewResponder(ip.DefaultVendor)
+	defer res.Close()
+	res.Handle(ptp.OC_GetStorageIDs, iptest.Reply(iptest.Data([]byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00})))
+	res.Handle(ptp.OC_GetObject, iptest.Reply(iptest.Fail(ptp.RC_InvalidObjectHandle)))
+	res.HandleDataOut(ptp.OC_SetDevicePropValue, iptest.Reply(iptest.OK()))
+
+	c, err := res.NewPipeClient("tèster", "", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	i := ptp.NewInitiator(c)
+	ids, err := i.GetStorageIDs()
+	if err != nil {
+		t.Fatalf("GetStorageIDs() error = %s; want <nil>", err)
+	}
+	if want := []ptp.StorageID{0x00010001}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetStorageIDs() = %#x; want %#x", ids, want)
+	}
+
+	pv, _ := ptp.NewPropertyValue(ptp.DTC_UINT16, uint16(ptp.WB_Daylight))
+	if err := i.SetDevicePropValue(ptp.DPC_WhiteBalance, pv); err != nil {
+		t.Fatalf("SetDevicePropValue() error = %s; want <nil>", err)
+	}
+	reqs := res.RequestsFor(ptp.OC_SetDevicePropValue)
+	if len(reqs) != 1 {
+		t.Fatalf("SetDevicePropValue() requests = %d; want 1", len(reqs))
+	}
+	if want := []byte{byte(ptp.WB_Daylight), 0x00}; !bytes.Equal(reqs[0].Data, want) {
+		t.Errorf("SetDevicePropValue() data = %#x; want %#x", reqs[0].Data, want)
+	}
+
+	want := ptp.OperationResponseCodeAsError(ptp.RC_InvalidObjectHandle)
+	if _, err := i.GetObject(1); err == nil || err.Error() != want.Error() {
+		t.Errorf("GetObject() error = %v; want %s", err, want)
+	}
+}
+
+func TestClient_TransactFuji(t *testing.T) {
+	res := iptest.NewResponder("fuji")
+	defer res.Close()
+	res.SetObject(1, []byte{0xff, 0xd8, 0xff, 0xd9})
+
+	c, err := res.NewPipeClient("testèr", "67bace55-e7a4-4fbc-8e31-5122ee73a17c", ip.LogLevelUnderTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	i := ptp.NewInitiator(c)
+	hs, err := i.GetObjectHandles(0, 0, 0)
+	if err != nil {
+		t.Fatalf("GetObjectHandles() error = %s; want <nil>", err)
+	}
+	if want := []ptp.ObjectHandle{1}; !reflect.DeepEqual(hs, want) {
+		t.Errorf("GetObjectHandles() = %v; want %v", hs, want)
+	}
+
+	got, err := i.GetObject(1)
+	if err != nil {
+		t.Fatalf("GetObject() error = %s; want <nil>", err)
+	}
+	if want := []byte{0xff, 0xd8, 0xff, 0xd9}; !bytes.Equal(got, want) {
+		t.Errorf("GetObject() = %#x; want %#x", got, want)
+	}
+
+	pv, _ := ptp.NewPropertyValue(ptp.DTC_UINT16, uint16(ptp.WB_Daylight))
+	if err := i.SetDevicePropValue(ptp.DPC_WhiteBalance, pv); err == nil {
+		t.Error("SetDevicePropValue() error = <nil>; want error")
+	}
+}
@@ -1,12 +1,14 @@
 package ip
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/malc0mn/ptp-ip/ip/internal"
 	"github.com/malc0mn/ptp-ip/ptp"
+	"time"
 )
 
 // TODO: This solution is not OK, vendors can differ massively so it seems. Should this become an interface that all
@@ -15,38 +17,48 @@ import (
 //  calls the initCommandDataConn() and initEventConn() methods but when using embedding the methods on ip.Client get
 //  called and not the ones on ip.FujiClient so you would also have to "override" the Dial() as well.
 type VendorExtensions struct {
-	cmdDataInit            func(*Client) error
-	eventInit              func(*Client) error
-	processStreamData      func(*Client) error
-	newCmdDataInitPacket   func(uuid.UUID, string) InitCommandRequestPacket
-	newEventInitPacket     func(uint32) InitEventRequestPacket
-	newEventPacket         func() EventPacket
-	extractTransactionId   func([]byte, connectionType) (ptp.TransactionID, error)
-	getDeviceInfo          func(*Client) (interface{}, error)
-	getDeviceState         func(*Client) (interface{}, error)
-	getDevicePropertyDesc  func(*Client, ptp.DevicePropCode) (*ptp.DevicePropDesc, error)
-	getDevicePropertyValue func(*Client, ptp.DevicePropCode) (uint32, error)
-	setDeviceProperty      func(*Client, ptp.DevicePropCode, uint32) error
-	operationRequestRaw    func(*Client, ptp.OperationCode, []uint32) ([][]byte, error)
-	initiateCapture        func(*Client) ([]byte, error)
+	cmdDataInit             func(*Client) error
+	eventInit               func(*Client) error
+	processStreamData       func(*Client) error
+	newCmdDataInitPacket    func(uuid.UUID, string) InitCommandRequestPacket
+	newEventInitPacket      func(uint32) InitEventRequestPacket
+	newEventPacket          func() EventPacket
+	extractTransactionId    func([]byte, connectionType) (ptp.TransactionID, error)
+	getDeviceInfo           func(*Client) (interface{}, error)
+	getDeviceState          func(*Client) (interface{}, error)
+	getDevicePropertyDesc   func(*Client, ptp.DevicePropCode) (*ptp.DevicePropDesc, error)
+	getDevicePropertyValue  func(*Client, ptp.DevicePropCode) (uint32, error)
+	setDeviceProperty       func(*Client, ptp.DevicePropCode, uint32) error
+	operationRequestRaw     func(*Client, ptp.OperationCode, []uint32) ([][]byte, error)
+	operationRequestDataIn  func(*Client, ptp.OperationCode, []uint32) ([]byte, error)
+	operationRequestDataOut func(*Client, ptp.OperationCode, []uint32, []byte) error
+	transact                func(*Client, ptp.OperationRequest, []byte) (*ptp.OperationResponse, []byte, error)
+	initiateCapture         func(*Client) ([]byte, error)
+	bulbCapture             func(*Client, time.Duration) ([]byte, error)
+	toggleLiveView          func(*Client, bool) error
 }
 
 func (c *Client) loadVendorExtensions() {
 	c.vendorExtensions = &VendorExtensions{
-		cmdDataInit:            GenericInitCommandDataConn,
-		eventInit:              GenericInitEventConn,
-		processStreamData:      GenericProcessStreamData,
-		newCmdDataInitPacket:   NewInitCommandRequestPacket,
-		newEventInitPacket:     NewInitEventRequestPacket,
-		newEventPacket:         NewEventPacket,
-		extractTransactionId:   GenericExtractTransactionId,
-		getDeviceInfo:          GenericGetDeviceInfo,
-		getDeviceState:         GenericGetDeviceState,
-		getDevicePropertyDesc:  GenericGetDevicePropertyDesc,
-		getDevicePropertyValue: GenericGetDevicePropertyValue,
-		setDeviceProperty:      GenericSetDeviceProperty,
-		operationRequestRaw:    GenericOperationRequestRaw,
-		initiateCapture:        GenericInitiateCapture,
+		cmdDataInit:             GenericInitCommandDataConn,
+		eventInit:               GenericInitEventConn,
+		processStreamData:       GenericProcessStreamData,
+		newCmdDataInitPacket:    NewInitCommandRequestPacket,
+		newEventInitPacket:      NewInitEventRequestPacket,
+		newEventPacket:          NewEventPacket,
+		extractTransactionId:    GenericExtractTransactionId,
+		getDeviceInfo:           GenericGetDeviceInfo,
+		getDeviceState:          GenericGetDeviceState,
+		getDevicePropertyDesc:   GenericGetDevicePropertyDesc,
+		getDevicePropertyValue:  GenericGetDevicePropertyValue,
+		setDeviceProperty:       GenericSetDeviceProperty,
+		operationRequestRaw:     GenericOperationRequestRaw,
+		operationRequestDataIn:  GenericOperationRequestDataIn,
+		operationRequestDataOut: GenericOperationRequestDataOut,
+		transact:                GenericTransact,
+		initiateCapture:         GenericInitiateCapture,
+		bulbCapture:             GenericBulbCapture,
+		toggleLiveView:          GenericToggleLiveView,
 	}
 
 	switch c.ResponderVendor() {
@@ -63,7 +75,41 @@ func (c *Client) loadVendorExtensions() {
 		c.vendorExtensions.getDevicePropertyValue = FujiGetDevicePropertyValue
 		c.vendorExtensions.setDeviceProperty = FujiSetDeviceProperty
 		c.vendorExtensions.operationRequestRaw = FujiSendOperationRequestAndGetRawResponse
+		c.vendorExtensions.operationRequestDataIn = FujiOperationRequestDataIn
+		c.vendorExtensions.operationRequestDataOut = FujiOperationRequestDataOut
+		c.vendorExtensions.transact = FujiTransact
 		c.vendorExtensions.initiateCapture = FujiInitiateCapture
+		c.vendorExtensions.bulbCapture = FujiBulbCapture
+	case ptp.VE_CanonInc:
+		c.vendorExtensions.eventInit = CanonInitEventConn
+		c.vendorExtensions.getDeviceState = CanonGetDeviceState
+		c.vendorExtensions.setDeviceProperty = CanonSetDeviceProperty
+		c.vendorExtensions.initiateCapture = CanonInitiateCapture
+		c.vendorExtensions.bulbCapture = CanonBulbCapture
+		c.vendorExtensions.toggleLiveView = CanonToggleLiveView
+	case ptp.VE_NikonCorporation:
+		c.vendorExtensions.eventInit = NikonInitEventConn
+		c.vendorExtensions.initiateCapture = NikonInitiateCapture
+		c.vendorExtensions.toggleLiveView = NikonToggleLiveView
+	case ptp.VE_SonyCorporation:
+		c.vendorExtensions.eventInit = SonyInitEventConn
+		// Sony returns all property descriptions in a single operation, which is what GetDeviceInfo() returns for Fuji.
+		c.vendorExtensions.getDeviceInfo = SonyGetDeviceState
+		c.vendorExtensions.getDeviceState = SonyGetDeviceState
+		c.vendorExtensions.getDevicePropertyDesc = SonyGetDevicePropertyDesc
+		c.vendorExtensions.getDevicePropertyValue = SonyGetDevicePropertyValue
+		c.vendorExtensions.setDeviceProperty = SonySetDeviceProperty
+		c.vendorExtensions.initiateCapture = SonyInitiateCapture
+		c.vendorExtensions.toggleLiveView = SonyToggleLiveView
+	case ptp.VE_PanasonicCorporation:
+		c.vendorExtensions.eventInit = PanasonicInitEventConn
+		c.vendorExtensions.getDeviceInfo = PanasonicGetDeviceState
+		c.vendorExtensions.getDeviceState = PanasonicGetDeviceState
+		c.vendorExtensions.getDevicePropertyDesc = PanasonicGetDevicePropertyDesc
+		c.vendorExtensions.getDevicePropertyValue = PanasonicGetDevicePropertyValue
+		c.vendorExtensions.setDeviceProperty = PanasonicSetDeviceProperty
+		c.vendorExtensions.initiateCapture = PanasonicInitiateCapture
+		c.vendorExtensions.toggleLiveView = PanasonicToggleLiveView
 	}
 }
 
@@ -84,6 +130,16 @@ func GenericInitCommandDataConn(c *Client) error {
 	case *InitFailPacket:
 		err = pkt.ReasonAsError()
 	case *InitCommandAckPacket:
+		// The Responder GUID is only known now: when the Initiator identity does not match the one the Responder was
+		// paired with, start over using the stored identity so the user does not get prompted.
+		if c.switchIdentity(pkt.ResponderGUID) {
+			c.infow(SubsystemCmdData, "reconnecting using the identity the responder was paired with")
+			c.commandDataConn.Close()
+			if c.commandDataConn, err = c.openConn(cmdDataConnection); err != nil {
+				return err
+			}
+			return GenericInitCommandDataConn(c)
+		}
 		c.connectionNumber = pkt.ConnectionNumber
 		c.responder.GUID = pkt.ResponderGUID
 		c.responder.FriendlyName = pkt.ResponderFriendlyName
@@ -94,7 +150,7 @@ func GenericInitCommandDataConn(c *Client) error {
 		err = fmt.Errorf("unexpected packet received %T", res)
 	}
 
-	c.Infoln("Closing Command/Data connection!")
+	c.infow(SubsystemCmdData, "closing command/data connection", "error", err)
 	c.commandDataConn.Close()
 	return err
 }
@@ -103,16 +159,14 @@ func GenericInitCommandDataConn(c *Client) error {
 func GenericInitEventConn(c *Client) error {
 	var err error
 
-	c.eventConn, err = internal.RetryDialer(c.Network(), c.EventAddress(), DefaultDialTimeout)
+	c.eventConn, err = c.openConn(eventConnection)
 	if err != nil {
 		return err
 	}
 
-	c.configureTcpConn(eventConnection)
-
 	ierp := c.newEventInitPacket()
 	if ierp == nil {
-		c.Info("No further event channel init required.")
+		c.infow(SubsystemEvent, "no further event channel init required")
 		return nil
 	}
 	err = c.SendPacketToEventConn(ierp)
@@ -135,7 +189,7 @@ func GenericInitEventConn(c *Client) error {
 		err = fmt.Errorf("unexpected packet received %T", res)
 	}
 
-	c.Infoln("Closing Event connection!")
+	c.infow(SubsystemEvent, "closing event connection", "error", err)
 	c.eventConn.Close()
 	return err
 }
@@ -149,21 +203,27 @@ func GenericProcessStreamData(_ *Client) error {
 // GenericExtractTransactionId extracts the transaction ID from a full raw inbound packet. This packet must include the
 // full header containing length and packet type.
 func GenericExtractTransactionId(p []byte, _ connectionType) (ptp.TransactionID, error) {
-	if len(p) < 13 {
-		return 0, fmt.Errorf("packet too small: got length %d", len(p))
+	if len(p) < HeaderSize {
+		return 0, internal.ShortPacketError(len(p), HeaderSize)
 	}
 
-	var data []byte
+	var offset int
 	pt := PacketType(binary.LittleEndian.Uint32(p[4:8]))
 	switch pt {
 	case PKT_OperationResponse, PKT_Event:
-		data = p[10:14]
+		offset = 10
 	case PKT_StartData, PKT_Data, PKT_EndData, PKT_Cancel:
-		data = p[8:12]
-		// TODO: PKT_ProbeRequest and PKT_ProbeResponse do not have a transaction ID, how to handle those?
+		offset = 8
+	// TODO: PKT_ProbeRequest and PKT_ProbeResponse do not have a transaction ID, how to handle those?
+	default:
+		return 0, fmt.Errorf("%w %#x", ErrUnknownPacketType, pt)
 	}
 
-	return ptp.TransactionID(binary.LittleEndian.Uint32(data)), nil
+	if len(p) < offset+4 {
+		return 0, internal.ShortPacketError(len(p), offset+4)
+	}
+
+	return ptp.TransactionID(binary.LittleEndian.Uint32(p[offset : offset+4])), nil
 }
 
 // Request the Responder's device information.
@@ -205,9 +265,15 @@ func GenericGetDeviceState(_ *Client) (interface{}, error) {
 	return nil, errors.New("command not supported")
 }
 
-// GenericGetDevicePropertyValue requests the value for the given property from the Responder's.
+// GenericGetDevicePropertyDesc requests the description for the given property from the Responder.
 func GenericGetDevicePropertyDesc(c *Client, dpc ptp.DevicePropCode) (*ptp.DevicePropDesc, error) {
-	return nil, errors.New("command not YET supported")
+	c.infow(SubsystemCmdData, "requesting device property description", "responder", c.ResponderFriendlyName(), "property", dpc)
+	xs, err := GenericOperationRequestDataIn(c, ptp.OC_GetDevicePropDesc, []uint32{uint32(dpc)})
+	if err != nil {
+		return nil, err
+	}
+
+	return ptp.ReadDevicePropDesc(bytes.NewReader(xs))
 }
 
 // GenericGetDevicePropertyValue requests the value for the given property from the Responder's.
@@ -223,6 +289,147 @@ func GenericSetDeviceProperty(c *Client, dpc ptp.DevicePropCode, val uint32) err
 func GenericOperationRequestRaw(c *Client, code ptp.OperationCode, params []uint32) ([][]byte, error) {
 	tid := c.incrementTransactionId()
 
+	or := newOperationRequest(code, tid, params)
+	resCh := make(chan []byte, 2)
+	if err := c.subscribe(tid, resCh); err != nil {
+		return nil, err
+	}
+	defer c.unsubscribe(tid)
+
+	err := c.SendPacketToCmdDataConn(&OperationRequestPacket{
+		DataPhaseInfo:    DP_NoDataOrDataIn,
+		OperationRequest: or,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.WaitForRawPacketFromCommandDataSubscriber(resCh)
+	if err != nil {
+		return nil, err
+	}
+	raw := [][]byte{r}
+
+	// TODO: handle possible followup packets depending on the data phase returned.
+
+	return raw, err
+}
+
+// GenericOperationRequestDataIn sends an operation request with a data in phase and returns the data sent by the
+// Responder. An error is returned when the Responder does not respond with ptp.RC_OK.
+func GenericOperationRequestDataIn(c *Client, code ptp.OperationCode, params []uint32) ([]byte, error) {
+	data, rc, err := genericOperationRequestDataIn(c, code, params)
+	if err != nil {
+		return nil, err
+	}
+	if rc != ptp.RC_OK {
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
+
+	return data, nil
+}
+
+// genericOperationRequestDataIn sends an operation request with a data in phase and returns the data and the response
+// code sent by the Responder. Use this when a response code other than ptp.RC_OK needs to be handled.
+func genericOperationRequestDataIn(c *Client, code ptp.OperationCode, params []uint32) ([]byte, ptp.OperationResponseCode, error) {
+	return genericOperationRequestDataInWithTid(c, c.incrementTransactionId(), code, params)
+}
+
+// genericOperationRequestDataInWithTid is genericOperationRequestDataIn using the given transaction ID. Use this when
+// the transaction ID is needed by a subsequent operation, such as ptp.OC_TerminateOpenCapture.
+func genericOperationRequestDataInWithTid(c *Client, tid ptp.TransactionID, code ptp.OperationCode, params []uint32) ([]byte, ptp.OperationResponseCode, error) {
+	res, data, err := GenericTransact(c, newOperationRequest(code, tid, params), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, res.ResponseCode, nil
+}
+
+// GenericOperationRequestDataOut sends an operation request followed by a data out phase holding the given data. An
+// error is returned when the Responder does not respond with ptp.RC_OK.
+func GenericOperationRequestDataOut(c *Client, code ptp.OperationCode, params []uint32, data []byte) error {
+	if data == nil {
+		data = []byte{}
+	}
+
+	res, _, err := GenericTransact(c, newOperationRequest(code, c.incrementTransactionId(), params), data)
+	if err != nil {
+		return err
+	}
+
+	return ptp.OperationResponseCodeAsError(res.ResponseCode)
+}
+
+// GenericTransact performs the given operation request using the packets defined by the PTP/IP standard. When dataOut
+// is not nil, it is sent in a data out phase following the request. The data sent by the Responder in the data in
+// phase, if any, is returned together with the operation response. The transaction ID of the request must have been
+// assigned by the caller.
+func GenericTransact(c *Client, req ptp.OperationRequest, dataOut []byte) (*ptp.OperationResponse, []byte, error) {
+	tid := req.TransactionID
+
+	resCh := make(chan []byte, 2)
+	if err := c.subscribe(tid, resCh); err != nil {
+		return nil, nil, err
+	}
+	defer c.unsubscribe(tid)
+
+	ps := []PacketOut{
+		&OperationRequestPacket{
+			DataPhaseInfo:    DP_NoDataOrDataIn,
+			OperationRequest: req,
+		},
+	}
+	if dataOut != nil {
+		ps = []PacketOut{
+			&OperationRequestPacket{
+				DataPhaseInfo:    DP_DataOut,
+				OperationRequest: req,
+			},
+			&StartDataPacket{
+				TransactionId:   tid,
+				TotalDataLength: uint64(len(dataOut)),
+			},
+			&EndDataPacket{
+				TransactionId: tid,
+				DataPayload:   dataOut,
+			},
+		}
+	}
+	for _, p := range ps {
+		if err := c.SendPacketToCmdDataConn(p); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var data []byte
+	for {
+		p, err := c.WaitForRawPacketFromCommandDataSubscriber(resCh)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The response listener has made sure the packet holds at least the transaction ID.
+		switch pt := PacketType(binary.LittleEndian.Uint32(p[4:8])); pt {
+		case PKT_StartData:
+		case PKT_Data, PKT_EndData:
+			data = append(data, p[HeaderSize+4:]...)
+		case PKT_OperationResponse:
+			orp := new(OperationResponsePacket)
+			if _, _, err := c.readResponse(bytes.NewReader(p), orp); err != nil {
+				return nil, nil, err
+			}
+			return &orp.OperationResponse, data, nil
+		default:
+			return nil, nil, fmt.Errorf("%w %#x", ErrUnknownPacketType, pt)
+		}
+	}
+}
+
+// newOperationRequest returns an operation request holding the given parameters. Only the first five parameters are
+// used.
+func newOperationRequest(code ptp.OperationCode, tid ptp.TransactionID, params []uint32) ptp.OperationRequest {
 	or := ptp.OperationRequest{
 		OperationCode: code,
 		TransactionID: tid,
@@ -242,35 +449,52 @@ func GenericOperationRequestRaw(c *Client, code ptp.OperationCode, params []uint
 	if len(params) >= 4 {
 		or.Parameter4 = params[3]
 	}
-	if len(params) == 5 {
+	if len(params) >= 5 {
 		or.Parameter5 = params[4]
 	}
-	resCh := make(chan []byte, 2)
-	if err := c.subscribe(tid, resCh); err != nil {
-		return nil, err
-	}
-	defer c.unsubscribe(tid)
 
-	err := c.SendPacketToCmdDataConn(&OperationRequestPacket{
-		DataPhaseInfo:    DP_NoDataOrDataIn,
-		OperationRequest: or,
-	})
+	return or
+}
 
+func GenericInitiateCapture(c *Client) ([]byte, error) {
+	return nil, errors.New("command not YET supported")
+}
+
+// GenericBulbCapture starts an exposure using ptp.OC_InitiateOpenCapture and terminates it using
+// ptp.OC_TerminateOpenCapture once the given duration has passed. The Responder is probed using ptp.OC_GetDeviceInfo in
+// the meantime to keep the session alive. No preview is returned: the object that was added is reported on the event
+// connection.
+func GenericBulbCapture(c *Client, d time.Duration) ([]byte, error) {
+	c.infow(SubsystemVendor, "opening shutter", "responder", c.ResponderFriendlyName(), "duration", d)
+	start := time.Now()
+	tid := c.incrementTransactionId()
+	// Zero for both the storage ID and the object format lets the Responder decide.
+	_, rc, err := genericOperationRequestDataInWithTid(c, tid, ptp.OC_InitiateOpenCapture, []uint32{0, 0})
 	if err != nil {
 		return nil, err
 	}
+	if rc != ptp.RC_OK {
+		return nil, ptp.OperationResponseCodeAsError(rc)
+	}
 
-	var raw [][]byte
-	raw[0], err = c.WaitForRawPacketFromCommandDataSubscriber(resCh)
-	if err != nil {
+	c.holdOpenCapture(start, d, func(c *Client) error {
+		_, err := GenericOperationRequestDataIn(c, ptp.OC_GetDeviceInfo, nil)
+		return err
+	})
+
+	c.infow(SubsystemVendor, "closing shutter", "responder", c.ResponderFriendlyName())
+	if _, err := GenericOperationRequestDataIn(c, ptp.OC_TerminateOpenCapture, []uint32{uint32(tid)}); err != nil {
 		return nil, err
 	}
 
-	// TODO: handle possible followup packets depending on the data phase returned.
-
-	return raw, err
+	return nil, nil
 }
 
-func GenericInitiateCapture(c *Client) ([]byte, error) {
-	return nil, errors.New("command not YET supported")
+// GenericToggleLiveView opens or closes the streamer connection.
+func GenericToggleLiveView(c *Client, en bool) error {
+	if en {
+		return c.initStreamConn()
+	}
+
+	return c.closeStreamConn()
 }
@@ -0,0 +1,352 @@
+package ptp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The datasets in this package are encoded following the rules of ISO 15740: all integers are little endian, strings
+// are prefixed with an 8 bit character count and hold null terminated UTF-16 characters, arrays are prefixed with a 32
+// bit element count and dates are strings formatted as described by FormatDateTime().
+
+const (
+	// dateTimeLayout is the layout of the DateTime string, the optional tenths of a second and time zone are handled
+	// separately.
+	dateTimeLayout = "20060102T150405"
+)
+
+var ErrInvalidDateTime = errors.New("invalid DateTime string")
+
+// FormatDateTime formats t as a PTP DateTime string: YYYYMMDDThhmmss.s where .s holds the optional tenths of a second.
+// Times in UTC get a Z appended, times in any other location except time.Local get their offset appended as +hhmm or
+// -hhmm. The zero time is formatted as the empty string.
+func FormatDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	s := t.Format(dateTimeLayout)
+	if d := t.Nanosecond() / 1e8; d != 0 {
+		s += fmt.Sprintf(".%d", d)
+	}
+	switch t.Location() {
+	case time.Local:
+	case time.UTC:
+		s += "Z"
+	default:
+		s += t.Format("-0700")
+	}
+
+	return s
+}
+
+// ParseDateTime parses a PTP DateTime string. Strings without a time zone are in the local time of the Responder which
+// is unknown, they are returned in time.Local. The empty string is parsed as the zero time.
+func ParseDateTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	// Go accepts fractional seconds after the seconds field when parsing even if the layout does not mention them.
+	if len(s) > len(dateTimeLayout) && strings.ContainsAny(s[len(dateTimeLayout):], "Z+-") {
+		if t, err := time.Parse(dateTimeLayout+"Z0700", s); err == nil {
+			return t, nil
+		}
+	} else if t, err := time.ParseInLocation(dateTimeLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w '%s'", ErrInvalidDateTime, s)
+}
+
+// encoder writes dataset fields to w. After the first error, all writes are ignored and the error is kept in err.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) write(v interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.LittleEndian, v)
+	}
+}
+
+func (e *encoder) string(s string) {
+	if e.err == nil {
+		e.err = encodeString(e.w, s)
+	}
+}
+
+func (e *encoder) array(a interface{}) {
+	if e.err == nil {
+		e.err = encodeArray(e.w, a)
+	}
+}
+
+func (e *encoder) dateTime(t time.Time) {
+	e.string(FormatDateTime(t))
+}
+
+func (e *encoder) raw(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+// form writes the raw values of a range or enumeration form. Any other form is ignored.
+func (e *encoder) form(f Form) {
+	switch form := f.(type) {
+	case *RangeForm:
+		e.raw(form.MinimumValue)
+		e.raw(form.MaximumValue)
+		e.raw(form.StepSize)
+	case *EnumerationForm:
+		e.write(uint16(len(form.SupportedValues)))
+		for _, v := range form.SupportedValues {
+			e.raw(v)
+		}
+	}
+}
+
+// decoder reads dataset fields from r. After the first error, all reads are ignored and the error is kept in err.
+type decoder struct {
+	r   io.Reader
+	err error
+}
+
+func (d *decoder) read(v interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.r, binary.LittleEndian, v)
+	}
+}
+
+func (d *decoder) string() string {
+	if d.err != nil {
+		return ""
+	}
+
+	var s string
+	s, d.err = decodeString(d.r)
+
+	return s
+}
+
+// array reads an array into the slice p points to. The slice is left untouched when the array is empty.
+func (d *decoder) array(p interface{}) {
+	if d.err != nil {
+		return
+	}
+
+	v := reflect.ValueOf(p).Elem()
+	a, err := decodeArray(d.r, v.Type().Elem())
+	if err != nil {
+		d.err = err
+		return
+	}
+	if av := reflect.ValueOf(a); av.Len() > 0 {
+		v.Set(av)
+	}
+}
+
+func (d *decoder) dateTime() time.Time {
+	s := d.string()
+	if d.err != nil {
+		return time.Time{}
+	}
+
+	var t time.Time
+	t, d.err = ParseDateTime(s)
+
+	return t
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740.
+func (di *DeviceInfo) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	e := &encoder{w: &b}
+	e.write(di.StandardVersion)
+	e.write(di.VendorExtensionID)
+	e.write(di.VendorExtensionVersion)
+	e.string(di.VendorExtensionDesc)
+	e.write(di.FunctionalMode)
+	e.array(di.OperationsSupported)
+	e.array(di.EventsSupported)
+	e.array(di.DevicePropertiesSupported)
+	e.array(di.CaptureFormats)
+	e.array(di.ImageFormats)
+	e.string(di.Manufacturer)
+	e.string(di.Model)
+	e.string(di.DeviceVersion)
+	e.string(di.SerialNumber)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740.
+func (di *DeviceInfo) UnmarshalPTP(b []byte) error {
+	var v DeviceInfo
+	d := &decoder{r: bytes.NewReader(b)}
+	d.read(&v.StandardVersion)
+	d.read(&v.VendorExtensionID)
+	d.read(&v.VendorExtensionVersion)
+	v.VendorExtensionDesc = d.string()
+	d.read(&v.FunctionalMode)
+	d.array(&v.OperationsSupported)
+	d.array(&v.EventsSupported)
+	d.array(&v.DevicePropertiesSupported)
+	d.array(&v.CaptureFormats)
+	d.array(&v.ImageFormats)
+	v.Manufacturer = d.string()
+	v.Model = d.string()
+	v.DeviceVersion = d.string()
+	v.SerialNumber = d.string()
+	if d.err != nil {
+		return d.err
+	}
+	*di = v
+
+	return nil
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740.
+func (si *StorageInfo) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	e := &encoder{w: &b}
+	e.write(si.StorageType)
+	e.write(si.FilesystemType)
+	e.write(si.AccessCapability)
+	e.write(si.MaxCapacity)
+	e.write(si.FreeSpaceInBytes)
+	e.write(si.FreeSpaceInImages)
+	e.string(si.StorageDescription)
+	e.string(si.VolumeLabel)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740.
+func (si *StorageInfo) UnmarshalPTP(b []byte) error {
+	var v StorageInfo
+	d := &decoder{r: bytes.NewReader(b)}
+	d.read(&v.StorageType)
+	d.read(&v.FilesystemType)
+	d.read(&v.AccessCapability)
+	d.read(&v.MaxCapacity)
+	d.read(&v.FreeSpaceInBytes)
+	d.read(&v.FreeSpaceInImages)
+	v.StorageDescription = d.string()
+	v.VolumeLabel = d.string()
+	if d.err != nil {
+		return d.err
+	}
+	*si = v
+
+	return nil
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740. The StorageID is included: it is ignored by the
+// Responder when sending the dataset with SendObjectInfo.
+func (oi *ObjectInfo) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	e := &encoder{w: &b}
+	e.write(oi.StorageID)
+	e.write(oi.ObjectFormat)
+	e.write(oi.ProtectionStatus)
+	e.write(oi.ObjectCompressedSize)
+	e.write(oi.ThumbFormat)
+	e.write(oi.ThumbCompressedSize)
+	e.write(oi.ThumbPixWidth)
+	e.write(oi.ThumbPixHeight)
+	e.write(oi.ImagePixWidth)
+	e.write(oi.ImagePixHeight)
+	e.write(oi.ImageBitDepth)
+	e.write(oi.ParentObject)
+	e.write(oi.AssociationType)
+	e.write(oi.AssociationDesc)
+	e.write(oi.SequenceNumber)
+	e.string(oi.Filename)
+	e.dateTime(oi.CaptureDate)
+	e.dateTime(oi.ModificationDate)
+	e.string(oi.Keywords)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740.
+func (oi *ObjectInfo) UnmarshalPTP(b []byte) error {
+	var v ObjectInfo
+	d := &decoder{r: bytes.NewReader(b)}
+	d.read(&v.StorageID)
+	d.read(&v.ObjectFormat)
+	d.read(&v.ProtectionStatus)
+	d.read(&v.ObjectCompressedSize)
+	d.read(&v.ThumbFormat)
+	d.read(&v.ThumbCompressedSize)
+	d.read(&v.ThumbPixWidth)
+	d.read(&v.ThumbPixHeight)
+	d.read(&v.ImagePixWidth)
+	d.read(&v.ImagePixHeight)
+	d.read(&v.ImageBitDepth)
+	d.read(&v.ParentObject)
+	d.read(&v.AssociationType)
+	d.read(&v.AssociationDesc)
+	d.read(&v.SequenceNumber)
+	v.Filename = d.string()
+	v.CaptureDate = d.dateTime()
+	v.ModificationDate = d.dateTime()
+	v.Keywords = d.string()
+	if d.err != nil {
+		return d.err
+	}
+	*oi = v
+
+	return nil
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740. The values are expected to hold the raw bytes of
+// the values as returned by ReadDevicePropDesc().
+func (dpd *DevicePropDesc) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	e := &encoder{w: &b}
+	e.write(dpd.DevicePropertyCode)
+	e.write(dpd.DataType)
+	e.write(dpd.GetSet)
+	e.raw(dpd.FactoryDefaultValue)
+	e.raw(dpd.CurrentValue)
+	e.write(dpd.FormFlag)
+	e.form(dpd.Form)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740 using ReadDevicePropDesc().
+func (dpd *DevicePropDesc) UnmarshalPTP(b []byte) error {
+	v, err := ReadDevicePropDesc(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*dpd = *v
+	if dpd.Form != nil {
+		dpd.Form.SetDevicePropDesc(dpd)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package ptp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// str returns the PTP encoding of an ASCII string.
+func str(s string) []byte {
+	if s == "" {
+		return []byte{0x00}
+	}
+
+	b := []byte{byte(len(s) + 1)}
+	for _, c := range []byte(s) {
+		b = append(b, c, 0x00)
+	}
+
+	return append(b, 0x00, 0x00)
+}
+
+func join(bs ...[]byte) []byte {
+	return bytes.Join(bs, nil)
+}
+
+func TestFormatDateTime(t *testing.T) {
+	check := map[string]time.Time{
+		"":                       {},
+		"20201231T235958Z":       time.Date(2020, 12, 31, 23, 59, 58, 0, time.UTC),
+		"20200102T030405.6Z":     time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC),
+		"20200102T030405+0130":   time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5400)),
+		"20200102T030405.1-0800": time.Date(2020, 1, 2, 3, 4, 5, 100000000, time.FixedZone("", -28800)),
+		"20200102T030405":        time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+	}
+
+	for want, tm := range check {
+		if got := FormatDateTime(tm); got != want {
+			t.Errorf("FormatDateTime() got = %s; want %s", got, want)
+		}
+
+		got, err := ParseDateTime(want)
+		if err != nil {
+			t.Errorf("ParseDateTime() error = %s; want <nil>", err)
+			continue
+		}
+		if !got.Equal(tm) {
+			t.Errorf("ParseDateTime() got = %s; want %s", got, tm)
+		}
+	}
+}
+
+func TestParseDateTime_Invalid(t *testing.T) {
+	for _, s := range []string{"2020", "2020-01-02T03:04:05", "20200102T030405X"} {
+		if _, err := ParseDateTime(s); !errors.Is(err, ErrInvalidDateTime) {
+			t.Errorf("ParseDateTime() error = %v; want %s", err, ErrInvalidDateTime)
+		}
+	}
+}
+
+func TestDeviceInfo_MarshalPTP(t *testing.T) {
+	want := join(
+		[]byte{0x64, 0x00},
+		[]byte{0x0e, 0x00, 0x00, 0x00},
+		[]byte{0x64, 0x00},
+		str("fujifilm.co.jp: 1.0;"),
+		[]byte{0x00, 0x00},
+		[]byte{0x03, 0x00, 0x00, 0x00, 0x01, 0x10, 0x02, 0x10, 0x2b, 0x90},
+		[]byte{0x01, 0x00, 0x00, 0x00, 0x02, 0x40},
+		[]byte{0x02, 0x00, 0x00, 0x00, 0x01, 0x50, 0x05, 0x50},
+		[]byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x38},
+		[]byte{0x00, 0x00, 0x00, 0x00},
+		str("FUJIFILM"),
+		str("X-T1"),
+		str("5.51"),
+		str(""),
+	)
+
+	di := &DeviceInfo{
+		StandardVersion:           100,
+		VendorExtensionID:         uint32(VE_FujiPhotoFilmCoLtd),
+		VendorExtensionVersion:    100,
+		VendorExtensionDesc:       "fujifilm.co.jp: 1.0;",
+		FunctionalMode:            0,
+		OperationsSupported:       []OperationCode{OC_GetDeviceInfo, OC_OpenSession, 0x902b},
+		EventsSupported:           []EventCode{EC_ObjectAdded},
+		DevicePropertiesSupported: []DevicePropCode{DPC_BatteryLevel, DPC_WhiteBalance},
+		CaptureFormats:            []ObjectFormatCode{OFC_EXIF_JPEG},
+		Manufacturer:              "FUJIFILM",
+		Model:                     "X-T1",
+		DeviceVersion:             "5.51",
+	}
+
+	got, err := di.MarshalPTP()
+	if err != nil {
+		t.Fatalf("MarshalPTP() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalPTP() got = %#v; want %#v", got, want)
+	}
+
+	var dec DeviceInfo
+	if err := dec.UnmarshalPTP(want); err != nil {
+		t.Fatalf("UnmarshalPTP() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(&dec, di) {
+		t.Errorf("UnmarshalPTP() got = %#v; want %#v", dec, di)
+	}
+
+	if err := dec.UnmarshalPTP(want[:len(want)-3]); err != io.ErrUnexpectedEOF {
+		t.Errorf("UnmarshalPTP() error = %v; want %s", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestStorageInfo_MarshalPTP(t *testing.T) {
+	want := join(
+		[]byte{0x04, 0x00, 0x03, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x00, 0x00, 0xc0, 0x07, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x00, 0x00, 0x40, 0x01, 0x00, 0x00, 0x00},
+		[]byte{0xa6, 0x03, 0x00, 0x00},
+		str("SD"),
+		str(""),
+	)
+
+	si := &StorageInfo{
+		StorageType:        ST_RemovableRAM,
+		FilesystemType:     FT_DCF,
+		AccessCapability:   AC_ReadWrite,
+		MaxCapacity:        0x7c0000000,
+		FreeSpaceInBytes:   0x140000000,
+		FreeSpaceInImages:  934,
+		StorageDescription: "SD",
+	}
+
+	got, err := si.MarshalPTP()
+	if err != nil {
+		t.Fatalf("MarshalPTP() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalPTP() got = %#v; want %#v", got, want)
+	}
+
+	var dec StorageInfo
+	if err := dec.UnmarshalPTP(want); err != nil {
+		t.Fatalf("UnmarshalPTP() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(&dec, si) {
+		t.Errorf("UnmarshalPTP() got = %#v; want %#v", dec, si)
+	}
+}
+
+func TestObjectInfo_MarshalPTP(t *testing.T) {
+	want := join(
+		[]byte{0x01, 0x00, 0x01, 0x00},
+		[]byte{0x01, 0x38, 0x00, 0x00},
+		[]byte{0x00, 0x40, 0x6b, 0x00},
+		[]byte{0x08, 0x38},
+		[]byte{0x00, 0x20, 0x00, 0x00},
+		[]byte{0xa0, 0x00, 0x00, 0x00, 0x78, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x0d, 0x00, 0x00, 0xc0, 0x09, 0x00, 0x00},
+		[]byte{0x18, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x00},
+		[]byte{0x00, 0x00, 0x00, 0x00},
+		[]byte{0x00, 0x00, 0x00, 0x00},
+		str("DSCF0001.JPG"),
+		str("20200102T030405Z"),
+		str(""),
+		str(""),
+	)
+
+	oi := &ObjectInfo{
+		StorageID:            0x10001,
+		ObjectFormat:         OFC_EXIF_JPEG,
+		ProtectionStatus:     PS_NoProtection,
+		ObjectCompressedSize: 0x6b4000,
+		ThumbFormat:          OFC_JFIF,
+		ThumbCompressedSize:  0x2000,
+		ThumbPixWidth:        160,
+		ThumbPixHeight:       120,
+		ImagePixWidth:        3328,
+		ImagePixHeight:       2496,
+		ImageBitDepth:        24,
+		Filename:             "DSCF0001.JPG",
+		CaptureDate:          time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	got, err := oi.MarshalPTP()
+	if err != nil {
+		t.Fatalf("MarshalPTP() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalPTP() got = %#v; want %#v", got, want)
+	}
+
+	var dec ObjectInfo
+	if err := dec.UnmarshalPTP(want); err != nil {
+		t.Fatalf("UnmarshalPTP() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(&dec, oi) {
+		t.Errorf("UnmarshalPTP() got = %#v; want %#v", dec, oi)
+	}
+}
+
+func TestDevicePropDesc_MarshalPTP(t *testing.T) {
+	// The payloads of the GetDevicePropDesc dumps in docs/fuji_x-t1_known-properties.md.
+	check := []struct {
+		prop string
+		b    []byte
+	}{
+		{
+			"battery level",
+			[]byte{0x01, 0x50, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x03, 0x01},
+		},
+		{
+			"white balance",
+			[]byte{
+				0x05, 0x50, 0x04, 0x00, 0x01, 0x02, 0x00, 0x02, 0x00, 0x02, 0x0a, 0x00, 0x02, 0x00, 0x04, 0x00, 0x06,
+				0x80, 0x01, 0x80, 0x02, 0x80, 0x03, 0x80, 0x06, 0x00, 0x0a, 0x80, 0x0b, 0x80, 0x0c, 0x80,
+			},
+		},
+		{
+			"focus metering mode",
+			[]byte{
+				0x7c, 0xd1, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x07, 0x02, 0x03, 0x01, 0x00, 0x00, 0x00,
+				0x00, 0x07, 0x07, 0x09, 0x10, 0x01, 0x00, 0x00, 0x00,
+			},
+		},
+	}
+
+	for _, c := range check {
+		var dpd DevicePropDesc
+		if err := dpd.UnmarshalPTP(c.b); err != nil {
+			t.Errorf("UnmarshalPTP() %s error = %s; want <nil>", c.prop, err)
+			continue
+		}
+		got, err := dpd.MarshalPTP()
+		if err != nil {
+			t.Errorf("MarshalPTP() %s error = %s; want <nil>", c.prop, err)
+			continue
+		}
+		if !bytes.Equal(got, c.b) {
+			t.Errorf("MarshalPTP() %s got = %#v; want %#v", c.prop, got, c.b)
+		}
+	}
+
+	var dpd DevicePropDesc
+	if err := dpd.UnmarshalPTP(check[2].b); err != nil {
+		t.Fatal(err)
+	}
+	if dpd.Form.(*RangeForm).DevicePropDesc != &dpd {
+		t.Errorf("UnmarshalPTP() form not linked to the unmarshalled DevicePropDesc")
+	}
+	if got := dpd.CurrentValueAsInt64(); got != 0x03020702 {
+		t.Errorf("UnmarshalPTP() current value = %#x; want %#x", got, 0x03020702)
+	}
+}
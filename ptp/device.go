@@ -1,5 +1,11 @@
 package ptp
 
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
 type DataTypeCode uint16
 
 // The most significant nibble (4 bits) is used to indicate the category of the code and whether the code value is
@@ -241,19 +247,14 @@ type DevicePropDesc struct {
 	Form Form
 }
 
+// SizeOfValueInBytes returns the size of a single value of the property. Zero is returned for strings and arrays
+// since the size of those values varies: use ReadDevicePropDesc() to read them.
 func (dpd *DevicePropDesc) SizeOfValueInBytes() int {
-	switch dpd.DataType {
-	case DTC_INT8, DTC_UINT8:
-		return 1
-	case DTC_INT16, DTC_UINT16:
-		return 2
-	case DTC_INT32, DTC_UINT32:
-		return 4
-	case DTC_INT64, DTC_UINT64:
-		return 8
-	default:
+	if dpd.DataType.IsArray() {
 		return 0
 	}
+
+	return dpd.DataType.ElementSize()
 }
 
 func (dpd *DevicePropDesc) FactoryDefaultValueAsInt64() int64 {
@@ -264,6 +265,16 @@ func (dpd *DevicePropDesc) CurrentValueAsInt64() int64 {
 	return byteArrayToInt64(dpd.CurrentValue, dpd.SizeOfValueInBytes())
 }
 
+// FactoryDefault returns the factory default value decoded according to the DataType of the property.
+func (dpd *DevicePropDesc) FactoryDefault() (PropertyValue, error) {
+	return UnmarshalPropertyValue(dpd.FactoryDefaultValue, dpd.DataType)
+}
+
+// Current returns the current value decoded according to the DataType of the property.
+func (dpd *DevicePropDesc) Current() (PropertyValue, error) {
+	return UnmarshalPropertyValue(dpd.CurrentValue, dpd.DataType)
+}
+
 type Form interface {
 	SetDevicePropDesc(*DevicePropDesc)
 }
@@ -296,6 +307,21 @@ func (rf *RangeForm) StepSizeAsInt64() int64 {
 	return byteArrayToInt64(rf.StepSize, 0)
 }
 
+// Minimum returns the minimum value decoded according to the DataType of the property the form belongs to.
+func (rf *RangeForm) Minimum() (PropertyValue, error) {
+	return UnmarshalPropertyValue(rf.MinimumValue, formDataType(rf.DevicePropDesc))
+}
+
+// Maximum returns the maximum value decoded according to the DataType of the property the form belongs to.
+func (rf *RangeForm) Maximum() (PropertyValue, error) {
+	return UnmarshalPropertyValue(rf.MaximumValue, formDataType(rf.DevicePropDesc))
+}
+
+// Step returns the step size decoded according to the DataType of the property the form belongs to.
+func (rf *RangeForm) Step() (PropertyValue, error) {
+	return UnmarshalPropertyValue(rf.StepSize, formDataType(rf.DevicePropDesc))
+}
+
 type EnumerationForm struct {
 	DevicePropDesc *DevicePropDesc
 	// NumberOfValues indicates the number of values of size DTS of the particular property supported by the device.
@@ -318,6 +344,114 @@ func (ef *EnumerationForm) SupportedValuesAsInt64Array() []int64 {
 	return a
 }
 
+// Values returns the supported values decoded according to the DataType of the property the form belongs to.
+func (ef *EnumerationForm) Values() ([]PropertyValue, error) {
+	dt := formDataType(ef.DevicePropDesc)
+	vals := make([]PropertyValue, len(ef.SupportedValues))
+	for i, b := range ef.SupportedValues {
+		v, err := UnmarshalPropertyValue(b, dt)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+
+	return vals, nil
+}
+
+// formDataType returns the DataType of the property a form belongs to or DTC_UNDEF when the form was not linked to a
+// property.
+func formDataType(dpd *DevicePropDesc) DataTypeCode {
+	if dpd == nil {
+		return DTC_UNDEF
+	}
+
+	return dpd.DataType
+}
+
+// ReadDevicePropDesc reads a DevicePropDesc dataset from r. All values are read according to the DataType of the
+// property, so strings, arrays and 128 bit integers are handled as well. The raw bytes of each value are kept in the
+// dataset. When r holds no data at all, io.EOF is returned.
+func ReadDevicePropDesc(r io.Reader) (*DevicePropDesc, error) {
+	dpd := new(DevicePropDesc)
+	if err := binary.Read(r, binary.LittleEndian, &dpd.DevicePropertyCode); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &dpd.DataType); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &dpd.GetSet); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if dpd.FactoryDefaultValue, err = readRawValue(r, dpd.DataType); err != nil {
+		return nil, err
+	}
+	if dpd.CurrentValue, err = readRawValue(r, dpd.DataType); err != nil {
+		return nil, err
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &dpd.FormFlag); err != nil {
+		return nil, err
+	}
+
+	if dpd.Form, err = readForm(r, dpd.FormFlag, dpd.DataType); err != nil {
+		return nil, err
+	}
+	if dpd.Form != nil {
+		dpd.Form.SetDevicePropDesc(dpd)
+	}
+
+	return dpd, nil
+}
+
+// readForm reads the form indicated by the form flag from r. Nil is returned when the form flag does not indicate a
+// range or enumeration form.
+func readForm(r io.Reader, flag DevicePropFormFlag, dt DataTypeCode) (Form, error) {
+	var err error
+	switch flag {
+	case DPF_FormFlag_Range:
+		form := new(RangeForm)
+		for _, v := range []*[]byte{&form.MinimumValue, &form.MaximumValue, &form.StepSize} {
+			if *v, err = readRawValue(r, dt); err != nil {
+				return nil, err
+			}
+		}
+		return form, nil
+	case DPF_FormFlag_Enum:
+		form := new(EnumerationForm)
+
+		var num uint16
+		if err := binary.Read(r, binary.LittleEndian, &num); err != nil {
+			return nil, err
+		}
+		form.NumberOfValues = int(num)
+
+		// Do not allocate the values up front since the number of values comes straight from the network.
+		for i := 0; i < form.NumberOfValues; i++ {
+			v, err := readRawValue(r, dt)
+			if err != nil {
+				return nil, err
+			}
+			form.SupportedValues = append(form.SupportedValues, v)
+		}
+		return form, nil
+	}
+
+	return nil, nil
+}
+
+// readRawValue reads a single value of the given data type from r and returns its raw bytes.
+func readRawValue(r io.Reader, dt DataTypeCode) ([]byte, error) {
+	var b bytes.Buffer
+	if _, err := DecodePropertyValue(io.TeeReader(r, &b), dt); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
 // DeviceInfo is used to hold the description information for a device. The Initiator can obtain this dataset from the
 // Responder without opening a session with the device. This dataset holds data that describes the device and its
 // capabilities. This information is only static if the device capabilities cannot change during a session, which would
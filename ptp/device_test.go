@@ -7,14 +7,18 @@ import (
 
 func TestDevicePropDesc_SizeOfValueInBytes(t *testing.T) {
 	check := map[DataTypeCode]int{
-		DTC_INT8:   1,
-		DTC_UINT8:  1,
-		DTC_INT16:  2,
-		DTC_UINT16: 2,
-		DTC_INT32:  4,
-		DTC_UINT32: 4,
-		DTC_INT64:  8,
-		DTC_UINT64: 8,
+		DTC_INT8:    1,
+		DTC_UINT8:   1,
+		DTC_INT16:   2,
+		DTC_UINT16:  2,
+		DTC_INT32:   4,
+		DTC_UINT32:  4,
+		DTC_INT64:   8,
+		DTC_UINT64:  8,
+		DTC_INT128:  16,
+		DTC_UINT128: 16,
+		DTC_AUINT8:  0,
+		DTC_STR:     0,
 	}
 
 	for code, want := range check {
@@ -0,0 +1,166 @@
+package ptp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrUnexpectedData is returned when the data received from the Responder does not hold the expected dataset.
+var ErrUnexpectedData = errors.New("unexpected data received")
+
+// Initiator performs operations on a Responder using the given Transport. The operations are independent of the
+// transport being used.
+type Initiator struct {
+	Transport Transport
+}
+
+// NewInitiator returns an Initiator performing operations over the given transport.
+func NewInitiator(t Transport) *Initiator {
+	return &Initiator{Transport: t}
+}
+
+// Do performs the given operation request, sending dataOut in a data out phase when it is not nil, and returns the data
+// received in the data in phase. An error is returned when the Responder does not respond with RC_OK.
+func (i *Initiator) Do(req OperationRequest, dataOut []byte) ([]byte, error) {
+	res, data, err := i.Transport.Transact(req, dataOut)
+	if err != nil {
+		return nil, err
+	}
+	if res.ResponseCode != RC_OK {
+		return nil, OperationResponseCodeAsError(res.ResponseCode)
+	}
+
+	return data, nil
+}
+
+// GetDeviceInfo returns the DeviceInfo dataset of the Responder.
+func (i *Initiator) GetDeviceInfo() (*DeviceInfo, error) {
+	b, err := i.Do(GetDeviceInfo(0), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	di := new(DeviceInfo)
+	if err := di.UnmarshalPTP(b); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedData, err)
+	}
+
+	return di, nil
+}
+
+// GetStorageIDs returns the IDs of the logical stores of the Responder.
+func (i *Initiator) GetStorageIDs() ([]StorageID, error) {
+	var ids []StorageID
+	if err := i.doArray(GetStorageIDs(), &ids); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetStorageInfo returns the StorageInfo dataset of the given store.
+func (i *Initiator) GetStorageInfo(sid StorageID) (*StorageInfo, error) {
+	b, err := i.Do(GetStorageInfo(sid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	si := new(StorageInfo)
+	if err := si.UnmarshalPTP(b); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedData, err)
+	}
+
+	return si, nil
+}
+
+// GetObjectHandles returns the handles of the objects in the given store having the given format and parent. See
+// GetObjectHandles() for the meaning of the zero values.
+func (i *Initiator) GetObjectHandles(sid StorageID, code ObjectFormatCode, parent ObjectHandle) ([]ObjectHandle, error) {
+	var hs []ObjectHandle
+	if err := i.doArray(GetObjectHandles(sid, code, parent), &hs); err != nil {
+		return nil, err
+	}
+
+	return hs, nil
+}
+
+// GetObjectInfo returns the ObjectInfo dataset of the given object.
+func (i *Initiator) GetObjectInfo(h ObjectHandle) (*ObjectInfo, error) {
+	b, err := i.Do(GetObjectInfo(h), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	oi := new(ObjectInfo)
+	if err := oi.UnmarshalPTP(b); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedData, err)
+	}
+
+	return oi, nil
+}
+
+// GetObject returns the data of the given object.
+func (i *Initiator) GetObject(h ObjectHandle) ([]byte, error) {
+	return i.Do(GetObject(h), nil)
+}
+
+// GetDevicePropDesc returns the description of the given device property.
+func (i *Initiator) GetDevicePropDesc(code DevicePropCode) (*DevicePropDesc, error) {
+	b, err := i.Do(GetDevicePropDesc(code), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dpd := new(DevicePropDesc)
+	if err := dpd.UnmarshalPTP(b); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedData, err)
+	}
+
+	return dpd, nil
+}
+
+// GetDevicePropValue returns the current value of the given device property. The data type of the value must be taken
+// from the property's DevicePropDesc.
+func (i *Initiator) GetDevicePropValue(code DevicePropCode, dt DataTypeCode) (PropertyValue, error) {
+	b, err := i.Do(GetDevicePropValue(code), nil)
+	if err != nil {
+		return PropertyValue{}, err
+	}
+
+	pv, err := UnmarshalPropertyValue(b, dt)
+	if err != nil {
+		return PropertyValue{}, fmt.Errorf("%w: %s", ErrUnexpectedData, err)
+	}
+
+	return pv, nil
+}
+
+// SetDevicePropValue sets the given device property to the given value, which is sent in the data phase.
+func (i *Initiator) SetDevicePropValue(code DevicePropCode, v PropertyValue) error {
+	var b bytes.Buffer
+	if err := v.Encode(&b); err != nil {
+		return err
+	}
+
+	_, err := i.Do(SetDevicePropValue(code, v), b.Bytes())
+
+	return err
+}
+
+// doArray performs the given operation request and decodes the data received as an array of uint32 elements into the
+// slice p points to.
+func (i *Initiator) doArray(req OperationRequest, p interface{}) error {
+	b, err := i.Do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	d := &decoder{r: bytes.NewReader(b)}
+	d.array(p)
+	if d.err != nil {
+		return fmt.Errorf("%w: %s", ErrUnexpectedData, d.err)
+	}
+
+	return nil
+}
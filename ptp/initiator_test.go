@@ -0,0 +1,106 @@
+package ptp
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeTransport answers each operation code with the data and response code configured for it and records the requests
+// and the data sent in the data out phase.
+type fakeTransport struct {
+	data    map[OperationCode][]byte
+	codes   map[OperationCode]OperationResponseCode
+	reqs    []OperationRequest
+	dataOut [][]byte
+}
+
+func (ft *fakeTransport) Transact(req OperationRequest, dataOut []byte) (*OperationResponse, []byte, error) {
+	ft.reqs = append(ft.reqs, req)
+	ft.dataOut = append(ft.dataOut, dataOut)
+
+	rc, ok := ft.codes[req.OperationCode]
+	if !ok {
+		rc = RC_OK
+	}
+
+	return &OperationResponse{ResponseCode: rc, TransactionID: TransactionID(len(ft.reqs))}, ft.data[req.OperationCode], nil
+}
+
+func TestInitiator_GetDeviceInfo(t *testing.T) {
+	want := &DeviceInfo{
+		StandardVersion:     100,
+		VendorExtensionID:   uint32(VE_FujiPhotoFilmCoLtd),
+		OperationsSupported: []OperationCode{OC_GetDeviceInfo, OC_OpenSession},
+		Manufacturer:        "FUJIFILM",
+		Model:               "X-T1",
+	}
+	b, err := want.MarshalPTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft := &fakeTransport{data: map[OperationCode][]byte{OC_GetDeviceInfo: b}}
+	got, err := NewInitiator(ft).GetDeviceInfo()
+	if err != nil {
+		t.Fatalf("GetDeviceInfo() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeviceInfo() = %+v; want %+v", got, want)
+	}
+	if ft.reqs[0].OperationCode != OC_GetDeviceInfo {
+		t.Errorf("GetDeviceInfo() OperationCode = %#x; want %#x", ft.reqs[0].OperationCode, OC_GetDeviceInfo)
+	}
+}
+
+func TestInitiator_GetObjectHandles(t *testing.T) {
+	ft := &fakeTransport{data: map[OperationCode][]byte{
+		OC_GetObjectHandles: {0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+	}}
+	got, err := NewInitiator(ft).GetObjectHandles(0xffffffff, OFC_EXIF_JPEG, 0)
+	if err != nil {
+		t.Fatalf("GetObjectHandles() error = %s; want <nil>", err)
+	}
+	want := []ObjectHandle{1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObjectHandles() = %v; want %v", got, want)
+	}
+	req := ft.reqs[0]
+	if req.Parameter1 != 0xffffffff || req.Parameter2 != uint32(OFC_EXIF_JPEG) || req.Parameter3 != 0 {
+		t.Errorf("GetObjectHandles() parameters = %#x, %#x, %#x", req.Parameter1, req.Parameter2, req.Parameter3)
+	}
+}
+
+func TestInitiator_GetObjectHandles_invalidData(t *testing.T) {
+	ft := &fakeTransport{data: map[OperationCode][]byte{OC_GetObjectHandles: {0x02, 0x00, 0x00, 0x00, 0x01}}}
+	if _, err := NewInitiator(ft).GetObjectHandles(0, 0, 0); !errors.Is(err, ErrUnexpectedData) {
+		t.Errorf("GetObjectHandles() error = %v; want %s", err, ErrUnexpectedData)
+	}
+}
+
+func TestInitiator_SetDevicePropValue(t *testing.T) {
+	ft := &fakeTransport{}
+	pv, err := NewPropertyValue(DTC_UINT16, uint16(WB_Automatic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewInitiator(ft).SetDevicePropValue(DPC_WhiteBalance, pv); err != nil {
+		t.Fatalf("SetDevicePropValue() error = %s; want <nil>", err)
+	}
+	if ft.reqs[0].OperationCode != OC_SetDevicePropValue || ft.reqs[0].Parameter1 != uint32(DPC_WhiteBalance) {
+		t.Errorf("SetDevicePropValue() request = %+v", ft.reqs[0])
+	}
+	if want := []byte{byte(WB_Automatic), 0x00}; !bytes.Equal(ft.dataOut[0], want) {
+		t.Errorf("SetDevicePropValue() data out = %#x; want %#x", ft.dataOut[0], want)
+	}
+}
+
+func TestInitiator_Do_responseCode(t *testing.T) {
+	ft := &fakeTransport{codes: map[OperationCode]OperationResponseCode{OC_GetObject: RC_InvalidObjectHandle}}
+	_, err := NewInitiator(ft).GetObject(5)
+	want := OperationResponseCodeAsError(RC_InvalidObjectHandle)
+	if err == nil || err.Error() != want.Error() {
+		t.Errorf("GetObject() error = %v; want %s", err, want)
+	}
+}
@@ -0,0 +1,334 @@
+package ptp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// The Media Transfer Protocol (MTP) is the PTP vendor extension of VE_MicrosoftCorporation. Many cameras implement its
+// object property operations on top of their own vendor extension which allows fetching object metadata in bulk
+// instead of requesting an ObjectInfo dataset for each object.
+
+// ObjectPropCode identifies an MTP object property. The most significant nibble is 1101 for all object properties.
+type ObjectPropCode uint16
+
+const (
+	OC_MTP_GetObjectPropsSupported   OperationCode = 0x9801
+	OC_MTP_GetObjectPropDesc         OperationCode = 0x9802
+	OC_MTP_GetObjectPropValue        OperationCode = 0x9803
+	OC_MTP_SetObjectPropValue        OperationCode = 0x9804
+	OC_MTP_GetObjectPropList         OperationCode = 0x9805
+	OC_MTP_SetObjectPropList         OperationCode = 0x9806
+	OC_MTP_GetInterdependentPropDesc OperationCode = 0x9807
+	OC_MTP_SendObjectPropList        OperationCode = 0x9808
+	OC_MTP_GetObjectReferences       OperationCode = 0x9810
+	OC_MTP_SetObjectReferences       OperationCode = 0x9811
+	OC_MTP_Skip                      OperationCode = 0x9820
+
+	RC_MTP_InvalidObjectPropCode           OperationResponseCode = 0xA801
+	RC_MTP_InvalidObjectPropFormat         OperationResponseCode = 0xA802
+	RC_MTP_InvalidObjectPropValue          OperationResponseCode = 0xA803
+	RC_MTP_InvalidObjectReference          OperationResponseCode = 0xA804
+	RC_MTP_GroupNotSupported               OperationResponseCode = 0xA805
+	RC_MTP_InvalidDataset                  OperationResponseCode = 0xA806
+	RC_MTP_SpecificationByGroupUnsupported OperationResponseCode = 0xA807
+	RC_MTP_SpecificationByDepthUnsupported OperationResponseCode = 0xA808
+	RC_MTP_ObjectTooLarge                  OperationResponseCode = 0xA809
+	RC_MTP_ObjectPropNotSupported          OperationResponseCode = 0xA80A
+
+	EC_MTP_ObjectPropChanged       EventCode = 0xC801
+	EC_MTP_ObjectPropDescChanged   EventCode = 0xC802
+	EC_MTP_ObjectReferencesChanged EventCode = 0xC803
+
+	OFC_MTP_UndefinedFirmware   ObjectFormatCode = 0xB802
+	OFC_MTP_WindowsImageFormat  ObjectFormatCode = 0xB881
+	OFC_MTP_UndefinedAudio      ObjectFormatCode = 0xB900
+	OFC_MTP_WMA                 ObjectFormatCode = 0xB901
+	OFC_MTP_OGG                 ObjectFormatCode = 0xB902
+	OFC_MTP_AAC                 ObjectFormatCode = 0xB903
+	OFC_MTP_FLAC                ObjectFormatCode = 0xB906
+	OFC_MTP_UndefinedVideo      ObjectFormatCode = 0xB980
+	OFC_MTP_WMV                 ObjectFormatCode = 0xB981
+	OFC_MTP_MP4Container        ObjectFormatCode = 0xB982
+	OFC_MTP_3GPContainer        ObjectFormatCode = 0xB984
+	OFC_MTP_AbstractImageAlbum  ObjectFormatCode = 0xBA02
+	OFC_MTP_AbstractVideoAlbum  ObjectFormatCode = 0xBA04
+	OFC_MTP_UndefinedCollection ObjectFormatCode = 0xBA00
+
+	OPC_StorageID                        ObjectPropCode = 0xDC01
+	OPC_ObjectFormat                     ObjectPropCode = 0xDC02
+	OPC_ProtectionStatus                 ObjectPropCode = 0xDC03
+	OPC_ObjectSize                       ObjectPropCode = 0xDC04
+	OPC_AssociationType                  ObjectPropCode = 0xDC05
+	OPC_AssociationDesc                  ObjectPropCode = 0xDC06
+	OPC_ObjectFileName                   ObjectPropCode = 0xDC07
+	OPC_DateCreated                      ObjectPropCode = 0xDC08
+	OPC_DateModified                     ObjectPropCode = 0xDC09
+	OPC_Keywords                         ObjectPropCode = 0xDC0A
+	OPC_ParentObject                     ObjectPropCode = 0xDC0B
+	OPC_AllowedFolderContents            ObjectPropCode = 0xDC0C
+	OPC_Hidden                           ObjectPropCode = 0xDC0D
+	OPC_SystemObject                     ObjectPropCode = 0xDC0E
+	OPC_PersistentUniqueObjectIdentifier ObjectPropCode = 0xDC41
+	OPC_SyncID                           ObjectPropCode = 0xDC42
+	OPC_PropertyBag                      ObjectPropCode = 0xDC43
+	OPC_Name                             ObjectPropCode = 0xDC44
+	OPC_CreatedBy                        ObjectPropCode = 0xDC45
+	OPC_Artist                           ObjectPropCode = 0xDC46
+	OPC_DateAuthored                     ObjectPropCode = 0xDC47
+	OPC_Description                      ObjectPropCode = 0xDC48
+	OPC_URLReference                     ObjectPropCode = 0xDC49
+	OPC_LanguageLocale                   ObjectPropCode = 0xDC4A
+	OPC_CopyrightInformation             ObjectPropCode = 0xDC4B
+	OPC_Source                           ObjectPropCode = 0xDC4C
+	OPC_OriginLocation                   ObjectPropCode = 0xDC4D
+	OPC_DateAdded                        ObjectPropCode = 0xDC4E
+	OPC_NonConsumable                    ObjectPropCode = 0xDC4F
+	OPC_CorruptUnplayable                ObjectPropCode = 0xDC50
+	OPC_RepresentativeSampleFormat       ObjectPropCode = 0xDC81
+	OPC_RepresentativeSampleSize         ObjectPropCode = 0xDC82
+	OPC_RepresentativeSampleHeight       ObjectPropCode = 0xDC83
+	OPC_RepresentativeSampleWidth        ObjectPropCode = 0xDC84
+	OPC_RepresentativeSampleDuration     ObjectPropCode = 0xDC85
+	OPC_RepresentativeSampleData         ObjectPropCode = 0xDC86
+	OPC_Width                            ObjectPropCode = 0xDC87
+	OPC_Height                           ObjectPropCode = 0xDC88
+	OPC_Duration                         ObjectPropCode = 0xDC89
+	OPC_Rating                           ObjectPropCode = 0xDC8A
+	OPC_FrameRate                        ObjectPropCode = 0xDE82
+	OPC_VideoFourCCCodec                 ObjectPropCode = 0xDE9A
+	OPC_VideoBitRate                     ObjectPropCode = 0xDE9C
+
+	// DPF_FormFlag_DateTime indicates an object property holding a DateTime string. The form is absent.
+	DPF_FormFlag_DateTime DevicePropFormFlag = 0x03
+	// DPF_FormFlag_FixedLengthArray indicates an array object property holding a fixed number of elements.
+	DPF_FormFlag_FixedLengthArray DevicePropFormFlag = 0x04
+	// DPF_FormFlag_RegularExpression indicates a string object property the value of which must match a regular
+	// expression.
+	DPF_FormFlag_RegularExpression DevicePropFormFlag = 0x05
+	// DPF_FormFlag_ByteArray indicates an AUINT8 object property that should be interpreted as a binary blob.
+	DPF_FormFlag_ByteArray DevicePropFormFlag = 0x06
+	// DPF_FormFlag_LongString indicates an AUINT16 object property that should be interpreted as a string exceeding the
+	// maximum PTP string length.
+	DPF_FormFlag_LongString DevicePropFormFlag = 0xFF
+
+	// PM_MTP_AllObjects selects all objects as the ObjectHandle parameter of GetObjectPropList.
+	PM_MTP_AllObjects ObjectHandle = 0xFFFFFFFF
+	// PM_MTP_AllProperties selects all properties as the ObjectPropCode parameter of GetObjectPropList.
+	PM_MTP_AllProperties uint32 = 0xFFFFFFFF
+	// PM_MTP_ByGroup indicates the properties are selected using the group code parameter of GetObjectPropList.
+	PM_MTP_ByGroup uint32 = 0x00000000
+	// PM_MTP_AllDepths selects the objects at all levels below the given object as the depth parameter of
+	// GetObjectPropList.
+	PM_MTP_AllDepths uint32 = 0xFFFFFFFF
+)
+
+// ObjectPropDesc describes an MTP object property for a specific object format.
+type ObjectPropDesc struct {
+	// ObjectPropertyCode identifies the object property.
+	ObjectPropertyCode ObjectPropCode
+	// DataType identifies the DataTypeCode of the property.
+	DataType DataTypeCode
+	// GetSet indicates whether the property is read-only (Get) or read-write (Get/Set).
+	GetSet DevicePropDescCode
+	// FactoryDefaultValue holds the raw factory default value of the property.
+	FactoryDefaultValue []byte
+	// GroupCode identifies the group the property belongs to, which allows retrieving related properties in one go
+	// using GetObjectPropList.
+	GroupCode uint32
+	// FormFlag indicates the format of the next field.
+	FormFlag DevicePropFormFlag
+	// Form is a RangeForm, EnumerationForm, LengthForm or RegularExpressionForm depending on the FormFlag or is absent.
+	// Range and enumeration forms are not linked to a DevicePropDesc: decode their raw values using DataType.
+	Form Form
+}
+
+// FactoryDefault returns the factory default value decoded according to the DataType of the property.
+func (opd *ObjectPropDesc) FactoryDefault() (PropertyValue, error) {
+	return UnmarshalPropertyValue(opd.FactoryDefaultValue, opd.DataType)
+}
+
+// LengthForm holds the maximum length of the DPF_FormFlag_ByteArray and DPF_FormFlag_LongString forms or the fixed
+// length of the DPF_FormFlag_FixedLengthArray form.
+type LengthForm struct {
+	Length uint32
+}
+
+// SetDevicePropDesc does nothing: the form does not depend on the property it belongs to.
+func (lf *LengthForm) SetDevicePropDesc(_ *DevicePropDesc) {}
+
+// RegularExpressionForm holds the regular expression the value of a string property must match.
+type RegularExpressionForm struct {
+	Pattern string
+}
+
+// SetDevicePropDesc does nothing: the form does not depend on the property it belongs to.
+func (ref *RegularExpressionForm) SetDevicePropDesc(_ *DevicePropDesc) {}
+
+// ReadObjectPropDesc reads an ObjectPropDesc dataset from r.
+func ReadObjectPropDesc(r io.Reader) (*ObjectPropDesc, error) {
+	opd := new(ObjectPropDesc)
+	d := &decoder{r: r}
+	d.read(&opd.ObjectPropertyCode)
+	d.read(&opd.DataType)
+	d.read(&opd.GetSet)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	var err error
+	if opd.FactoryDefaultValue, err = readRawValue(r, opd.DataType); err != nil {
+		return nil, err
+	}
+
+	d.read(&opd.GroupCode)
+	d.read(&opd.FormFlag)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	switch opd.FormFlag {
+	case DPF_FormFlag_FixedLengthArray:
+		var l uint16
+		d.read(&l)
+		opd.Form = &LengthForm{Length: uint32(l)}
+	case DPF_FormFlag_ByteArray, DPF_FormFlag_LongString:
+		form := new(LengthForm)
+		d.read(&form.Length)
+		opd.Form = form
+	case DPF_FormFlag_RegularExpression:
+		opd.Form = &RegularExpressionForm{Pattern: d.string()}
+	default:
+		opd.Form, d.err = readForm(r, opd.FormFlag, opd.DataType)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return opd, nil
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740.
+func (opd *ObjectPropDesc) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	e := &encoder{w: &b}
+	e.write(opd.ObjectPropertyCode)
+	e.write(opd.DataType)
+	e.write(opd.GetSet)
+	e.raw(opd.FactoryDefaultValue)
+	e.write(opd.GroupCode)
+	e.write(opd.FormFlag)
+	switch form := opd.Form.(type) {
+	case *LengthForm:
+		if opd.FormFlag == DPF_FormFlag_FixedLengthArray {
+			e.write(uint16(form.Length))
+		} else {
+			e.write(form.Length)
+		}
+	case *RegularExpressionForm:
+		e.string(form.Pattern)
+	default:
+		e.form(form)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740 using ReadObjectPropDesc().
+func (opd *ObjectPropDesc) UnmarshalPTP(b []byte) error {
+	v, err := ReadObjectPropDesc(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*opd = *v
+
+	return nil
+}
+
+// ObjectPropListElement is a single property value of a single object.
+type ObjectPropListElement struct {
+	ObjectHandle ObjectHandle
+	PropertyCode ObjectPropCode
+	Value        PropertyValue
+}
+
+// ObjectPropList is the dataset returned by GetObjectPropList and sent by SetObjectPropList. It holds any number of
+// property values for any number of objects.
+type ObjectPropList []ObjectPropListElement
+
+// Objects groups the property values per object.
+func (l ObjectPropList) Objects() map[ObjectHandle]map[ObjectPropCode]PropertyValue {
+	objs := make(map[ObjectHandle]map[ObjectPropCode]PropertyValue)
+	for _, el := range l {
+		props, ok := objs[el.ObjectHandle]
+		if !ok {
+			props = make(map[ObjectPropCode]PropertyValue)
+			objs[el.ObjectHandle] = props
+		}
+		props[el.PropertyCode] = el.Value
+	}
+
+	return objs
+}
+
+// MarshalPTP encodes the dataset following the rules of ISO 15740.
+func (l ObjectPropList) MarshalPTP() ([]byte, error) {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, uint32(len(l))); err != nil {
+		return nil, err
+	}
+	for _, el := range l {
+		e := &encoder{w: &b}
+		e.write(el.ObjectHandle)
+		e.write(el.PropertyCode)
+		e.write(el.Value.DataType)
+		if e.err != nil {
+			return nil, e.err
+		}
+		if err := el.Value.Encode(&b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalPTP decodes a dataset encoded following the rules of ISO 15740.
+func (l *ObjectPropList) UnmarshalPTP(b []byte) error {
+	r := bytes.NewReader(b)
+
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return err
+	}
+
+	// Do not allocate the list up front since the number of elements comes straight from the network.
+	var list ObjectPropList
+	for i := uint32(0); i < n; i++ {
+		var (
+			el ObjectPropListElement
+			dt DataTypeCode
+		)
+		d := &decoder{r: r}
+		d.read(&el.ObjectHandle)
+		d.read(&el.PropertyCode)
+		d.read(&dt)
+		if d.err != nil {
+			return d.err
+		}
+
+		v, err := DecodePropertyValue(r, dt)
+		if err != nil {
+			return err
+		}
+		el.Value = v
+		list = append(list, el)
+	}
+	*l = list
+
+	return nil
+}
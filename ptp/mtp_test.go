@@ -0,0 +1,152 @@
+package ptp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestObjectPropDesc_MarshalPTP(t *testing.T) {
+	check := []struct {
+		prop string
+		b    []byte
+		form Form
+	}{
+		{
+			"object size",
+			join(
+				[]byte{0x04, 0xdc, 0x08, 0x00, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x00},
+			),
+			nil,
+		},
+		{
+			"rating",
+			join(
+				[]byte{0x8a, 0xdc, 0x04, 0x00, 0x01},
+				[]byte{0x00, 0x00},
+				[]byte{0x02, 0x00, 0x00, 0x00},
+				[]byte{0x01, 0x00, 0x00, 0x63, 0x00, 0x01, 0x00},
+			),
+			&RangeForm{MinimumValue: []byte{0x00, 0x00}, MaximumValue: []byte{0x63, 0x00}, StepSize: []byte{0x01, 0x00}},
+		},
+		{
+			"protection status",
+			join(
+				[]byte{0x03, 0xdc, 0x04, 0x00, 0x01},
+				[]byte{0x00, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x02, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00},
+			),
+			&EnumerationForm{NumberOfValues: 2, SupportedValues: [][]byte{{0x00, 0x00}, {0x01, 0x00}}},
+		},
+		{
+			"date created",
+			join(
+				[]byte{0x08, 0xdc, 0xff, 0xff, 0x00},
+				str(""),
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x03},
+			),
+			nil,
+		},
+		{
+			"file name",
+			join(
+				[]byte{0x07, 0xdc, 0xff, 0xff, 0x01},
+				str(""),
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x05},
+				str("[A-Z0-9_]{8}\\.JPG"),
+			),
+			&RegularExpressionForm{Pattern: "[A-Z0-9_]{8}\\.JPG"},
+		},
+		{
+			"fixed length array",
+			join(
+				[]byte{0x41, 0xdc, 0x06, 0x40, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x04, 0x04, 0x00},
+			),
+			&LengthForm{Length: 4},
+		},
+		{
+			"long string",
+			join(
+				[]byte{0x48, 0xdc, 0x04, 0x40, 0x01},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				[]byte{0xff, 0x00, 0x04, 0x00, 0x00},
+			),
+			&LengthForm{Length: 1024},
+		},
+	}
+
+	for _, c := range check {
+		var opd ObjectPropDesc
+		if err := opd.UnmarshalPTP(c.b); err != nil {
+			t.Errorf("UnmarshalPTP() %s error = %s; want <nil>", c.prop, err)
+			continue
+		}
+		if !reflect.DeepEqual(opd.Form, c.form) {
+			t.Errorf("UnmarshalPTP() %s form got = %#v; want %#v", c.prop, opd.Form, c.form)
+		}
+		got, err := opd.MarshalPTP()
+		if err != nil {
+			t.Errorf("MarshalPTP() %s error = %s; want <nil>", c.prop, err)
+			continue
+		}
+		if !bytes.Equal(got, c.b) {
+			t.Errorf("MarshalPTP() %s got = %#v; want %#v", c.prop, got, c.b)
+		}
+	}
+}
+
+func TestObjectPropList_MarshalPTP(t *testing.T) {
+	want := join(
+		[]byte{0x03, 0x00, 0x00, 0x00},
+		[]byte{0x01, 0x00, 0x00, 0x00, 0x07, 0xdc, 0xff, 0xff},
+		str("DSCF0001.JPG"),
+		[]byte{0x01, 0x00, 0x00, 0x00, 0x04, 0xdc, 0x08, 0x00},
+		[]byte{0x00, 0x40, 0x6b, 0x00, 0x00, 0x00, 0x00, 0x00},
+		[]byte{0x02, 0x00, 0x00, 0x00, 0x07, 0xdc, 0xff, 0xff},
+		str("DSCF0002.RAF"),
+	)
+
+	l := ObjectPropList{
+		{ObjectHandle: 1, PropertyCode: OPC_ObjectFileName, Value: PropertyValue{DataType: DTC_STR, Value: "DSCF0001.JPG"}},
+		{ObjectHandle: 1, PropertyCode: OPC_ObjectSize, Value: PropertyValue{DataType: DTC_UINT64, Value: uint64(0x6b4000)}},
+		{ObjectHandle: 2, PropertyCode: OPC_ObjectFileName, Value: PropertyValue{DataType: DTC_STR, Value: "DSCF0002.RAF"}},
+	}
+
+	got, err := l.MarshalPTP()
+	if err != nil {
+		t.Fatalf("MarshalPTP() error = %s; want <nil>", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalPTP() got = %#v; want %#v", got, want)
+	}
+
+	var dec ObjectPropList
+	if err := dec.UnmarshalPTP(want); err != nil {
+		t.Fatalf("UnmarshalPTP() error = %s; want <nil>", err)
+	}
+	if !reflect.DeepEqual(dec, l) {
+		t.Errorf("UnmarshalPTP() got = %#v; want %#v", dec, l)
+	}
+
+	objs := dec.Objects()
+	if len(objs) != 2 {
+		t.Errorf("Objects() got %d objects; want 2", len(objs))
+	}
+	if got := objs[1][OPC_ObjectSize].Value; got != uint64(0x6b4000) {
+		t.Errorf("Objects() size got = %v; want %v", got, 0x6b4000)
+	}
+
+	if err := dec.UnmarshalPTP(want[:len(want)-3]); err == nil {
+		t.Errorf("UnmarshalPTP() error = <nil>; want error")
+	}
+}
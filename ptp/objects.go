@@ -2,7 +2,7 @@ package ptp
 
 import "time"
 
-type AssociationDesc uint16
+type AssociationDesc uint32
 type AssociationType uint16
 
 // The most significant nibble (4 bits) is used to indicate the category of the code and whether the code value is
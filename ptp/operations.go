@@ -165,6 +165,26 @@ func OperationResponseCodeAsError(code OperationResponseCode) error {
 		err = "transaction cancelled"
 	case RC_SpecificationofDestinationUnsupported:
 		err = "specification of destination unsupported"
+	case RC_MTP_InvalidObjectPropCode:
+		err = "invalid object property code"
+	case RC_MTP_InvalidObjectPropFormat:
+		err = "invalid object property format"
+	case RC_MTP_InvalidObjectPropValue:
+		err = "invalid object property value"
+	case RC_MTP_InvalidObjectReference:
+		err = "invalid object reference"
+	case RC_MTP_GroupNotSupported:
+		err = "group not supported"
+	case RC_MTP_InvalidDataset:
+		err = "invalid dataset"
+	case RC_MTP_SpecificationByGroupUnsupported:
+		err = "specification by group unsupported"
+	case RC_MTP_SpecificationByDepthUnsupported:
+		err = "specification by depth unsupported"
+	case RC_MTP_ObjectTooLarge:
+		err = "object too large"
+	case RC_MTP_ObjectPropNotSupported:
+		err = "object property not supported"
 	default:
 		err = fmt.Sprintf("unknown operation response code: %#x", code)
 	}
@@ -0,0 +1,26 @@
+package ptp
+
+// Transport executes operations on the Responder. An operation consists of an operation request, an optional data
+// phase in either direction and an operation response. How these are framed and carried is up to the Transport: PTP/IP
+// wraps them in packets sent over TCP connections whereas PTP over USB (ISO 15740 Annex D) wraps them in containers
+// sent over the bulk pipes.
+//
+// Operations only talk to the Responder through a Transport which makes them independent of the transport being used.
+// To add support for a new transport, e.g. a USB backend, implement Transact.
+type Transport interface {
+	// Transact sends the given operation request to the Responder. When dataOut is not nil, it is sent in a data out
+	// phase following the request. The operation response is returned together with the data received in the data in
+	// phase, which is nil when there was none. The SessionID and TransactionID of the request are assigned by the
+	// Transport.
+	// An error is only returned when the operation could not be carried out: a response code other than RC_OK is not
+	// an error at this level.
+	Transact(req OperationRequest, dataOut []byte) (*OperationResponse, []byte, error)
+}
+
+// TransportFunc is an adapter to allow the use of an ordinary function as a Transport.
+type TransportFunc func(req OperationRequest, dataOut []byte) (*OperationResponse, []byte, error)
+
+// Transact calls f(req, dataOut).
+func (f TransportFunc) Transact(req OperationRequest, dataOut []byte) (*OperationResponse, []byte, error) {
+	return f(req, dataOut)
+}
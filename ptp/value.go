@@ -0,0 +1,314 @@
+package ptp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"unicode/utf16"
+)
+
+const (
+	// arrayFlag is set in the DataTypeCode of all array types.
+	arrayFlag DataTypeCode = 0x4000
+	// maxStringChars is the maximum number of characters of a PTP string, including the null terminator.
+	maxStringChars = 255
+	// maxArrayPrealloc caps the capacity allocated up front for arrays since the number of elements comes straight from
+	// the network.
+	maxArrayPrealloc = 256
+)
+
+var (
+	ErrUnsupportedDataType = errors.New("unsupported data type")
+	ErrValueTypeMismatch   = errors.New("value does not match data type")
+	ErrStringTooLong       = errors.New("string too long")
+)
+
+// Int128 holds a signed 128 bit integer.
+type Int128 struct {
+	Lo uint64
+	Hi int64
+}
+
+// Big returns the value as a big.Int.
+func (i Int128) Big() *big.Int {
+	b := new(big.Int).Lsh(big.NewInt(i.Hi), 64)
+
+	return b.Or(b, new(big.Int).SetUint64(i.Lo))
+}
+
+func (i Int128) String() string {
+	return i.Big().String()
+}
+
+// UInt128 holds an unsigned 128 bit integer.
+type UInt128 struct {
+	Lo uint64
+	Hi uint64
+}
+
+// Big returns the value as a big.Int.
+func (u UInt128) Big() *big.Int {
+	b := new(big.Int).Lsh(new(big.Int).SetUint64(u.Hi), 64)
+
+	return b.Or(b, new(big.Int).SetUint64(u.Lo))
+}
+
+func (u UInt128) String() string {
+	return u.Big().String()
+}
+
+// scalarTypes maps the integer DataTypeCodes to the Go types used to hold their values.
+var scalarTypes = map[DataTypeCode]reflect.Type{
+	DTC_INT8:    reflect.TypeOf(int8(0)),
+	DTC_UINT8:   reflect.TypeOf(uint8(0)),
+	DTC_INT16:   reflect.TypeOf(int16(0)),
+	DTC_UINT16:  reflect.TypeOf(uint16(0)),
+	DTC_INT32:   reflect.TypeOf(int32(0)),
+	DTC_UINT32:  reflect.TypeOf(uint32(0)),
+	DTC_INT64:   reflect.TypeOf(int64(0)),
+	DTC_UINT64:  reflect.TypeOf(uint64(0)),
+	DTC_INT128:  reflect.TypeOf(Int128{}),
+	DTC_UINT128: reflect.TypeOf(UInt128{}),
+}
+
+// IsArray returns true for the array data types.
+func (dt DataTypeCode) IsArray() bool {
+	return dt != DTC_STR && dt&arrayFlag != 0
+}
+
+// ElementType returns the data type of the elements of an array data type. Any other data type is returned as is.
+func (dt DataTypeCode) ElementType() DataTypeCode {
+	if dt.IsArray() {
+		return dt &^ arrayFlag
+	}
+
+	return dt
+}
+
+// ElementSize returns the size in bytes of a single integer or of a single array element. Zero is returned for strings
+// and undefined data types.
+func (dt DataTypeCode) ElementSize() int {
+	if t, ok := scalarTypes[dt.ElementType()]; ok {
+		return int(t.Size())
+	}
+
+	return 0
+}
+
+// goType returns the Go type used to hold a value of the data type.
+func (dt DataTypeCode) goType() (reflect.Type, error) {
+	if dt == DTC_STR {
+		return reflect.TypeOf(""), nil
+	}
+	t, ok := scalarTypes[dt.ElementType()]
+	if !ok {
+		return nil, fmt.Errorf("%w %#04x", ErrUnsupportedDataType, uint16(dt))
+	}
+	if dt.IsArray() {
+		return reflect.SliceOf(t), nil
+	}
+
+	return t, nil
+}
+
+// PropertyValue is a value decoded according to its DataTypeCode. Value holds one of the following Go types depending
+// on the DataType: int8, uint8, int16, uint16, int32, uint32, int64, uint64, Int128 or UInt128 for the integer types,
+// a slice of one of these for the array types and a string for DTC_STR.
+type PropertyValue struct {
+	DataType DataTypeCode
+	Value    interface{}
+}
+
+// NewPropertyValue returns a property value of the given data type. An error wrapping ErrValueTypeMismatch is returned
+// when the Go type of v does not match the data type.
+func NewPropertyValue(dt DataTypeCode, v interface{}) (PropertyValue, error) {
+	t, err := dt.goType()
+	if err != nil {
+		return PropertyValue{}, err
+	}
+	if reflect.TypeOf(v) != t {
+		return PropertyValue{}, fmt.Errorf("%w: got %T; want %s", ErrValueTypeMismatch, v, t)
+	}
+
+	return PropertyValue{DataType: dt, Value: v}, nil
+}
+
+// DecodePropertyValue reads a single value of the given data type from r. Integers are little endian, arrays are
+// prefixed with a 32 bit element count and strings are prefixed with an 8 bit character count, including the null
+// terminator, followed by the UTF-16 characters.
+func DecodePropertyValue(r io.Reader, dt DataTypeCode) (PropertyValue, error) {
+	t, err := dt.goType()
+	if err != nil {
+		return PropertyValue{}, err
+	}
+
+	var v interface{}
+	switch {
+	case dt == DTC_STR:
+		v, err = decodeString(r)
+	case dt.IsArray():
+		v, err = decodeArray(r, t.Elem())
+	default:
+		p := reflect.New(t)
+		err = binary.Read(r, binary.LittleEndian, p.Interface())
+		v = p.Elem().Interface()
+	}
+	if err != nil {
+		return PropertyValue{}, err
+	}
+
+	return PropertyValue{DataType: dt, Value: v}, nil
+}
+
+// UnmarshalPropertyValue decodes a single value of the given data type from b.
+func UnmarshalPropertyValue(b []byte, dt DataTypeCode) (PropertyValue, error) {
+	return DecodePropertyValue(bytes.NewReader(b), dt)
+}
+
+func decodeArray(r io.Reader, t reflect.Type) (interface{}, error) {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+
+	c := int(n)
+	if c > maxArrayPrealloc {
+		c = maxArrayPrealloc
+	}
+	a := reflect.MakeSlice(reflect.SliceOf(t), 0, c)
+	for i := uint32(0); i < n; i++ {
+		p := reflect.New(t)
+		if err := binary.Read(r, binary.LittleEndian, p.Interface()); err != nil {
+			return nil, err
+		}
+		a = reflect.Append(a, p.Elem())
+	}
+
+	return a.Interface(), nil
+}
+
+func decodeString(r io.Reader) (string, error) {
+	var n uint8
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", nil
+	}
+
+	u := make([]uint16, n)
+	if err := binary.Read(r, binary.LittleEndian, u); err != nil {
+		return "", err
+	}
+	// Drop the null terminator and anything a sloppy Responder might have put after it.
+	for i, c := range u {
+		if c == 0 {
+			u = u[:i]
+			break
+		}
+	}
+
+	return string(utf16.Decode(u)), nil
+}
+
+// Encode writes the value to w in the same format DecodePropertyValue reads it.
+func (pv PropertyValue) Encode(w io.Writer) error {
+	t, err := pv.DataType.goType()
+	if err != nil {
+		return err
+	}
+	if reflect.TypeOf(pv.Value) != t {
+		return fmt.Errorf("%w: got %T; want %s", ErrValueTypeMismatch, pv.Value, t)
+	}
+
+	switch {
+	case pv.DataType == DTC_STR:
+		return encodeString(w, pv.Value.(string))
+	case pv.DataType.IsArray():
+		return encodeArray(w, pv.Value)
+	}
+
+	return binary.Write(w, binary.LittleEndian, pv.Value)
+}
+
+// encodeArray writes the slice a prefixed with a 32 bit element count.
+func encodeArray(w io.Writer, a interface{}) error {
+	n := reflect.ValueOf(a).Len()
+	if err := binary.Write(w, binary.LittleEndian, uint32(n)); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	return binary.Write(w, binary.LittleEndian, a)
+}
+
+func encodeString(w io.Writer, s string) error {
+	if s == "" {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+
+	u := append(utf16.Encode([]rune(s)), 0)
+	if len(u) > maxStringChars {
+		return fmt.Errorf("%w: %d characters", ErrStringTooLong, len(u)-1)
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint8(len(u))); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, u)
+}
+
+// MarshalBinary returns the encoded value.
+func (pv PropertyValue) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := pv.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Int64 returns the value of an integer data type as an int64. False is returned for arrays, strings and 128 bit
+// integers that do not fit an int64.
+func (pv PropertyValue) Int64() (int64, bool) {
+	switch v := pv.Value.(type) {
+	case int8:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case Int128:
+		if b := v.Big(); b.IsInt64() {
+			return b.Int64(), true
+		}
+	case UInt128:
+		if b := v.Big(); b.IsInt64() {
+			return b.Int64(), true
+		}
+	}
+
+	return 0, false
+}
+
+// String returns the value formatted using the default Go formatting, e.g. 42, [1 2 3] or the string itself.
+func (pv PropertyValue) String() string {
+	return fmt.Sprint(pv.Value)
+}
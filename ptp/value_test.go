@@ -0,0 +1,248 @@
+package ptp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPropertyValue_RoundTrip(t *testing.T) {
+	check := []struct {
+		dt   DataTypeCode
+		v    interface{}
+		want []byte
+	}{
+		{DTC_INT8, int8(-2), []byte{0xfe}},
+		{DTC_UINT8, uint8(0x12), []byte{0x12}},
+		{DTC_INT16, int16(-3000), []byte{0x48, 0xf4}},
+		{DTC_UINT16, uint16(0x8009), []byte{0x09, 0x80}},
+		{DTC_INT32, int32(-1), []byte{0xff, 0xff, 0xff, 0xff}},
+		{DTC_UINT32, uint32(0x03020702), []byte{0x02, 0x07, 0x02, 0x03}},
+		{DTC_INT64, int64(-2), []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{DTC_UINT64, uint64(1), []byte{0x01, 0, 0, 0, 0, 0, 0, 0}},
+		{
+			DTC_INT128,
+			Int128{Lo: 1, Hi: -1},
+			[]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			DTC_UINT128,
+			UInt128{Lo: 2, Hi: 1},
+			[]byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{DTC_AINT8, []int8{-1, 1}, []byte{0x02, 0, 0, 0, 0xff, 0x01}},
+		{DTC_AUINT8, []uint8{}, []byte{0, 0, 0, 0}},
+		{DTC_AINT16, []int16{-2}, []byte{0x01, 0, 0, 0, 0xfe, 0xff}},
+		{DTC_AUINT16, []uint16{1, 2}, []byte{0x02, 0, 0, 0, 0x01, 0, 0x02, 0}},
+		{DTC_AINT32, []int32{-1}, []byte{0x01, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}},
+		{DTC_AUINT32, []uint32{0x0a0b0c0d}, []byte{0x01, 0, 0, 0, 0x0d, 0x0c, 0x0b, 0x0a}},
+		{DTC_AINT64, []int64{-1}, []byte{0x01, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{DTC_AUINT64, []uint64{1}, []byte{0x01, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0}},
+		{
+			DTC_AINT128,
+			[]Int128{{Lo: 1}},
+			[]byte{0x01, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			DTC_AUINT128,
+			[]UInt128{{Hi: 1}},
+			[]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{DTC_STR, "", []byte{0x00}},
+		{DTC_STR, "Jö", []byte{0x03, 0x4a, 0x00, 0xf6, 0x00, 0x00, 0x00}},
+	}
+
+	for _, c := range check {
+		pv, err := NewPropertyValue(c.dt, c.v)
+		if err != nil {
+			t.Fatalf("NewPropertyValue() error = %s; want <nil>", err)
+		}
+
+		got, err := pv.MarshalBinary()
+		if err != nil {
+			t.Errorf("MarshalBinary() %#x error = %s; want <nil>", c.dt, err)
+			continue
+		}
+		if !bytes.Equal(got, c.want) {
+			t.Errorf("MarshalBinary() %#x got = %#v; want %#v", c.dt, got, c.want)
+		}
+
+		// Trailing data must be left alone.
+		r := bytes.NewReader(append(got, 0xaa))
+		dec, err := DecodePropertyValue(r, c.dt)
+		if err != nil {
+			t.Errorf("DecodePropertyValue() %#x error = %s; want <nil>", c.dt, err)
+			continue
+		}
+		if !reflect.DeepEqual(dec, pv) {
+			t.Errorf("DecodePropertyValue() %#x got = %#v; want %#v", c.dt, dec, pv)
+		}
+		if r.Len() != 1 {
+			t.Errorf("DecodePropertyValue() %#x left %d bytes; want 1", c.dt, r.Len())
+		}
+	}
+}
+
+func TestDecodePropertyValue_StringTerminator(t *testing.T) {
+	// Anything after the null terminator is ignored.
+	got, err := UnmarshalPropertyValue([]byte{0x03, 0x41, 0x00, 0x00, 0x00, 0x42, 0x00}, DTC_STR)
+	if err != nil {
+		t.Fatalf("UnmarshalPropertyValue() error = %s; want <nil>", err)
+	}
+	if got.Value != "A" {
+		t.Errorf("UnmarshalPropertyValue() got = %v; want A", got.Value)
+	}
+}
+
+func TestDecodePropertyValue_Errors(t *testing.T) {
+	if _, err := UnmarshalPropertyValue([]byte{0x01}, DTC_UNDEF); !errors.Is(err, ErrUnsupportedDataType) {
+		t.Errorf("UnmarshalPropertyValue() error = %v; want %s", err, ErrUnsupportedDataType)
+	}
+	if _, err := UnmarshalPropertyValue([]byte{0x01}, DTC_UINT16); err != io.ErrUnexpectedEOF {
+		t.Errorf("UnmarshalPropertyValue() error = %v; want %s", err, io.ErrUnexpectedEOF)
+	}
+	// A huge element count must not be trusted.
+	if _, err := UnmarshalPropertyValue([]byte{0xff, 0xff, 0xff, 0xff, 0x01}, DTC_AUINT8); err != io.EOF {
+		t.Errorf("UnmarshalPropertyValue() error = %v; want %s", err, io.EOF)
+	}
+	if _, err := UnmarshalPropertyValue([]byte{0x05, 0x41, 0x00}, DTC_STR); err != io.ErrUnexpectedEOF {
+		t.Errorf("UnmarshalPropertyValue() error = %v; want %s", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestPropertyValue_EncodeErrors(t *testing.T) {
+	if _, err := NewPropertyValue(DTC_UINT16, 1); !errors.Is(err, ErrValueTypeMismatch) {
+		t.Errorf("NewPropertyValue() error = %v; want %s", err, ErrValueTypeMismatch)
+	}
+	pv := PropertyValue{DataType: DTC_AUINT16, Value: []uint32{1}}
+	if _, err := pv.MarshalBinary(); !errors.Is(err, ErrValueTypeMismatch) {
+		t.Errorf("MarshalBinary() error = %v; want %s", err, ErrValueTypeMismatch)
+	}
+	pv = PropertyValue{DataType: DTC_STR, Value: strings.Repeat("a", 255)}
+	if _, err := pv.MarshalBinary(); !errors.Is(err, ErrStringTooLong) {
+		t.Errorf("MarshalBinary() error = %v; want %s", err, ErrStringTooLong)
+	}
+}
+
+func TestPropertyValue_Int64(t *testing.T) {
+	check := []struct {
+		pv   PropertyValue
+		want int64
+		ok   bool
+	}{
+		{PropertyValue{DTC_INT16, int16(-3000)}, -3000, true},
+		{PropertyValue{DTC_UINT32, uint32(0xffffffff)}, 0xffffffff, true},
+		{PropertyValue{DTC_INT128, Int128{Lo: 0xfffffffffffffffe, Hi: -1}}, -2, true},
+		{PropertyValue{DTC_UINT128, UInt128{Hi: 1}}, 0, false},
+		{PropertyValue{DTC_AUINT8, []uint8{1}}, 0, false},
+		{PropertyValue{DTC_STR, "1"}, 0, false},
+	}
+
+	for _, c := range check {
+		got, ok := c.pv.Int64()
+		if got != c.want || ok != c.ok {
+			t.Errorf("Int64() got = %d, %t; want %d, %t", got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestPropertyValue_String(t *testing.T) {
+	check := map[string]PropertyValue{
+		"42":                   {DTC_UINT8, uint8(42)},
+		"[1 2 3]":              {DTC_AUINT16, []uint16{1, 2, 3}},
+		"Jo":                   {DTC_STR, "Jo"},
+		"18446744073709551616": {DTC_UINT128, UInt128{Hi: 1}},
+		"-1":                   {DTC_INT128, Int128{Lo: 0xffffffffffffffff, Hi: -1}},
+	}
+
+	for want, pv := range check {
+		if got := pv.String(); got != want {
+			t.Errorf("String() got = %s; want %s", got, want)
+		}
+	}
+}
+
+func TestReadDevicePropDesc(t *testing.T) {
+	// DPC_Artist: string, get/set, factory default "", current "Jo", enumeration form holding "A" and "Jo".
+	b := []byte{
+		0x1e, 0x50, 0xff, 0xff, 0x01,
+		0x00,
+		0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00,
+		0x02,
+		0x02, 0x00,
+		0x02, 0x41, 0x00, 0x00, 0x00,
+		0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00,
+	}
+
+	got, err := ReadDevicePropDesc(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadDevicePropDesc() error = %s; want <nil>", err)
+	}
+
+	want := &DevicePropDesc{
+		DevicePropertyCode:  DPC_Artist,
+		DataType:            DTC_STR,
+		GetSet:              DPD_GetSet,
+		FactoryDefaultValue: []byte{0x00},
+		CurrentValue:        []byte{0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00},
+		FormFlag:            DPF_FormFlag_Enum,
+		Form: &EnumerationForm{
+			NumberOfValues: 2,
+			SupportedValues: [][]byte{
+				{0x02, 0x41, 0x00, 0x00, 0x00},
+				{0x03, 0x4a, 0x00, 0x6f, 0x00, 0x00, 0x00},
+			},
+		},
+	}
+	want.Form.SetDevicePropDesc(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDevicePropDesc() got = %#v; want %#v", got, want)
+	}
+
+	vals, err := got.Form.(*EnumerationForm).Values()
+	if err != nil {
+		t.Fatalf("Values() error = %s; want <nil>", err)
+	}
+	if len(vals) != 2 || vals[0].Value != "A" || vals[1].Value != "Jo" {
+		t.Errorf("Values() got = %v; want [A Jo]", vals)
+	}
+
+	if _, err := ReadDevicePropDesc(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("ReadDevicePropDesc() error = %v; want %s", err, io.EOF)
+	}
+}
+
+func TestReadDevicePropDesc_Range(t *testing.T) {
+	// A uint128 range from 0 to 2^64 in steps of 1.
+	b := []byte{0x00, 0xd0, 0x0a, 0x00, 0x00}
+	b = append(b, make([]byte, 32)...)
+	b = append(b, byte(DPF_FormFlag_Range))
+	b = append(b, make([]byte, 16)...)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0)
+	b = append(b, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	got, err := ReadDevicePropDesc(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadDevicePropDesc() error = %s; want <nil>", err)
+	}
+
+	rf := got.Form.(*RangeForm)
+	max, err := rf.Maximum()
+	if err != nil {
+		t.Fatalf("Maximum() error = %s; want <nil>", err)
+	}
+	if max.Value != (UInt128{Hi: 1}) {
+		t.Errorf("Maximum() got = %v; want %v", max.Value, UInt128{Hi: 1})
+	}
+	step, err := rf.Step()
+	if err != nil {
+		t.Fatalf("Step() error = %s; want <nil>", err)
+	}
+	if v, _ := step.Int64(); v != 1 {
+		t.Errorf("Step() got = %d; want 1", v)
+	}
+}
@@ -17,6 +17,7 @@ const (
 	VE_FotoNationInc           VendorExtension = 0x0000000C
 	VE_PENTAXCorporation       VendorExtension = 0x0000000D
 	VE_FujiPhotoFilmCoLtd      VendorExtension = 0x0000000E
+	VE_SonyCorporation         VendorExtension = 0x00000011
 	VE_NddMedicalTechnologies  VendorExtension = 0x00000012
 	VE_SamsungElectronicsCoLtd VendorExtension = 0x0000001A
 	VE_ParrotDronesSAS         VendorExtension = 0x0000001B
@@ -53,6 +54,8 @@ func VendorStringToType(vendor string) VendorExtension {
 		return VE_PENTAXCorporation
 	case "fuji":
 		return VE_FujiPhotoFilmCoLtd
+	case "sony":
+		return VE_SonyCorporation
 	case "ndd":
 		return VE_NddMedicalTechnologies
 	case "samsung":
@@ -18,6 +18,7 @@ func TestVendorStringToType(t *testing.T) {
 		"fn":        VE_FotoNationInc,
 		"pentax":    VE_PENTAXCorporation,
 		"fuji":      VE_FujiPhotoFilmCoLtd,
+		"sony":      VE_SonyCorporation,
 		"ndd":       VE_NddMedicalTechnologies,
 		"samsung":   VE_SamsungElectronicsCoLtd,
 		"parrot":    VE_ParrotDronesSAS,
@@ -1,6 +1,7 @@
 package viewfinder
 
 import (
+	"fmt"
 	ptpfmt "github.com/malc0mn/ptp-ip/fmt"
 	"github.com/malc0mn/ptp-ip/ip"
 	"github.com/malc0mn/ptp-ip/ptp"
@@ -10,8 +11,12 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// FujiXT1Layout is the name the Fuji X-T1 viewfinder layout is registered under.
+const FujiXT1Layout = "fuji-x-t1"
+
 // NewFujiXT1Viewfinder returns a new Fuji X-T1 viewfinder containing a Widget list mimicking the real viewfinder.
 // The image is needed for the widgets to calibrate their origin so they can render in their own designated place.
 func NewFujiXT1Viewfinder(img *image.RGBA) *Viewfinder {
@@ -27,6 +32,7 @@ func NewFujiXT1Viewfinder(img *image.RGBA) *Viewfinder {
 			ptp.DPC_FNumber:                  NewFujiFNumberWidget(img),
 			ip.DPC_Fuji_ImageAspectRatio:     NewFujiImageSizeWidget(img),
 			ip.DPC_Fuji_ImageQuality:         NewFujiImageQualityWidget(img),
+			ip.DPC_Fuji_MovieRemainingTime:   NewFujiMovieRemainingTimeWidget(img),
 			ptp.DPC_WhiteBalance:             NewFujiWhiteBalanceWidget(img),
 		},
 	}
@@ -408,3 +414,27 @@ func drawFujiWhiteBalance(w *Widget, val int64) {
 
 	w.DrawString(icon)
 }
+
+func NewFujiMovieRemainingTimeWidget(img *image.RGBA) *Widget {
+	// Calculate starting position.
+	x := float64(img.Bounds().Min.X) + (float64(img.Bounds().Max.X) * 0.4)
+	y := 18
+
+	w := NewFontWidget(img, 255, 255, 255, int(x), y)
+	w.Draw = drawFujiMovieRemainingTime
+
+	return w
+}
+
+func drawFujiMovieRemainingTime(w *Widget, val int64) {
+	w.ResetToOrigin()
+
+	w.DrawString(formatMovieTime(time.Duration(val) * time.Second))
+}
+
+// formatMovieTime formats the given duration as minutes and seconds.
+func formatMovieTime(d time.Duration) string {
+	s := int64(d / time.Second)
+
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
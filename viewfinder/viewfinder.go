@@ -25,6 +25,31 @@ func (vf *Viewfinder) DrawWidget(img *image.RGBA, code ptp.DevicePropCode, val i
 	}
 }
 
+// LayoutFunc defines the signature of a function creating a viewfinder for a given layout.
+type LayoutFunc func(img *image.RGBA) *Viewfinder
+
+// layouts holds the registered viewfinder layouts mapped to their name.
+var layouts = map[string]LayoutFunc{
+	FujiXT1Layout: NewFujiXT1Viewfinder,
+}
+
+// RegisterLayout registers a viewfinder layout under the given name, replacing any layout registered under that name.
+// The name is what a camera model refers to, e.g. the Viewfinder field of ip.FujiProfile.
+// This function is not safe for concurrent use and is meant to be called from an init() function.
+func RegisterLayout(name string, f LayoutFunc) {
+	layouts[name] = f
+}
+
+// NewViewfinderForLayout creates the viewfinder using the layout registered under the given name. When there is no
+// such layout, the vendor specific viewfinder is created using NewViewfinder().
+func NewViewfinderForLayout(img *image.RGBA, v ptp.VendorExtension, layout string) *Viewfinder {
+	if f, ok := layouts[layout]; ok {
+		return f(img)
+	}
+
+	return NewViewfinder(img, v)
+}
+
 // NewViewfinder creates a vendor specific viewfinder using the image passed in to allow each Widget to calibrate its
 // starting position.
 // When the vendor has no viewfinder defined, nothing will happen.